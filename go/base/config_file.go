@@ -0,0 +1,368 @@
+/*
+   Copyright 2022 GitHub Inc.
+	 See https://github.com/github/gh-ost/blob/master/LICENSE
+*/
+
+package base
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/github/gh-ost/go/mysql"
+
+	"github.com/go-ini/ini"
+	"gopkg.in/yaml.v3"
+)
+
+// ReloadableFlags are the flags which are reapplied from the config file upon SIGHUP
+var ReloadableFlags = []string{
+	"chunk-size",
+	"dml-batch-size",
+	"nice-ratio",
+	"max-lag-millis",
+	"max-load",
+	"critical-load",
+	"throttle-query",
+	"throttle-http",
+	"throttle-control-replicas",
+}
+
+// ConfigFileSection holds flag values which only apply to hosts, or tables, matching its pattern
+type ConfigFileSection struct {
+	Pattern string
+	Values  map[string]string
+}
+
+// ConfigFileSettings is what a --conf file holds: credentials, flag values by flag name, and
+// sections of flag values which apply to given hosts and tables only
+type ConfigFileSettings struct {
+	Client  map[string]string
+	General map[string]string
+	Hosts   []*ConfigFileSection
+	Tables  []*ConfigFileSection
+}
+
+func newConfigFileSettings() *ConfigFileSettings {
+	return &ConfigFileSettings{
+		Client:  map[string]string{},
+		General: map[string]string{},
+	}
+}
+
+// configFileKey normalizes a config file key into a flag name: `chunk_size` and `chunk-size` both
+// name --chunk-size
+func configFileKey(key string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(key)), "_", "-")
+}
+
+// ReadConfigFileSettings reads a config file: YAML when named *.yaml or *.yml, INI otherwise.
+func ReadConfigFileSettings(configFile string) (*ConfigFileSettings, error) {
+	switch strings.ToLower(filepath.Ext(configFile)) {
+	case ".yaml", ".yml":
+		return readYAMLConfigFile(configFile)
+	default:
+		return readINIConfigFile(configFile)
+	}
+}
+
+// readINIConfigFile reads an INI config file, such as:
+//
+//	[client]
+//	user=gromit
+//	[osc]
+//	chunk_size=500
+//	[host db1.example.com]
+//	max-lag-millis=3000
+//	[table mydb.orders]
+//	nice-ratio=1
+//
+// Other sections, as found in a my.cnf file, are ignored.
+func readINIConfigFile(configFile string) (*ConfigFileSettings, error) {
+	// Inline comments are not recognized: flags such as --throttle-schedule hold semicolons
+	cfg, err := ini.LoadSources(ini.LoadOptions{IgnoreInlineComment: true}, configFile)
+	if err != nil {
+		return nil, err
+	}
+	sectionValues := func(section *ini.Section) map[string]string {
+		values := map[string]string{}
+		for _, key := range section.Keys() {
+			values[configFileKey(key.Name())] = key.String()
+		}
+		return values
+	}
+
+	settings := newConfigFileSettings()
+	for _, section := range cfg.Sections() {
+		kind, pattern, _ := strings.Cut(strings.TrimSpace(section.Name()), " ")
+		pattern = strings.TrimSpace(pattern)
+		switch kind {
+		case "client":
+			settings.Client = sectionValues(section)
+		case "osc":
+			settings.General = sectionValues(section)
+		case "host":
+			settings.Hosts = append(settings.Hosts, &ConfigFileSection{Pattern: pattern, Values: sectionValues(section)})
+		case "table":
+			settings.Tables = append(settings.Tables, &ConfigFileSection{Pattern: pattern, Values: sectionValues(section)})
+		}
+	}
+	return settings, settings.validate()
+}
+
+// readYAMLConfigFile reads a YAML config file, such as:
+//
+//	client:
+//	  user: gromit
+//	osc:
+//	  chunk-size: 500
+//	hosts:
+//	  db1.example.com:
+//	    max-lag-millis: 3000
+//	tables:
+//	  mydb.orders:
+//	    nice-ratio: 1
+func readYAMLConfigFile(configFile string) (*ConfigFileSettings, error) {
+	file, err := os.Open(configFile)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var document struct {
+		Client map[string]interface{} `yaml:"client"`
+		Osc    map[string]interface{} `yaml:"osc"`
+		// Sections are nodes, such that they keep their order
+		Hosts  yaml.Node `yaml:"hosts"`
+		Tables yaml.Node `yaml:"tables"`
+	}
+	decoder := yaml.NewDecoder(file)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&document); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("Error parsing config file %s: %+v", configFile, err)
+	}
+
+	settings := newConfigFileSettings()
+	settings.Client = yamlValues(document.Client)
+	settings.General = yamlValues(document.Osc)
+	if settings.Hosts, err = yamlSections(&document.Hosts); err != nil {
+		return nil, fmt.Errorf("Error parsing hosts in config file %s: %+v", configFile, err)
+	}
+	if settings.Tables, err = yamlSections(&document.Tables); err != nil {
+		return nil, fmt.Errorf("Error parsing tables in config file %s: %+v", configFile, err)
+	}
+	return settings, settings.validate()
+}
+
+func yamlValues(document map[string]interface{}) map[string]string {
+	values := map[string]string{}
+	for key, value := range document {
+		switch value := value.(type) {
+		case nil:
+			values[configFileKey(key)] = ""
+		case []interface{}:
+			// Lists, such as of throttle control replicas, are comma delimited
+			tokens := []string{}
+			for _, token := range value {
+				tokens = append(tokens, fmt.Sprint(token))
+			}
+			values[configFileKey(key)] = strings.Join(tokens, ",")
+		default:
+			values[configFileKey(key)] = fmt.Sprint(value)
+		}
+	}
+	return values
+}
+
+func yamlSections(node *yaml.Node) (sections []*ConfigFileSection, err error) {
+	if node.Kind == 0 {
+		return sections, nil
+	}
+	if node.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("expected a mapping of patterns to flag values")
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		var document map[string]interface{}
+		if err := node.Content[i+1].Decode(&document); err != nil {
+			return nil, err
+		}
+		sections = append(sections, &ConfigFileSection{Pattern: node.Content[i].Value, Values: yamlValues(document)})
+	}
+	return sections, nil
+}
+
+func (this *ConfigFileSettings) validate() error {
+	for _, sections := range [][]*ConfigFileSection{this.Hosts, this.Tables} {
+		for _, section := range sections {
+			if section.Pattern == "" {
+				return fmt.Errorf("Config file section has no host or table pattern")
+			}
+			if _, err := path.Match(section.Pattern, ""); err != nil {
+				return fmt.Errorf("Config file section pattern %q: %+v", section.Pattern, err)
+			}
+		}
+	}
+	return nil
+}
+
+// matchesHost returns true when a host section's pattern matches the given hostname, with or
+// without port
+func (this *ConfigFileSection) matchesHost(instanceKey mysql.InstanceKey) bool {
+	if instanceKey.Hostname == "" {
+		return false
+	}
+	for _, name := range []string{instanceKey.Hostname, instanceKey.StringCode()} {
+		if matched, _ := path.Match(this.Pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesTable returns true when a table section's pattern matches `database.table`
+func (this *ConfigFileSection) matchesTable(databaseName, tableName string) bool {
+	if databaseName == "" || tableName == "" {
+		return false
+	}
+	matched, _ := path.Match(this.Pattern, fmt.Sprintf("%s.%s", databaseName, tableName))
+	return matched
+}
+
+// FlagValues returns the flag values which apply to a migration of the given table on the given
+// host. Host sections override the general section, and table sections override host sections.
+// Where several host, or table, sections match, those further down the file win.
+func (this *ConfigFileSettings) FlagValues(instanceKey mysql.InstanceKey, databaseName, tableName string) map[string]string {
+	flagValues := map[string]string{}
+	for name, value := range this.General {
+		flagValues[name] = value
+	}
+	for _, section := range this.Hosts {
+		if section.matchesHost(instanceKey) {
+			for name, value := range section.Values {
+				flagValues[name] = value
+			}
+		}
+	}
+	for _, section := range this.Tables {
+		if section.matchesTable(databaseName, tableName) {
+			for name, value := range section.Values {
+				flagValues[name] = value
+			}
+		}
+	}
+	return flagValues
+}
+
+// GetConfigFileSettings returns the settings last read off the config file, or nil when there is none
+func (this *MigrationContext) GetConfigFileSettings() *ConfigFileSettings {
+	this.configMutex.Lock()
+	defer this.configMutex.Unlock()
+
+	return this.configFileSettings
+}
+
+// GetConfigFileFlagValues returns the config file's flag values which apply to this migration
+func (this *MigrationContext) GetConfigFileFlagValues() map[string]string {
+	settings := this.GetConfigFileSettings()
+	if settings == nil {
+		return map[string]string{}
+	}
+	return settings.FlagValues(this.InspectorConnectionConfig.Key, this.DatabaseName, this.OriginalTableName)
+}
+
+// getReloadableFlag returns the current value of a reloadable flag, in the same format it is set in
+func (this *MigrationContext) getReloadableFlag(name string) string {
+	switch name {
+	case "chunk-size":
+		return strconv.FormatInt(atomic.LoadInt64(&this.ChunkSize), 10)
+	case "dml-batch-size":
+		return strconv.FormatInt(atomic.LoadInt64(&this.DMLBatchSize), 10)
+	case "nice-ratio":
+		return strconv.FormatFloat(this.GetNiceRatio(), 'f', -1, 64)
+	case "max-lag-millis":
+		return strconv.FormatInt(atomic.LoadInt64(&this.MaxLagMillisecondsThrottleThreshold), 10)
+	case "max-load":
+		maxLoad := this.GetMaxLoad()
+		return maxLoad.String()
+	case "critical-load":
+		criticalLoad := this.GetCriticalLoad()
+		return criticalLoad.String()
+	case "throttle-query":
+		return this.GetThrottleQuery()
+	case "throttle-http":
+		return this.GetThrottleHTTP()
+	case "throttle-control-replicas":
+		replicas := strings.Split(this.GetThrottleControlReplicaKeys().ToCommaDelimitedList(), ",")
+		sort.Strings(replicas)
+		return strings.Join(replicas, ",")
+	}
+	return ""
+}
+
+// setReloadableFlag applies a reloadable flag, as do the interactive commands of the same name
+func (this *MigrationContext) setReloadableFlag(name, value string) error {
+	switch name {
+	case "chunk-size", "dml-batch-size", "max-lag-millis":
+		number, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("Error parsing %s: %+v", name, err)
+		}
+		switch name {
+		case "chunk-size":
+			this.SetChunkSize(number)
+		case "dml-batch-size":
+			this.SetDMLBatchSize(number)
+		case "max-lag-millis":
+			this.SetMaxLagMillisecondsThrottleThreshold(number)
+		}
+	case "nice-ratio":
+		niceRatio, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("Error parsing %s: %+v", name, err)
+		}
+		this.SetNiceRatio(niceRatio)
+	case "max-load":
+		return this.ReadMaxLoad(value)
+	case "critical-load":
+		return this.ReadCriticalLoad(value)
+	case "throttle-query":
+		this.SetThrottleQuery(value)
+	case "throttle-http":
+		this.SetThrottleHTTP(value)
+	case "throttle-control-replicas":
+		return this.ReadThrottleControlReplicaKeys(value)
+	}
+	return nil
+}
+
+// ReloadConfigFile rereads the config file and reapplies the reloadable flags it sets, overriding
+// their command line or interactive values. It returns the changes made, as `name: old -> new`.
+// When a value fails to apply, those before it remain applied.
+func (this *MigrationContext) ReloadConfigFile() (changes []string, err error) {
+	if err := this.ReadConfigFile(); err != nil {
+		return changes, err
+	}
+	flagValues := this.GetConfigFileFlagValues()
+	for _, name := range ReloadableFlags {
+		value, ok := flagValues[name]
+		if !ok {
+			continue
+		}
+		previous := this.getReloadableFlag(name)
+		if err := this.setReloadableFlag(name, value); err != nil {
+			return changes, err
+		}
+		if current := this.getReloadableFlag(name); current != previous {
+			changes = append(changes, fmt.Sprintf("%s: %q -> %q", name, previous, current))
+		}
+	}
+	return changes, nil
+}
@@ -0,0 +1,144 @@
+/*
+   Copyright 2022 GitHub Inc.
+	 See https://github.com/github/gh-ost/blob/master/LICENSE
+*/
+
+package base
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/github/gh-ost/go/mysql"
+)
+
+func writeTestConfigFile(t *testing.T, name, content string) string {
+	configFile := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(configFile, []byte(content), 0600))
+	return configFile
+}
+
+func TestReadConfigFileSettings(t *testing.T) {
+	expectFlagValues := func(t *testing.T, settings *ConfigFileSettings) {
+		require.Equal(t, "gromit", settings.Client["user"])
+		require.Equal(t, map[string]string{
+			"chunk-size":        "500",
+			"max-load":          "Threads_running=30",
+			"throttle-schedule": "mon-fri 08:00-20:00 nice-ratio=1; sat-sun 00:00-24:00 chunk-size=5000",
+		}, settings.FlagValues(mysql.InstanceKey{Hostname: "db9.example.com", Port: 3306}, "mydb", "mytable"))
+
+		flagValues := settings.FlagValues(mysql.InstanceKey{Hostname: "db1.example.com", Port: 3306}, "mydb", "mytable")
+		require.Equal(t, "3000", flagValues["max-lag-millis"])
+		require.Equal(t, "200", flagValues["chunk-size"])
+		require.Equal(t, "db2.example.com:3306,db3.example.com:3306", flagValues["throttle-control-replicas"])
+
+		flagValues = settings.FlagValues(mysql.InstanceKey{Hostname: "db1.example.com", Port: 3306}, "mydb", "orders")
+		require.Equal(t, "100", flagValues["chunk-size"])
+		require.Equal(t, "1", flagValues["nice-ratio"])
+		require.Equal(t, "3000", flagValues["max-lag-millis"])
+	}
+
+	t.Run("ini", func(t *testing.T) {
+		settings, err := ReadConfigFileSettings(writeTestConfigFile(t, "gh-ost.cnf", `
+[client]
+user=gromit
+
+[mysqld]
+max_connections=100
+
+[osc]
+chunk_size=500
+max-load=Threads_running=30
+throttle-schedule=mon-fri 08:00-20:00 nice-ratio=1; sat-sun 00:00-24:00 chunk-size=5000
+
+[host db1.example.com]
+max-lag-millis=3000
+throttle-control-replicas=db2.example.com:3306,db3.example.com:3306
+
+[host db1.example.com:3306]
+chunk-size=200
+
+[table mydb.orders]
+chunk-size=100
+nice-ratio=1
+`))
+		require.NoError(t, err)
+		expectFlagValues(t, settings)
+	})
+
+	t.Run("yaml", func(t *testing.T) {
+		settings, err := ReadConfigFileSettings(writeTestConfigFile(t, "gh-ost.yaml", `
+client:
+  user: gromit
+osc:
+  chunk_size: 500
+  max-load: Threads_running=30
+  throttle-schedule: "mon-fri 08:00-20:00 nice-ratio=1; sat-sun 00:00-24:00 chunk-size=5000"
+hosts:
+  db1.*:
+    max-lag-millis: 3000
+    throttle-control-replicas:
+      - db2.example.com:3306
+      - db3.example.com:3306
+  "db1.*:3306":
+    chunk-size: 200
+tables:
+  "*.orders":
+    chunk-size: 100
+    nice-ratio: 1
+`))
+		require.NoError(t, err)
+		expectFlagValues(t, settings)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := ReadConfigFileSettings(writeTestConfigFile(t, "gh-ost.yml", "osc:\n  chunk-size: 500\nunknown: 1\n"))
+		require.Error(t, err)
+		_, err = ReadConfigFileSettings(writeTestConfigFile(t, "gh-ost.yml", "hosts:\n  - db1.example.com\n"))
+		require.Error(t, err)
+		_, err = ReadConfigFileSettings(writeTestConfigFile(t, "gh-ost.cnf", "[table [mydb.orders]\nchunk-size=100\n"))
+		require.Error(t, err)
+	})
+}
+
+func TestReloadConfigFile(t *testing.T) {
+	context := NewMigrationContext()
+	context.InspectorConnectionConfig.Key = mysql.InstanceKey{Hostname: "db1.example.com", Port: 3306}
+	context.DatabaseName = "mydb"
+	context.OriginalTableName = "orders"
+	context.SetChunkSize(1000)
+	context.SetNiceRatio(0.5)
+	context.ConfigFile = writeTestConfigFile(t, "gh-ost.cnf", `
+[osc]
+chunk-size=500
+nice-ratio=0.5
+max-load=Threads_running=30
+alter=ENGINE=InnoDB
+
+[table mydb.orders]
+dml-batch-size=50
+throttle-control-replicas=db3.example.com,db2.example.com
+`)
+	changes, err := context.ReloadConfigFile()
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		`chunk-size: "1000" -> "500"`,
+		`dml-batch-size: "10" -> "50"`,
+		`max-load: "" -> "Threads_running=30"`,
+		`throttle-control-replicas: "" -> "db2.example.com:3306,db3.example.com:3306"`,
+	}, changes)
+	require.Equal(t, int64(500), context.ChunkSize)
+	require.Equal(t, int64(50), context.DMLBatchSize)
+
+	changes, err = context.ReloadConfigFile()
+	require.NoError(t, err)
+	require.Empty(t, changes)
+
+	require.NoError(t, os.WriteFile(context.ConfigFile, []byte("[osc]\nchunk-size=600\nmax-load=Threads_running\n"), 0600))
+	changes, err = context.ReloadConfigFile()
+	require.Error(t, err)
+	require.Equal(t, []string{`chunk-size: "500" -> "600"`}, changes)
+}
@@ -21,8 +21,6 @@ import (
 	"github.com/github/gh-ost/go/mysql"
 	"github.com/github/gh-ost/go/sql"
 	"github.com/openark/golib/log"
-
-	"github.com/go-ini/ini"
 )
 
 // RowsEstimateMethod is the type of row number estimation
@@ -39,8 +37,71 @@ type CutOver int
 const (
 	CutOverAtomic CutOver = iota
 	CutOverTwoStep
+	CutOverLockAndRename
+	// CutOverAuto picks CutOverLockAndRename where the applier supports it, and CutOverAtomic otherwise
+	CutOverAuto
+	// CutOverCrossServer moves the table onto the --target-host server; it is not given by flag, but
+	// is what a cross-server move resolves to
+	CutOverCrossServer
+)
+
+// String returns the cut-over type as named by the --cut-over flag
+func (this CutOver) String() string {
+	switch this {
+	case CutOverAtomic:
+		return "atomic"
+	case CutOverTwoStep:
+		return "two-step"
+	case CutOverLockAndRename:
+		return "lock-and-rename"
+	case CutOverCrossServer:
+		return "cross-server"
+	default:
+		return "default"
+	}
+}
+
+// MigrationPhase is the phase a migration is in, as found in structured log records
+type MigrationPhase string
+
+const (
+	StartupPhase    MigrationPhase = "startup"
+	InspectionPhase MigrationPhase = "inspection"
+	RowCopyPhase    MigrationPhase = "row-copy"
+	CutOverPhase    MigrationPhase = "cut-over"
+	CleanupPhase    MigrationPhase = "cleanup"
+	DonePhase       MigrationPhase = "done"
+)
+
+// CutOverBlockersPolicy is what gh-ost does about sessions blocking the cut-over
+type CutOverBlockersPolicy int
+
+const (
+	CutOverBlockersLog CutOverBlockersPolicy = iota
+	CutOverBlockersKill
+	CutOverBlockersPostpone
+)
+
+// ReplicationCluster is the kind of multi-source cluster which the inspected server is a member of, if any
+type ReplicationCluster int
+
+const (
+	NoReplicationCluster ReplicationCluster = iota
+	GroupReplicationCluster
+	GaleraCluster
 )
 
+func (this ReplicationCluster) String() string {
+	switch this {
+	case GroupReplicationCluster:
+		return "group-replication"
+	case GaleraCluster:
+		return "galera"
+	default:
+		return "none"
+	}
+}
+
 type ThrottleReasonHint string
 
 const (
@@ -53,12 +114,27 @@ const (
 	HTTPStatusOK       = 200
 	MaxEventsBatchSize = 1000
 	ETAUnknown         = math.MinInt64
+	// MaxThrottleHistory is how many of the latest throttling changes are kept
+	MaxThrottleHistory = 20
 )
 
+// ThrottleEvent is a change in throttling: throttling starting, its reason changing, or it ending
+type ThrottleEvent struct {
+	Time      time.Time
+	Throttled bool
+	Reason    string
+}
+
 var (
 	envVariableRegexp = regexp.MustCompile("[$][{](.*)[}]")
 )
 
+// pendingApplyEventTime counts the pending events written to the binary log at a given second
+type pendingApplyEventTime struct {
+	unixTime int64
+	events   int64
+}
+
 type ThrottleCheckResult struct {
 	ShouldThrottle bool
 	Reason         string
@@ -103,59 +179,99 @@ type MigrationContext struct {
 	GoogleCloudPlatform      bool
 	AzureMySQL               bool
 	AttemptInstantDDL        bool
+	ReplicationCluster       ReplicationCluster
 
 	// SkipPortValidation allows skipping the port validation in `ValidateConnection`
 	// This is useful when connecting to a MySQL instance where the external port
 	// may not match the internal port.
 	SkipPortValidation bool
 
-	config            ContextConfig
-	configMutex       *sync.Mutex
-	ConfigFile        string
-	CliUser           string
-	CliPassword       string
-	UseTLS            bool
-	TLSAllowInsecure  bool
-	TLSCACertificate  string
-	TLSCertificate    string
-	TLSKey            string
-	CliMasterUser     string
-	CliMasterPassword string
-
-	HeartbeatIntervalMilliseconds       int64
-	defaultNumRetries                   int64
-	ChunkSize                           int64
-	niceRatio                           float64
-	MaxLagMillisecondsThrottleThreshold int64
-	throttleControlReplicaKeys          *mysql.InstanceKeyMap
-	ThrottleFlagFile                    string
-	ThrottleAdditionalFlagFile          string
-	throttleQuery                       string
-	throttleHTTP                        string
-	IgnoreHTTPErrors                    bool
-	ThrottleCommandedByUser             int64
-	HibernateUntil                      int64
-	maxLoad                             LoadMap
-	criticalLoad                        LoadMap
-	CriticalLoadIntervalMilliseconds    int64
-	CriticalLoadHibernateSeconds        int64
-	PostponeCutOverFlagFile             string
-	CutOverLockTimeoutSeconds           int64
-	CutOverExponentialBackoff           bool
-	ExponentialBackoffMaxInterval       int64
-	ForceNamedCutOverCommand            bool
-	ForceNamedPanicCommand              bool
-	PanicFlagFile                       string
-	HooksPath                           string
-	HooksHintMessage                    string
-	HooksHintOwner                      string
-	HooksHintToken                      string
-	HooksStatusIntervalSec              int64
-	PanicOnWarnings                     bool
+	config             ContextConfig
+	configFileSettings *ConfigFileSettings
+	configMutex        *sync.Mutex
+	ConfigFile         string
+	DefaultsFile       string
+	LoginPath          string
+	CredentialHelper   string
+	optionFileOptions  mysql.Options
+	CliUser            string
+	CliPassword        string
+	UseTLS             bool
+	TLSAllowInsecure   bool
+	TLSCACertificate   string
+	TLSCertificate     string
+	TLSKey             string
+	CliMasterUser      string
+	CliMasterPassword  string
+	CliTargetUser      string
+	CliTargetPassword  string
+
+	HeartbeatIntervalMilliseconds                   int64
+	defaultNumRetries                               int64
+	ChunkSize                                       int64
+	niceRatio                                       float64
+	MaxLagMillisecondsThrottleThreshold             int64
+	MaxApplyLagMillisecondsThrottleThreshold        int64
+	throttleControlReplicaKeys                      *mysql.InstanceKeyMap
+	discoveredThrottleControlReplicaKeys            *mysql.InstanceKeyMap
+	DiscoverThrottleControlReplicas                 bool
+	ThrottleControlReplicasDiscoveryIntervalSeconds int64
+	ThrottleControlReplicasInclude                  *regexp.Regexp
+	ThrottleControlReplicasExclude                  *regexp.Regexp
+	ThrottleFlagFile                                string
+	ThrottleAdditionalFlagFile                      string
+	throttleQuery                                   string
+	throttleHTTP                                    string
+	IgnoreHTTPErrors                                bool
+	ThrottleCommandedByUser                         int64
+	HibernateUntil                                  int64
+	maxLoad                                         LoadMap
+	maxInnoDBMetrics                                LoadMap
+	MaxGroupReplicationQueue                        int64
+	MaxGaleraRecvQueue                              int64
+	MaxGaleraFlowControlPaused                      float64
+	FreeDiskSpaceQuery                              string
+	MinFreeDiskSpaceMB                              int64
+	CriticalFreeDiskSpaceMB                         int64
+	BinlogRetentionGuardSeconds                     int64
+	BinlogRetentionOverrideForcedThrottle           bool
+	criticalLoad                                    LoadMap
+	CriticalLoadIntervalMilliseconds                int64
+	CriticalLoadHibernateSeconds                    int64
+	ThrottleScheduleTimezone                        string
+	throttleSchedule                                *ThrottleSchedule
+	activeThrottleScheduleEntry                     *ThrottleScheduleEntry
+	PostponeCutOverFlagFile                         string
+	CutOverWindowTimezone                           string
+	cutOverWindow                                   *Schedule
+	cutOverDeadline                                 time.Time
+	cutOverScheduleMutex                            *sync.Mutex
+	CutOverLockTimeoutSeconds                       int64
+	CutOverExponentialBackoff                       bool
+	ExponentialBackoffMaxInterval                   int64
+	ForceNamedCutOverCommand                        bool
+	ForceNamedPanicCommand                          bool
+	PanicFlagFile                                   string
+	HooksPath                                       string
+	HooksHintMessage                                string
+	HooksHintOwner                                  string
+	HooksHintToken                                  string
+	HooksStatusIntervalSec                          int64
+	HookWebhooks                                    []*HookWebhook
+	PanicOnWarnings                                 bool
 
 	DropServeSocket bool
 	ServeSocketFile string
 	ServeTCPPort    int64
+	// ServeTCPTLSCertFile and ServeTCPTLSKeyFile have the TCP interface serve over TLS, and
+	// ServeTCPTLSCAFile verifies client certificates
+	ServeTCPTLSCertFile string
+	ServeTCPTLSKeyFile  string
+	ServeTCPTLSCAFile   string
+	ServeTCPAuthFile    string
+	// ServeTCPAuth, when set, requires TCP clients to authenticate, and gates commands by role
+	ServeTCPAuth      *ServerAuth
+	ServeAuditLogFile string
 
 	Noop                         bool
 	TestOnReplica                bool
@@ -166,10 +282,21 @@ type MigrationContext struct {
 	InitiallyDropGhostTable      bool
 	TimestampOldTable            bool // Should old table name include a timestamp
 	CutOverType                  CutOver
+	CutOverBlockersPolicy        CutOverBlockersPolicy
+	CutOverBlockersMinAgeSeconds int64
 	ReplicaServerId              uint
 
 	Hostname                               string
 	AssumeMasterHostname                   string
+	TargetHostname                         string
+	TargetSkipRename                       bool
+	CDCExportSink                          string
+	CDCCheckpointFile                      string
+	ShadowVerifyIntervalSeconds            int64
+	ShadowVerifySampleSize                 int64
+	ShadowVerifyMaxMismatches              int64
+	DMLBacklogSpillDir                     string
+	DMLBacklogSpillMaxMB                   int64
 	ApplierTimeZone                        string
 	ApplierWaitTimeout                     int64
 	TableEngine                            string
@@ -183,6 +310,8 @@ type MigrationContext struct {
 	InspectorMySQLVersion                  string
 	ApplierConnectionConfig                *mysql.ConnectionConfig
 	ApplierMySQLVersion                    string
+	TargetConnectionConfig                 *mysql.ConnectionConfig
+	TargetMySQLVersion                     string
 	StartTime                              time.Time
 	RowCopyStartTime                       time.Time
 	RowCopyEndTime                         time.Time
@@ -201,15 +330,25 @@ type MigrationContext struct {
 	ThrottleHTTPStatusCode                 int64
 	ThrottleHTTPTimeoutMillis              int64
 	controlReplicasLagResult               mysql.ReplicationLagResult
+	controlReplicasLagResults              []mysql.ReplicationLagResult
 	TotalRowsCopied                        int64
 	TotalDMLEventsApplied                  int64
+	ShadowVerifiedRowsCount                int64
+	ShadowVerifyMismatchesCount            int64
+	BinlogSecondsUntilPurge                int64 // -1 when the binary log being read is not expected to be purged
+	BinlogRetentionGuardBreached           int64
+	FreeDiskSpaceBytes                     int64 // -1 when unknown
+	GhostTableSizeEstimate                 int64
 	DMLBatchSize                           int64
 	isThrottled                            bool
 	throttleReason                         string
 	throttleReasonHint                     ThrottleReasonHint
 	throttleGeneralCheckResult             ThrottleCheckResult
+	throttleHistory                        []ThrottleEvent
 	throttleMutex                          *sync.Mutex
 	throttleHTTPMutex                      *sync.Mutex
+	pendingApplyEventTimes                 []pendingApplyEventTime
+	applyLagMutex                          *sync.Mutex
 	IsPostponingCutOver                    int64
 	CountingRowsFlag                       int64
 	AllEventsUpToLockProcessedInjectedFlag int64
@@ -218,6 +357,9 @@ type MigrationContext struct {
 	CutOverCompleteFlag                    int64
 	InCutOverCriticalSectionFlag           int64
 	PanicAbort                             chan error
+	// panicAbortClosed is closed once PanicAbort is no longer listened on, as the migration aborts or is over
+	panicAbortClosed    chan struct{}
+	panicAbortCloseOnce *sync.Once
 
 	OriginalTableColumnsOnApplier    *sql.ColumnList
 	OriginalTableColumns             *sql.ColumnList
@@ -230,6 +372,7 @@ type MigrationContext struct {
 	UniqueKey                        *sql.UniqueKey
 	SharedColumns                    *sql.ColumnList
 	ColumnRenameMap                  map[string]string
+	ColumnExpressions                map[string]string
 	DroppedColumnsMap                map[string]bool
 	MappedSharedColumns              *sql.ColumnList
 	MigrationLastInsertSQLWarnings   []string
@@ -240,6 +383,11 @@ type MigrationContext struct {
 	MigrationIterationRangeMaxValues *sql.ColumnValues
 	ForceTmpTableName                string
 
+	// currentChunkRange is the range of the chunk being copied, as printed, which is safe to read
+	// while the applier iterates
+	currentChunkRange      [2]string
+	currentChunkRangeMutex *sync.Mutex
+
 	IncludeTriggers     bool
 	RemoveTriggerSuffix bool
 	TriggerSuffix       string
@@ -249,7 +397,11 @@ type MigrationContext struct {
 
 	BinlogSyncerMaxReconnectAttempts int
 
-	Log Logger
+	phase      MigrationPhase
+	phaseMutex *sync.Mutex
+
+	Log      Logger
+	Observer MigrationObserver
 }
 
 type Logger interface {
@@ -269,41 +421,62 @@ type Logger interface {
 	SetPrintStackTrace(printStackTraceFlag bool)
 }
 
+// LogFields are the fields of a structured log record
+type LogFields map[string]interface{}
+
+// ComponentLogger is a Logger which tags its records with the component of gh-ost logging them
+type ComponentLogger interface {
+	Logger
+	WithComponent(component string) Logger
+}
+
+// FieldsLogger is a Logger which logs fields as such, rather than as part of a text message
+type FieldsLogger interface {
+	Logger
+	InfoFields(message string, fields LogFields)
+}
+
 type ContextConfig struct {
 	Client struct {
 		User     string
 		Password string
 	}
-	Osc struct {
-		Chunk_Size            int64
-		Max_Lag_Millis        int64
-		Replication_Lag_Query string
-		Max_Load              string
-	}
 }
 
 func NewMigrationContext() *MigrationContext {
 	return &MigrationContext{
-		Uuid:                                uuid.NewString(),
-		defaultNumRetries:                   60,
-		ChunkSize:                           1000,
-		InspectorConnectionConfig:           mysql.NewConnectionConfig(),
-		ApplierConnectionConfig:             mysql.NewConnectionConfig(),
-		MaxLagMillisecondsThrottleThreshold: 1500,
-		CutOverLockTimeoutSeconds:           3,
-		DMLBatchSize:                        10,
-		etaNanoseonds:                       ETAUnknown,
-		maxLoad:                             NewLoadMap(),
-		criticalLoad:                        NewLoadMap(),
-		throttleMutex:                       &sync.Mutex{},
-		throttleHTTPMutex:                   &sync.Mutex{},
-		throttleControlReplicaKeys:          mysql.NewInstanceKeyMap(),
-		configMutex:                         &sync.Mutex{},
-		pointOfInterestTimeMutex:            &sync.Mutex{},
-		lastHeartbeatOnChangelogMutex:       &sync.Mutex{},
-		ColumnRenameMap:                     make(map[string]string),
-		PanicAbort:                          make(chan error),
-		Log:                                 NewDefaultLogger(),
+		Uuid:                                 uuid.NewString(),
+		defaultNumRetries:                    60,
+		ChunkSize:                            1000,
+		InspectorConnectionConfig:            mysql.NewConnectionConfig(),
+		ApplierConnectionConfig:              mysql.NewConnectionConfig(),
+		MaxLagMillisecondsThrottleThreshold:  1500,
+		BinlogSecondsUntilPurge:              -1,
+		FreeDiskSpaceBytes:                   -1,
+		CutOverLockTimeoutSeconds:            3,
+		DMLBatchSize:                         10,
+		etaNanoseonds:                        ETAUnknown,
+		maxLoad:                              NewLoadMap(),
+		maxInnoDBMetrics:                     NewLoadMap(),
+		criticalLoad:                         NewLoadMap(),
+		throttleMutex:                        &sync.Mutex{},
+		applyLagMutex:                        &sync.Mutex{},
+		throttleHTTPMutex:                    &sync.Mutex{},
+		throttleControlReplicaKeys:           mysql.NewInstanceKeyMap(),
+		discoveredThrottleControlReplicaKeys: mysql.NewInstanceKeyMap(),
+		configMutex:                          &sync.Mutex{},
+		cutOverScheduleMutex:                 &sync.Mutex{},
+		pointOfInterestTimeMutex:             &sync.Mutex{},
+		lastHeartbeatOnChangelogMutex:        &sync.Mutex{},
+		phase:                                StartupPhase,
+		phaseMutex:                           &sync.Mutex{},
+		currentChunkRangeMutex:               &sync.Mutex{},
+		ColumnRenameMap:                      make(map[string]string),
+		ColumnExpressions:                    make(map[string]string),
+		PanicAbort:                           make(chan error),
+		panicAbortClosed:                     make(chan struct{}),
+		panicAbortCloseOnce:                  &sync.Once{},
+		Log:                                  NewDefaultLogger(),
 	}
 }
 
@@ -410,6 +583,29 @@ func (this *MigrationContext) GetInspectorHostname() string {
 	return this.InspectorConnectionConfig.ImpliedKey.Hostname
 }
 
+// GetTargetHostname is a safe access method to the target hostname of a cross-server move
+func (this *MigrationContext) GetTargetHostname() string {
+	if this.TargetConnectionConfig == nil {
+		return ""
+	}
+	if this.TargetConnectionConfig.ImpliedKey == nil {
+		return ""
+	}
+	return this.TargetConnectionConfig.ImpliedKey.Hostname
+}
+
+// IsCrossServerMove is `true` when the ghost table is to be created on a server other
+// than the applier, via `--target-host`. The migration then moves the table onto that server.
+func (this *MigrationContext) IsCrossServerMove() bool {
+	return this.TargetHostname != ""
+}
+
+// IsCDCExport is `true` when gh-ost runs in change data capture export mode, via `--cdc-export`.
+// Rather than migrating the table, gh-ost then streams the table's changes onto a sink.
+func (this *MigrationContext) IsCDCExport() bool {
+	return this.CDCExportSink != ""
+}
+
 // InspectorIsAlsoApplier is `true` when the both inspector and applier are the
 // same database instance. This would be true when running directly on master or when
 // testing on replica.
@@ -548,6 +744,68 @@ func (this *MigrationContext) GetCurrentLagDuration() time.Duration {
 	return time.Duration(atomic.LoadInt64(&this.CurrentLag))
 }
 
+// AddPendingApplyEvent records the binlog timestamp of an event queued to be applied onto the ghost
+// table. Events are recorded, and applied, in binlog order.
+func (this *MigrationContext) AddPendingApplyEvent(timestamp time.Time) {
+	this.applyLagMutex.Lock()
+	defer this.applyLagMutex.Unlock()
+
+	unixTime := timestamp.Unix()
+	if count := len(this.pendingApplyEventTimes); count > 0 && this.pendingApplyEventTimes[count-1].unixTime == unixTime {
+		this.pendingApplyEventTimes[count-1].events++
+		return
+	}
+	this.pendingApplyEventTimes = append(this.pendingApplyEventTimes, pendingApplyEventTime{unixTime: unixTime, events: 1})
+}
+
+// RemoveAppliedEvents forgets the given number of oldest pending events, which are now applied
+func (this *MigrationContext) RemoveAppliedEvents(events int64) {
+	this.applyLagMutex.Lock()
+	defer this.applyLagMutex.Unlock()
+
+	for events > 0 && len(this.pendingApplyEventTimes) > 0 {
+		oldest := &this.pendingApplyEventTimes[0]
+		if oldest.events > events {
+			oldest.events -= events
+			return
+		}
+		events -= oldest.events
+		this.pendingApplyEventTimes = this.pendingApplyEventTimes[1:]
+	}
+}
+
+// GetApplyLag returns the age of the oldest event read off the binary log and not yet applied onto
+// the ghost table, or zero when there is none
+func (this *MigrationContext) GetApplyLag() time.Duration {
+	this.applyLagMutex.Lock()
+	defer this.applyLagMutex.Unlock()
+
+	if len(this.pendingApplyEventTimes) == 0 {
+		return 0
+	}
+	lag := time.Since(time.Unix(this.pendingApplyEventTimes[0].unixTime, 0))
+	if lag < 0 {
+		return 0
+	}
+	return lag
+}
+
+// IsApplyLagExceeded returns true when --max-apply-lag-millis is set and the apply lag exceeds it
+func (this *MigrationContext) IsApplyLagExceeded() bool {
+	maxApplyLagMillis := atomic.LoadInt64(&this.MaxApplyLagMillisecondsThrottleThreshold)
+	if maxApplyLagMillis <= 0 {
+		return false
+	}
+	return this.GetApplyLag() > time.Duration(maxApplyLagMillis)*time.Millisecond
+}
+
+// IsRowCopyPaused returns true while a `pause` entry of the throttle schedule applies. Row copy then
+// waits, while binary log events are still applied.
+func (this *MigrationContext) IsRowCopyPaused() bool {
+	entry := this.GetActiveThrottleScheduleEntry()
+	return entry != nil && entry.Pause
+}
+
 func (this *MigrationContext) GetProgressPct() float64 {
 	return math.Float64frombits(atomic.LoadUint64(&this.currentProgress))
 }
@@ -630,6 +888,17 @@ func (this *MigrationContext) SetMaxLagMillisecondsThrottleThreshold(maxLagMilli
 	atomic.StoreInt64(&this.MaxLagMillisecondsThrottleThreshold, maxLagMillisecondsThrottleThreshold)
 }
 
+// SetMaxApplyLagMillisecondsThrottleThreshold sets --max-apply-lag-millis; 0 disables
+func (this *MigrationContext) SetMaxApplyLagMillisecondsThrottleThreshold(maxApplyLagMillisecondsThrottleThreshold int64) {
+	if maxApplyLagMillisecondsThrottleThreshold < 0 {
+		maxApplyLagMillisecondsThrottleThreshold = 0
+	} else if maxApplyLagMillisecondsThrottleThreshold > 0 && maxApplyLagMillisecondsThrottleThreshold < 1000 {
+		// Binary log timestamps have a resolution of one second
+		maxApplyLagMillisecondsThrottleThreshold = 1000
+	}
+	atomic.StoreInt64(&this.MaxApplyLagMillisecondsThrottleThreshold, maxApplyLagMillisecondsThrottleThreshold)
+}
+
 func (this *MigrationContext) SetChunkSize(chunkSize int64) {
 	if chunkSize < 10 {
 		chunkSize = 10
@@ -664,12 +933,85 @@ func (this *MigrationContext) GetThrottleGeneralCheckResult() *ThrottleCheckResu
 	return &result
 }
 
+// SendPanicAbort requests the migration to abort. Once PanicAbort is closed, the request is
+// dropped rather than left blocking forever.
+func (this *MigrationContext) SendPanicAbort(err error) {
+	select {
+	case this.PanicAbort <- err:
+	case <-this.panicAbortClosed:
+	}
+}
+
+// ClosePanicAbort tells PanicAbort is no longer listened on: the migration aborts, or is over
+func (this *MigrationContext) ClosePanicAbort() {
+	this.panicAbortCloseOnce.Do(func() {
+		close(this.panicAbortClosed)
+	})
+}
+
+// IsPanicAbortClosed returns true once the migration aborts, or is over
+func (this *MigrationContext) IsPanicAbortClosed() bool {
+	select {
+	case <-this.panicAbortClosed:
+		return true
+	default:
+		return false
+	}
+}
+
 func (this *MigrationContext) SetThrottled(throttle bool, reason string, reasonHint ThrottleReasonHint) {
 	this.throttleMutex.Lock()
-	defer this.throttleMutex.Unlock()
+	changed := throttle != this.isThrottled || (throttle && reason != this.throttleReason)
 	this.isThrottled = throttle
 	this.throttleReason = reason
 	this.throttleReasonHint = reasonHint
+	if changed {
+		this.throttleHistory = append(this.throttleHistory, ThrottleEvent{Time: time.Now(), Throttled: throttle, Reason: reason})
+		if len(this.throttleHistory) > MaxThrottleHistory {
+			this.throttleHistory = this.throttleHistory[len(this.throttleHistory)-MaxThrottleHistory:]
+		}
+	}
+	this.throttleMutex.Unlock()
+
+	if changed && this.Observer != nil {
+		this.Observer.OnThrottle(throttle, reason)
+	}
+}
+
+// GetThrottleHistory returns the latest changes in throttling, oldest first
+func (this *MigrationContext) GetThrottleHistory() []ThrottleEvent {
+	this.throttleMutex.Lock()
+	defer this.throttleMutex.Unlock()
+
+	return append([]ThrottleEvent{}, this.throttleHistory...)
+}
+
+// GetPhase returns the phase the migration is in
+func (this *MigrationContext) GetPhase() MigrationPhase {
+	this.phaseMutex.Lock()
+	defer this.phaseMutex.Unlock()
+
+	return this.phase
+}
+
+func (this *MigrationContext) SetPhase(phase MigrationPhase) {
+	this.phaseMutex.Lock()
+	changed := phase != this.phase
+	this.phase = phase
+	this.phaseMutex.Unlock()
+
+	if changed && this.Observer != nil {
+		this.Observer.OnPhase(phase)
+	}
+}
+
+// ComponentLog returns the logger a component of gh-ost, such as the applier, logs with: one which
+// tags records with the component, where Log supports that, or else Log itself
+func (this *MigrationContext) ComponentLog(component string) Logger {
+	if componentLogger, ok := this.Log.(ComponentLogger); ok {
+		return componentLogger.WithComponent(component)
+	}
+	return this.Log
 }
 
 func (this *MigrationContext) IsThrottled() (bool, string, ThrottleReasonHint) {
@@ -732,6 +1074,13 @@ func (this *MigrationContext) GetMaxLoad() LoadMap {
 	return this.maxLoad.Duplicate()
 }
 
+func (this *MigrationContext) GetMaxInnoDBMetrics() LoadMap {
+	this.throttleMutex.Lock()
+	defer this.throttleMutex.Unlock()
+
+	return this.maxInnoDBMetrics.Duplicate()
+}
+
 func (this *MigrationContext) GetCriticalLoad() LoadMap {
 	this.throttleMutex.Lock()
 	defer this.throttleMutex.Unlock()
@@ -787,6 +1136,21 @@ func (this *MigrationContext) ReadMaxLoad(maxLoadList string) error {
 	return nil
 }
 
+// ReadMaxInnoDBMetrics parses the `--max-innodb-metrics` flag, which is in multiple key-value format,
+// such as: 'trx_rseg_history_len=1000000,dirty_pages_pct=75'
+// It only applies changes in case there's no parsing error.
+func (this *MigrationContext) ReadMaxInnoDBMetrics(maxInnoDBMetricsList string) error {
+	loadMap, err := ParseLoadMap(maxInnoDBMetricsList)
+	if err != nil {
+		return err
+	}
+	this.throttleMutex.Lock()
+	defer this.throttleMutex.Unlock()
+
+	this.maxInnoDBMetrics = loadMap
+	return nil
+}
+
 // ReadCriticalLoad parses the `--max-load` flag, which is in multiple key-value format,
 // such as: 'Threads_running=100,Threads_connected=500'
 // It only applies changes in case there's no parsing error.
@@ -802,6 +1166,139 @@ func (this *MigrationContext) ReadCriticalLoad(criticalLoadList string) error {
 	return nil
 }
 
+// ReadHookWebhooks parses the `--hooks-webhooks` flag
+func (this *MigrationContext) ReadHookWebhooks(hookWebhooksSpec string) (err error) {
+	this.HookWebhooks, err = ParseHookWebhooks(hookWebhooksSpec)
+	return err
+}
+
+// ReadThrottleSchedule parses the `--throttle-schedule` flag, in the `--throttle-schedule-timezone`
+// time zone. An empty value removes the schedule.
+// It only applies changes in case there's no parsing error.
+func (this *MigrationContext) ReadThrottleSchedule(throttleScheduleSpec string) error {
+	var throttleSchedule *ThrottleSchedule
+	if strings.TrimSpace(throttleScheduleSpec) != "" {
+		location, err := time.LoadLocation(this.ThrottleScheduleTimezone)
+		if err != nil {
+			return err
+		}
+		if throttleSchedule, err = ParseThrottleSchedule(throttleScheduleSpec, location); err != nil {
+			return err
+		}
+	}
+	this.throttleMutex.Lock()
+	defer this.throttleMutex.Unlock()
+
+	this.throttleSchedule = throttleSchedule
+	return nil
+}
+
+func (this *MigrationContext) GetThrottleSchedule() *ThrottleSchedule {
+	this.throttleMutex.Lock()
+	defer this.throttleMutex.Unlock()
+
+	return this.throttleSchedule
+}
+
+// GetActiveThrottleScheduleEntry returns the throttle schedule entry currently in effect, if any
+func (this *MigrationContext) GetActiveThrottleScheduleEntry() *ThrottleScheduleEntry {
+	this.throttleMutex.Lock()
+	defer this.throttleMutex.Unlock()
+
+	return this.activeThrottleScheduleEntry
+}
+
+func (this *MigrationContext) SetActiveThrottleScheduleEntry(entry *ThrottleScheduleEntry) {
+	this.throttleMutex.Lock()
+	defer this.throttleMutex.Unlock()
+
+	this.activeThrottleScheduleEntry = entry
+}
+
+// ReadCutOverWindow parses the `--cut-over-window` flag, a schedule such as
+// 'mon-fri 01:00-05:00,sat-sun 00:00-24:00', in the `--cut-over-window-timezone` time zone.
+// An empty value removes the window, allowing cut-over at any time.
+// It only applies changes in case there's no parsing error.
+func (this *MigrationContext) ReadCutOverWindow(cutOverWindow string) error {
+	var schedule *Schedule
+	if strings.TrimSpace(cutOverWindow) != "" {
+		location, err := time.LoadLocation(this.CutOverWindowTimezone)
+		if err != nil {
+			return err
+		}
+		if schedule, err = ParseSchedule(cutOverWindow, location); err != nil {
+			return err
+		}
+	}
+	this.cutOverScheduleMutex.Lock()
+	defer this.cutOverScheduleMutex.Unlock()
+
+	this.cutOverWindow = schedule
+	return nil
+}
+
+// GetCutOverWindow returns the schedule within which cut-over is allowed, or nil when cut-over is
+// allowed at any time
+func (this *MigrationContext) GetCutOverWindow() *Schedule {
+	this.cutOverScheduleMutex.Lock()
+	defer this.cutOverScheduleMutex.Unlock()
+
+	return this.cutOverWindow
+}
+
+// ReadCutOverDeadline parses the `--cut-over-deadline` flag, either an absolute time in RFC3339
+// format, such as '2022-05-17T06:00:00Z', or a duration from now, such as '36h'.
+// An empty value removes the deadline.
+// It only applies changes in case there's no parsing error.
+func (this *MigrationContext) ReadCutOverDeadline(cutOverDeadline string) error {
+	var deadline time.Time
+	if cutOverDeadline = strings.TrimSpace(cutOverDeadline); cutOverDeadline != "" {
+		if duration, err := time.ParseDuration(cutOverDeadline); err == nil {
+			deadline = time.Now().Add(duration)
+		} else if deadline, err = time.Parse(time.RFC3339, cutOverDeadline); err != nil {
+			return fmt.Errorf("Invalid cut-over deadline: %q. Expected RFC3339 time (e.g. 2022-05-17T06:00:00Z) or duration (e.g. 36h)", cutOverDeadline)
+		}
+	}
+	this.cutOverScheduleMutex.Lock()
+	defer this.cutOverScheduleMutex.Unlock()
+
+	this.cutOverDeadline = deadline
+	return nil
+}
+
+// GetCutOverDeadline returns the time by which cut-over must complete, or the zero time when there
+// is no deadline
+func (this *MigrationContext) GetCutOverDeadline() time.Time {
+	this.cutOverScheduleMutex.Lock()
+	defer this.cutOverScheduleMutex.Unlock()
+
+	return this.cutOverDeadline
+}
+
+// ReadColumnExpressions parses the `--column-expression` flag, which is a semicolon delimited list of
+// ghost column assignments, such as: "full_name=concat(first, ' ', last);email=lower(email)"
+// It only applies changes in case there's no parsing error.
+func (this *MigrationContext) ReadColumnExpressions(columnExpressionsList string) error {
+	columnExpressions := make(map[string]string)
+	for _, token := range strings.Split(columnExpressionsList, ";") {
+		if strings.TrimSpace(token) == "" {
+			continue
+		}
+		columnName, expression, found := strings.Cut(token, "=")
+		columnName = strings.Trim(strings.TrimSpace(columnName), "`")
+		expression = strings.TrimSpace(expression)
+		if !found || columnName == "" || expression == "" {
+			return fmt.Errorf("Invalid column expression: %q. Expected format is column=expression", token)
+		}
+		if _, ok := columnExpressions[columnName]; ok {
+			return fmt.Errorf("Column expression given more than once for column %s", columnName)
+		}
+		columnExpressions[columnName] = expression
+	}
+	this.ColumnExpressions = columnExpressions
+	return nil
+}
+
 func (this *MigrationContext) GetControlReplicasLagResult() mysql.ReplicationLagResult {
 	this.throttleMutex.Lock()
 	defer this.throttleMutex.Unlock()
@@ -810,6 +1307,37 @@ func (this *MigrationContext) GetControlReplicasLagResult() mysql.ReplicationLag
 	return lagResult
 }
 
+// GetControlReplicasLagResults returns the lag of each throttle control replica, as last read
+func (this *MigrationContext) GetControlReplicasLagResults() []mysql.ReplicationLagResult {
+	this.throttleMutex.Lock()
+	defer this.throttleMutex.Unlock()
+
+	return append([]mysql.ReplicationLagResult{}, this.controlReplicasLagResults...)
+}
+
+func (this *MigrationContext) SetControlReplicasLagResults(lagResults []mysql.ReplicationLagResult) {
+	this.throttleMutex.Lock()
+	defer this.throttleMutex.Unlock()
+
+	this.controlReplicasLagResults = lagResults
+}
+
+// GetCurrentChunkRange returns the range of the chunk being copied, comma delimited values of the
+// unique key columns; empty before row copy
+func (this *MigrationContext) GetCurrentChunkRange() (minValues string, maxValues string) {
+	this.currentChunkRangeMutex.Lock()
+	defer this.currentChunkRangeMutex.Unlock()
+
+	return this.currentChunkRange[0], this.currentChunkRange[1]
+}
+
+func (this *MigrationContext) SetCurrentChunkRange(minValues, maxValues *sql.ColumnValues) {
+	this.currentChunkRangeMutex.Lock()
+	defer this.currentChunkRangeMutex.Unlock()
+
+	this.currentChunkRange = [2]string{minValues.String(), maxValues.String()}
+}
+
 func (this *MigrationContext) SetControlReplicasLagResult(lagResult *mysql.ReplicationLagResult) {
 	this.throttleMutex.Lock()
 	defer this.throttleMutex.Unlock()
@@ -820,15 +1348,49 @@ func (this *MigrationContext) SetControlReplicasLagResult(lagResult *mysql.Repli
 	}
 }
 
+// GetThrottleControlReplicaKeys returns the throttle control replicas, both those listed and those discovered
 func (this *MigrationContext) GetThrottleControlReplicaKeys() *mysql.InstanceKeyMap {
 	this.throttleMutex.Lock()
 	defer this.throttleMutex.Unlock()
 
 	keys := mysql.NewInstanceKeyMap()
 	keys.AddKeys(this.throttleControlReplicaKeys.GetInstanceKeys())
+	keys.AddKeys(this.discoveredThrottleControlReplicaKeys.GetInstanceKeys())
+	return keys
+}
+
+func (this *MigrationContext) GetDiscoveredThrottleControlReplicaKeys() *mysql.InstanceKeyMap {
+	this.throttleMutex.Lock()
+	defer this.throttleMutex.Unlock()
+
+	keys := mysql.NewInstanceKeyMap()
+	keys.AddKeys(this.discoveredThrottleControlReplicaKeys.GetInstanceKeys())
 	return keys
 }
 
+// SetDiscoveredThrottleControlReplicaKeys replaces the previously discovered throttle control replicas
+func (this *MigrationContext) SetDiscoveredThrottleControlReplicaKeys(keys *mysql.InstanceKeyMap) {
+	discoveredKeys := mysql.NewInstanceKeyMap()
+	discoveredKeys.AddKeys(keys.GetInstanceKeys())
+
+	this.throttleMutex.Lock()
+	defer this.throttleMutex.Unlock()
+
+	this.discoveredThrottleControlReplicaKeys = discoveredKeys
+}
+
+// IsThrottleControlReplicaExcluded checks whether a discovered replica is excluded from the throttle
+// control replicas, along with its own replicas
+func (this *MigrationContext) IsThrottleControlReplicaExcluded(key mysql.InstanceKey) bool {
+	return this.ThrottleControlReplicasExclude != nil && this.ThrottleControlReplicasExclude.MatchString(key.StringCode())
+}
+
+// IsThrottleControlReplicaIncluded checks whether a discovered replica, which is not excluded, is to be
+// checked for lag
+func (this *MigrationContext) IsThrottleControlReplicaIncluded(key mysql.InstanceKey) bool {
+	return this.ThrottleControlReplicasInclude == nil || this.ThrottleControlReplicasInclude.MatchString(key.StringCode())
+}
+
 func (this *MigrationContext) ReadThrottleControlReplicaKeys(throttleControlReplicas string) error {
 	keys := mysql.NewInstanceKeyMap()
 	if err := keys.ReadCommaDelimitedList(throttleControlReplicas); err != nil {
@@ -850,11 +1412,49 @@ func (this *MigrationContext) AddThrottleControlReplicaKey(key mysql.InstanceKey
 	return nil
 }
 
-// ApplyCredentials sorts out the credentials between the config file and the CLI flags
+// ReadOptionFiles reads credentials off the MySQL option file given by --defaults-file, and off the
+// login path given by --login-path, which overrides it
+func (this *MigrationContext) ReadOptionFiles() error {
+	options := mysql.Options{}
+	if this.DefaultsFile != "" {
+		defaultsFileOptions, err := mysql.ReadOptionFile(this.DefaultsFile, mysql.OptionFileGroups...)
+		if err != nil {
+			return err
+		}
+		for name, value := range defaultsFileOptions {
+			options[name] = value
+		}
+	}
+	if this.LoginPath != "" {
+		loginPathOptions, err := mysql.ReadLoginPath(mysql.DefaultLoginPathFile(), this.LoginPath)
+		if err != nil {
+			return err
+		}
+		for name, value := range loginPathOptions {
+			options[name] = value
+		}
+	}
+
+	this.configMutex.Lock()
+	defer this.configMutex.Unlock()
+
+	this.optionFileOptions = options
+	return nil
+}
+
+// ApplyCredentials sorts out the credentials between MySQL option files, the config file and the
+// CLI flags, in increasing precedence. A credential helper, if given, overrides them all whenever
+// connecting.
 func (this *MigrationContext) ApplyCredentials() {
 	this.configMutex.Lock()
 	defer this.configMutex.Unlock()
 
+	if user := this.optionFileOptions["user"]; user != "" {
+		this.InspectorConnectionConfig.User = user
+	}
+	if password, ok := this.optionFileOptions["password"]; ok {
+		this.InspectorConnectionConfig.Password = password
+	}
 	if this.config.Client.User != "" {
 		this.InspectorConnectionConfig.User = this.config.Client.User
 	}
@@ -869,6 +1469,7 @@ func (this *MigrationContext) ApplyCredentials() {
 		// Override
 		this.InspectorConnectionConfig.Password = this.CliPassword
 	}
+	this.InspectorConnectionConfig.CredentialHelper = this.CredentialHelper
 }
 
 func (this *MigrationContext) SetupTLS() error {
@@ -878,47 +1479,26 @@ func (this *MigrationContext) SetupTLS() error {
 	return nil
 }
 
-// ReadConfigFile attempts to read the config file, if it exists
+// ReadConfigFile attempts to read the config file, if it exists. Flag values it holds are
+// applied by the caller; see GetConfigFileFlagValues.
 func (this *MigrationContext) ReadConfigFile() error {
-	this.configMutex.Lock()
-	defer this.configMutex.Unlock()
-
 	if this.ConfigFile == "" {
 		return nil
 	}
-	cfg, err := ini.Load(this.ConfigFile)
+	settings, err := ReadConfigFileSettings(this.ConfigFile)
 	if err != nil {
 		return err
 	}
 
-	if cfg.Section("client").HasKey("user") {
-		this.config.Client.User = cfg.Section("client").Key("user").String()
-	}
-
-	if cfg.Section("client").HasKey("password") {
-		this.config.Client.Password = cfg.Section("client").Key("password").String()
-	}
-
-	if cfg.Section("osc").HasKey("chunk_size") {
-		this.config.Osc.Chunk_Size, err = cfg.Section("osc").Key("chunk_size").Int64()
-		if err != nil {
-			return fmt.Errorf("Unable to read osc chunk size: %w", err)
-		}
-	}
-
-	if cfg.Section("osc").HasKey("max_load") {
-		this.config.Osc.Max_Load = cfg.Section("osc").Key("max_load").String()
-	}
+	this.configMutex.Lock()
+	defer this.configMutex.Unlock()
 
-	if cfg.Section("osc").HasKey("replication_lag_query") {
-		this.config.Osc.Replication_Lag_Query = cfg.Section("osc").Key("replication_lag_query").String()
+	this.configFileSettings = settings
+	if user, ok := settings.Client["user"]; ok {
+		this.config.Client.User = user
 	}
-
-	if cfg.Section("osc").HasKey("max_lag_millis") {
-		this.config.Osc.Max_Lag_Millis, err = cfg.Section("osc").Key("max_lag_millis").Int64()
-		if err != nil {
-			return fmt.Errorf("Unable to read max lag millis: %w", err)
-		}
+	if password, ok := settings.Client["password"]; ok {
+		this.config.Client.Password = password
 	}
 
 	// We accept user & password in the form "${SOME_ENV_VARIABLE}" in which case we pull
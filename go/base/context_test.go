@@ -6,11 +6,16 @@
 package base
 
 import (
+	"errors"
 	"os"
+	"path/filepath"
+	"regexp"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/github/gh-ost/go/mysql"
+	"github.com/github/gh-ost/go/sql"
 	"github.com/openark/golib/log"
 	"github.com/stretchr/testify/require"
 )
@@ -177,8 +182,243 @@ func TestReadConfigFile(t *testing.T) {
 			t.Fatalf(".ReadConfigFile() failed: %v", err)
 		}
 
-		if context.config.Osc.Max_Load != "10" {
-			t.Fatalf("Expected osc 'max_load' %q, got %q", "10", context.config.Osc.Max_Load)
+		if maxLoad := context.GetConfigFileFlagValues()["max-load"]; maxLoad != "10" {
+			t.Fatalf("Expected osc 'max_load' %q, got %q", "10", maxLoad)
 		}
 	}
 }
+
+func TestApplyCredentials(t *testing.T) {
+	defaultsFile := filepath.Join(t.TempDir(), "my.cnf")
+	require.NoError(t, os.WriteFile(defaultsFile, []byte("[client]\nuser=gromit\npassword=cheese\n"), 0600))
+	configFile := filepath.Join(t.TempDir(), "gh-ost.cnf")
+	require.NoError(t, os.WriteFile(configFile, []byte("[client]\npassword=crackers\n"), 0600))
+
+	context := NewMigrationContext()
+	context.DefaultsFile = defaultsFile
+	require.NoError(t, context.ReadOptionFiles())
+	context.ApplyCredentials()
+	require.Equal(t, "gromit", context.InspectorConnectionConfig.User)
+	require.Equal(t, "cheese", context.InspectorConnectionConfig.Password)
+
+	context.ConfigFile = configFile
+	require.NoError(t, context.ReadConfigFile())
+	context.ApplyCredentials()
+	require.Equal(t, "gromit", context.InspectorConnectionConfig.User)
+	require.Equal(t, "crackers", context.InspectorConnectionConfig.Password)
+
+	context.CliUser = "wallace"
+	context.CredentialHelper = "vault-mysql-credentials"
+	context.ApplyCredentials()
+	require.Equal(t, "wallace", context.InspectorConnectionConfig.User)
+	require.Equal(t, "crackers", context.InspectorConnectionConfig.Password)
+	require.Equal(t, "vault-mysql-credentials", context.InspectorConnectionConfig.CredentialHelper)
+
+	context.DefaultsFile = filepath.Join(t.TempDir(), "missing.cnf")
+	require.Error(t, context.ReadOptionFiles())
+}
+
+func TestReadColumnExpressions(t *testing.T) {
+	{
+		context := NewMigrationContext()
+		require.NoError(t, context.ReadColumnExpressions(""))
+		require.Len(t, context.ColumnExpressions, 0)
+	}
+	{
+		context := NewMigrationContext()
+		require.NoError(t, context.ReadColumnExpressions("full_name=concat(first, ' ', last); `email` = lower(email);"))
+		require.Equal(t, map[string]string{
+			"full_name": "concat(first, ' ', last)",
+			"email":     "lower(email)",
+		}, context.ColumnExpressions)
+	}
+	{
+		context := NewMigrationContext()
+		require.NoError(t, context.ReadColumnExpressions("is_active=status='active'"))
+		require.Equal(t, "status='active'", context.ColumnExpressions["is_active"])
+	}
+	{
+		context := NewMigrationContext()
+		require.Error(t, context.ReadColumnExpressions("full_name"))
+		require.Error(t, context.ReadColumnExpressions("=lower(email)"))
+		require.Error(t, context.ReadColumnExpressions("email=lower(email);email=upper(email)"))
+	}
+}
+
+func TestIsCrossServerMove(t *testing.T) {
+	context := NewMigrationContext()
+	require.False(t, context.IsCrossServerMove())
+	require.Equal(t, "", context.GetTargetHostname())
+
+	context.TargetHostname = "shard2.example.com:3306"
+	require.True(t, context.IsCrossServerMove())
+	context.TargetConnectionConfig = context.InspectorConnectionConfig.DuplicateCredentials(mysql.InstanceKey{Hostname: "shard2.example.com", Port: 3306})
+	require.Equal(t, "shard2.example.com", context.GetTargetHostname())
+}
+
+func TestReadCutOverWindow(t *testing.T) {
+	context := NewMigrationContext()
+	require.Nil(t, context.GetCutOverWindow())
+
+	context.CutOverWindowTimezone = "UTC"
+	require.NoError(t, context.ReadCutOverWindow("mon-fri 01:00-05:00"))
+	require.Equal(t, "mon-fri 01:00-05:00 (UTC)", context.GetCutOverWindow().String())
+
+	// Parsing errors leave the window as is
+	require.Error(t, context.ReadCutOverWindow("mon-fri"))
+	require.NotNil(t, context.GetCutOverWindow())
+
+	require.NoError(t, context.ReadCutOverWindow(""))
+	require.Nil(t, context.GetCutOverWindow())
+
+	context.CutOverWindowTimezone = "Not/A_Timezone"
+	require.Error(t, context.ReadCutOverWindow("mon-fri 01:00-05:00"))
+}
+
+func TestReadCutOverDeadline(t *testing.T) {
+	context := NewMigrationContext()
+	require.True(t, context.GetCutOverDeadline().IsZero())
+
+	require.NoError(t, context.ReadCutOverDeadline("2022-05-17T06:00:00Z"))
+	require.Equal(t, time.Date(2022, 5, 17, 6, 0, 0, 0, time.UTC), context.GetCutOverDeadline().UTC())
+
+	require.NoError(t, context.ReadCutOverDeadline("36h"))
+	require.WithinDuration(t, time.Now().Add(36*time.Hour), context.GetCutOverDeadline(), time.Minute)
+
+	require.Error(t, context.ReadCutOverDeadline("tomorrow"))
+	require.False(t, context.GetCutOverDeadline().IsZero())
+
+	require.NoError(t, context.ReadCutOverDeadline(""))
+	require.True(t, context.GetCutOverDeadline().IsZero())
+}
+
+func TestDiscoveredThrottleControlReplicaKeys(t *testing.T) {
+	context := NewMigrationContext()
+	require.NoError(t, context.ReadThrottleControlReplicaKeys("replica1.example.com:3306"))
+
+	discoveredKeys := mysql.NewInstanceKeyMap()
+	discoveredKeys.AddKey(mysql.InstanceKey{Hostname: "replica2.example.com", Port: 3306})
+	context.SetDiscoveredThrottleControlReplicaKeys(discoveredKeys)
+	require.Equal(t, 2, context.GetThrottleControlReplicaKeys().Len())
+	require.Equal(t, 1, context.GetDiscoveredThrottleControlReplicaKeys().Len())
+
+	// Listed replicas may change without losing discovered ones
+	require.NoError(t, context.ReadThrottleControlReplicaKeys(""))
+	require.Equal(t, "replica2.example.com:3306", context.GetThrottleControlReplicaKeys().ToCommaDelimitedList())
+
+	context.SetDiscoveredThrottleControlReplicaKeys(mysql.NewInstanceKeyMap())
+	require.Equal(t, 0, context.GetThrottleControlReplicaKeys().Len())
+}
+
+func TestThrottleControlReplicaIncludeExclude(t *testing.T) {
+	context := NewMigrationContext()
+	delayedKey := mysql.InstanceKey{Hostname: "delayed-replica1.example.com", Port: 3306}
+	replicaKey := mysql.InstanceKey{Hostname: "replica1.example.com", Port: 3307}
+	require.True(t, context.IsThrottleControlReplicaIncluded(delayedKey))
+	require.False(t, context.IsThrottleControlReplicaExcluded(delayedKey))
+
+	context.ThrottleControlReplicasExclude = regexp.MustCompile(`^delayed-`)
+	require.True(t, context.IsThrottleControlReplicaExcluded(delayedKey))
+	require.False(t, context.IsThrottleControlReplicaExcluded(replicaKey))
+
+	context.ThrottleControlReplicasInclude = regexp.MustCompile(`:3306$`)
+	require.True(t, context.IsThrottleControlReplicaIncluded(delayedKey))
+	require.False(t, context.IsThrottleControlReplicaIncluded(replicaKey))
+}
+
+func TestCutOverString(t *testing.T) {
+	require.Equal(t, "atomic", CutOverAtomic.String())
+	require.Equal(t, "two-step", CutOverTwoStep.String())
+	require.Equal(t, "lock-and-rename", CutOverLockAndRename.String())
+	require.Equal(t, "default", CutOverAuto.String())
+}
+
+func TestReplicationClusterString(t *testing.T) {
+	require.Equal(t, "none", NoReplicationCluster.String())
+	require.Equal(t, "group-replication", GroupReplicationCluster.String())
+	require.Equal(t, "galera", GaleraCluster.String())
+}
+
+func TestApplyLag(t *testing.T) {
+	context := NewMigrationContext()
+	require.Equal(t, time.Duration(0), context.GetApplyLag())
+
+	now := time.Now()
+	context.AddPendingApplyEvent(now.Add(-30 * time.Second))
+	context.AddPendingApplyEvent(now.Add(-30 * time.Second))
+	context.AddPendingApplyEvent(now.Add(-10 * time.Second))
+	context.AddPendingApplyEvent(now.Add(time.Minute))
+	require.Len(t, context.pendingApplyEventTimes, 3)
+	require.InDelta(t, 30, context.GetApplyLag().Seconds(), 1.5)
+
+	context.SetMaxApplyLagMillisecondsThrottleThreshold(20000)
+	require.True(t, context.IsApplyLagExceeded())
+
+	context.RemoveAppliedEvents(2)
+	require.InDelta(t, 10, context.GetApplyLag().Seconds(), 1.5)
+	require.False(t, context.IsApplyLagExceeded())
+
+	// Events timestamped ahead of the local clock are not lagging
+	context.RemoveAppliedEvents(1)
+	require.Equal(t, time.Duration(0), context.GetApplyLag())
+
+	context.RemoveAppliedEvents(5)
+	require.Empty(t, context.pendingApplyEventTimes)
+	require.Equal(t, time.Duration(0), context.GetApplyLag())
+
+	context.SetMaxApplyLagMillisecondsThrottleThreshold(0)
+	require.False(t, context.IsApplyLagExceeded())
+	context.SetMaxApplyLagMillisecondsThrottleThreshold(200)
+	require.Equal(t, int64(1000), context.MaxApplyLagMillisecondsThrottleThreshold)
+}
+
+func TestThrottleHistory(t *testing.T) {
+	context := NewMigrationContext()
+	require.Empty(t, context.GetThrottleHistory())
+
+	context.SetThrottled(true, "flag-file", NoThrottleReasonHint)
+	context.SetThrottled(true, "flag-file", NoThrottleReasonHint)
+	context.SetThrottled(true, "max-load", NoThrottleReasonHint)
+	context.SetThrottled(false, "", NoThrottleReasonHint)
+	context.SetThrottled(false, "", NoThrottleReasonHint)
+	history := context.GetThrottleHistory()
+	require.Len(t, history, 3)
+	require.Equal(t, "flag-file", history[0].Reason)
+	require.True(t, history[1].Throttled)
+	require.Equal(t, "max-load", history[1].Reason)
+	require.False(t, history[2].Throttled)
+
+	for i := 0; i < MaxThrottleHistory; i++ {
+		context.SetThrottled(true, strings.Repeat("x", i+1), NoThrottleReasonHint)
+	}
+	history = context.GetThrottleHistory()
+	require.Len(t, history, MaxThrottleHistory)
+	require.Equal(t, "x", history[0].Reason)
+}
+
+func TestCurrentChunkRange(t *testing.T) {
+	context := NewMigrationContext()
+	minValues, maxValues := context.GetCurrentChunkRange()
+	require.Empty(t, minValues)
+	require.Empty(t, maxValues)
+
+	context.SetCurrentChunkRange(sql.ToColumnValues([]interface{}{1, "a"}), sql.ToColumnValues([]interface{}{1000, []uint8("z")}))
+	minValues, maxValues = context.GetCurrentChunkRange()
+	require.Equal(t, "1,a", minValues)
+	require.Equal(t, "1000,z", maxValues)
+}
+
+func TestSendPanicAbort(t *testing.T) {
+	context := NewMigrationContext()
+	require.False(t, context.IsPanicAbortClosed())
+
+	abortErr := errors.New("row copy failed")
+	go context.SendPanicAbort(abortErr)
+	require.Equal(t, abortErr, <-context.PanicAbort)
+
+	// Once no longer listened on, requests are dropped rather than blocking
+	context.ClosePanicAbort()
+	context.ClosePanicAbort()
+	require.True(t, context.IsPanicAbortClosed())
+	context.SendPanicAbort(errors.New("streamer failed"))
+}
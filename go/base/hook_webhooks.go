@@ -0,0 +1,122 @@
+/*
+   Copyright 2022 GitHub Inc.
+	 See https://github.com/github/gh-ost/blob/master/LICENSE
+*/
+
+package base
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultHookWebhookTimeout = 10 * time.Second
+	AllHooksWebhook           = "*"
+	// GateHookPrefix prefixes the names of gating hooks, which respond with whether to proceed
+	GateHookPrefix = "gh-ost-gate-"
+)
+
+type HookWebhookFailurePolicy string
+
+const (
+	// AbortOnHookWebhookFailure fails the hook, as does a failing hook executable
+	AbortOnHookWebhookFailure HookWebhookFailurePolicy = "abort"
+	// IgnoreHookWebhookFailure logs the failure and carries on
+	IgnoreHookWebhookFailure HookWebhookFailurePolicy = "ignore"
+)
+
+// HookWebhook is a URL to which hook events are posted, as JSON
+type HookWebhook struct {
+	spec      string
+	Hook      string // a hook name, such as gh-ost-on-success, or "*" for all hooks
+	URL       string
+	Timeout   time.Duration
+	Retries   int64
+	OnFailure HookWebhookFailurePolicy
+}
+
+func (this *HookWebhook) String() string {
+	return this.spec
+}
+
+// RedactedURL returns the URL without any credentials or query, which may hold tokens, for logging
+func (this *HookWebhook) RedactedURL() string {
+	webhookURL, err := url.Parse(this.URL)
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%s://%s%s", webhookURL.Scheme, webhookURL.Host, webhookURL.Path)
+}
+
+// Matches returns true when events of the given hook are to be posted to this webhook. Gating hooks
+// are only posted to webhooks which name them.
+func (this *HookWebhook) Matches(hook string) bool {
+	if this.Hook == AllHooksWebhook {
+		return !strings.HasPrefix(hook, GateHookPrefix)
+	}
+	return this.Hook == hook
+}
+
+// ParseHookWebhooks parses a `--hooks-webhooks` flag, a semicolon delimited list of webhooks, such as:
+//
+//	'gh-ost-on-status https://hooks.example.com/status timeout=2 on-failure=ignore; * https://hooks.example.com/gh-ost retries=3'
+//
+// Each webhook is a hook name, or "*" for all hooks but gating hooks, followed by a http(s) URL, followed by any of
+// timeout=<seconds>, retries=<count> or on-failure=abort|ignore.
+func ParseHookWebhooks(spec string) (webhooks []*HookWebhook, err error) {
+	for _, webhookSpec := range strings.Split(spec, ";") {
+		webhookSpec = strings.TrimSpace(webhookSpec)
+		if webhookSpec == "" {
+			continue
+		}
+		tokens := strings.Fields(webhookSpec)
+		if len(tokens) < 2 {
+			return nil, fmt.Errorf("Error parsing webhook %q: expected a hook name followed by a URL", webhookSpec)
+		}
+		webhook := &HookWebhook{
+			spec:      webhookSpec,
+			Hook:      tokens[0],
+			URL:       tokens[1],
+			Timeout:   DefaultHookWebhookTimeout,
+			OnFailure: AbortOnHookWebhookFailure,
+		}
+		if webhook.Hook != AllHooksWebhook && !strings.HasPrefix(webhook.Hook, "gh-ost-on-") && !strings.HasPrefix(webhook.Hook, GateHookPrefix) {
+			return nil, fmt.Errorf("Error parsing webhook %q: unknown hook %q", webhookSpec, webhook.Hook)
+		}
+		if webhookURL, err := url.Parse(webhook.URL); err != nil || (webhookURL.Scheme != "http" && webhookURL.Scheme != "https") || webhookURL.Host == "" {
+			return nil, fmt.Errorf("Error parsing webhook %q: expected a http or https URL", webhookSpec)
+		}
+		for _, token := range tokens[2:] {
+			name, value, _ := strings.Cut(token, "=")
+			var err error
+			switch name {
+			case "timeout":
+				var seconds float64
+				if seconds, err = strconv.ParseFloat(value, 64); err == nil && seconds <= 0 {
+					err = fmt.Errorf("must be positive")
+				}
+				webhook.Timeout = time.Duration(seconds * float64(time.Second))
+			case "retries":
+				if webhook.Retries, err = strconv.ParseInt(value, 10, 64); err == nil && webhook.Retries < 0 {
+					err = fmt.Errorf("must not be negative")
+				}
+			case "on-failure":
+				webhook.OnFailure = HookWebhookFailurePolicy(value)
+				if webhook.OnFailure != AbortOnHookWebhookFailure && webhook.OnFailure != IgnoreHookWebhookFailure {
+					err = fmt.Errorf("expected abort or ignore")
+				}
+			default:
+				err = fmt.Errorf("unknown setting; expected timeout, retries or on-failure")
+			}
+			if err != nil {
+				return nil, fmt.Errorf("Error parsing %q in webhook %q: %+v", token, webhookSpec, err)
+			}
+		}
+		webhooks = append(webhooks, webhook)
+	}
+	return webhooks, nil
+}
@@ -0,0 +1,64 @@
+/*
+   Copyright 2022 GitHub Inc.
+	 See https://github.com/github/gh-ost/blob/master/LICENSE
+*/
+
+package base
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseHookWebhooks(t *testing.T) {
+	{
+		webhooks, err := ParseHookWebhooks("")
+		require.NoError(t, err)
+		require.Empty(t, webhooks)
+	}
+	{
+		webhooks, err := ParseHookWebhooks("gh-ost-on-status https://hooks.example.com/status?token=abc timeout=2.5 on-failure=ignore; * http://localhost:8080/gh-ost retries=3;")
+		require.NoError(t, err)
+		require.Len(t, webhooks, 2)
+
+		webhook := webhooks[0]
+		require.Equal(t, "gh-ost-on-status", webhook.Hook)
+		require.Equal(t, "https://hooks.example.com/status?token=abc", webhook.URL)
+		require.Equal(t, 2500*time.Millisecond, webhook.Timeout)
+		require.Equal(t, int64(0), webhook.Retries)
+		require.Equal(t, IgnoreHookWebhookFailure, webhook.OnFailure)
+		require.Equal(t, "https://hooks.example.com/status", webhook.RedactedURL())
+		require.True(t, webhook.Matches("gh-ost-on-status"))
+		require.False(t, webhook.Matches("gh-ost-on-success"))
+
+		webhook = webhooks[1]
+		require.Equal(t, AllHooksWebhook, webhook.Hook)
+		require.Equal(t, DefaultHookWebhookTimeout, webhook.Timeout)
+		require.Equal(t, int64(3), webhook.Retries)
+		require.Equal(t, AbortOnHookWebhookFailure, webhook.OnFailure)
+		require.True(t, webhook.Matches("gh-ost-on-success"))
+		require.False(t, webhook.Matches("gh-ost-gate-before-cut-over"))
+		require.Equal(t, "* http://localhost:8080/gh-ost retries=3", webhook.String())
+	}
+	{
+		webhooks, err := ParseHookWebhooks("gh-ost-gate-before-cut-over https://change-management.example.com/gh-ost")
+		require.NoError(t, err)
+		require.True(t, webhooks[0].Matches("gh-ost-gate-before-cut-over"))
+		require.False(t, webhooks[0].Matches("gh-ost-gate-before-row-copy"))
+	}
+	for _, spec := range []string{
+		"gh-ost-on-status",
+		"on-status https://hooks.example.com",
+		"* hooks.example.com/status",
+		"* ftp://hooks.example.com/status",
+		"* https://hooks.example.com timeout=0",
+		"* https://hooks.example.com retries=-1",
+		"* https://hooks.example.com on-failure=retry",
+		"* https://hooks.example.com verbose",
+	} {
+		_, err := ParseHookWebhooks(spec)
+		require.Error(t, err, spec)
+	}
+}
@@ -0,0 +1,200 @@
+/*
+   Copyright 2022 GitHub Inc.
+	 See https://github.com/github/gh-ost/blob/master/LICENSE
+*/
+
+package base
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/openark/golib/log"
+)
+
+// jsonLoggerOutput is what the loggers derived from one another share: where they write, and at
+// which level
+type jsonLoggerOutput struct {
+	mutex           sync.Mutex
+	writer          io.Writer
+	level           log.LogLevel
+	printStackTrace bool
+}
+
+// jsonLogger logs JSON records, one per line, each holding the migration's UUID, database, table
+// and phase, and the component logging it
+type jsonLogger struct {
+	migrationContext *MigrationContext
+	component        string
+	output           *jsonLoggerOutput
+}
+
+// NewJSONLogger returns a logger which writes JSON records onto stderr, such as:
+//
+//	{"component":"applier","database":"mydb","level":"info","message":"Applier initiated","phase":"inspection","table":"mytable","time":"...","uuid":"..."}
+func NewJSONLogger(migrationContext *MigrationContext) *jsonLogger {
+	return newJSONLogger(migrationContext, os.Stderr)
+}
+
+func newJSONLogger(migrationContext *MigrationContext, writer io.Writer) *jsonLogger {
+	return &jsonLogger{
+		migrationContext: migrationContext,
+		output:           &jsonLoggerOutput{writer: writer, level: log.GetLevel()},
+	}
+}
+
+// WithComponent returns a logger which tags records with the given component, and shares this
+// logger's output and level
+func (this *jsonLogger) WithComponent(component string) Logger {
+	return &jsonLogger{
+		migrationContext: this.migrationContext,
+		component:        component,
+		output:           this.output,
+	}
+}
+
+func (this *jsonLogger) enabled(level log.LogLevel) bool {
+	this.output.mutex.Lock()
+	defer this.output.mutex.Unlock()
+
+	return level <= this.output.level
+}
+
+func (this *jsonLogger) log(level log.LogLevel, message string, fields LogFields) {
+	if !this.enabled(level) {
+		return
+	}
+	record := LogFields{}
+	for name, value := range fields {
+		record[name] = value
+	}
+	record["time"] = time.Now().Format(time.RFC3339Nano)
+	record["level"] = strings.ToLower(level.String())
+	record["message"] = message
+	if this.component != "" {
+		record["component"] = this.component
+	}
+	if this.migrationContext != nil {
+		record["uuid"] = this.migrationContext.Uuid
+		record["database"] = this.migrationContext.DatabaseName
+		record["table"] = this.migrationContext.OriginalTableName
+		record["phase"] = this.migrationContext.GetPhase()
+	}
+	line, err := json.Marshal(record)
+	if err != nil {
+		line, _ = json.Marshal(LogFields{"time": record["time"], "level": "error", "message": fmt.Sprintf("Cannot marshal log record %q: %+v", message, err)})
+	}
+
+	this.output.mutex.Lock()
+	defer this.output.mutex.Unlock()
+	fmt.Fprintln(this.output.writer, string(line))
+}
+
+// logError logs an error, along with the stack, when so configured
+func (this *jsonLogger) logError(level log.LogLevel, err error) error {
+	if err == nil {
+		return nil
+	}
+	fields := LogFields{}
+	this.output.mutex.Lock()
+	if this.output.printStackTrace {
+		fields["stack"] = string(debug.Stack())
+	}
+	this.output.mutex.Unlock()
+
+	this.log(level, fmt.Sprintf("%+v", err), fields)
+	return err
+}
+
+// joinArgs joins arguments the way the text logger does: space delimited
+func joinArgs(args ...interface{}) string {
+	return strings.TrimSuffix(fmt.Sprintln(args...), "\n")
+}
+
+// InfoFields logs a message along with fields, such as those of a status line
+func (this *jsonLogger) InfoFields(message string, fields LogFields) {
+	this.log(log.INFO, message, fields)
+}
+
+func (this *jsonLogger) Debug(args ...interface{}) {
+	this.log(log.DEBUG, joinArgs(args...), nil)
+}
+
+func (this *jsonLogger) Debugf(format string, args ...interface{}) {
+	this.log(log.DEBUG, fmt.Sprintf(format, args...), nil)
+}
+
+func (this *jsonLogger) Info(args ...interface{}) {
+	this.log(log.INFO, joinArgs(args...), nil)
+}
+
+func (this *jsonLogger) Infof(format string, args ...interface{}) {
+	this.log(log.INFO, fmt.Sprintf(format, args...), nil)
+}
+
+func (this *jsonLogger) Warning(args ...interface{}) error {
+	message := joinArgs(args...)
+	this.log(log.WARNING, message, nil)
+	return errors.New(message)
+}
+
+func (this *jsonLogger) Warningf(format string, args ...interface{}) error {
+	message := fmt.Sprintf(format, args...)
+	this.log(log.WARNING, message, nil)
+	return errors.New(message)
+}
+
+func (this *jsonLogger) Error(args ...interface{}) error {
+	message := joinArgs(args...)
+	this.log(log.ERROR, message, nil)
+	return errors.New(message)
+}
+
+func (this *jsonLogger) Errorf(format string, args ...interface{}) error {
+	message := fmt.Sprintf(format, args...)
+	this.log(log.ERROR, message, nil)
+	return errors.New(message)
+}
+
+func (this *jsonLogger) Errore(err error) error {
+	return this.logError(log.ERROR, err)
+}
+
+func (this *jsonLogger) Fatal(args ...interface{}) error {
+	this.log(log.FATAL, joinArgs(args...), nil)
+	os.Exit(1)
+	return nil
+}
+
+func (this *jsonLogger) Fatalf(format string, args ...interface{}) error {
+	this.log(log.FATAL, fmt.Sprintf(format, args...), nil)
+	os.Exit(1)
+	return nil
+}
+
+func (this *jsonLogger) Fatale(err error) error {
+	this.logError(log.FATAL, err)
+	os.Exit(1)
+	return err
+}
+
+func (this *jsonLogger) SetLevel(level log.LogLevel) {
+	this.output.mutex.Lock()
+	defer this.output.mutex.Unlock()
+
+	this.output.level = level
+}
+
+func (this *jsonLogger) SetPrintStackTrace(printStackTraceFlag bool) {
+	this.output.mutex.Lock()
+	defer this.output.mutex.Unlock()
+
+	this.output.printStackTrace = printStackTraceFlag
+}
@@ -0,0 +1,93 @@
+/*
+   Copyright 2022 GitHub Inc.
+	 See https://github.com/github/gh-ost/blob/master/LICENSE
+*/
+
+package base
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/openark/golib/log"
+	"github.com/stretchr/testify/require"
+)
+
+func readJSONRecords(t *testing.T, output *bytes.Buffer) []map[string]interface{} {
+	records := []map[string]interface{}{}
+	for _, line := range strings.Split(strings.TrimSpace(output.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		record := map[string]interface{}{}
+		require.NoError(t, json.Unmarshal([]byte(line), &record), line)
+		records = append(records, record)
+	}
+	output.Reset()
+	return records
+}
+
+func TestJSONLogger(t *testing.T) {
+	migrationContext := NewMigrationContext()
+	migrationContext.DatabaseName = "mydb"
+	migrationContext.OriginalTableName = "mytable"
+
+	var output bytes.Buffer
+	logger := newJSONLogger(migrationContext, &output)
+	logger.SetLevel(log.INFO)
+	migrationContext.Log = logger
+
+	applierLog := migrationContext.ComponentLog("applier")
+	migrationContext.SetPhase(RowCopyPhase)
+	applierLog.Infof("Copied %d rows", 100)
+	applierLog.Debugf("Not logged at info level")
+	err := applierLog.Errorf("Cannot apply %s", "event")
+	require.EqualError(t, err, "Cannot apply event")
+
+	records := readJSONRecords(t, &output)
+	require.Len(t, records, 2)
+	require.Equal(t, "info", records[0]["level"])
+	require.Equal(t, "Copied 100 rows", records[0]["message"])
+	require.Equal(t, "applier", records[0]["component"])
+	require.Equal(t, migrationContext.Uuid, records[0]["uuid"])
+	require.Equal(t, "mydb", records[0]["database"])
+	require.Equal(t, "mytable", records[0]["table"])
+	require.Equal(t, "row-copy", records[0]["phase"])
+	require.NotEmpty(t, records[0]["time"])
+	require.Equal(t, "error", records[1]["level"])
+	require.Equal(t, "Cannot apply event", records[1]["message"])
+
+	// Derived loggers share the level
+	logger.SetLevel(log.DEBUG)
+	applierLog.Debug("Debugging", 1, "row")
+	logger.Warning("Unassigned")
+	records = readJSONRecords(t, &output)
+	require.Len(t, records, 2)
+	require.Equal(t, "Debugging 1 row", records[0]["message"])
+	require.Equal(t, "debug", records[0]["level"])
+	require.Equal(t, "warning", records[1]["level"])
+	require.NotContains(t, records[1], "component")
+
+	logger.InfoFields("Status", LogFields{"copied_rows": 100, "state": "migrating", "message": "overridden"})
+	records = readJSONRecords(t, &output)
+	require.Len(t, records, 1)
+	require.Equal(t, "Status", records[0]["message"])
+	require.Equal(t, float64(100), records[0]["copied_rows"])
+	require.Equal(t, "migrating", records[0]["state"])
+
+	logger.SetPrintStackTrace(true)
+	require.Error(t, logger.Errore(errors.New("broken")))
+	require.NoError(t, logger.Errore(nil))
+	records = readJSONRecords(t, &output)
+	require.Len(t, records, 1)
+	require.Equal(t, "broken", records[0]["message"])
+	require.Contains(t, records[0]["stack"], "TestJSONLogger")
+}
+
+func TestComponentLog(t *testing.T) {
+	migrationContext := NewMigrationContext()
+	require.Equal(t, migrationContext.Log, migrationContext.ComponentLog("applier"))
+}
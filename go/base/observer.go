@@ -0,0 +1,44 @@
+/*
+   Copyright 2022 GitHub Inc.
+	 See https://github.com/github/gh-ost/blob/master/LICENSE
+*/
+
+package base
+
+import (
+	"time"
+)
+
+// MigrationObserver is notified of a migration's progress, in-process, by programs which embed
+// gh-ost. Its methods are called synchronously from gh-ost's own goroutines, and should return
+// promptly.
+type MigrationObserver interface {
+	// OnPhase is called as the migration enters a phase
+	OnPhase(phase MigrationPhase)
+	// OnProgress is called once a second, as of row copy, with what the status line shows
+	OnProgress(progress MigrationProgress)
+	// OnThrottle is called as throttling begins or ends, or its reason changes
+	OnThrottle(throttled bool, reason string)
+}
+
+// MigrationProgress is a migration's progress, as shown by its status line
+type MigrationProgress struct {
+	CopiedRows    int64
+	EstimatedRows int64
+	ProgressPct   float64
+	AppliedEvents int64
+	Backlog       int
+	Elapsed       time.Duration
+	CopyElapsed   time.Duration
+	Lag           time.Duration
+	State         string
+	// ETA is negative while unknown
+	ETA time.Duration
+}
+
+// NotifyProgress hands the observer, if any, the migration's progress
+func (this *MigrationContext) NotifyProgress(progress MigrationProgress) {
+	if this.Observer != nil {
+		this.Observer.OnProgress(progress)
+	}
+}
@@ -0,0 +1,56 @@
+/*
+   Copyright 2022 GitHub Inc.
+	 See https://github.com/github/gh-ost/blob/master/LICENSE
+*/
+
+package base
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type recordingObserver struct {
+	phases    []MigrationPhase
+	progress  []MigrationProgress
+	throttles []string
+}
+
+func (this *recordingObserver) OnPhase(phase MigrationPhase) {
+	this.phases = append(this.phases, phase)
+}
+
+func (this *recordingObserver) OnProgress(progress MigrationProgress) {
+	this.progress = append(this.progress, progress)
+}
+
+func (this *recordingObserver) OnThrottle(throttled bool, reason string) {
+	if !throttled {
+		reason = "released"
+	}
+	this.throttles = append(this.throttles, reason)
+}
+
+func TestMigrationObserver(t *testing.T) {
+	observer := &recordingObserver{}
+	migrationContext := NewMigrationContext()
+	migrationContext.Observer = observer
+
+	migrationContext.SetPhase(InspectionPhase)
+	migrationContext.SetPhase(InspectionPhase)
+	migrationContext.SetPhase(RowCopyPhase)
+	require.Equal(t, []MigrationPhase{InspectionPhase, RowCopyPhase}, observer.phases)
+
+	// Only changes in throttling are notified
+	migrationContext.SetThrottled(false, "", NoThrottleReasonHint)
+	migrationContext.SetThrottled(true, "lag=2s", NoThrottleReasonHint)
+	migrationContext.SetThrottled(true, "lag=2s", NoThrottleReasonHint)
+	migrationContext.SetThrottled(true, "max-load", NoThrottleReasonHint)
+	migrationContext.SetThrottled(false, "", NoThrottleReasonHint)
+	require.Equal(t, []string{"lag=2s", "max-load", "released"}, observer.throttles)
+
+	migrationContext.NotifyProgress(MigrationProgress{CopiedRows: 100, State: "migrating"})
+	require.Len(t, observer.progress, 1)
+	require.Equal(t, int64(100), observer.progress[0].CopiedRows)
+}
@@ -0,0 +1,176 @@
+/*
+   Copyright 2022 GitHub Inc.
+	 See https://github.com/github/gh-ost/blob/master/LICENSE
+*/
+
+package base
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const minutesPerDay = 24 * 60
+
+var scheduleWeekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// scheduleWindow is a range of hours, recurring on a range of weekdays. A range of hours which
+// ends before it starts wraps past midnight, into the following day.
+type scheduleWindow struct {
+	fromWeekday time.Weekday
+	toWeekday   time.Weekday
+	fromMinute  int
+	toMinute    int
+}
+
+func (this *scheduleWindow) onWeekday(weekday time.Weekday) bool {
+	if this.fromWeekday <= this.toWeekday {
+		return weekday >= this.fromWeekday && weekday <= this.toWeekday
+	}
+	return weekday >= this.fromWeekday || weekday <= this.toWeekday
+}
+
+func (this *scheduleWindow) contains(weekday time.Weekday, minute int) bool {
+	if this.fromMinute < this.toMinute {
+		return this.onWeekday(weekday) && minute >= this.fromMinute && minute < this.toMinute
+	}
+	previousWeekday := (weekday + 6) % 7
+	return (this.onWeekday(weekday) && minute >= this.fromMinute) || (this.onWeekday(previousWeekday) && minute < this.toMinute)
+}
+
+// Schedule is a set of windows of time, each a range of hours recurring on a range of weekdays,
+// in a given time zone.
+type Schedule struct {
+	spec     string
+	windows  []scheduleWindow
+	location *time.Location
+}
+
+// ParseSchedule parses a comma delimited list of windows, such as:
+//
+//	'mon-fri 01:00-05:00,sat-sun 00:00-24:00,22:30-23:30'
+//
+// Each window is an optional weekday or range of weekdays (all days when omitted), followed
+// by a range of hours. Times are read in the given location.
+func ParseSchedule(spec string, location *time.Location) (*Schedule, error) {
+	schedule := &Schedule{
+		spec:     strings.TrimSpace(spec),
+		location: location,
+	}
+	if schedule.spec == "" {
+		return nil, fmt.Errorf("Empty schedule")
+	}
+	for _, windowSpec := range strings.Split(schedule.spec, ",") {
+		window := scheduleWindow{fromWeekday: time.Sunday, toWeekday: time.Saturday}
+		tokens := strings.Fields(windowSpec)
+		switch len(tokens) {
+		case 1:
+		case 2:
+			var err error
+			if window.fromWeekday, window.toWeekday, err = parseWeekdayRange(tokens[0]); err != nil {
+				return nil, fmt.Errorf("Error parsing weekdays in schedule window %q: %+v", windowSpec, err)
+			}
+		default:
+			return nil, fmt.Errorf("Error parsing schedule window %q: expected [weekdays] hh:mm-hh:mm", windowSpec)
+		}
+		hours := strings.Split(tokens[len(tokens)-1], "-")
+		if len(hours) != 2 {
+			return nil, fmt.Errorf("Error parsing hours in schedule window %q: expected hh:mm-hh:mm", windowSpec)
+		}
+		var err error
+		if window.fromMinute, err = parseScheduleTime(hours[0]); err != nil {
+			return nil, fmt.Errorf("Error parsing hours in schedule window %q: %+v", windowSpec, err)
+		}
+		if window.toMinute, err = parseScheduleTime(hours[1]); err != nil {
+			return nil, fmt.Errorf("Error parsing hours in schedule window %q: %+v", windowSpec, err)
+		}
+		if window.fromMinute == window.toMinute || window.fromMinute == minutesPerDay {
+			return nil, fmt.Errorf("Error parsing hours in schedule window %q: empty range", windowSpec)
+		}
+		schedule.windows = append(schedule.windows, window)
+	}
+	return schedule, nil
+}
+
+// parseWeekdayRange parses '*', a weekday such as 'mon', or a range of weekdays such as 'mon-fri'
+func parseWeekdayRange(weekdays string) (from, to time.Weekday, err error) {
+	if weekdays == "*" {
+		return time.Sunday, time.Saturday, nil
+	}
+	tokens := strings.Split(weekdays, "-")
+	if len(tokens) > 2 {
+		return from, to, fmt.Errorf("expected weekday or range of weekdays, got %q", weekdays)
+	}
+	var found bool
+	if from, found = scheduleWeekdays[strings.ToLower(tokens[0])]; !found {
+		return from, to, fmt.Errorf("unknown weekday %q", tokens[0])
+	}
+	to = from
+	if len(tokens) == 2 {
+		if to, found = scheduleWeekdays[strings.ToLower(tokens[1])]; !found {
+			return from, to, fmt.Errorf("unknown weekday %q", tokens[1])
+		}
+	}
+	return from, to, nil
+}
+
+// parseScheduleTime parses hh:mm into minutes since midnight. 24:00 stands for end of day.
+func parseScheduleTime(hhmm string) (minute int, err error) {
+	tokens := strings.Split(hhmm, ":")
+	if len(tokens) != 2 {
+		return 0, fmt.Errorf("expected hh:mm, got %q", hhmm)
+	}
+	hour, err := strconv.Atoi(tokens[0])
+	if err != nil {
+		return 0, fmt.Errorf("expected hh:mm, got %q", hhmm)
+	}
+	minute, err = strconv.Atoi(tokens[1])
+	if err != nil || len(tokens[1]) != 2 || minute > 59 || hour < 0 || hour > 24 || (hour == 24 && minute > 0) {
+		return 0, fmt.Errorf("expected hh:mm, got %q", hhmm)
+	}
+	return hour*60 + minute, nil
+}
+
+// Contains checks whether the given time falls within any of the schedule's windows
+func (this *Schedule) Contains(t time.Time) bool {
+	t = t.In(this.location)
+	minute := t.Hour()*60 + t.Minute()
+	for _, window := range this.windows {
+		if window.contains(t.Weekday(), minute) {
+			return true
+		}
+	}
+	return false
+}
+
+// NextStart returns the earliest time, from the given time onwards, which falls within any of the
+// schedule's windows, at minute resolution.
+func (this *Schedule) NextStart(t time.Time) time.Time {
+	if this.Contains(t) {
+		return t
+	}
+	next := t.Truncate(time.Minute)
+	for i := 0; i <= 8*minutesPerDay; i++ {
+		next = next.Add(time.Minute)
+		if this.Contains(next) {
+			return next
+		}
+	}
+	// Not reached: any window recurs within a week
+	return next
+}
+
+// String returns the schedule as parsed, along with its location
+func (this *Schedule) String() string {
+	return fmt.Sprintf("%s (%s)", this.spec, this.location)
+}
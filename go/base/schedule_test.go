@@ -0,0 +1,99 @@
+/*
+   Copyright 2022 GitHub Inc.
+	 See https://github.com/github/gh-ost/blob/master/LICENSE
+*/
+
+package base
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSchedule(t *testing.T) {
+	{
+		schedule, err := ParseSchedule("mon-fri 01:00-05:00, sat-sun 00:00-24:00,22:30-23:30", time.UTC)
+		require.NoError(t, err)
+		require.Len(t, schedule.windows, 3)
+		require.Equal(t, time.Monday, schedule.windows[0].fromWeekday)
+		require.Equal(t, time.Friday, schedule.windows[0].toWeekday)
+		require.Equal(t, 60, schedule.windows[0].fromMinute)
+		require.Equal(t, 300, schedule.windows[0].toMinute)
+		require.Equal(t, 24*60, schedule.windows[1].toMinute)
+		require.Equal(t, time.Sunday, schedule.windows[2].fromWeekday)
+		require.Equal(t, time.Saturday, schedule.windows[2].toWeekday)
+		require.Equal(t, "mon-fri 01:00-05:00, sat-sun 00:00-24:00,22:30-23:30 (UTC)", schedule.String())
+	}
+	{
+		schedule, err := ParseSchedule("* 22:00-02:00", time.UTC)
+		require.NoError(t, err)
+		require.Len(t, schedule.windows, 1)
+	}
+	for _, spec := range []string{
+		"",
+		"mon-fri",
+		"mon-fri 01:00",
+		"mon-fri 01:00-05:00 UTC",
+		"mon-fry 01:00-05:00",
+		"mon-wed-fri 01:00-05:00",
+		"01:00-01:00",
+		"24:00-01:00",
+		"1:0-05:00",
+		"01:60-05:00",
+		"24:30-05:00",
+		"01:00-25:00",
+	} {
+		_, err := ParseSchedule(spec, time.UTC)
+		require.Error(t, err, spec)
+	}
+}
+
+func TestScheduleContains(t *testing.T) {
+	// 2022-05-16 is a Monday
+	at := func(day int, hour int, minute int) time.Time {
+		return time.Date(2022, 5, day, hour, minute, 0, 0, time.UTC)
+	}
+	{
+		schedule, err := ParseSchedule("mon-fri 01:00-05:00,sat 00:00-24:00", time.UTC)
+		require.NoError(t, err)
+		require.False(t, schedule.Contains(at(16, 0, 59)))
+		require.True(t, schedule.Contains(at(16, 1, 0)))
+		require.True(t, schedule.Contains(at(20, 4, 59)))
+		require.False(t, schedule.Contains(at(20, 5, 0)))
+		require.True(t, schedule.Contains(at(21, 23, 59)))
+		require.False(t, schedule.Contains(at(22, 2, 0)))
+	}
+	{
+		// Wraps past midnight, and past the end of the week
+		schedule, err := ParseSchedule("sat-sun 22:00-02:00", time.UTC)
+		require.NoError(t, err)
+		require.True(t, schedule.Contains(at(21, 22, 0)))
+		require.True(t, schedule.Contains(at(22, 1, 59)))
+		require.True(t, schedule.Contains(at(23, 1, 59)))
+		require.False(t, schedule.Contains(at(23, 2, 0)))
+		require.False(t, schedule.Contains(at(23, 22, 0)))
+		require.False(t, schedule.Contains(at(21, 1, 0)))
+	}
+	{
+		location := time.FixedZone("UTC+2", 2*60*60)
+		schedule, err := ParseSchedule("01:00-05:00", location)
+		require.NoError(t, err)
+		require.True(t, schedule.Contains(at(16, 0, 0)))
+		require.False(t, schedule.Contains(at(16, 3, 0)))
+	}
+}
+
+func TestScheduleNextStart(t *testing.T) {
+	schedule, err := ParseSchedule("mon-fri 01:00-05:00", time.UTC)
+	require.NoError(t, err)
+
+	// Within the schedule
+	now := time.Date(2022, 5, 16, 2, 30, 15, 0, time.UTC)
+	require.Equal(t, now, schedule.NextStart(now))
+
+	// Friday afternoon: next is Monday
+	now = time.Date(2022, 5, 20, 14, 30, 15, 0, time.UTC)
+	require.Equal(t, time.Date(2022, 5, 23, 1, 0, 0, 0, time.UTC), schedule.NextStart(now))
+}
@@ -0,0 +1,131 @@
+/*
+   Copyright 2022 GitHub Inc.
+	 See https://github.com/github/gh-ost/blob/master/LICENSE
+*/
+
+package base
+
+import (
+	"bufio"
+	"crypto/subtle"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ServerRole gates which interactive commands a client may run
+type ServerRole string
+
+const (
+	// NoServerRole is the role of TCP clients which did not authenticate, when authentication is
+	// required: such clients may run no command
+	NoServerRole ServerRole = ""
+	// ReadOnlyServerRole may run commands which report, and query settings with "?"
+	ReadOnlyServerRole ServerRole = "read-only"
+	// AdminServerRole may run any command
+	AdminServerRole ServerRole = "admin"
+)
+
+const (
+	serverAuthTokenPrefix = "token:"
+	serverAuthCert        = "cert"
+)
+
+// ServerPrincipal is a client allowed onto the TCP interactive interface
+type ServerPrincipal struct {
+	Identity string
+	Role     ServerRole
+	// Token, if set, is what the client authenticates with; otherwise it authenticates with a
+	// client certificate whose subject common name is the identity
+	Token string
+}
+
+// ServerAuth is who may run interactive commands over TCP, as read off --serve-tcp-auth-file
+type ServerAuth struct {
+	Principals []ServerPrincipal
+}
+
+// TokenPrincipal returns the principal authenticating with the given token
+func (this *ServerAuth) TokenPrincipal(token string) (principal ServerPrincipal, ok bool) {
+	for _, candidate := range this.Principals {
+		// Compare all tokens, in constant time, such that timing tells nothing about them
+		if candidate.Token != "" && subtle.ConstantTimeCompare([]byte(candidate.Token), []byte(token)) == 1 {
+			principal, ok = candidate, true
+		}
+	}
+	return principal, ok
+}
+
+// CertPrincipal returns the principal authenticating with a client certificate of the given common name
+func (this *ServerAuth) CertPrincipal(commonName string) (principal ServerPrincipal, ok bool) {
+	for _, candidate := range this.Principals {
+		if candidate.Token == "" && candidate.Identity == commonName {
+			return candidate, true
+		}
+	}
+	return principal, false
+}
+
+// HasCertPrincipals returns true when any principal authenticates with a client certificate
+func (this *ServerAuth) HasCertPrincipals() bool {
+	for _, principal := range this.Principals {
+		if principal.Token == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// ReadServerAuthFile reads who may run interactive commands over TCP. Each line names an identity,
+// its role and how it authenticates, either with a token or a client certificate:
+//
+//	# identity  role       credential
+//	alice       admin      token:8f2a61c3e7...
+//	grafana     read-only  token:0b5d72e4a9...
+//	deploy-bot  admin      cert
+func ReadServerAuthFile(fileName string) (*ServerAuth, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	auth := &ServerAuth{}
+	identities := map[string]bool{}
+	tokens := map[string]bool{}
+	scanner := bufio.NewScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("Auth file %s, line %d: expected <identity> <role> <credential>", fileName, lineNumber)
+		}
+		principal := ServerPrincipal{Identity: fields[0], Role: ServerRole(fields[1])}
+		if principal.Role != ReadOnlyServerRole && principal.Role != AdminServerRole {
+			return nil, fmt.Errorf("Auth file %s, line %d: unknown role %q; expected %s or %s", fileName, lineNumber, fields[1], ReadOnlyServerRole, AdminServerRole)
+		}
+		switch credential := fields[2]; {
+		case credential == serverAuthCert:
+		case strings.HasPrefix(credential, serverAuthTokenPrefix) && len(credential) > len(serverAuthTokenPrefix):
+			principal.Token = strings.TrimPrefix(credential, serverAuthTokenPrefix)
+		default:
+			return nil, fmt.Errorf("Auth file %s, line %d: expected credential token:<token> or %s", fileName, lineNumber, serverAuthCert)
+		}
+		if identities[principal.Identity] {
+			return nil, fmt.Errorf("Auth file %s, line %d: %s is listed more than once", fileName, lineNumber, principal.Identity)
+		}
+		if principal.Token != "" && tokens[principal.Token] {
+			return nil, fmt.Errorf("Auth file %s, line %d: token of %s is not unique", fileName, lineNumber, principal.Identity)
+		}
+		identities[principal.Identity] = true
+		tokens[principal.Token] = true
+		auth.Principals = append(auth.Principals, principal)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return auth, nil
+}
@@ -0,0 +1,61 @@
+/*
+   Copyright 2022 GitHub Inc.
+	 See https://github.com/github/gh-ost/blob/master/LICENSE
+*/
+
+package base
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadServerAuthFile(t *testing.T) {
+	writeAuthFile := func(content string) string {
+		fileName := filepath.Join(t.TempDir(), "auth")
+		require.NoError(t, os.WriteFile(fileName, []byte(content), 0600))
+		return fileName
+	}
+
+	auth, err := ReadServerAuthFile(writeAuthFile(`
+# identity  role       credential
+alice       admin      token:s3cr3t
+grafana     read-only  token:dashb0ard
+deploy-bot  admin      cert
+`))
+	require.NoError(t, err)
+	require.Len(t, auth.Principals, 3)
+	require.True(t, auth.HasCertPrincipals())
+
+	principal, ok := auth.TokenPrincipal("dashb0ard")
+	require.True(t, ok)
+	require.Equal(t, ServerPrincipal{Identity: "grafana", Role: ReadOnlyServerRole, Token: "dashb0ard"}, principal)
+	_, ok = auth.TokenPrincipal("s3cr3")
+	require.False(t, ok)
+	_, ok = auth.TokenPrincipal("")
+	require.False(t, ok)
+
+	principal, ok = auth.CertPrincipal("deploy-bot")
+	require.True(t, ok)
+	require.Equal(t, AdminServerRole, principal.Role)
+	// Token principals do not authenticate with certificates
+	_, ok = auth.CertPrincipal("alice")
+	require.False(t, ok)
+
+	for _, content := range []string{
+		"alice admin",
+		"alice superuser token:s3cr3t",
+		"alice admin password:s3cr3t",
+		"alice admin token:",
+		"alice admin token:a\nalice read-only token:b",
+		"alice admin token:a\nbob read-only token:a",
+	} {
+		_, err := ReadServerAuthFile(writeAuthFile(content))
+		require.Error(t, err, content)
+	}
+	_, err = ReadServerAuthFile(filepath.Join(t.TempDir(), "missing"))
+	require.Error(t, err)
+}
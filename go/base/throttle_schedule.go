@@ -0,0 +1,114 @@
+/*
+   Copyright 2022 GitHub Inc.
+	 See https://github.com/github/gh-ost/blob/master/LICENSE
+*/
+
+package base
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ThrottleScheduleEntry overrides throttle parameters within a schedule
+type ThrottleScheduleEntry struct {
+	spec         string
+	Schedule     *Schedule
+	NiceRatio    float64 // negative when not overridden
+	MaxLagMillis int64   // zero when not overridden
+	ChunkSize    int64   // zero when not overridden
+	Pause        bool
+}
+
+func (this *ThrottleScheduleEntry) String() string {
+	return this.spec
+}
+
+// ThrottleSchedule is a list of entries, each overriding throttle parameters within its own schedule.
+// Where entries overlap, the first one listed applies.
+type ThrottleSchedule struct {
+	spec    string
+	entries []*ThrottleScheduleEntry
+}
+
+// ParseThrottleSchedule parses a `--throttle-schedule` flag, a semicolon delimited list of entries,
+// such as:
+//
+//	'mon-fri 08:00-20:00 nice-ratio=1 chunk-size=500; mon-fri 12:00-14:00 pause'
+//
+// Each entry is a schedule (see ParseSchedule), followed by any of nice-ratio=<ratio>,
+// max-lag-millis=<millis>, chunk-size=<size>, or pause. Times are read in the given location.
+func ParseThrottleSchedule(spec string, location *time.Location) (*ThrottleSchedule, error) {
+	throttleSchedule := &ThrottleSchedule{spec: strings.TrimSpace(spec)}
+	for _, entrySpec := range strings.Split(throttleSchedule.spec, ";") {
+		entrySpec = strings.TrimSpace(entrySpec)
+		if entrySpec == "" {
+			continue
+		}
+		entry := &ThrottleScheduleEntry{spec: entrySpec, NiceRatio: -1}
+		scheduleTokens := []string{}
+		hasSettings := false
+		for _, token := range strings.Fields(entrySpec) {
+			name, value, isSetting := strings.Cut(token, "=")
+			if !isSetting && token != "pause" {
+				if hasSettings {
+					return nil, fmt.Errorf("Error parsing throttle schedule entry %q: schedule must precede settings", entrySpec)
+				}
+				scheduleTokens = append(scheduleTokens, token)
+				continue
+			}
+			hasSettings = true
+			var err error
+			switch name {
+			case "pause":
+				entry.Pause = true
+			case "nice-ratio":
+				if entry.NiceRatio, err = strconv.ParseFloat(value, 64); err == nil && entry.NiceRatio < 0 {
+					err = fmt.Errorf("must not be negative")
+				}
+			case "max-lag-millis":
+				if entry.MaxLagMillis, err = strconv.ParseInt(value, 10, 64); err == nil && entry.MaxLagMillis <= 0 {
+					err = fmt.Errorf("must be positive")
+				}
+			case "chunk-size":
+				if entry.ChunkSize, err = strconv.ParseInt(value, 10, 64); err == nil && entry.ChunkSize <= 0 {
+					err = fmt.Errorf("must be positive")
+				}
+			default:
+				err = fmt.Errorf("unknown setting; expected nice-ratio, max-lag-millis, chunk-size or pause")
+			}
+			if err != nil {
+				return nil, fmt.Errorf("Error parsing %q in throttle schedule entry %q: %+v", token, entrySpec, err)
+			}
+		}
+		if !hasSettings {
+			return nil, fmt.Errorf("Error parsing throttle schedule entry %q: no settings given", entrySpec)
+		}
+		schedule, err := ParseSchedule(strings.Join(scheduleTokens, " "), location)
+		if err != nil {
+			return nil, fmt.Errorf("Error parsing throttle schedule entry %q: %+v", entrySpec, err)
+		}
+		entry.Schedule = schedule
+		throttleSchedule.entries = append(throttleSchedule.entries, entry)
+	}
+	if len(throttleSchedule.entries) == 0 {
+		return nil, fmt.Errorf("Empty throttle schedule")
+	}
+	return throttleSchedule, nil
+}
+
+// ActiveEntry returns the entry which applies at the given time, or nil when none does
+func (this *ThrottleSchedule) ActiveEntry(t time.Time) *ThrottleScheduleEntry {
+	for _, entry := range this.entries {
+		if entry.Schedule.Contains(t) {
+			return entry
+		}
+	}
+	return nil
+}
+
+func (this *ThrottleSchedule) String() string {
+	return this.spec
+}
@@ -0,0 +1,66 @@
+/*
+   Copyright 2022 GitHub Inc.
+	 See https://github.com/github/gh-ost/blob/master/LICENSE
+*/
+
+package base
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseThrottleSchedule(t *testing.T) {
+	{
+		throttleSchedule, err := ParseThrottleSchedule("mon-fri 12:00-14:00 pause; mon-fri 08:00-20:00 nice-ratio=1.5 max-lag-millis=1000 chunk-size=500;", time.UTC)
+		require.NoError(t, err)
+		require.Len(t, throttleSchedule.entries, 2)
+
+		entry := throttleSchedule.entries[0]
+		require.True(t, entry.Pause)
+		require.Equal(t, float64(-1), entry.NiceRatio)
+		require.Equal(t, int64(0), entry.ChunkSize)
+		require.Equal(t, "mon-fri 12:00-14:00 pause", entry.String())
+
+		entry = throttleSchedule.entries[1]
+		require.False(t, entry.Pause)
+		require.Equal(t, 1.5, entry.NiceRatio)
+		require.Equal(t, int64(1000), entry.MaxLagMillis)
+		require.Equal(t, int64(500), entry.ChunkSize)
+		require.Equal(t, "mon-fri 08:00-20:00 (UTC)", entry.Schedule.String())
+	}
+	{
+		throttleSchedule, err := ParseThrottleSchedule("22:00-06:00 nice-ratio=0", time.UTC)
+		require.NoError(t, err)
+		require.Equal(t, float64(0), throttleSchedule.entries[0].NiceRatio)
+	}
+	for _, spec := range []string{
+		"",
+		";",
+		"mon-fri 08:00-20:00",
+		"nice-ratio=1",
+		"mon-fri nice-ratio=1 08:00-20:00",
+		"mon-fri 08:00-20:00 nice-ratio=fast",
+		"mon-fri 08:00-20:00 nice-ratio=-1",
+		"mon-fri 08:00-20:00 chunk-size=0",
+		"mon-fri 08:00-20:00 max-lag-millis=0",
+		"mon-fri 08:00-20:00 dml-batch-size=10",
+		"mon-fry 08:00-20:00 pause",
+	} {
+		_, err := ParseThrottleSchedule(spec, time.UTC)
+		require.Error(t, err, spec)
+	}
+}
+
+func TestThrottleScheduleActiveEntry(t *testing.T) {
+	throttleSchedule, err := ParseThrottleSchedule("mon-fri 12:00-14:00 pause; mon-fri 08:00-20:00 nice-ratio=1", time.UTC)
+	require.NoError(t, err)
+
+	// 2022-05-16 is a Monday
+	require.Nil(t, throttleSchedule.ActiveEntry(time.Date(2022, 5, 16, 7, 0, 0, 0, time.UTC)))
+	require.Equal(t, throttleSchedule.entries[1], throttleSchedule.ActiveEntry(time.Date(2022, 5, 16, 9, 0, 0, 0, time.UTC)))
+	require.Equal(t, throttleSchedule.entries[0], throttleSchedule.ActiveEntry(time.Date(2022, 5, 16, 13, 0, 0, 0, time.UTC)))
+	require.Nil(t, throttleSchedule.ActiveEntry(time.Date(2022, 5, 21, 13, 0, 0, 0, time.UTC)))
+}
@@ -7,6 +7,8 @@ package binlog
 
 import (
 	"fmt"
+	"time"
+
 	"github.com/github/gh-ost/go/mysql"
 )
 
@@ -14,6 +16,8 @@ import (
 type BinlogEntry struct {
 	Coordinates mysql.BinlogCoordinates
 	EndLogPos   uint64
+	// Timestamp is the time at which the event was written to the binary log, in second resolution
+	Timestamp time.Time
 
 	DmlEvent *BinlogDMLEvent
 }
@@ -38,6 +42,7 @@ func NewBinlogEntryAt(coordinates mysql.BinlogCoordinates) *BinlogEntry {
 func (this *BinlogEntry) Duplicate() *BinlogEntry {
 	binlogEntry := NewBinlogEntry(this.Coordinates.LogFile, uint64(this.Coordinates.LogPos))
 	binlogEntry.EndLogPos = this.EndLogPos
+	binlogEntry.Timestamp = this.Timestamp
 	return binlogEntry
 }
 
@@ -0,0 +1,170 @@
+/*
+   Copyright 2022 GitHub Inc.
+	 See https://github.com/github/gh-ost/blob/master/LICENSE
+*/
+
+package ctl
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/github/gh-ost/go/logic"
+)
+
+// DefaultSocketGlob matches the socket files gh-ost serves on when --serve-socket-file is not given
+const DefaultSocketGlob = "/tmp/gh-ost.*.sock"
+
+// ErrCommandFailed is returned when gh-ost responds to a command with an error
+var ErrCommandFailed = errors.New("Command failed")
+
+// Target is a migration to send commands to
+type Target struct {
+	Network string
+	Address string
+	// DatabaseName and TableName are those of the migrated table, when told by the socket file name
+	DatabaseName string
+	TableName    string
+}
+
+// Matches tells whether the target migrates the given table; empty names match any
+func (this Target) Matches(databaseName, tableName string) bool {
+	if databaseName != "" && databaseName != this.DatabaseName {
+		return false
+	}
+	if tableName != "" && tableName != this.TableName {
+		return false
+	}
+	return true
+}
+
+// socketTarget returns the target serving on a socket file, telling the migrated table off names such
+// as /tmp/gh-ost.mydb.mytable.sock
+func socketTarget(socketFile string) Target {
+	target := Target{Network: "unix", Address: socketFile}
+	name := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(socketFile), "gh-ost."), ".sock")
+	if databaseName, tableName, ok := strings.Cut(name, "."); ok {
+		target.DatabaseName = databaseName
+		target.TableName = tableName
+	}
+	return target
+}
+
+// DiscoverTargets returns the migrations serving on socket files matching the glob. Socket files
+// which do not accept connections, such as those left behind by a killed gh-ost, are skipped.
+func DiscoverTargets(socketGlob string, dialTimeout time.Duration) ([]Target, error) {
+	socketFiles, err := filepath.Glob(socketGlob)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid socket glob %s: %+v", socketGlob, err)
+	}
+	sort.Strings(socketFiles)
+	targets := []Target{}
+	for _, socketFile := range socketFiles {
+		conn, err := net.DialTimeout("unix", socketFile, dialTimeout)
+		if err != nil {
+			continue
+		}
+		conn.Close()
+		targets = append(targets, socketTarget(socketFile))
+	}
+	return targets, nil
+}
+
+// Client sends commands to a migration
+type Client struct {
+	Network     string
+	Address     string
+	DialTimeout time.Duration
+	// Timeout, unless zero, bounds how long a reply takes
+	Timeout time.Duration
+	// TLSConfig, if set, has the client connect over TLS
+	TLSConfig *tls.Config
+	// Token, if set, authenticates the client, as gh-ost requires with --serve-tcp-auth-file
+	Token string
+}
+
+// roundTrip sends a line and reads the reply through to the connection's close
+func (this *Client) roundTrip(line string) (string, error) {
+	var conn net.Conn
+	var err error
+	dialer := &net.Dialer{Timeout: this.DialTimeout}
+	if this.TLSConfig != nil {
+		conn, err = tls.DialWithDialer(dialer, this.Network, this.Address, this.TLSConfig)
+	} else {
+		conn, err = dialer.Dial(this.Network, this.Address)
+	}
+	if err != nil {
+		return "", fmt.Errorf("Cannot connect to %s: %+v", this.Address, err)
+	}
+	defer conn.Close()
+	if this.Timeout > 0 {
+		conn.SetDeadline(time.Now().Add(this.Timeout))
+	}
+	if this.Token != "" {
+		line = logic.AuthCommandPrefix + this.Token + "\n" + line
+	}
+	if _, err := fmt.Fprintf(conn, "%s\n", line); err != nil {
+		return "", fmt.Errorf("Cannot send command to %s: %+v", this.Address, err)
+	}
+	reply, err := io.ReadAll(conn)
+	if err != nil {
+		return "", fmt.Errorf("Cannot read reply from %s: %+v", this.Address, err)
+	}
+	return string(reply), nil
+}
+
+// Send sends a command, such as "chunk-size=500", and returns the reply. ErrCommandFailed is
+// returned, along with the reply, when gh-ost responds with an error.
+func (this *Client) Send(command string) (string, error) {
+	reply, err := this.roundTrip(logic.CtlCommandPrefix + command)
+	if err != nil {
+		return "", err
+	}
+	if body, ok := strings.CutSuffix(reply, logic.CtlReplyOK+"\n"); ok {
+		return body, nil
+	}
+	if body, ok := strings.CutSuffix(reply, logic.CtlReplyError+"\n"); ok {
+		return body, ErrCommandFailed
+	}
+	if strings.HasPrefix(reply, "Unknown command: "+strings.TrimSpace(logic.CtlCommandPrefix)) {
+		// gh-ost predating ctl tells no errors apart: the reply is all there is
+		return this.roundTrip(command)
+	}
+	if reply == "" {
+		return "", fmt.Errorf("%s closed the connection without replying; does it serve over TLS? See --tls", this.Address)
+	}
+	return "", fmt.Errorf("Unexpected reply from %s: %q", this.Address, reply)
+}
+
+// tlsConfig returns the TLS configuration to connect with: verifying the server against a CA, or
+// the system's, and presenting a client certificate, if given
+func tlsConfig(caFile, certFile, keyFile, serverName string) (*tls.Config, error) {
+	config := &tls.Config{ServerName: serverName, MinVersion: tls.VersionTLS12}
+	if caFile != "" {
+		caCertificates, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(caCertificates) {
+			return nil, fmt.Errorf("No certificates found in %s", caFile)
+		}
+	}
+	if certFile != "" || keyFile != "" {
+		certificate, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("Cannot load client certificate: %+v", err)
+		}
+		config.Certificates = []tls.Certificate{certificate}
+	}
+	return config, nil
+}
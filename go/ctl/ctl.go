@@ -0,0 +1,368 @@
+/*
+   Copyright 2022 GitHub Inc.
+	 See https://github.com/github/gh-ost/blob/master/LICENSE
+*/
+
+// Package ctl implements `gh-ost ctl`, which sends interactive commands to running migrations, and
+// `gh-ost top`, a live dashboard of a running migration.
+package ctl
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+// Exit codes of `gh-ost ctl`, such that scripts tell failures apart
+const (
+	ExitOK = iota
+	// ExitCommandError is returned when gh-ost responded to the command with an error
+	ExitCommandError
+	// ExitUsage is returned for invalid arguments, or when the migration to control is ambiguous
+	ExitUsage
+	// ExitConnectionError is returned when no migration is found, or it cannot be reached
+	ExitConnectionError
+)
+
+// TokenEnvVariable holds the token to authenticate with, unless --token-file is given
+const TokenEnvVariable = "GH_OST_CTL_TOKEN"
+
+const (
+	defaultWatchInterval = 2 * time.Second
+	defaultDialTimeout   = 5 * time.Second
+)
+
+// usageError is an error in the arguments `gh-ost ctl` was invoked with
+type usageError struct {
+	message string
+}
+
+func (this usageError) Error() string {
+	return this.message
+}
+
+func usageErrorf(format string, args ...interface{}) error {
+	return usageError{message: fmt.Sprintf(format, args...)}
+}
+
+// exitCode maps an error onto the exit code `gh-ost ctl` exits with
+func exitCode(err error) int {
+	var usage usageError
+	switch {
+	case err == nil:
+		return ExitOK
+	case errors.As(err, &usage):
+		return ExitUsage
+	case errors.Is(err, ErrCommandFailed):
+		return ExitCommandError
+	default:
+		return ExitConnectionError
+	}
+}
+
+// Ctl is an invocation of `gh-ost ctl`
+type Ctl struct {
+	SocketFile   string
+	TCPAddress   string
+	DatabaseName string
+	TableName    string
+	SocketGlob   string
+	DialTimeout  time.Duration
+	Timeout      time.Duration
+	Raw          bool
+	OutputFile   string
+	Interval     time.Duration
+	Count        int
+	// TLS and authentication of TCP targets
+	TLS           bool
+	TLSCAFile     string
+	TLSCertFile   string
+	TLSKeyFile    string
+	TLSServerName string
+	TokenFile     string
+
+	stdout io.Writer
+	stderr io.Writer
+	now    func() time.Time
+}
+
+const usage = `Usage: gh-ost ctl [flags] <command>
+
+Sends an interactive command to a running migration, and prints its reply.
+
+Commands:
+  list                        List the migrations running on this host
+  watch [<command>]           Repeat a command, by default status, every --interval
+  completion bash             Print a bash completion script
+  <command>[=<value>]         Any interactive command, such as status, chunk-size=500 or max-load=?
+  <command> <value>           Same as <command>=<value>
+
+Exit codes: 0 on success, 1 when gh-ost replied with an error, 2 on usage errors, 3 when the
+migration cannot be found or reached.
+
+Flags:
+`
+
+func newCtl(stdout, stderr io.Writer) *Ctl {
+	return &Ctl{
+		SocketGlob:  DefaultSocketGlob,
+		DialTimeout: defaultDialTimeout,
+		Interval:    defaultWatchInterval,
+		stdout:      stdout,
+		stderr:      stderr,
+		now:         time.Now,
+	}
+}
+
+// targetFlags defines the flags choosing the migration to control
+func (this *Ctl) targetFlags(flagSet *flag.FlagSet) {
+	flagSet.StringVar(&this.SocketFile, "socket", "", "Unix socket file of the migration to control. Default: discovered")
+	flagSet.StringVar(&this.TCPAddress, "tcp", "", "host:port of a migration serving on --serve-tcp-port, rather than a socket file")
+	flagSet.StringVar(&this.DatabaseName, "database", "", "Control the migration of a table in this schema, when several run")
+	flagSet.StringVar(&this.TableName, "table", "", "Control the migration of this table, when several run")
+	flagSet.StringVar(&this.SocketGlob, "socket-glob", this.SocketGlob, "Where to discover socket files")
+	flagSet.DurationVar(&this.DialTimeout, "connect-timeout", this.DialTimeout, "Timeout connecting to the migration")
+	flagSet.BoolVar(&this.TLS, "tls", false, "Connect to --tcp over TLS, as served with --serve-tcp-tls-cert. Implied by the other --tls flags")
+	flagSet.StringVar(&this.TLSCAFile, "tls-ca", "", "CA certificates file (PEM) to verify the migration's certificate with. Default: the system's")
+	flagSet.StringVar(&this.TLSCertFile, "tls-cert", "", "Client certificate file (PEM) to authenticate with")
+	flagSet.StringVar(&this.TLSKeyFile, "tls-key", "", "Private key file (PEM) of --tls-cert")
+	flagSet.StringVar(&this.TLSServerName, "tls-server-name", "", "Name to verify the migration's certificate against. Default: the host of --tcp")
+	flagSet.StringVar(&this.TokenFile, "token-file", "", fmt.Sprintf("File holding the token to authenticate with, as listed in --serve-tcp-auth-file. Default: $%s", TokenEnvVariable))
+}
+
+func (this *Ctl) flagSet() *flag.FlagSet {
+	flagSet := flag.NewFlagSet("gh-ost ctl", flag.ContinueOnError)
+	flagSet.SetOutput(this.stderr)
+	this.targetFlags(flagSet)
+	flagSet.DurationVar(&this.Timeout, "timeout", 0, "Timeout awaiting a reply; 0 to wait for as long as the command takes, such as a cpu-profile")
+	flagSet.BoolVar(&this.Raw, "raw", false, "Print replies as received rather than pretty-printed")
+	flagSet.StringVar(&this.OutputFile, "output", "", "File to write a cpu-profile to. Default: gh-ost.<database>.<table>.<time>.pprof in the current directory")
+	flagSet.DurationVar(&this.Interval, "interval", this.Interval, "Interval between replies of watch")
+	flagSet.IntVar(&this.Count, "count", 0, "Number of replies watch prints before it exits; 0 to watch until the migration ends")
+	flagSet.Usage = func() {
+		fmt.Fprint(this.stderr, usage)
+		flagSet.PrintDefaults()
+	}
+	return flagSet
+}
+
+// Main runs `gh-ost ctl` with the given arguments, those following "ctl", and returns the exit code
+func Main(args []string) int {
+	return run(args, os.Stdout, os.Stderr)
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	ctl := newCtl(stdout, stderr)
+	flagSet := ctl.flagSet()
+	if err := flagSet.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return ExitOK
+		}
+		return ExitUsage
+	}
+	err := ctl.run(flagSet.Args())
+	if err != nil && !errors.Is(err, ErrCommandFailed) {
+		fmt.Fprintf(stderr, "gh-ost ctl: %+v\n", err)
+	}
+	return exitCode(err)
+}
+
+func (this *Ctl) run(args []string) error {
+	if len(args) == 0 {
+		return usageErrorf("No command given; see gh-ost ctl --help")
+	}
+	switch args[0] {
+	case "list":
+		if len(args) > 1 {
+			return usageErrorf("list takes no arguments")
+		}
+		return this.list()
+	case "completion":
+		if len(args) != 2 || args[1] != "bash" {
+			return usageErrorf("Usage: gh-ost ctl completion bash")
+		}
+		fmt.Fprint(this.stdout, bashCompletion())
+		return nil
+	case "watch":
+		command, err := joinCommand(args[1:])
+		if err != nil {
+			return err
+		}
+		if command == "" {
+			command = "status"
+		}
+		return this.watch(command)
+	}
+	command, err := joinCommand(args)
+	if err != nil {
+		return err
+	}
+	return this.send(command)
+}
+
+// joinCommand joins `name value` arguments into the `name=value` form the server reads
+func joinCommand(args []string) (string, error) {
+	switch len(args) {
+	case 0:
+		return "", nil
+	case 1:
+		return strings.TrimSpace(args[0]), nil
+	case 2:
+		if strings.Contains(args[0], "=") {
+			return "", usageErrorf("Unexpected argument: %s", args[1])
+		}
+		return fmt.Sprintf("%s=%s", strings.TrimSpace(args[0]), args[1]), nil
+	default:
+		return "", usageErrorf("Too many arguments; quote values holding spaces, e.g. throttle-query='select ...'")
+	}
+}
+
+// commandName returns the name of a `name=value` command
+func commandName(command string) string {
+	name, _, _ := strings.Cut(command, "=")
+	return strings.TrimSpace(name)
+}
+
+// send sends a command to the migration and prints its reply
+func (this *Ctl) send(command string) error {
+	target, err := this.target()
+	if err != nil {
+		return err
+	}
+	reply, err := this.request(target, command)
+	if commandName(command) == "cpu-profile" && err == nil {
+		return this.writeCPUProfile(target, command, reply)
+	}
+	if errors.Is(err, ErrCommandFailed) {
+		// The reply is gh-ost's error message
+		fmt.Fprint(this.stderr, reply)
+		return err
+	}
+	this.printReply(reply)
+	return err
+}
+
+func (this *Ctl) request(target Target, command string) (string, error) {
+	client, err := this.client(target)
+	if err != nil {
+		return "", err
+	}
+	return client.Send(command)
+}
+
+// client returns the client of a target, which, over TCP, may connect over TLS and authenticate
+func (this *Ctl) client(target Target) (*Client, error) {
+	client := &Client{Network: target.Network, Address: target.Address, DialTimeout: this.DialTimeout, Timeout: this.Timeout}
+	if target.Network != "tcp" {
+		return client, nil
+	}
+	if this.TLS || this.TLSCAFile != "" || this.TLSCertFile != "" || this.TLSKeyFile != "" || this.TLSServerName != "" {
+		serverName := this.TLSServerName
+		if serverName == "" {
+			serverName, _, _ = net.SplitHostPort(target.Address)
+		}
+		config, err := tlsConfig(this.TLSCAFile, this.TLSCertFile, this.TLSKeyFile, serverName)
+		if err != nil {
+			return nil, err
+		}
+		client.TLSConfig = config
+	}
+	client.Token = os.Getenv(TokenEnvVariable)
+	if this.TokenFile != "" {
+		token, err := os.ReadFile(this.TokenFile)
+		if err != nil {
+			return nil, err
+		}
+		client.Token = strings.TrimSpace(string(token))
+	}
+	return client, nil
+}
+
+func (this *Ctl) printReply(reply string) {
+	if !this.Raw {
+		reply = Prettify(reply)
+	}
+	fmt.Fprint(this.stdout, reply)
+}
+
+// list prints the migrations running on this host
+func (this *Ctl) list() error {
+	targets, err := DiscoverTargets(this.SocketGlob, this.DialTimeout)
+	if err != nil {
+		return err
+	}
+	for _, target := range targets {
+		fmt.Fprintf(this.stdout, "%s\t%s.%s\n", target.Address, target.DatabaseName, target.TableName)
+	}
+	return nil
+}
+
+// target returns the migration to control: the one given by --socket or --tcp, or the one
+// discovered running, as narrowed down by --database and --table
+func (this *Ctl) target() (Target, error) {
+	if this.SocketFile != "" && this.TCPAddress != "" {
+		return Target{}, usageErrorf("--socket and --tcp are mutually exclusive")
+	}
+	if this.SocketFile != "" {
+		return Target{Network: "unix", Address: this.SocketFile}, nil
+	}
+	if this.TCPAddress != "" {
+		return Target{Network: "tcp", Address: this.TCPAddress}, nil
+	}
+	targets, err := DiscoverTargets(this.SocketGlob, this.DialTimeout)
+	if err != nil {
+		return Target{}, err
+	}
+	matching := []Target{}
+	for _, target := range targets {
+		if target.Matches(this.DatabaseName, this.TableName) {
+			matching = append(matching, target)
+		}
+	}
+	switch len(matching) {
+	case 0:
+		return Target{}, fmt.Errorf("No running migration found at %s; see --socket", this.SocketGlob)
+	case 1:
+		return matching[0], nil
+	}
+	names := make([]string, 0, len(matching))
+	for _, target := range matching {
+		names = append(names, fmt.Sprintf("%s.%s", target.DatabaseName, target.TableName))
+	}
+	return Target{}, usageErrorf("%d migrations are running: %s; choose one with --database and --table, or --socket", len(matching), strings.Join(names, ", "))
+}
+
+// watch repeats a command, printing each reply onto a cleared terminal, until the migration ends
+func (this *Ctl) watch(command string) error {
+	if commandName(command) == "cpu-profile" {
+		return usageErrorf("Cannot watch cpu-profile")
+	}
+	if this.Interval <= 0 {
+		return usageErrorf("--interval must be positive")
+	}
+	target, err := this.target()
+	if err != nil {
+		return err
+	}
+	clear := isTerminal(this.stdout)
+	for i := 1; ; i++ {
+		reply, err := this.request(target, command)
+		if err != nil && !errors.Is(err, ErrCommandFailed) {
+			return err
+		}
+		if clear {
+			fmt.Fprint(this.stdout, "\033[H\033[2J")
+		}
+		fmt.Fprintf(this.stdout, "Every %s: %s on %s\t%s\n\n", this.Interval, command, target.Address, this.now().Format(time.RFC1123))
+		this.printReply(reply)
+		if this.Count > 0 && i >= this.Count {
+			return err
+		}
+		time.Sleep(this.Interval)
+	}
+}
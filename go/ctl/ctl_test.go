@@ -0,0 +1,164 @@
+/*
+   Copyright 2022 GitHub Inc.
+	 See https://github.com/github/gh-ost/blob/master/LICENSE
+*/
+
+package ctl
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/github/gh-ost/go/base"
+	"github.com/github/gh-ost/go/logic"
+	"github.com/stretchr/testify/require"
+)
+
+const testStatusLine = "Copy: 100/200 50.0%; Applied: 3; Backlog: 0/1000; Time: 41s(total), 40s(copy); streamer: mysql-bin.000550:49942; Lag: 0.01s, HeartbeatLag: 0.01s, ApplyLag: 0.00s, State: throttled, flag-file; ETA: N/A"
+
+// serveMigration serves interactive commands on a socket file in dir, as a migration of the given
+// table does
+func serveMigration(t *testing.T, dir string, databaseName, tableName string) *base.MigrationContext {
+	migrationContext := base.NewMigrationContext()
+	migrationContext.DatabaseName = databaseName
+	migrationContext.OriginalTableName = tableName
+	migrationContext.ServeSocketFile = filepath.Join(dir, fmt.Sprintf("gh-ost.%s.%s.sock", databaseName, tableName))
+	server := logic.NewServer(migrationContext, nil, logic.NewHooksExecutor(migrationContext), func(rule logic.PrintStatusRule, writer io.Writer) {
+		if rule != logic.NoPrintStatusRule {
+			fmt.Fprintln(writer, testStatusLine)
+		}
+	})
+	require.NoError(t, server.BindSocketFile())
+	require.NoError(t, server.Serve())
+	return migrationContext
+}
+
+func runCtl(args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	exitCode := run(args, &stdout, &stderr)
+	return exitCode, stdout.String(), stderr.String()
+}
+
+func TestStatusFields(t *testing.T) {
+	require.Equal(t, [][2]string{
+		{"Copy", "100/200 50.0%"},
+		{"Applied", "3"},
+		{"Backlog", "0/1000"},
+		{"Time", "41s(total), 40s(copy)"},
+		{"streamer", "mysql-bin.000550:49942"},
+		{"Lag", "0.01s"},
+		{"HeartbeatLag", "0.01s"},
+		{"ApplyLag", "0.00s"},
+		{"State", "throttled, flag-file"},
+		{"ETA", "N/A"},
+	}, statusFields(testStatusLine))
+
+	pretty := Prettify("# Migrating `test`.`t`\n" + testStatusLine + "\n")
+	require.Contains(t, pretty, "# Migrating `test`.`t`\n")
+	require.Contains(t, pretty, "Copy:         100/200 50.0%\n")
+	require.Contains(t, pretty, "State:        throttled, flag-file\n")
+}
+
+func TestJoinCommand(t *testing.T) {
+	command, err := joinCommand([]string{"chunk-size", "500"})
+	require.NoError(t, err)
+	require.Equal(t, "chunk-size=500", command)
+
+	command, err = joinCommand([]string{"max-load=?"})
+	require.NoError(t, err)
+	require.Equal(t, "max-load=?", command)
+
+	_, err = joinCommand([]string{"chunk-size=1", "500"})
+	require.Error(t, err)
+	_, err = joinCommand([]string{"throttle-query", "select", "1"})
+	require.Error(t, err)
+}
+
+func TestCtl(t *testing.T) {
+	dir, err := os.MkdirTemp("", "ctl")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	socketGlob := "--socket-glob=" + filepath.Join(dir, "gh-ost.*.sock")
+
+	exitCode, _, stderr := runCtl(socketGlob, "status")
+	require.Equal(t, ExitConnectionError, exitCode)
+	require.Contains(t, stderr, "No running migration found")
+
+	migrationContext := serveMigration(t, dir, "test", "t1")
+	// A socket file left behind by a killed migration
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "gh-ost.test.stale.sock"), nil, 0644))
+
+	exitCode, stdout, _ := runCtl(socketGlob, "list")
+	require.Equal(t, ExitOK, exitCode)
+	require.Equal(t, fmt.Sprintf("%s\ttest.t1\n", migrationContext.ServeSocketFile), stdout)
+
+	exitCode, stdout, _ = runCtl(socketGlob, "chunk-size", "500")
+	require.Equal(t, ExitOK, exitCode)
+	require.Contains(t, stdout, "Copy:         100/200 50.0%")
+	require.Equal(t, int64(500), migrationContext.ChunkSize)
+
+	exitCode, stdout, _ = runCtl(socketGlob, "--raw", "sup")
+	require.Equal(t, ExitOK, exitCode)
+	require.Equal(t, testStatusLine+"\n", stdout)
+
+	exitCode, stdout, _ = runCtl(socketGlob, "chunk-size=?")
+	require.Equal(t, ExitOK, exitCode)
+	require.Equal(t, "500\n", stdout)
+
+	exitCode, _, stderr = runCtl(socketGlob, "chunk-size=many")
+	require.Equal(t, ExitCommandError, exitCode)
+	require.Contains(t, stderr, "invalid syntax")
+
+	exitCode, _, stderr = runCtl(socketGlob, "no-such-command")
+	require.Equal(t, ExitCommandError, exitCode)
+	require.Equal(t, "Unknown command: no-such-command\n", stderr)
+
+	exitCode, _, _ = runCtl("--socket", migrationContext.ServeSocketFile, "--tcp", "localhost:1", "sup")
+	require.Equal(t, ExitUsage, exitCode)
+	exitCode, _, _ = runCtl("--socket", filepath.Join(dir, "gh-ost.test.stale.sock"), "sup")
+	require.Equal(t, ExitConnectionError, exitCode)
+	exitCode, _, _ = runCtl()
+	require.Equal(t, ExitUsage, exitCode)
+
+	exitCode, stdout, _ = runCtl(socketGlob, "--interval=1ms", "--count=2", "--raw", "watch", "sup")
+	require.Equal(t, ExitOK, exitCode)
+	require.Equal(t, 2, strings.Count(stdout, testStatusLine))
+	require.Equal(t, 2, strings.Count(stdout, "Every 1ms: sup on "))
+
+	outputFile := filepath.Join(dir, "cpu.pprof")
+	exitCode, stdout, _ = runCtl(socketGlob, "--output", outputFile, "cpu-profile=10ms")
+	require.Equal(t, ExitOK, exitCode)
+	require.Contains(t, stdout, "go tool pprof "+outputFile)
+	profile, err := os.ReadFile(outputFile)
+	require.NoError(t, err)
+	require.NotEmpty(t, profile)
+
+	// Several migrations are running
+	serveMigration(t, dir, "test", "t2")
+	exitCode, _, stderr = runCtl(socketGlob, "sup")
+	require.Equal(t, ExitUsage, exitCode)
+	require.Contains(t, stderr, "test.t1, test.t2")
+	exitCode, _, _ = runCtl(socketGlob, "--table=t2", "chunk-size=?")
+	require.Equal(t, ExitOK, exitCode)
+}
+
+func TestCPUProfileFileName(t *testing.T) {
+	target := Target{DatabaseName: "test", TableName: "t1"}
+	require.Equal(t, "gh-ost.test.t1.20240101-120000.pprof", cpuProfileFileName(target, "cpu-profile", "20240101-120000"))
+	require.Equal(t, "gh-ost.test.t1.20240101-120000.pprof.gz", cpuProfileFileName(target, "cpu-profile=10s,block,gzip", "20240101-120000"))
+	require.Equal(t, "gh-ost.20240101-120000.pprof", cpuProfileFileName(Target{}, "cpu-profile", "20240101-120000"))
+}
+
+func TestBashCompletion(t *testing.T) {
+	exitCode, stdout, _ := runCtl("completion", "bash")
+	require.Equal(t, ExitOK, exitCode)
+	require.Contains(t, stdout, "complete -o default -F _gh_ost_ctl gh-ost")
+	require.Contains(t, stdout, " max-load ")
+	require.Contains(t, stdout, " no-postpone ")
+	require.Contains(t, stdout, "--socket ")
+}
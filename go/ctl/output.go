@@ -0,0 +1,121 @@
+/*
+   Copyright 2022 GitHub Inc.
+	 See https://github.com/github/gh-ost/blob/master/LICENSE
+*/
+
+package ctl
+
+import (
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/github/gh-ost/go/logic"
+	"golang.org/x/term"
+)
+
+// statusKeyRegexp matches the start of a status line field, such as "Backlog: "
+var statusKeyRegexp = regexp.MustCompile(`^[A-Za-z]+: `)
+
+// statusFields splits a status line, such as "Copy: 0/2915 0.0%; Applied: 0; ...; Lag: 0.01s,
+// HeartbeatLag: 0.01s, State: throttled, flag-file; ETA: N/A", into its key and value pairs
+func statusFields(line string) [][2]string {
+	fields := [][2]string{}
+	for _, part := range strings.Split(line, "; ") {
+		for i, field := range strings.Split(part, ", ") {
+			key, value, _ := strings.Cut(field, ": ")
+			if i > 0 && len(fields) > 0 && !statusKeyRegexp.MatchString(field) {
+				// A value holding a comma, such as a throttle reason
+				fields[len(fields)-1][1] += ", " + field
+				continue
+			}
+			fields = append(fields, [2]string{key, value})
+		}
+	}
+	return fields
+}
+
+// Prettify lays out status lines of a reply as aligned key and value lines. Other lines are kept.
+func Prettify(reply string) string {
+	var pretty strings.Builder
+	for _, line := range strings.SplitAfter(reply, "\n") {
+		if !strings.HasPrefix(line, "Copy: ") {
+			pretty.WriteString(line)
+			continue
+		}
+		writer := tabwriter.NewWriter(&pretty, 0, 0, 1, ' ', 0)
+		for _, field := range statusFields(strings.TrimSpace(line)) {
+			fmt.Fprintf(writer, "%s:\t%s\n", field[0], field[1])
+		}
+		writer.Flush()
+	}
+	return pretty.String()
+}
+
+// cpuProfileFileName returns the file a cpu-profile is written to by default
+func cpuProfileFileName(target Target, command string, timestamp string) string {
+	name := "gh-ost"
+	if target.DatabaseName != "" {
+		name = fmt.Sprintf("gh-ost.%s.%s", target.DatabaseName, target.TableName)
+	}
+	name = fmt.Sprintf("%s.%s.pprof", name, timestamp)
+	_, options, _ := strings.Cut(command, "=")
+	for _, option := range strings.Split(options, ",") {
+		if strings.TrimSpace(option) == "gzip" {
+			return name + ".gz"
+		}
+	}
+	return name
+}
+
+// writeCPUProfile decodes the base64 encoded reply to a cpu-profile command onto a file
+func (this *Ctl) writeCPUProfile(target Target, command string, reply string) error {
+	profile, err := base64.StdEncoding.DecodeString(strings.TrimSpace(reply))
+	if err != nil {
+		return fmt.Errorf("Cannot decode cpu-profile: %+v", err)
+	}
+	outputFile := this.OutputFile
+	if outputFile == "" {
+		outputFile = cpuProfileFileName(target, command, this.now().Format("20060102-150405"))
+	}
+	if err := os.WriteFile(outputFile, profile, 0644); err != nil {
+		return err
+	}
+	fmt.Fprintf(this.stdout, "Wrote %d bytes of CPU profile to %s; inspect with: go tool pprof %s\n", len(profile), outputFile, outputFile)
+	return nil
+}
+
+// bashCompletion returns a bash script completing `gh-ost ctl` subcommands, command names and flags
+func bashCompletion() string {
+	words := []string{"list", "watch", "completion"}
+	for _, interactiveCommand := range logic.InteractiveCommands {
+		words = append(words, interactiveCommand.Name)
+		words = append(words, interactiveCommand.Aliases...)
+	}
+	flags := []string{}
+	newCtl(io.Discard, io.Discard).flagSet().VisitAll(func(f *flag.Flag) {
+		flags = append(flags, "--"+f.Name)
+	})
+	return fmt.Sprintf(`# gh-ost ctl completion; load with: source <(gh-ost ctl completion bash)
+_gh_ost_ctl() {
+  local cur="${COMP_WORDS[COMP_CWORD]}"
+  [ "${COMP_WORDS[1]}" = "ctl" ] || return 0
+  if [[ "$cur" == -* ]]; then
+    COMPREPLY=( $(compgen -W "%s" -- "$cur") )
+  else
+    COMPREPLY=( $(compgen -W "%s" -- "$cur") )
+  fi
+}
+complete -o default -F _gh_ost_ctl gh-ost
+`, strings.Join(flags, " "), strings.Join(words, " "))
+}
+
+func isTerminal(writer io.Writer) bool {
+	file, ok := writer.(*os.File)
+	return ok && term.IsTerminal(int(file.Fd()))
+}
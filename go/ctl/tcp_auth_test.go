@@ -0,0 +1,147 @@
+/*
+   Copyright 2022 GitHub Inc.
+	 See https://github.com/github/gh-ost/blob/master/LICENSE
+*/
+
+package ctl
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/github/gh-ost/go/base"
+	"github.com/github/gh-ost/go/logic"
+	"github.com/stretchr/testify/require"
+)
+
+// writeCertificate writes a certificate, signed by the parent, and its key, as PEM files
+func writeCertificate(t *testing.T, dir, name string, template *x509.Certificate, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	require.NoError(t, err)
+	certificate, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name+".pem"), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name+"-key.pem"), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600))
+	return certificate, key
+}
+
+func certificateTemplate(serial int64, commonName string) *x509.Certificate {
+	return &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+}
+
+func freeTCPPort(t *testing.T) int {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+func TestCtlTCPAuth(t *testing.T) {
+	dir := t.TempDir()
+	caTemplate := certificateTemplate(1, "gh-ost test CA")
+	caTemplate.IsCA = true
+	caTemplate.BasicConstraintsValid = true
+	caTemplate.KeyUsage = x509.KeyUsageCertSign
+	ca, caKey := writeCertificate(t, dir, "ca", caTemplate, nil, nil)
+	serverTemplate := certificateTemplate(2, "gh-ost")
+	serverTemplate.DNSNames = []string{"localhost"}
+	serverTemplate.IPAddresses = []net.IP{net.ParseIP("127.0.0.1")}
+	serverTemplate.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+	writeCertificate(t, dir, "server", serverTemplate, ca, caKey)
+	for serial, commonName := range []string{"deploy-bot", "stranger"} {
+		clientTemplate := certificateTemplate(int64(serial+3), commonName)
+		clientTemplate.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+		writeCertificate(t, dir, commonName, clientTemplate, ca, caKey)
+	}
+	authFile := filepath.Join(dir, "auth")
+	require.NoError(t, os.WriteFile(authFile, []byte("alice admin token:s3cr3t\ngrafana read-only token:dashb0ard\ndeploy-bot admin cert\n"), 0600))
+	tokenFile := filepath.Join(dir, "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("s3cr3t\n"), 0600))
+
+	migrationContext := base.NewMigrationContext()
+	migrationContext.ServeSocketFile = filepath.Join(dir, "gh-ost.test.t1.sock")
+	migrationContext.ServeTCPPort = int64(freeTCPPort(t))
+	migrationContext.ServeTCPTLSCertFile = filepath.Join(dir, "server.pem")
+	migrationContext.ServeTCPTLSKeyFile = filepath.Join(dir, "server-key.pem")
+	migrationContext.ServeTCPTLSCAFile = filepath.Join(dir, "ca.pem")
+	auth, err := base.ReadServerAuthFile(authFile)
+	require.NoError(t, err)
+	migrationContext.ServeTCPAuth = auth
+	server := logic.NewServer(migrationContext, nil, logic.NewHooksExecutor(migrationContext), func(rule logic.PrintStatusRule, writer io.Writer) {
+		if rule != logic.NoPrintStatusRule {
+			fmt.Fprintln(writer, testStatusLine)
+		}
+	})
+	require.NoError(t, server.BindSocketFile())
+	require.NoError(t, server.BindTCPPort())
+	require.NoError(t, server.Serve())
+
+	tcp := fmt.Sprintf("--tcp=127.0.0.1:%d", migrationContext.ServeTCPPort)
+	tlsCA := "--tls-ca=" + filepath.Join(dir, "ca.pem")
+
+	exitCode, _, stderr := runCtl(tcp, tlsCA, "sup")
+	require.Equal(t, ExitCommandError, exitCode)
+	require.Equal(t, "Authentication required\n", stderr)
+
+	exitCode, _, stderr = runCtl(tcp, "sup")
+	require.Equal(t, ExitConnectionError, exitCode)
+	require.Contains(t, stderr, "does it serve over TLS?")
+
+	t.Setenv(TokenEnvVariable, "wrong")
+	exitCode, _, stderr = runCtl(tcp, tlsCA, "sup")
+	require.Equal(t, ExitCommandError, exitCode)
+	require.Equal(t, "Authentication failed\n", stderr)
+
+	t.Setenv(TokenEnvVariable, "dashb0ard")
+	exitCode, stdout, _ := runCtl(tcp, tlsCA, "--raw", "sup")
+	require.Equal(t, ExitOK, exitCode)
+	require.Equal(t, testStatusLine+"\n", stdout)
+	exitCode, stdout, _ = runCtl(tcp, tlsCA, "chunk-size=?")
+	require.Equal(t, ExitOK, exitCode)
+	require.Equal(t, "1000\n", stdout)
+	exitCode, _, stderr = runCtl(tcp, tlsCA, "chunk-size", "500")
+	require.Equal(t, ExitCommandError, exitCode)
+	require.Contains(t, stderr, "requires the admin role")
+	require.Equal(t, int64(1000), migrationContext.ChunkSize)
+
+	exitCode, _, _ = runCtl(tcp, tlsCA, "--token-file", tokenFile, "chunk-size", "500")
+	require.Equal(t, ExitOK, exitCode)
+	require.Equal(t, int64(500), migrationContext.ChunkSize)
+
+	t.Setenv(TokenEnvVariable, "")
+	exitCode, _, _ = runCtl(tcp, tlsCA, "--tls-cert", filepath.Join(dir, "deploy-bot.pem"), "--tls-key", filepath.Join(dir, "deploy-bot-key.pem"), "chunk-size", "600")
+	require.Equal(t, ExitOK, exitCode)
+	require.Equal(t, int64(600), migrationContext.ChunkSize)
+	exitCode, _, stderr = runCtl(tcp, tlsCA, "--tls-cert", filepath.Join(dir, "stranger.pem"), "--tls-key", filepath.Join(dir, "stranger-key.pem"), "sup")
+	require.Equal(t, ExitCommandError, exitCode)
+	require.Equal(t, "Certificate of stranger is not listed in --serve-tcp-auth-file\n", stderr)
+
+	// The socket file requires no authentication
+	exitCode, _, _ = runCtl("--socket", migrationContext.ServeSocketFile, "chunk-size", "700")
+	require.Equal(t, ExitOK, exitCode)
+	require.Equal(t, int64(700), migrationContext.ChunkSize)
+}
@@ -0,0 +1,328 @@
+/*
+   Copyright 2022 GitHub Inc.
+	 See https://github.com/github/gh-ost/blob/master/LICENSE
+*/
+
+package ctl
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/github/gh-ost/go/base"
+	"golang.org/x/term"
+)
+
+const (
+	defaultTopInterval = time.Second
+	progressBarWidth   = 40
+	// chunkSizeStep is the factor the chunk-size keys grow or shrink the chunk-size by
+	chunkSizeStep = 1.25
+	// niceRatioStep is what the nice-ratio keys add to or subtract from the nice-ratio
+	niceRatioStep = 0.1
+)
+
+// Status is the status of a migration, as printed by the status-json command
+type Status struct {
+	Database            string  `json:"database"`
+	Table               string  `json:"table"`
+	Phase               string  `json:"phase"`
+	CopiedRows          int64   `json:"copied_rows"`
+	EstimatedRows       int64   `json:"estimated_rows"`
+	ProgressPct         float64 `json:"progress_pct"`
+	AppliedEvents       int64   `json:"applied_events"`
+	Backlog             int64   `json:"backlog"`
+	BacklogCapacity     int64   `json:"backlog_capacity"`
+	SpilledEvents       int64   `json:"spilled_events"`
+	SpilledBytes        int64   `json:"spilled_bytes"`
+	ElapsedSeconds      float64 `json:"elapsed_seconds"`
+	CopyElapsedSeconds  float64 `json:"copy_elapsed_seconds"`
+	BinlogFile          string  `json:"binlog_file"`
+	BinlogPos           int64   `json:"binlog_pos"`
+	LagSeconds          float64 `json:"lag_seconds"`
+	HeartbeatLagSeconds float64 `json:"heartbeat_lag_seconds"`
+	ApplyLagSeconds     float64 `json:"apply_lag_seconds"`
+	State               string  `json:"state"`
+	ETA                 string  `json:"eta"`
+	RowsPerSecond       int64   `json:"rows_per_second"`
+	ChunkSize           int64   `json:"chunk_size"`
+	NiceRatio           float64 `json:"nice_ratio"`
+	PostponingCutOver   bool    `json:"postponing_cut_over"`
+	Throttled           bool    `json:"throttled"`
+	ThrottleReason      string  `json:"throttle_reason"`
+	ThrottleHistory     []struct {
+		Time      string `json:"time"`
+		Throttled bool   `json:"throttled"`
+		Reason    string `json:"reason"`
+	} `json:"throttle_history"`
+	ControlReplicas []struct {
+		Replica    string  `json:"replica"`
+		LagSeconds float64 `json:"lag_seconds"`
+		Error      string  `json:"error"`
+	} `json:"control_replicas"`
+	ChunkRangeMin string `json:"chunk_range_min"`
+	ChunkRangeMax string `json:"chunk_range_max"`
+	Iteration     int64  `json:"iteration"`
+}
+
+// ParseStatus parses the reply to the status-json command
+func ParseStatus(reply string) (*Status, error) {
+	status := &Status{}
+	if err := json.Unmarshal([]byte(reply), status); err != nil {
+		return nil, fmt.Errorf("Cannot parse status: %+v; is gh-ost older than gh-ost top?", err)
+	}
+	return status, nil
+}
+
+// top is the state of `gh-ost top`: the latest status, and the outcome of the latest key pressed
+type top struct {
+	target     Target
+	status     *Status
+	polledAt   time.Time
+	pollError  error
+	message    string
+	confirming bool
+}
+
+// onKey handles a key press, returning the command it sends, if any, and whether to quit
+func (this *top) onKey(key byte) (command string, quit bool) {
+	if this.confirming {
+		this.confirming = false
+		if key == 'y' || key == 'Y' {
+			return "unpostpone", false
+		}
+		this.message = "Cut-over not requested"
+		return "", false
+	}
+	switch key {
+	case 'q', 'Q', 3: // ctrl-c, as the terminal is raw
+		return "", true
+	case 't':
+		return "throttle", false
+	case 'r':
+		return "no-throttle", false
+	case 'c':
+		this.confirming = true
+		this.message = "Proceed to cut-over? [y/N]"
+		return "", false
+	}
+	if this.status == nil {
+		return "", false
+	}
+	switch key {
+	case '+', '=':
+		return fmt.Sprintf("chunk-size=%d", int64(math.Ceil(float64(this.status.ChunkSize)*chunkSizeStep))), false
+	case '-', '_':
+		return fmt.Sprintf("chunk-size=%d", int64(math.Floor(float64(this.status.ChunkSize)/chunkSizeStep))), false
+	case '>', '.':
+		return fmt.Sprintf("nice-ratio=%.2f", this.status.NiceRatio+niceRatioStep), false
+	case '<', ',':
+		return fmt.Sprintf("nice-ratio=%.2f", math.Max(this.status.NiceRatio-niceRatioStep, 0)), false
+	}
+	return "", false
+}
+
+func seconds(s float64) string {
+	return base.PrettifyDurationOutput(time.Duration(s * float64(time.Second)))
+}
+
+func progressBar(progressPct float64) string {
+	filled := int(math.Round(progressPct / 100 * progressBarWidth))
+	filled = int(math.Max(0, math.Min(progressBarWidth, float64(filled))))
+	return "[" + strings.Repeat("#", filled) + strings.Repeat(".", progressBarWidth-filled) + "]"
+}
+
+// render returns the screen's lines, each cut to the terminal's width
+func (this *top) render(width int) []string {
+	lines := []string{fmt.Sprintf("gh-ost top: %s   %s", this.target.Address, this.polledAt.Format("2006-01-02 15:04:05"))}
+	status := this.status
+	if status == nil {
+		lines = append(lines, "", "Awaiting status...")
+	} else {
+		avgRowsPerSecond := 0.0
+		if status.CopyElapsedSeconds > 0 {
+			avgRowsPerSecond = float64(status.CopiedRows) / status.CopyElapsedSeconds
+		}
+		backlog := fmt.Sprintf("%d/%d", status.Backlog, status.BacklogCapacity)
+		if status.SpilledEvents > 0 {
+			backlog = fmt.Sprintf("%s, spilled: %d (%.1fMB)", backlog, status.SpilledEvents, float64(status.SpilledBytes)/(1024*1024))
+		}
+		lines = append(lines,
+			fmt.Sprintf("Table:     %s.%s   phase: %s", status.Database, status.Table, status.Phase),
+			fmt.Sprintf("State:     %s", status.State),
+			"",
+			fmt.Sprintf("Progress:  %s %.1f%%   %d/%d rows", progressBar(status.ProgressPct), status.ProgressPct, status.CopiedRows, status.EstimatedRows),
+			fmt.Sprintf("Rows/s:    %d now, %.0f average", status.RowsPerSecond, avgRowsPerSecond),
+			fmt.Sprintf("ETA:       %s", status.ETA),
+			fmt.Sprintf("Time:      %s total, %s copy", seconds(status.ElapsedSeconds), seconds(status.CopyElapsedSeconds)),
+			fmt.Sprintf("Applied:   %d events   backlog: %s", status.AppliedEvents, backlog),
+			fmt.Sprintf("Lag:       %.2fs replication, %.2fs heartbeat, %.2fs apply", status.LagSeconds, status.HeartbeatLagSeconds, status.ApplyLagSeconds),
+			fmt.Sprintf("Streamer:  %s:%d", status.BinlogFile, status.BinlogPos),
+			fmt.Sprintf("Chunk:     iteration %d, range (%s) .. (%s)", status.Iteration, status.ChunkRangeMin, status.ChunkRangeMax),
+			fmt.Sprintf("Settings:  chunk-size %d, nice-ratio %.2f", status.ChunkSize, status.NiceRatio),
+		)
+		if len(status.ControlReplicas) > 0 {
+			lines = append(lines, "", "Control replicas:")
+			for _, controlReplica := range status.ControlReplicas {
+				if controlReplica.Error != "" {
+					lines = append(lines, fmt.Sprintf("  %-30s error: %s", controlReplica.Replica, controlReplica.Error))
+				} else {
+					lines = append(lines, fmt.Sprintf("  %-30s %.2fs", controlReplica.Replica, controlReplica.LagSeconds))
+				}
+			}
+		}
+		if len(status.ThrottleHistory) > 0 {
+			lines = append(lines, "", "Throttle history:")
+			for i := len(status.ThrottleHistory) - 1; i >= 0; i-- {
+				throttleEvent := status.ThrottleHistory[i]
+				change := "throttled: " + throttleEvent.Reason
+				if !throttleEvent.Throttled {
+					change = "unthrottled"
+				}
+				eventTime := throttleEvent.Time
+				if parsed, err := time.Parse(time.RFC3339, eventTime); err == nil {
+					eventTime = parsed.Local().Format("15:04:05")
+				}
+				lines = append(lines, fmt.Sprintf("  %s %s", eventTime, change))
+			}
+		}
+	}
+	lines = append(lines, "", "[t] throttle  [r] resume  [+/-] chunk-size  [>/<] nice-ratio  [c] cut-over  [q] quit")
+	if this.pollError != nil {
+		lines = append(lines, fmt.Sprintf("Cannot get status: %+v", this.pollError))
+	}
+	if this.message != "" {
+		lines = append(lines, this.message)
+	}
+	if width > 0 {
+		for i, line := range lines {
+			if len(line) > width {
+				lines[i] = line[:width]
+			}
+		}
+	}
+	return lines
+}
+
+// poll reads the migration's status
+func (this *top) poll(ctl *Ctl, now time.Time) {
+	this.polledAt = now
+	reply, err := ctl.request(this.target, "status-json")
+	if err == nil {
+		this.status, err = ParseStatus(reply)
+	}
+	this.pollError = err
+}
+
+// send sends a command upon a key press, and tells its outcome
+func (this *top) send(ctl *Ctl, command string) {
+	reply, err := ctl.request(this.target, command)
+	switch {
+	case errors.Is(err, ErrCommandFailed):
+		this.message = fmt.Sprintf("%s: %s", command, strings.TrimSpace(reply))
+	case err != nil:
+		this.message = fmt.Sprintf("%s: %+v", command, err)
+	default:
+		this.message = fmt.Sprintf("Sent %s", command)
+	}
+}
+
+// TopMain runs `gh-ost top` with the given arguments, those following "top", and returns the exit code
+func TopMain(args []string) int {
+	ctl := newCtl(os.Stdout, os.Stderr)
+	ctl.Interval = defaultTopInterval
+	flagSet := flag.NewFlagSet("gh-ost top", flag.ContinueOnError)
+	flagSet.SetOutput(os.Stderr)
+	ctl.targetFlags(flagSet)
+	flagSet.DurationVar(&ctl.Interval, "interval", ctl.Interval, "Interval between status updates")
+	flagSet.Usage = func() {
+		fmt.Fprint(os.Stderr, "Usage: gh-ost top [flags]\n\nShows a live dashboard of a running migration.\n\nFlags:\n")
+		flagSet.PrintDefaults()
+	}
+	if err := flagSet.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return ExitOK
+		}
+		return ExitUsage
+	}
+	err := ctl.top(flagSet.Args())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gh-ost top: %+v\n", err)
+	}
+	return exitCode(err)
+}
+
+func (this *Ctl) top(args []string) error {
+	if len(args) > 0 {
+		return usageErrorf("Unexpected argument: %s", args[0])
+	}
+	if this.Interval <= 0 {
+		return usageErrorf("--interval must be positive")
+	}
+	if !isTerminal(os.Stdin) || !isTerminal(this.stdout) {
+		return usageErrorf("gh-ost top runs on a terminal; see gh-ost ctl watch")
+	}
+	target, err := this.target()
+	if err != nil {
+		return err
+	}
+
+	state, err := term.MakeRaw(int(os.Stdin.Fd()))
+	if err != nil {
+		return err
+	}
+	defer term.Restore(int(os.Stdin.Fd()), state)
+	// The alternate screen, without a cursor, leaves the shell's screen as it was upon quitting
+	fmt.Fprint(this.stdout, "\033[?1049h\033[?25l")
+	defer fmt.Fprint(this.stdout, "\033[?25h\033[?1049l")
+
+	keys := make(chan byte)
+	go func() {
+		key := make([]byte, 1)
+		for {
+			if _, err := os.Stdin.Read(key); err != nil {
+				close(keys)
+				return
+			}
+			keys <- key[0]
+		}
+	}()
+
+	top := &top{target: target}
+	ticker := time.NewTicker(this.Interval)
+	defer ticker.Stop()
+	for {
+		top.poll(this, this.now())
+		this.draw(top)
+		select {
+		case <-ticker.C:
+		case key, ok := <-keys:
+			if !ok {
+				return nil
+			}
+			command, quit := top.onKey(key)
+			if quit {
+				return nil
+			}
+			if command != "" {
+				top.send(this, command)
+			}
+		}
+	}
+}
+
+func (this *Ctl) draw(top *top) {
+	width := 0
+	if file, ok := this.stdout.(*os.File); ok {
+		width, _, _ = term.GetSize(int(file.Fd()))
+	}
+	// Raw terminals need carriage returns
+	io.WriteString(this.stdout, "\033[H\033[2J"+strings.Join(top.render(width), "\r\n"))
+}
@@ -0,0 +1,83 @@
+/*
+   Copyright 2022 GitHub Inc.
+	 See https://github.com/github/gh-ost/blob/master/LICENSE
+*/
+
+package ctl
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testStatusJSON = `{"database":"test","table":"t1","phase":"row-copy","copied_rows":100,"estimated_rows":200,"progress_pct":50,"applied_events":3,"backlog":2,"backlog_capacity":1000,"spilled_events":0,"spilled_bytes":0,"elapsed_seconds":41,"copy_elapsed_seconds":40,"binlog_file":"mysql-bin.000550","binlog_pos":49942,"lag_seconds":0.01,"heartbeat_lag_seconds":0.02,"apply_lag_seconds":0,"state":"throttled, flag-file","eta":"40s","eta_seconds":40,"rows_per_second":3,"chunk_size":1000,"nice_ratio":0.5,"postponing_cut_over":false,"throttled":true,"throttle_reason":"flag-file","throttle_history":[{"time":"2024-01-01T12:00:00Z","throttled":true,"reason":"max-load"},{"time":"2024-01-01T12:00:05Z","throttled":false,"reason":""}],"control_replicas":[{"replica":"replica1:3306","lag_seconds":1.5},{"replica":"replica2:3306","lag_seconds":0,"error":"unreachable"}],"chunk_range_min":"1","chunk_range_max":"1000","iteration":7}`
+
+func TestTopRender(t *testing.T) {
+	status, err := ParseStatus(testStatusJSON)
+	require.NoError(t, err)
+	require.Equal(t, int64(1000), status.ChunkSize)
+
+	top := &top{target: Target{Address: "/tmp/gh-ost.test.t1.sock"}, polledAt: time.Now()}
+	screen := strings.Join(top.render(0), "\n")
+	require.Contains(t, screen, "Awaiting status")
+
+	top.status = status
+	screen = strings.Join(top.render(0), "\n")
+	require.Contains(t, screen, "Table:     test.t1   phase: row-copy")
+	require.Contains(t, screen, "State:     throttled, flag-file")
+	require.Contains(t, screen, "[####################....................] 50.0%   100/200 rows")
+	require.Contains(t, screen, "Rows/s:    3 now, 2 average")
+	require.Contains(t, screen, "Applied:   3 events   backlog: 2/1000")
+	require.Contains(t, screen, "Chunk:     iteration 7, range (1) .. (1000)")
+	require.Contains(t, screen, "replica1:3306                  1.50s")
+	require.Contains(t, screen, "replica2:3306                  error: unreachable")
+	// Most recent throttle changes first
+	require.Regexp(t, `(?s)unthrottled.*throttled: max-load`, screen)
+
+	for _, line := range top.render(20) {
+		require.LessOrEqual(t, len(line), 20)
+	}
+
+	_, err = ParseStatus("Unknown command: status-json\n")
+	require.Error(t, err)
+}
+
+func TestTopOnKey(t *testing.T) {
+	top := &top{}
+	command, quit := top.onKey('+')
+	require.Empty(t, command)
+	require.False(t, quit)
+
+	top.status = &Status{ChunkSize: 1000, NiceRatio: 0.05}
+	for key, expected := range map[byte]string{
+		't': "throttle",
+		'r': "no-throttle",
+		'+': "chunk-size=1250",
+		'-': "chunk-size=800",
+		'>': "nice-ratio=0.15",
+		'<': "nice-ratio=0.00",
+		'x': "",
+	} {
+		command, quit := top.onKey(key)
+		require.Equal(t, expected, command, string(key))
+		require.False(t, quit)
+	}
+
+	// Cut-over is confirmed
+	command, _ = top.onKey('c')
+	require.Empty(t, command)
+	require.Contains(t, top.message, "[y/N]")
+	command, _ = top.onKey('n')
+	require.Empty(t, command)
+	top.onKey('c')
+	command, _ = top.onKey('y')
+	require.Equal(t, "unpostpone", command)
+
+	_, quit = top.onKey('q')
+	require.True(t, quit)
+	_, quit = top.onKey(3)
+	require.True(t, quit)
+}
@@ -0,0 +1,562 @@
+/*
+   Copyright 2022 GitHub Inc.
+	 See https://github.com/github/gh-ost/blob/master/LICENSE
+*/
+
+package ghost
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net/url"
+	"regexp"
+	"time"
+
+	"github.com/github/gh-ost/go/base"
+	"github.com/github/gh-ost/go/sql"
+
+	"github.com/openark/golib/log"
+)
+
+// Flags are gh-ost's command line flags, bound to a migration context. The gh-ost command and
+// embedded migrations share them, such that a migration is configured, and validated, alike.
+type Flags struct {
+	migrationContext *base.MigrationContext
+	flagSet          *flag.FlagSet
+
+	// PasswordPrompt, if set, prompts for the password upon --ask-pass
+	PasswordPrompt func() (string, error)
+
+	askPass                        *bool
+	charset                        *string
+	storageEngine                  *string
+	columnExpressions              *string
+	executeFlag                    *bool
+	cutOver                        *string
+	cutOverBlockers                *string
+	exponentialBackoffMaxInterval  *int64
+	chunkSize                      *int64
+	dmlBatchSize                   *int64
+	defaultRetries                 *int64
+	cutOverLockTimeoutSeconds      *int64
+	niceRatio                      *float64
+	maxLagMillis                   *int64
+	maxApplyLagMillis              *int64
+	replicationLagQuery            *string
+	throttleSchedule               *string
+	throttleControlReplicas        *string
+	throttleControlReplicasInclude *string
+	throttleControlReplicasExclude *string
+	throttleQuery                  *string
+	throttleHTTP                   *string
+	ignoreHTTPErrors               *bool
+	heartbeatIntervalMillis        *int64
+	cutOverWindow                  *string
+	cutOverDeadline                *string
+	hooksWebhooks                  *string
+	maxLoad                        *string
+	maxInnoDBMetrics               *string
+	criticalLoad                   *string
+	quiet                          *bool
+	verbose                        *bool
+	debug                          *bool
+	stack                          *bool
+	logFormat                      *string
+}
+
+// NewFlags defines gh-ost's flags on the given flag set, bound to the given migration context
+func NewFlags(migrationContext *base.MigrationContext, flagSet *flag.FlagSet) *Flags {
+	this := &Flags{
+		migrationContext: migrationContext,
+		flagSet:          flagSet,
+	}
+	flagSet.StringVar(&this.migrationContext.InspectorConnectionConfig.Key.Hostname, "host", "127.0.0.1", "MySQL hostname (preferably a replica, not the master)")
+	flagSet.StringVar(&this.migrationContext.AssumeMasterHostname, "assume-master-host", "", "(optional) explicitly tell gh-ost the identity of the master. Format: some.host.com[:port] This is useful in master-master setups where you wish to pick an explicit master, or in a tungsten-replicator where gh-ost is unable to determine the master")
+	flagSet.IntVar(&this.migrationContext.InspectorConnectionConfig.Key.Port, "port", 3306, "MySQL port (preferably a replica, not the master)")
+	flagSet.Float64Var(&this.migrationContext.InspectorConnectionConfig.Timeout, "mysql-timeout", 0.0, "Connect, read and write timeout for MySQL")
+	flagSet.StringVar(&this.migrationContext.CliUser, "user", "", "MySQL user")
+	flagSet.StringVar(&this.migrationContext.CliPassword, "password", "", "MySQL password")
+	flagSet.StringVar(&this.migrationContext.CliMasterUser, "master-user", "", "MySQL user on master, if different from that on replica. Requires --assume-master-host")
+	flagSet.StringVar(&this.migrationContext.CliMasterPassword, "master-password", "", "MySQL password on master, if different from that on replica. Requires --assume-master-host")
+	flagSet.StringVar(&this.migrationContext.TargetHostname, "target-host", "", "(optional) move the table onto another server: the ghost table is created, populated and cut-over on this server rather than on the master. Format: some.host.com[:port]")
+	flagSet.StringVar(&this.migrationContext.CliTargetUser, "target-user", "", "MySQL user on target, if different from that on master. Requires --target-host")
+	flagSet.StringVar(&this.migrationContext.CliTargetPassword, "target-password", "", "MySQL password on target, if different from that on master. Requires --target-host")
+	flagSet.BoolVar(&this.migrationContext.TargetSkipRename, "target-skip-rename", false, "with --target-host: on cut-over, do not rename the ghost table on target into the original table name. Leaves the final step to the operator or to hooks")
+	flagSet.StringVar(&this.migrationContext.CDCExportSink, "cdc-export", "", "(optional) rather than migrating, export the table's changes as JSON lines onto given sink: '-' for stdout, 'unix:/path/to/socket' for a unix socket, or a file path, which is appended to. Requires --database and --table; mutually exclusive with --alter")
+	flagSet.StringVar(&this.migrationContext.CDCCheckpointFile, "cdc-checkpoint-file", "", "with --cdc-export: file in which export progress is checkpointed. When the file exists, the export resumes from its checkpoint")
+	flagSet.StringVar(&this.migrationContext.ConfigFile, "conf", "", "Config file, INI or (when named *.yaml or *.yml) YAML, holding credentials and any flags, optionally per host and per table. Flags given on the command line take precedence. Reloaded upon SIGHUP")
+	flagSet.StringVar(&this.migrationContext.DefaultsFile, "defaults-file", "", "MySQL option file, such as ~/.my.cnf, to read user and password off, from its [client] and [gh-ost] groups. !include and !includedir directives are followed")
+	flagSet.StringVar(&this.migrationContext.LoginPath, "login-path", "", "login path to read user and password off, as written by mysql_config_editor into ~/.mylogin.cnf (or $MYSQL_TEST_LOGIN_FILE). Overrides --defaults-file")
+	flagSet.StringVar(&this.migrationContext.CredentialHelper, "credential-helper", "", "shell command printing MySQL credentials as JSON: {\"user\": \"...\", \"password\": \"...\"}. Invoked with GH_OST_CREDENTIAL_HOST, GH_OST_CREDENTIAL_PORT and GH_OST_CREDENTIAL_USER upon connecting and reconnecting to a server, such that short-lived credentials are renewed. Overrides all other credentials")
+	this.askPass = flagSet.Bool("ask-pass", false, "prompt for MySQL password")
+	this.charset = flagSet.String("charset", "utf8mb4,utf8,latin1", "The default charset for the database connection is utf8mb4, utf8, latin1.")
+
+	flagSet.BoolVar(&this.migrationContext.UseTLS, "ssl", false, "Enable SSL encrypted connections to MySQL hosts")
+	flagSet.StringVar(&this.migrationContext.TLSCACertificate, "ssl-ca", "", "CA certificate in PEM format for TLS connections to MySQL hosts. Requires --ssl")
+	flagSet.StringVar(&this.migrationContext.TLSCertificate, "ssl-cert", "", "Certificate in PEM format for TLS connections to MySQL hosts. Requires --ssl")
+	flagSet.StringVar(&this.migrationContext.TLSKey, "ssl-key", "", "Key in PEM format for TLS connections to MySQL hosts. Requires --ssl")
+	flagSet.BoolVar(&this.migrationContext.TLSAllowInsecure, "ssl-allow-insecure", false, "Skips verification of MySQL hosts' certificate chain and host name. Requires --ssl")
+
+	flagSet.StringVar(&this.migrationContext.DatabaseName, "database", "", "database name (mandatory)")
+	flagSet.StringVar(&this.migrationContext.OriginalTableName, "table", "", "table name (mandatory)")
+	flagSet.StringVar(&this.migrationContext.AlterStatement, "alter", "", "alter statement (mandatory)")
+	flagSet.BoolVar(&this.migrationContext.AttemptInstantDDL, "attempt-instant-ddl", false, "Attempt to use instant DDL for this migration first")
+	this.storageEngine = flagSet.String("storage-engine", "innodb", "Specify table storage engine (default: 'innodb'). When 'rocksdb': the session transaction isolation level is changed from REPEATABLE_READ to READ_COMMITTED.")
+
+	flagSet.BoolVar(&this.migrationContext.CountTableRows, "exact-rowcount", false, "actually count table rows as opposed to estimate them (results in more accurate progress estimation)")
+	flagSet.BoolVar(&this.migrationContext.ConcurrentCountTableRows, "concurrent-rowcount", true, "(with --exact-rowcount), when true (default): count rows after row-copy begins, concurrently, and adjust row estimate later on; when false: first count rows, then start row copy")
+	flagSet.BoolVar(&this.migrationContext.AllowedRunningOnMaster, "allow-on-master", false, "allow this migration to run directly on master. Preferably it would run on a replica")
+	flagSet.BoolVar(&this.migrationContext.AllowedMasterMaster, "allow-master-master", false, "explicitly allow running in a master-master setup")
+	flagSet.BoolVar(&this.migrationContext.NullableUniqueKeyAllowed, "allow-nullable-unique-key", false, "allow gh-ost to migrate based on a unique key with nullable columns. As long as no NULL values exist, this should be OK. If NULL values exist in chosen key, data may be corrupted. Use at your own risk!")
+	flagSet.BoolVar(&this.migrationContext.ApproveRenamedColumns, "approve-renamed-columns", false, "in case your `ALTER` statement renames columns, gh-ost will note that and offer its interpretation of the rename. By default gh-ost does not proceed to execute. This flag approves that gh-ost's interpretation is correct")
+	flagSet.BoolVar(&this.migrationContext.SkipRenamedColumns, "skip-renamed-columns", false, "in case your `ALTER` statement renames columns, gh-ost will note that and offer its interpretation of the rename. By default gh-ost does not proceed to execute. This flag tells gh-ost to skip the renamed columns, i.e. to treat what gh-ost thinks are renamed columns as unrelated columns. NOTE: you may lose column data")
+	this.columnExpressions = flagSet.String("column-expression", "", "populate ghost columns by SQL expressions over the original table's columns, applied both on row copy and binlog apply; semicolon delimited. Example: \"full_name=concat(first_name, ' ', last_name);email=lower(email)\"")
+	flagSet.BoolVar(&this.migrationContext.IsTungsten, "tungsten", false, "explicitly let gh-ost know that you are running on a tungsten-replication based topology (you are likely to also provide --assume-master-host)")
+	flagSet.BoolVar(&this.migrationContext.DiscardForeignKeys, "discard-foreign-keys", false, "DANGER! This flag will migrate a table that has foreign keys and will NOT create foreign keys on the ghost table, thus your altered table will have NO foreign keys. This is useful for intentional dropping of foreign keys")
+	flagSet.BoolVar(&this.migrationContext.SkipForeignKeyChecks, "skip-foreign-key-checks", false, "set to 'true' when you know for certain there are no foreign keys on your table, and wish to skip the time it takes for gh-ost to verify that")
+	flagSet.BoolVar(&this.migrationContext.SkipStrictMode, "skip-strict-mode", false, "explicitly tell gh-ost binlog applier not to enforce strict sql mode")
+	flagSet.BoolVar(&this.migrationContext.AllowZeroInDate, "allow-zero-in-date", false, "explicitly tell gh-ost binlog applier to ignore NO_ZERO_IN_DATE,NO_ZERO_DATE in sql_mode")
+	flagSet.BoolVar(&this.migrationContext.AliyunRDS, "aliyun-rds", false, "set to 'true' when you execute on Aliyun RDS.")
+	flagSet.BoolVar(&this.migrationContext.GoogleCloudPlatform, "gcp", false, "set to 'true' when you execute on a 1st generation Google Cloud Platform (GCP).")
+	flagSet.BoolVar(&this.migrationContext.AzureMySQL, "azure", false, "set to 'true' when you execute on Azure Database on MySQL.")
+
+	this.executeFlag = flagSet.Bool("execute", false, "actually execute the alter & migrate the table. Default is noop: do some tests and exit")
+	flagSet.BoolVar(&this.migrationContext.TestOnReplica, "test-on-replica", false, "Have the migration run on a replica, not on the master. At the end of migration replication is stopped, and tables are swapped and immediately swap-revert. Replication remains stopped and you can compare the two tables for building trust")
+	flagSet.BoolVar(&this.migrationContext.TestOnReplicaSkipReplicaStop, "test-on-replica-skip-replica-stop", false, "When --test-on-replica is enabled, do not issue commands stop replication (requires --test-on-replica)")
+	flagSet.BoolVar(&this.migrationContext.MigrateOnReplica, "migrate-on-replica", false, "Have the migration run on a replica, not on the master. This will do the full migration on the replica including cut-over (as opposed to --test-on-replica)")
+
+	flagSet.BoolVar(&this.migrationContext.OkToDropTable, "ok-to-drop-table", false, "Shall the tool drop the old table at end of operation. DROPping tables can be a long locking operation, which is why I'm not doing it by default. I'm an online tool, yes?")
+	flagSet.BoolVar(&this.migrationContext.InitiallyDropOldTable, "initially-drop-old-table", false, "Drop a possibly existing OLD table (remains from a previous run?) before beginning operation. Default is to panic and abort if such table exists")
+	flagSet.BoolVar(&this.migrationContext.InitiallyDropGhostTable, "initially-drop-ghost-table", false, "Drop a possibly existing Ghost table (remains from a previous run?) before beginning operation. Default is to panic and abort if such table exists")
+	flagSet.BoolVar(&this.migrationContext.TimestampOldTable, "timestamp-old-table", false, "Use a timestamp in old table name. This makes old table names unique and non conflicting cross migrations")
+	this.cutOver = flagSet.String("cut-over", "default", "choose cut-over type (default, atomic, two-step, lock-and-rename). default: lock-and-rename when the applier runs MySQL 8.0.13 or newer, atomic otherwise")
+	this.cutOverBlockers = flagSet.String("cut-over-blockers", "log", "what to do about sessions holding locks on the original table, which block the cut-over (log, kill, postpone). log: only log them. kill: kill those holding their lock for --cut-over-blockers-min-age-seconds or longer. postpone: postpone cut-over while there are such sessions")
+	flagSet.Int64Var(&this.migrationContext.CutOverBlockersMinAgeSeconds, "cut-over-blockers-min-age-seconds", 10, "with --cut-over-blockers: sessions holding their lock for at least this many seconds are considered long transactions, to be killed or waited for")
+	flagSet.BoolVar(&this.migrationContext.ForceNamedCutOverCommand, "force-named-cut-over", false, "When true, the 'unpostpone|cut-over' interactive command must name the migrated table")
+	flagSet.BoolVar(&this.migrationContext.ForceNamedPanicCommand, "force-named-panic", false, "When true, the 'panic' interactive command must name the migrated table")
+
+	flagSet.BoolVar(&this.migrationContext.SwitchToRowBinlogFormat, "switch-to-rbr", false, "let this tool automatically switch binary log format to 'ROW' on the replica, if needed. The format will NOT be switched back. I'm too scared to do that, and wish to protect you if you happen to execute another migration while this one is running")
+	flagSet.BoolVar(&this.migrationContext.AssumeRBR, "assume-rbr", false, "set to 'true' when you know for certain your server uses 'ROW' binlog_format. gh-ost is unable to tell, event after reading binlog_format, whether the replication process does indeed use 'ROW', and restarts replication to be certain RBR setting is applied. Such operation requires SUPER privileges which you might not have. Setting this flag avoids restarting replication and you can proceed to use gh-ost without SUPER privileges")
+	flagSet.BoolVar(&this.migrationContext.CutOverExponentialBackoff, "cut-over-exponential-backoff", false, "Wait exponentially longer intervals between failed cut-over attempts. Wait intervals obey a maximum configurable with 'exponential-backoff-max-interval').")
+	this.exponentialBackoffMaxInterval = flagSet.Int64("exponential-backoff-max-interval", 64, "Maximum number of seconds to wait between attempts when performing various operations with exponential backoff.")
+	this.chunkSize = flagSet.Int64("chunk-size", 1000, "amount of rows to handle in each iteration (allowed range: 10-100,000)")
+	this.dmlBatchSize = flagSet.Int64("dml-batch-size", 10, "batch size for DML events to apply in a single transaction (range 1-100)")
+	flagSet.StringVar(&this.migrationContext.DMLBacklogSpillDir, "dml-backlog-spill-dir", "", "when given, DML events which the applier lags behind on are spilled to a new directory under this one, rather than blocking binlog reading, and are applied from disk as the applier catches up")
+	flagSet.Int64Var(&this.migrationContext.DMLBacklogSpillMaxMB, "dml-backlog-spill-max-mb", 10240, "with --dml-backlog-spill-dir: max size of events spilled to disk. Binlog reading blocks beyond this size")
+	this.defaultRetries = flagSet.Int64("default-retries", 60, "Default number of retries for various operations before panicking")
+	flagSet.Int64Var(&this.migrationContext.ShadowVerifyIntervalSeconds, "shadow-verify-interval-seconds", 0, "when > 0, periodically compare a sample of the rows affected by applied DML events between the original and ghost tables, every given number of seconds. 0 disables")
+	flagSet.Int64Var(&this.migrationContext.ShadowVerifySampleSize, "shadow-verify-sample-size", 100, "with --shadow-verify-interval-seconds: max number of rows to verify per interval")
+	flagSet.Int64Var(&this.migrationContext.ShadowVerifyMaxMismatches, "shadow-verify-max-mismatches", 0, "with --shadow-verify-interval-seconds: abort the migration once this many mismatching rows are found. 0 means never abort; mismatches are only logged")
+	flagSet.BoolVar(&this.migrationContext.PanicOnWarnings, "panic-on-warnings", false, "Panic when SQL warnings are encountered when copying a batch indicating data loss")
+	this.cutOverLockTimeoutSeconds = flagSet.Int64("cut-over-lock-timeout-seconds", 3, "Max number of seconds to hold locks on tables while attempting to cut-over (retry attempted when lock exceeds timeout) or attempting instant DDL")
+	this.niceRatio = flagSet.Float64("nice-ratio", 0, "force being 'nice', imply sleep time per chunk time; range: [0.0..100.0]. Example values: 0 is aggressive. 1: for every 1ms spent copying rows, sleep additional 1ms (effectively doubling runtime); 0.7: for every 10ms spend in a rowcopy chunk, spend 7ms sleeping immediately after")
+
+	this.maxLagMillis = flagSet.Int64("max-lag-millis", 1500, "replication lag at which to throttle operation")
+	this.maxApplyLagMillis = flagSet.Int64("max-apply-lag-millis", 0, "age of the oldest DML event read off the binary log and not yet applied, at which to throttle row copy, such that the applier catches up. DML events keep being applied. 0 disables")
+	this.replicationLagQuery = flagSet.String("replication-lag-query", "", "Deprecated. gh-ost uses an internal, subsecond resolution query")
+	this.throttleSchedule = flagSet.String("throttle-schedule", "", "override throttle parameters within time windows. Semicolon delimited list of entries, each a schedule followed by any of nice-ratio=<ratio>, max-lag-millis=<millis>, chunk-size=<size>, or pause. Where entries overlap, the first applies. Example: 'mon-fri 08:00-20:00 nice-ratio=1 chunk-size=500; sat-sun 00:00-24:00 chunk-size=5000'")
+	flagSet.StringVar(&this.migrationContext.ThrottleScheduleTimezone, "throttle-schedule-timezone", "Local", "time zone of --throttle-schedule, by IANA name (e.g. 'America/New_York', 'UTC'). Default: the local time zone")
+	this.throttleControlReplicas = flagSet.String("throttle-control-replicas", "", "List of replicas on which to check for lag; comma delimited. Example: myhost1.com:3306,myhost2.com,myhost3.com:3307")
+	flagSet.BoolVar(&this.migrationContext.DiscoverThrottleControlReplicas, "discover-throttle-control-replicas", false, "recursively discover replicas of the migrated server, periodically, and check them for lag along with --throttle-control-replicas")
+	flagSet.Int64Var(&this.migrationContext.ThrottleControlReplicasDiscoveryIntervalSeconds, "throttle-control-replicas-discovery-interval-seconds", 60, "with --discover-throttle-control-replicas, number of seconds between discoveries")
+	this.throttleControlReplicasInclude = flagSet.String("throttle-control-replicas-include", "", "with --discover-throttle-control-replicas, regular expression on hostname:port; only discovered replicas matching it are checked for lag")
+	this.throttleControlReplicasExclude = flagSet.String("throttle-control-replicas-exclude", "", "with --discover-throttle-control-replicas, regular expression on hostname:port; discovered replicas matching it, and their own replicas, are not checked for lag (e.g. delayed or backup replicas)")
+	this.throttleQuery = flagSet.String("throttle-query", "", "when given, issued (every second) to check if operation should throttle. Expecting to return zero for no-throttle, >0 for throttle. Query is issued on the migrated server. Make sure this query is lightweight")
+	this.throttleHTTP = flagSet.String("throttle-http", "", "when given, gh-ost checks given URL via HEAD request; any response code other than 200 (OK) causes throttling; make sure it has low latency response")
+	flagSet.Int64Var(&this.migrationContext.ThrottleHTTPIntervalMillis, "throttle-http-interval-millis", 100, "Number of milliseconds to wait before triggering another HTTP throttle check")
+	flagSet.Int64Var(&this.migrationContext.ThrottleHTTPTimeoutMillis, "throttle-http-timeout-millis", 1000, "Number of milliseconds to use as an HTTP throttle check timeout")
+	this.ignoreHTTPErrors = flagSet.Bool("ignore-http-errors", false, "ignore HTTP connection errors during throttle check")
+	this.heartbeatIntervalMillis = flagSet.Int64("heartbeat-interval-millis", 100, "how frequently would gh-ost inject a heartbeat value")
+	flagSet.StringVar(&this.migrationContext.ThrottleFlagFile, "throttle-flag-file", "", "operation pauses when this file exists; hint: use a file that is specific to the table being altered")
+	flagSet.StringVar(&this.migrationContext.ThrottleAdditionalFlagFile, "throttle-additional-flag-file", "/tmp/gh-ost.throttle", "operation pauses when this file exists; hint: keep default, use for throttling multiple gh-ost operations")
+	this.cutOverWindow = flagSet.String("cut-over-window", "", "only cut-over within this schedule; postpone cut-over otherwise. Comma delimited list of windows, each an optional weekday or range of weekdays followed by a range of hours. Example: 'mon-fri 01:00-05:00,sat-sun 00:00-24:00'")
+	flagSet.StringVar(&this.migrationContext.CutOverWindowTimezone, "cut-over-window-timezone", "Local", "time zone of --cut-over-window, by IANA name (e.g. 'America/New_York', 'UTC'). Default: the local time zone")
+	this.cutOverDeadline = flagSet.String("cut-over-deadline", "", "abort the migration, dropping the ghost and changelog tables, if cut-over has not completed by this time. Either an RFC3339 time (e.g. '2022-05-17T06:00:00Z') or a duration from startup (e.g. '36h')")
+	flagSet.StringVar(&this.migrationContext.PostponeCutOverFlagFile, "postpone-cut-over-flag-file", "", "while this file exists, migration will postpone the final stage of swapping tables, and will keep on syncing the ghost table. Cut-over/swapping would be ready to perform the moment the file is deleted.")
+	flagSet.StringVar(&this.migrationContext.PanicFlagFile, "panic-flag-file", "", "when this file is created, gh-ost will immediately terminate, without cleanup")
+
+	flagSet.BoolVar(&this.migrationContext.DropServeSocket, "initially-drop-socket-file", false, "Should gh-ost forcibly delete an existing socket file. Be careful: this might drop the socket file of a running migration!")
+	flagSet.StringVar(&this.migrationContext.ServeSocketFile, "serve-socket-file", "", "Unix socket file to serve on. Default: auto-determined and advertised upon startup")
+	flagSet.Int64Var(&this.migrationContext.ServeTCPPort, "serve-tcp-port", 0, "TCP port to serve on. Default: disabled")
+	flagSet.StringVar(&this.migrationContext.ServeTCPTLSCertFile, "serve-tcp-tls-cert", "", "Certificate file (PEM) to serve --serve-tcp-port over TLS with. Requires --serve-tcp-tls-key")
+	flagSet.StringVar(&this.migrationContext.ServeTCPTLSKeyFile, "serve-tcp-tls-key", "", "Private key file (PEM) of --serve-tcp-tls-cert")
+	flagSet.StringVar(&this.migrationContext.ServeTCPTLSCAFile, "serve-tcp-tls-ca", "", "CA certificates file (PEM) to verify TCP clients' certificates with. Without --serve-tcp-auth-file, TCP clients must present a certificate it verifies")
+	flagSet.StringVar(&this.migrationContext.ServeTCPAuthFile, "serve-tcp-auth-file", "", "File listing who may send interactive commands over TCP, with which role and credential: a token or a client certificate. Default: anyone reaching --serve-tcp-port may send any command")
+	flagSet.StringVar(&this.migrationContext.ServeAuditLogFile, "serve-audit-log", "", "File to append a JSON record of each interactive command to, with who sent it and whether it was allowed")
+
+	flagSet.StringVar(&this.migrationContext.HooksPath, "hooks-path", "", "directory where hook files are found (default: empty, ie. hooks disabled). Hook files found on this path, and conforming to hook naming conventions will be executed")
+	flagSet.StringVar(&this.migrationContext.HooksHintMessage, "hooks-hint", "", "arbitrary message to be injected to hooks via GH_OST_HOOKS_HINT, for your convenience")
+	flagSet.StringVar(&this.migrationContext.HooksHintOwner, "hooks-hint-owner", "", "arbitrary name of owner to be injected to hooks via GH_OST_HOOKS_HINT_OWNER, for your convenience")
+	flagSet.StringVar(&this.migrationContext.HooksHintToken, "hooks-hint-token", "", "arbitrary token to be injected to hooks via GH_OST_HOOKS_HINT_TOKEN, for your convenience")
+	this.hooksWebhooks = flagSet.String("hooks-webhooks", "", "URLs to which hook events are posted as JSON. Semicolon delimited list of webhooks, each a hook name or '*' for all hooks, a URL, and any of timeout=<seconds>, retries=<count>, on-failure=abort|ignore. Example: 'gh-ost-on-status https://hooks.example.com/status on-failure=ignore; * https://hooks.example.com/gh-ost retries=3'")
+	flagSet.Int64Var(&this.migrationContext.HooksStatusIntervalSec, "hooks-status-interval", 60, "how many seconds to wait between calling onStatus hook")
+
+	flagSet.UintVar(&this.migrationContext.ReplicaServerId, "replica-server-id", 99999, "server id used by gh-ost process. Default: 99999")
+	flagSet.IntVar(&this.migrationContext.BinlogSyncerMaxReconnectAttempts, "binlogsyncer-max-reconnect-attempts", 0, "when master node fails, the maximum number of binlog synchronization attempts to reconnect. 0 is unlimited")
+
+	flagSet.BoolVar(&this.migrationContext.IncludeTriggers, "include-triggers", false, "When true, the triggers (if exist) will be created on the new table")
+	flagSet.StringVar(&this.migrationContext.TriggerSuffix, "trigger-suffix", "", "Add a suffix to the trigger name (i.e '_v2'). Requires '--include-triggers'")
+	flagSet.BoolVar(&this.migrationContext.RemoveTriggerSuffix, "remove-trigger-suffix-if-exists", false, "Remove given suffix from name of trigger. Requires '--include-triggers' and '--trigger-suffix'")
+
+	this.maxLoad = flagSet.String("max-load", "", "Comma delimited status-name=threshold. e.g: 'Threads_running=100,Threads_connected=500'. When status exceeds threshold, app throttles writes")
+	this.maxInnoDBMetrics = flagSet.String("max-innodb-metrics", "", "Comma delimited metric-name=threshold, on information_schema.innodb_metrics counters, or dirty_pages_pct for the percentage of dirty buffer pool pages. e.g: 'trx_rseg_history_len=1000000,log_lsn_checkpoint_age=1000000000,dirty_pages_pct=75'. When a metric exceeds its threshold, app throttles writes")
+	flagSet.StringVar(&this.migrationContext.FreeDiskSpaceQuery, "free-disk-space-query", "", "query returning the number of free bytes on the data volume of the server holding the ghost table, e.g. from a monitoring table. When given, gh-ost validates there is space for the ghost table before copying rows, and checks free space throughout the migration")
+	flagSet.Int64Var(&this.migrationContext.MinFreeDiskSpaceMB, "min-free-disk-space-mb", 0, "with --free-disk-space-query, throttle when free disk space drops below this number of megabytes. 0 disables")
+	flagSet.Int64Var(&this.migrationContext.CriticalFreeDiskSpaceMB, "critical-free-disk-space-mb", 0, "with --free-disk-space-query, drop the ghost table and abort when free disk space drops below this number of megabytes. 0 disables")
+	flagSet.Int64Var(&this.migrationContext.BinlogRetentionGuardSeconds, "binlog-retention-guard-seconds", 3600, "warn, and fire the gh-ost-on-binlog-retention-warning hook, when the binary log being read is estimated to expire and be purged on the inspected server within this number of seconds. 0 disables")
+	flagSet.BoolVar(&this.migrationContext.BinlogRetentionOverrideForcedThrottle, "binlog-retention-override-forced-throttle", false, "when the binary log being read is expected to be purged within --binlog-retention-guard-seconds, override forced throttling (throttle command, flag files, throttle-schedule pause), and refuse the throttle command")
+	flagSet.Int64Var(&this.migrationContext.MaxGroupReplicationQueue, "max-group-replication-queue", 0, "On Group Replication, throttle when any member's queue of transactions awaiting certification, or awaiting to be applied, reaches this length. 0 disables")
+	flagSet.Int64Var(&this.migrationContext.MaxGaleraRecvQueue, "max-galera-recv-queue", 0, "On Galera, throttle when any member's receive queue (wsrep_local_recv_queue) reaches this length. 0 disables")
+	flagSet.Float64Var(&this.migrationContext.MaxGaleraFlowControlPaused, "max-galera-flow-control-paused", 0, "On Galera, throttle when any member spent this fraction of time (0..1) paused by flow control, per wsrep_flow_control_paused_ns, since the previous check. 0 disables")
+	this.criticalLoad = flagSet.String("critical-load", "", "Comma delimited status-name=threshold, same format as --max-load. When status exceeds threshold, app panics and quits")
+	flagSet.Int64Var(&this.migrationContext.CriticalLoadIntervalMilliseconds, "critical-load-interval-millis", 0, "When 0, migration immediately bails out upon meeting critical-load. When non-zero, a second check is done after given interval, and migration only bails out if 2nd check still meets critical load")
+	flagSet.Int64Var(&this.migrationContext.CriticalLoadHibernateSeconds, "critical-load-hibernate-seconds", 0, "When non-zero, critical-load does not panic and bail out; instead, gh-ost goes into hibernation for the specified duration. It will not read/write anything from/to any server")
+	this.quiet = flagSet.Bool("quiet", false, "quiet")
+	this.verbose = flagSet.Bool("verbose", false, "verbose")
+	this.debug = flagSet.Bool("debug", false, "debug mode (very verbose)")
+	this.stack = flagSet.Bool("stack", false, "add stack trace upon error")
+	this.logFormat = flagSet.String("log-format", "text", "Format of log records: 'text', or 'json' for structured records which hold the migration's UUID, database, table, phase and logging component")
+	flagSet.StringVar(&this.migrationContext.ForceTmpTableName, "force-table-names", "", "table name prefix to be used on the temporary tables")
+	return this
+}
+
+// ApplyConfigFile sets the flags the config file holds, unless already set. Host and table sections
+// apply by --host, --database and --table, which the config file may itself set.
+func (this *Flags) ApplyConfigFile() error {
+	if err := this.migrationContext.ReadConfigFile(); err != nil {
+		return err
+	}
+	givenFlags := map[string]bool{}
+	this.flagSet.Visit(func(f *flag.Flag) { givenFlags[f.Name] = true })
+	setFlags := func(flagValues map[string]string) error {
+		for name, value := range flagValues {
+			if givenFlags[name] {
+				continue
+			}
+			if name == "conf" || this.flagSet.Lookup(name) == nil {
+				return fmt.Errorf("Unknown flag in config file %s: %s", this.migrationContext.ConfigFile, name)
+			}
+			if err := this.flagSet.Set(name, value); err != nil {
+				return fmt.Errorf("Error setting %s from config file %s: %+v", name, this.migrationContext.ConfigFile, err)
+			}
+		}
+		return nil
+	}
+	if err := setFlags(this.migrationContext.GetConfigFileSettings().General); err != nil {
+		return err
+	}
+	return setFlags(this.migrationContext.GetConfigFileFlagValues())
+}
+
+// Apply validates the flags, once set, and applies them onto the migration context
+func (this *Flags) Apply() error {
+	switch *this.logFormat {
+	case "text":
+		// The logger is kept: the text logger, unless an embedding program provided another
+	case "json":
+		this.migrationContext.Log = base.NewJSONLogger(this.migrationContext)
+	default:
+		return errors.New("--log-format must be 'text' or 'json'")
+	}
+	this.migrationContext.Log.SetLevel(log.ERROR)
+	if *this.verbose {
+		this.migrationContext.Log.SetLevel(log.INFO)
+	}
+	if *this.debug {
+		this.migrationContext.Log.SetLevel(log.DEBUG)
+	}
+	if *this.stack {
+		this.migrationContext.Log.SetPrintStackTrace(*this.stack)
+	}
+	if *this.quiet {
+		// Override!!
+		this.migrationContext.Log.SetLevel(log.ERROR)
+	}
+
+	if err := this.migrationContext.SetConnectionConfig(*this.storageEngine); err != nil {
+		return err
+	}
+
+	this.migrationContext.SetConnectionCharset(*this.charset)
+
+	if this.migrationContext.IsCDCExport() {
+		if this.migrationContext.AlterStatement != "" {
+			return errors.New("--cdc-export and --alter are mutually exclusive")
+		}
+	} else if this.migrationContext.AlterStatement == "" {
+		return errors.New("--alter must be provided and statement must not be empty")
+	}
+	parser := sql.NewParserFromAlterStatement(this.migrationContext.AlterStatement)
+	this.migrationContext.AlterStatementOptions = parser.GetAlterStatementOptions()
+
+	if this.migrationContext.DatabaseName == "" {
+		if parser.HasExplicitSchema() {
+			this.migrationContext.DatabaseName = parser.GetExplicitSchema()
+		} else {
+			return errors.New("--database must be provided and database name must not be empty, or --alter must specify database name")
+		}
+	}
+
+	if err := this.flagSet.Set("database", url.QueryEscape(this.migrationContext.DatabaseName)); err != nil {
+		return err
+	}
+
+	if this.migrationContext.OriginalTableName == "" {
+		if parser.HasExplicitTable() {
+			this.migrationContext.OriginalTableName = parser.GetExplicitTable()
+		} else {
+			return errors.New("--table must be provided and table name must not be empty, or --alter must specify table name")
+		}
+	}
+	this.migrationContext.Noop = !(*this.executeFlag)
+	if this.migrationContext.AllowedRunningOnMaster && this.migrationContext.TestOnReplica {
+		return errors.New("--allow-on-master and --test-on-replica are mutually exclusive")
+	}
+	if this.migrationContext.AllowedRunningOnMaster && this.migrationContext.MigrateOnReplica {
+		return errors.New("--allow-on-master and --migrate-on-replica are mutually exclusive")
+	}
+	if this.migrationContext.MigrateOnReplica && this.migrationContext.TestOnReplica {
+		return errors.New("--migrate-on-replica and --test-on-replica are mutually exclusive")
+	}
+	if this.migrationContext.SwitchToRowBinlogFormat && this.migrationContext.AssumeRBR {
+		return errors.New("--switch-to-rbr and --assume-rbr are mutually exclusive")
+	}
+	if this.migrationContext.TestOnReplicaSkipReplicaStop {
+		if !this.migrationContext.TestOnReplica {
+			return errors.New("--test-on-replica-skip-replica-stop requires --test-on-replica to be enabled")
+		}
+		this.migrationContext.Log.Warning("--test-on-replica-skip-replica-stop enabled. We will not stop replication before cut-over. Ensure you have a plugin that does this.")
+	}
+	if this.migrationContext.CliMasterUser != "" && this.migrationContext.AssumeMasterHostname == "" {
+		return errors.New("--master-user requires --assume-master-host")
+	}
+	if this.migrationContext.CliMasterPassword != "" && this.migrationContext.AssumeMasterHostname == "" {
+		return errors.New("--master-password requires --assume-master-host")
+	}
+	if this.migrationContext.CliTargetUser != "" && this.migrationContext.TargetHostname == "" {
+		return errors.New("--target-user requires --target-host")
+	}
+	if this.migrationContext.CliTargetPassword != "" && this.migrationContext.TargetHostname == "" {
+		return errors.New("--target-password requires --target-host")
+	}
+	if this.migrationContext.TargetSkipRename && this.migrationContext.TargetHostname == "" {
+		return errors.New("--target-skip-rename requires --target-host")
+	}
+	if this.migrationContext.ShadowVerifyIntervalSeconds < 0 {
+		return errors.New("--shadow-verify-interval-seconds must not be negative")
+	}
+	if this.migrationContext.ShadowVerifyIntervalSeconds > 0 && this.migrationContext.ShadowVerifySampleSize < 1 {
+		return errors.New("--shadow-verify-sample-size must be at least 1")
+	}
+	if this.migrationContext.CDCCheckpointFile != "" && !this.migrationContext.IsCDCExport() {
+		return errors.New("--cdc-checkpoint-file requires --cdc-export")
+	}
+	if this.migrationContext.IsCDCExport() && this.migrationContext.TargetHostname != "" {
+		return errors.New("--cdc-export and --target-host are mutually exclusive")
+	}
+	if this.migrationContext.TargetHostname != "" {
+		if this.migrationContext.TestOnReplica || this.migrationContext.MigrateOnReplica {
+			return errors.New("--target-host is mutually exclusive with --test-on-replica and --migrate-on-replica")
+		}
+		if this.migrationContext.AttemptInstantDDL {
+			return errors.New("--target-host and --attempt-instant-ddl are mutually exclusive")
+		}
+	}
+	if this.migrationContext.TLSCACertificate != "" && !this.migrationContext.UseTLS {
+		return errors.New("--ssl-ca requires --ssl")
+	}
+	if this.migrationContext.TLSCertificate != "" && !this.migrationContext.UseTLS {
+		return errors.New("--ssl-cert requires --ssl")
+	}
+	if this.migrationContext.TLSKey != "" && !this.migrationContext.UseTLS {
+		return errors.New("--ssl-key requires --ssl")
+	}
+	if this.migrationContext.TLSAllowInsecure && !this.migrationContext.UseTLS {
+		return errors.New("--ssl-allow-insecure requires --ssl")
+	}
+	if *this.replicationLagQuery != "" {
+		this.migrationContext.Log.Warningf("--replication-lag-query is deprecated")
+	}
+	if this.migrationContext.IncludeTriggers && this.migrationContext.TriggerSuffix == "" {
+		return errors.New("--trigger-suffix must be used with --include-triggers")
+	}
+	if !this.migrationContext.IncludeTriggers && this.migrationContext.TriggerSuffix != "" {
+		return errors.New("--trigger-suffix cannot be be used without --include-triggers")
+	}
+	if this.migrationContext.TriggerSuffix != "" {
+		regex := regexp.MustCompile(`^[\da-zA-Z_]+$`)
+
+		if !regex.Match([]byte(this.migrationContext.TriggerSuffix)) {
+			return errors.New("--trigger-suffix must contain only alpha numeric characters and underscore (0-9,a-z,A-Z,_)")
+		}
+	}
+	if *this.storageEngine == "rocksdb" {
+		this.migrationContext.Log.Warning("RocksDB storage engine support is experimental")
+	}
+
+	switch *this.cutOver {
+	case "default", "":
+		this.migrationContext.CutOverType = base.CutOverAuto
+	case "atomic":
+		this.migrationContext.CutOverType = base.CutOverAtomic
+	case "two-step":
+		this.migrationContext.CutOverType = base.CutOverTwoStep
+	case "lock-and-rename":
+		this.migrationContext.CutOverType = base.CutOverLockAndRename
+	default:
+		return fmt.Errorf("Unknown cut-over: %s", *this.cutOver)
+	}
+	switch *this.cutOverBlockers {
+	case "log":
+		this.migrationContext.CutOverBlockersPolicy = base.CutOverBlockersLog
+	case "kill":
+		this.migrationContext.CutOverBlockersPolicy = base.CutOverBlockersKill
+	case "postpone":
+		this.migrationContext.CutOverBlockersPolicy = base.CutOverBlockersPostpone
+	default:
+		return fmt.Errorf("Unknown cut-over-blockers: %s", *this.cutOverBlockers)
+	}
+	if this.migrationContext.CutOverBlockersMinAgeSeconds < 0 {
+		return errors.New("--cut-over-blockers-min-age-seconds must not be negative")
+	}
+	if err := this.migrationContext.ReadHookWebhooks(*this.hooksWebhooks); err != nil {
+		return err
+	}
+	if err := this.migrationContext.ReadThrottleSchedule(*this.throttleSchedule); err != nil {
+		return err
+	}
+	if err := this.migrationContext.ReadCutOverWindow(*this.cutOverWindow); err != nil {
+		return err
+	}
+	if err := this.migrationContext.ReadCutOverDeadline(*this.cutOverDeadline); err != nil {
+		return err
+	}
+	if deadline := this.migrationContext.GetCutOverDeadline(); !deadline.IsZero() && deadline.Before(time.Now()) {
+		return fmt.Errorf("--cut-over-deadline %s has already passed", deadline.Format(time.RFC3339))
+	}
+	if err := this.migrationContext.ReadThrottleControlReplicaKeys(*this.throttleControlReplicas); err != nil {
+		return err
+	}
+	if this.migrationContext.ThrottleControlReplicasDiscoveryIntervalSeconds < 1 {
+		return errors.New("--throttle-control-replicas-discovery-interval-seconds must be at least 1")
+	}
+	if *this.throttleControlReplicasInclude != "" {
+		var err error
+		if this.migrationContext.ThrottleControlReplicasInclude, err = regexp.Compile(*this.throttleControlReplicasInclude); err != nil {
+			return fmt.Errorf("Error parsing --throttle-control-replicas-include: %+v", err)
+		}
+	}
+	if *this.throttleControlReplicasExclude != "" {
+		var err error
+		if this.migrationContext.ThrottleControlReplicasExclude, err = regexp.Compile(*this.throttleControlReplicasExclude); err != nil {
+			return fmt.Errorf("Error parsing --throttle-control-replicas-exclude: %+v", err)
+		}
+	}
+	if (*this.throttleControlReplicasInclude != "" || *this.throttleControlReplicasExclude != "") && !this.migrationContext.DiscoverThrottleControlReplicas {
+		return errors.New("--throttle-control-replicas-include and --throttle-control-replicas-exclude require --discover-throttle-control-replicas")
+	}
+	if err := this.migrationContext.ReadColumnExpressions(*this.columnExpressions); err != nil {
+		return err
+	}
+	if err := this.migrationContext.ReadMaxLoad(*this.maxLoad); err != nil {
+		return err
+	}
+	if err := this.migrationContext.ReadMaxInnoDBMetrics(*this.maxInnoDBMetrics); err != nil {
+		return err
+	}
+	if this.migrationContext.MinFreeDiskSpaceMB < 0 || this.migrationContext.CriticalFreeDiskSpaceMB < 0 {
+		return errors.New("--min-free-disk-space-mb and --critical-free-disk-space-mb must not be negative")
+	}
+	if this.migrationContext.DMLBacklogSpillDir != "" && this.migrationContext.DMLBacklogSpillMaxMB < 1 {
+		return errors.New("--dml-backlog-spill-max-mb must be at least 1")
+	}
+	if (this.migrationContext.MinFreeDiskSpaceMB > 0 || this.migrationContext.CriticalFreeDiskSpaceMB > 0) && this.migrationContext.FreeDiskSpaceQuery == "" {
+		return errors.New("--min-free-disk-space-mb and --critical-free-disk-space-mb require --free-disk-space-query")
+	}
+	if this.migrationContext.BinlogRetentionOverrideForcedThrottle && this.migrationContext.BinlogRetentionGuardSeconds <= 0 {
+		return errors.New("--binlog-retention-override-forced-throttle requires --binlog-retention-guard-seconds")
+	}
+	if this.migrationContext.MaxGroupReplicationQueue < 0 {
+		return errors.New("--max-group-replication-queue must not be negative")
+	}
+	if this.migrationContext.MaxGaleraRecvQueue < 0 {
+		return errors.New("--max-galera-recv-queue must not be negative")
+	}
+	if this.migrationContext.MaxGaleraFlowControlPaused < 0 || this.migrationContext.MaxGaleraFlowControlPaused > 1 {
+		return errors.New("--max-galera-flow-control-paused must be between 0 and 1")
+	}
+	if err := this.migrationContext.ReadCriticalLoad(*this.criticalLoad); err != nil {
+		return err
+	}
+	if err := this.applyServeTCPFlags(); err != nil {
+		return err
+	}
+	if this.migrationContext.ServeSocketFile == "" {
+		this.migrationContext.ServeSocketFile = fmt.Sprintf("/tmp/gh-ost.%s.%s.sock", this.migrationContext.DatabaseName, this.migrationContext.OriginalTableName)
+	}
+	if *this.askPass {
+		if this.PasswordPrompt == nil {
+			return errors.New("--ask-pass is not supported here")
+		}
+		password, err := this.PasswordPrompt()
+		if err != nil {
+			return err
+		}
+		this.migrationContext.CliPassword = password
+	}
+	this.migrationContext.SetHeartbeatIntervalMilliseconds(*this.heartbeatIntervalMillis)
+	this.migrationContext.SetNiceRatio(*this.niceRatio)
+	this.migrationContext.SetChunkSize(*this.chunkSize)
+	this.migrationContext.SetDMLBatchSize(*this.dmlBatchSize)
+	this.migrationContext.SetMaxLagMillisecondsThrottleThreshold(*this.maxLagMillis)
+	this.migrationContext.SetMaxApplyLagMillisecondsThrottleThreshold(*this.maxApplyLagMillis)
+	this.migrationContext.SetThrottleQuery(*this.throttleQuery)
+	this.migrationContext.SetThrottleHTTP(*this.throttleHTTP)
+	this.migrationContext.SetIgnoreHTTPErrors(*this.ignoreHTTPErrors)
+	this.migrationContext.SetDefaultNumRetries(*this.defaultRetries)
+	if err := this.migrationContext.ReadOptionFiles(); err != nil {
+		return err
+	}
+	this.migrationContext.ApplyCredentials()
+	if err := this.migrationContext.SetupTLS(); err != nil {
+		return err
+	}
+	if err := this.migrationContext.SetCutOverLockTimeoutSeconds(*this.cutOverLockTimeoutSeconds); err != nil {
+		this.migrationContext.Log.Errore(err)
+	}
+	if err := this.migrationContext.SetExponentialBackoffMaxInterval(*this.exponentialBackoffMaxInterval); err != nil {
+		this.migrationContext.Log.Errore(err)
+	}
+	return nil
+}
+
+// applyServeTCPFlags validates TLS and authentication of the TCP interactive interface, and reads
+// the auth file
+func (this *Flags) applyServeTCPFlags() error {
+	migrationContext := this.migrationContext
+	if (migrationContext.ServeTCPTLSCertFile == "") != (migrationContext.ServeTCPTLSKeyFile == "") {
+		return errors.New("--serve-tcp-tls-cert and --serve-tcp-tls-key must be given together")
+	}
+	if migrationContext.ServeTCPTLSCAFile != "" && migrationContext.ServeTCPTLSCertFile == "" {
+		return errors.New("--serve-tcp-tls-ca requires --serve-tcp-tls-cert")
+	}
+	if migrationContext.ServeTCPPort == 0 && (migrationContext.ServeTCPTLSCertFile != "" || migrationContext.ServeTCPAuthFile != "") {
+		return errors.New("--serve-tcp-tls-cert and --serve-tcp-auth-file require --serve-tcp-port")
+	}
+	if migrationContext.ServeTCPAuthFile == "" {
+		return nil
+	}
+	auth, err := base.ReadServerAuthFile(migrationContext.ServeTCPAuthFile)
+	if err != nil {
+		return err
+	}
+	if auth.HasCertPrincipals() && migrationContext.ServeTCPTLSCAFile == "" {
+		return errors.New("--serve-tcp-auth-file lists clients authenticating with a certificate, which requires --serve-tcp-tls-ca")
+	}
+	if migrationContext.ServeTCPTLSCertFile == "" {
+		migrationContext.Log.Warning("--serve-tcp-auth-file without --serve-tcp-tls-cert: tokens are sent in clear text")
+	}
+	migrationContext.ServeTCPAuth = auth
+	return nil
+}
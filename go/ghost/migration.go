@@ -0,0 +1,181 @@
+/*
+   Copyright 2022 GitHub Inc.
+	 See https://github.com/github/gh-ost/blob/master/LICENSE
+*/
+
+// Package ghost runs gh-ost migrations within another program, such as a schema migration service.
+package ghost
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/github/gh-ost/go/base"
+	"github.com/github/gh-ost/go/logic"
+)
+
+// DefaultAppVersion is the version embedded migrations identify as, such as to --throttle-http
+const DefaultAppVersion = "embedded"
+
+// Observer is notified of a migration's phases, progress and throttling
+type Observer = base.MigrationObserver
+
+// Progress is a migration's progress, as shown by its status line
+type Progress = base.MigrationProgress
+
+// Phase is the phase a migration is in
+type Phase = base.MigrationPhase
+
+// Options configure a migration. Fields left zero take the defaults of the corresponding gh-ost
+// command line flags.
+type Options struct {
+	Host     string
+	Port     int
+	User     string
+	Password string
+	Database string
+	Table    string
+	Alter    string
+	// Execute has the migration actually migrate the table. As with the gh-ost command, a migration
+	// is a dry run otherwise.
+	Execute       bool
+	AllowOnMaster bool
+	// ConfigFile, like --conf, holds further flags, which Flags take precedence over
+	ConfigFile string
+	// Flags are values of any further command line flags, by flag name, such as
+	// {"max-load": "Threads_running=25", "exact-rowcount": "true"}
+	Flags map[string]string
+
+	// Logger, if set, is what the migration logs with, rather than the text logger
+	Logger base.Logger
+	// Observer, if set, is notified of the migration's phases, progress and throttling
+	Observer Observer
+	// AppVersion is the version the migration identifies as; DefaultAppVersion if empty
+	AppVersion string
+}
+
+// flagValues returns the options as command line flag values
+func (this *Options) flagValues() map[string]string {
+	flagValues := map[string]string{}
+	for name, value := range this.Flags {
+		flagValues[name] = value
+	}
+	for name, value := range map[string]string{
+		"host":     this.Host,
+		"user":     this.User,
+		"password": this.Password,
+		"database": this.Database,
+		"table":    this.Table,
+		"alter":    this.Alter,
+		"conf":     this.ConfigFile,
+	} {
+		if value != "" {
+			flagValues[name] = value
+		}
+	}
+	if this.Port != 0 {
+		flagValues["port"] = strconv.Itoa(this.Port)
+	}
+	if this.Execute {
+		flagValues["execute"] = "true"
+	}
+	if this.AllowOnMaster {
+		flagValues["allow-on-master"] = "true"
+	}
+	return flagValues
+}
+
+// Migration is a gh-ost migration run within the calling program
+type Migration struct {
+	migrationContext *base.MigrationContext
+	appVersion       string
+	running          int64
+}
+
+// NewMigration configures a migration, validating the options as the gh-ost command validates its
+// flags. Nothing connects to MySQL until Run.
+func NewMigration(options Options) (*Migration, error) {
+	migrationContext := base.NewMigrationContext()
+	if options.Logger != nil {
+		migrationContext.Log = options.Logger
+	}
+	migrationContext.Observer = options.Observer
+
+	flagSet := flag.NewFlagSet("gh-ost", flag.ContinueOnError)
+	flagSet.SetOutput(io.Discard)
+	flags := NewFlags(migrationContext, flagSet)
+
+	flagValues := options.flagValues()
+	// Flags are set in name order, such that errors are consistent
+	names := make([]string, 0, len(flagValues))
+	for name := range flagValues {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if flagSet.Lookup(name) == nil {
+			return nil, fmt.Errorf("Unknown flag: %s", name)
+		}
+		if err := flagSet.Set(name, flagValues[name]); err != nil {
+			return nil, fmt.Errorf("Error setting %s: %+v", name, err)
+		}
+	}
+	if migrationContext.ConfigFile != "" {
+		if err := flags.ApplyConfigFile(); err != nil {
+			return nil, err
+		}
+	}
+	if err := flags.Apply(); err != nil {
+		return nil, err
+	}
+
+	appVersion := options.AppVersion
+	if appVersion == "" {
+		appVersion = DefaultAppVersion
+	}
+	return &Migration{migrationContext: migrationContext, appVersion: appVersion}, nil
+}
+
+// UUID identifies the migration, as do the records of the JSON logger
+func (this *Migration) UUID() string {
+	return this.migrationContext.Uuid
+}
+
+// Run runs the migration, or the CDC export, through to completion. Canceling the context aborts
+// it: the migration tears down, and Run returns the context's error. A migration runs once.
+func (this *Migration) Run(ctx context.Context) error {
+	if !atomic.CompareAndSwapInt64(&this.running, 0, 1) {
+		return errors.New("Migration has already run")
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	this.migrationContext.Log.Infof("starting gh-ost %+v (embedded)", this.appVersion)
+
+	if this.migrationContext.IsCDCExport() {
+		exporter := logic.NewCDCExporter(this.migrationContext)
+		stop := context.AfterFunc(ctx, exporter.Stop)
+		defer stop()
+		if err := exporter.Export(); err != nil {
+			return err
+		}
+		return ctx.Err()
+	}
+
+	migrator := logic.NewEmbeddedMigrator(this.migrationContext, this.appVersion)
+	stop := context.AfterFunc(ctx, func() {
+		migrator.Abort(ctx.Err())
+	})
+	defer stop()
+	if err := migrator.Migrate(); err != nil {
+		migrator.ExecOnFailureHook()
+		return err
+	}
+	return nil
+}
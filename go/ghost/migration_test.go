@@ -0,0 +1,129 @@
+/*
+   Copyright 2022 GitHub Inc.
+	 See https://github.com/github/gh-ost/blob/master/LICENSE
+*/
+
+package ghost
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/github/gh-ost/go/base"
+)
+
+type testObserver struct {
+	phases []Phase
+}
+
+func (this *testObserver) OnPhase(phase Phase)                      { this.phases = append(this.phases, phase) }
+func (this *testObserver) OnProgress(progress Progress)             {}
+func (this *testObserver) OnThrottle(throttled bool, reason string) {}
+
+func TestNewMigration(t *testing.T) {
+	t.Run("options", func(t *testing.T) {
+		logger := base.NewDefaultLogger()
+		observer := &testObserver{}
+		migration, err := NewMigration(Options{
+			Host:     "db1.example.com",
+			Port:     3307,
+			User:     "gh-ost",
+			Password: "secret",
+			Alter:    "ALTER TABLE mydb.mytable ADD COLUMN i INT",
+			Execute:  true,
+			Flags:    map[string]string{"chunk-size": "500", "max-load": "Threads_running=30", "exact-rowcount": "true"},
+			Logger:   logger,
+			Observer: observer,
+		})
+		require.NoError(t, err)
+		require.NotEmpty(t, migration.UUID())
+
+		migrationContext := migration.migrationContext
+		require.Equal(t, "db1.example.com", migrationContext.InspectorConnectionConfig.Key.Hostname)
+		require.Equal(t, 3307, migrationContext.InspectorConnectionConfig.Key.Port)
+		require.Equal(t, "gh-ost", migrationContext.InspectorConnectionConfig.User)
+		require.Equal(t, "mydb", migrationContext.DatabaseName)
+		require.Equal(t, "mytable", migrationContext.OriginalTableName)
+		require.False(t, migrationContext.Noop)
+		require.Equal(t, int64(500), migrationContext.ChunkSize)
+		require.True(t, migrationContext.CountTableRows)
+		maxLoad := migrationContext.GetMaxLoad()
+		require.Equal(t, "Threads_running=30", maxLoad.String())
+		require.Equal(t, logger, migrationContext.Log)
+		require.Equal(t, DefaultAppVersion, migration.appVersion)
+
+		migrationContext.SetPhase(base.RowCopyPhase)
+		require.Equal(t, []Phase{base.RowCopyPhase}, observer.phases)
+	})
+
+	t.Run("config file", func(t *testing.T) {
+		configFile := filepath.Join(t.TempDir(), "gh-ost.cnf")
+		require.NoError(t, os.WriteFile(configFile, []byte("[osc]\nchunk-size=200\nnice-ratio=1\n"), 0600))
+		migration, err := NewMigration(Options{
+			Database:   "mydb",
+			Table:      "mytable",
+			Alter:      "ADD COLUMN i INT",
+			ConfigFile: configFile,
+			Flags:      map[string]string{"chunk-size": "500"},
+		})
+		require.NoError(t, err)
+		require.True(t, migration.migrationContext.Noop)
+		require.Equal(t, int64(500), migration.migrationContext.ChunkSize)
+		require.Equal(t, 1.0, migration.migrationContext.GetNiceRatio())
+	})
+
+	t.Run("serve tcp auth", func(t *testing.T) {
+		dir := t.TempDir()
+		authFile := filepath.Join(dir, "auth")
+		require.NoError(t, os.WriteFile(authFile, []byte("alice admin token:s3cr3t\n"), 0600))
+		certAuthFile := filepath.Join(dir, "cert-auth")
+		require.NoError(t, os.WriteFile(certAuthFile, []byte("deploy-bot admin cert\n"), 0600))
+		options := func(flags map[string]string) Options {
+			return Options{Database: "mydb", Table: "mytable", Alter: "ADD COLUMN i INT", Flags: flags}
+		}
+
+		migration, err := NewMigration(options(map[string]string{"serve-tcp-port": "10001", "serve-tcp-auth-file": authFile}))
+		require.NoError(t, err)
+		require.Len(t, migration.migrationContext.ServeTCPAuth.Principals, 1)
+
+		for name, flags := range map[string]map[string]string{
+			"cert without key": {"serve-tcp-port": "10001", "serve-tcp-tls-cert": "server.pem"},
+			"ca without cert":  {"serve-tcp-port": "10001", "serve-tcp-tls-ca": "ca.pem"},
+			"no port":          {"serve-tcp-auth-file": authFile},
+			"missing file":     {"serve-tcp-port": "10001", "serve-tcp-auth-file": filepath.Join(dir, "missing")},
+			"cert without ca":  {"serve-tcp-port": "10001", "serve-tcp-auth-file": certAuthFile, "serve-tcp-tls-cert": "server.pem", "serve-tcp-tls-key": "server-key.pem"},
+		} {
+			_, err := NewMigration(options(flags))
+			require.Error(t, err, name)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for name, options := range map[string]Options{
+			"no alter":        {Database: "mydb", Table: "mytable"},
+			"no database":     {Table: "mytable", Alter: "ADD COLUMN i INT"},
+			"unknown flag":    {Database: "mydb", Table: "mytable", Alter: "ADD COLUMN i INT", Flags: map[string]string{"no-such-flag": "1"}},
+			"malformed flag":  {Database: "mydb", Table: "mytable", Alter: "ADD COLUMN i INT", Flags: map[string]string{"chunk-size": "many"}},
+			"exclusive flags": {Database: "mydb", Table: "mytable", Alter: "ADD COLUMN i INT", AllowOnMaster: true, Flags: map[string]string{"test-on-replica": "true"}},
+			"ask pass":        {Database: "mydb", Table: "mytable", Alter: "ADD COLUMN i INT", Flags: map[string]string{"ask-pass": "true"}},
+			"cut-over":        {Database: "mydb", Table: "mytable", Alter: "ADD COLUMN i INT", Flags: map[string]string{"cut-over": "instant"}},
+		} {
+			_, err := NewMigration(options)
+			require.Error(t, err, name)
+		}
+	})
+}
+
+func TestMigrationRun(t *testing.T) {
+	migration, err := NewMigration(Options{Database: "mydb", Table: "mytable", Alter: "ADD COLUMN i INT"})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.ErrorIs(t, migration.Run(ctx), context.Canceled)
+	require.Error(t, migration.Run(context.Background()))
+}
@@ -9,7 +9,9 @@ import (
 	gosql "database/sql"
 	"fmt"
 	"regexp"
+	"sort"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -28,6 +30,7 @@ import (
 const (
 	GhostChangelogTableComment = "gh-ost changelog"
 	atomicCutOverMagicHint     = "ghost-cut-over-sentry"
+	dirtyPagesPctInnoDBMetric  = "dirty_pages_pct"
 )
 
 type dmlBuildResult struct {
@@ -58,35 +61,55 @@ func newDmlBuildResultError(err error) *dmlBuildResult {
 // Applier is the one to actually write row data and apply binlog events onto the ghost table.
 // It is where the ghost & changelog tables get created. It is where the cut-over phase happens.
 type Applier struct {
-	connectionConfig  *mysql.ConnectionConfig
-	db                *gosql.DB
-	singletonDB       *gosql.DB
-	migrationContext  *base.MigrationContext
-	finishedMigrating int64
-	name              string
+	connectionConfig       *mysql.ConnectionConfig
+	db                     *gosql.DB
+	singletonDB            *gosql.DB
+	targetConnectionConfig *mysql.ConnectionConfig
+	targetDB               *gosql.DB
+	migrationContext       *base.MigrationContext
+	log                    base.Logger
+	finishedMigrating      int64
+	name                   string
 
 	dmlDeleteQueryBuilder *sql.DMLDeleteQueryBuilder
 	dmlInsertQueryBuilder *sql.DMLInsertQueryBuilder
 	dmlUpdateQueryBuilder *sql.DMLUpdateQueryBuilder
+
+	// lockingSessionApply is set while the singleton session holds both original and ghost tables
+	// locked, in which case DML events can only be applied via that session.
+	lockingSessionApply      int64
+	lockingSessionApplyMutex *sync.Mutex
+
+	// heartbeatMutex is held while injecting a heartbeat, so that StopHeartbeat can wait one out
+	heartbeatMutex   *sync.Mutex
+	heartbeatStopped int64
 }
 
 func NewApplier(migrationContext *base.MigrationContext) *Applier {
 	return &Applier{
-		connectionConfig:  migrationContext.ApplierConnectionConfig,
-		migrationContext:  migrationContext,
-		finishedMigrating: 0,
-		name:              "applier",
+		connectionConfig:       migrationContext.ApplierConnectionConfig,
+		targetConnectionConfig: migrationContext.TargetConnectionConfig,
+		migrationContext:       migrationContext,
+		log:                    migrationContext.ComponentLog("applier"),
+		finishedMigrating:      0,
+		name:                   "applier",
+
+		lockingSessionApplyMutex: &sync.Mutex{},
+		heartbeatMutex:           &sync.Mutex{},
 	}
 }
 
 func (this *Applier) InitDBConnections() (err error) {
+	if err := this.connectionConfig.RefreshCredentials(); err != nil {
+		return err
+	}
 	applierUri := this.connectionConfig.GetDBUri(this.migrationContext.DatabaseName)
 	uriWithMulti := fmt.Sprintf("%s&multiStatements=true", applierUri)
-	if this.db, _, err = mysql.GetDB(this.migrationContext.Uuid, uriWithMulti); err != nil {
+	if this.db, _, err = mysql.GetDB(this.migrationContext.Uuid, uriWithMulti, this.connectionConfig); err != nil {
 		return err
 	}
 	singletonApplierUri := fmt.Sprintf("%s&timeout=0", applierUri)
-	if this.singletonDB, _, err = mysql.GetDB(this.migrationContext.Uuid, singletonApplierUri); err != nil {
+	if this.singletonDB, _, err = mysql.GetDB(this.migrationContext.Uuid, singletonApplierUri, this.connectionConfig); err != nil {
 		return err
 	}
 	this.singletonDB.SetMaxOpenConns(1)
@@ -111,7 +134,31 @@ func (this *Applier) InitDBConnections() (err error) {
 	if err := this.readTableColumns(); err != nil {
 		return err
 	}
-	this.migrationContext.Log.Infof("Applier initiated on %+v, version %+v", this.connectionConfig.ImpliedKey, this.migrationContext.ApplierMySQLVersion)
+	this.log.Infof("Applier initiated on %+v, version %+v", this.connectionConfig.ImpliedKey, this.migrationContext.ApplierMySQLVersion)
+	return this.initTargetDBConnection()
+}
+
+// initTargetDBConnection connects to the server where the ghost table is created. That is the applier
+// itself, unless this is a cross-server move, in which case it is the `--target-host` server.
+func (this *Applier) initTargetDBConnection() (err error) {
+	if !this.migrationContext.IsCrossServerMove() {
+		this.targetDB = this.db
+		return nil
+	}
+	if err := this.targetConnectionConfig.RefreshCredentials(); err != nil {
+		return err
+	}
+	targetUri := this.targetConnectionConfig.GetDBUri(this.migrationContext.DatabaseName)
+	uriWithMulti := fmt.Sprintf("%s&multiStatements=true", targetUri)
+	if this.targetDB, _, err = mysql.GetDB(this.migrationContext.Uuid, uriWithMulti, this.targetConnectionConfig); err != nil {
+		return err
+	}
+	version, err := base.ValidateConnection(this.targetDB, this.targetConnectionConfig, this.migrationContext, "target")
+	if err != nil {
+		return err
+	}
+	this.migrationContext.TargetMySQLVersion = version
+	this.log.Infof("Target initiated on %+v, version %+v", this.targetConnectionConfig.ImpliedKey, this.migrationContext.TargetMySQLVersion)
 	return nil
 }
 
@@ -130,6 +177,7 @@ func (this *Applier) prepareQueries() (err error) {
 		this.migrationContext.OriginalTableColumns,
 		this.migrationContext.SharedColumns,
 		this.migrationContext.MappedSharedColumns,
+		this.migrationContext.ColumnExpressions,
 	); err != nil {
 		return err
 	}
@@ -139,6 +187,7 @@ func (this *Applier) prepareQueries() (err error) {
 		this.migrationContext.OriginalTableColumns,
 		this.migrationContext.SharedColumns,
 		this.migrationContext.MappedSharedColumns,
+		this.migrationContext.ColumnExpressions,
 		&this.migrationContext.UniqueKey.Columns,
 	); err != nil {
 		return err
@@ -156,7 +205,7 @@ func (this *Applier) validateAndReadGlobalVariables() error {
 		return err
 	}
 
-	this.migrationContext.Log.Infof("will use time_zone='%s' on applier", this.migrationContext.ApplierTimeZone)
+	this.log.Infof("will use time_zone='%s' on applier", this.migrationContext.ApplierTimeZone)
 	return nil
 }
 
@@ -189,7 +238,7 @@ func (this *Applier) generateInstantDDLQuery() string {
 
 // readTableColumns reads table columns on applier
 func (this *Applier) readTableColumns() (err error) {
-	this.migrationContext.Log.Infof("Examining table structure on applier")
+	this.log.Infof("Examining table structure on applier")
 	this.migrationContext.OriginalTableColumnsOnApplier, _, err = mysql.GetTableColumns(this.db, this.migrationContext.DatabaseName, this.migrationContext.OriginalTableName)
 	if err != nil {
 		return err
@@ -198,9 +247,9 @@ func (this *Applier) readTableColumns() (err error) {
 }
 
 // showTableStatus returns the output of `show table status like '...'` command
-func (this *Applier) showTableStatus(tableName string) (rowMap sqlutils.RowMap) {
+func (this *Applier) showTableStatus(db *gosql.DB, tableName string) (rowMap sqlutils.RowMap) {
 	query := fmt.Sprintf(`show /* gh-ost */ table status from %s like '%s'`, sql.EscapeName(this.migrationContext.DatabaseName), tableName)
-	sqlutils.QueryRowsMap(this.db, query, func(m sqlutils.RowMap) error {
+	sqlutils.QueryRowsMap(db, query, func(m sqlutils.RowMap) error {
 		rowMap = m
 		return nil
 	})
@@ -208,8 +257,8 @@ func (this *Applier) showTableStatus(tableName string) (rowMap sqlutils.RowMap)
 }
 
 // tableExists checks if a given table exists in database
-func (this *Applier) tableExists(tableName string) (tableFound bool) {
-	m := this.showTableStatus(tableName)
+func (this *Applier) tableExists(db *gosql.DB, tableName string) (tableFound bool) {
+	m := this.showTableStatus(db, tableName)
 	return (m != nil)
 }
 
@@ -221,19 +270,24 @@ func (this *Applier) ValidateOrDropExistingTables() error {
 			return err
 		}
 	}
-	if this.tableExists(this.migrationContext.GetGhostTableName()) {
+	if this.tableExists(this.targetDB, this.migrationContext.GetGhostTableName()) {
 		return fmt.Errorf("Table %s already exists. Panicking. Use --initially-drop-ghost-table to force dropping it, though I really prefer that you drop it or rename it away", sql.EscapeName(this.migrationContext.GetGhostTableName()))
 	}
+	if this.migrationContext.IsCrossServerMove() && !this.migrationContext.TargetSkipRename {
+		if this.tableExists(this.targetDB, this.migrationContext.OriginalTableName) {
+			return fmt.Errorf("Table %s already exists on target %+v. Panicking. Drop or rename it away, or use --target-skip-rename", sql.EscapeName(this.migrationContext.OriginalTableName), *this.targetConnectionConfig.ImpliedKey)
+		}
+	}
 	if this.migrationContext.InitiallyDropOldTable {
 		if err := this.DropOldTable(); err != nil {
 			return err
 		}
 	}
 	if len(this.migrationContext.GetOldTableName()) > mysql.MaxTableNameLength {
-		this.migrationContext.Log.Fatalf("--timestamp-old-table defined, but resulting table name (%s) is too long (only %d characters allowed)", this.migrationContext.GetOldTableName(), mysql.MaxTableNameLength)
+		return fmt.Errorf("--timestamp-old-table defined, but resulting table name (%s) is too long (only %d characters allowed)", this.migrationContext.GetOldTableName(), mysql.MaxTableNameLength)
 	}
 
-	if this.tableExists(this.migrationContext.GetOldTableName()) {
+	if this.tableExists(this.db, this.migrationContext.GetOldTableName()) {
 		return fmt.Errorf("Table %s already exists. Panicking. Use --initially-drop-old-table to force dropping it, though I really prefer that you drop it or rename it away", sql.EscapeName(this.migrationContext.GetOldTableName()))
 	}
 
@@ -255,12 +309,12 @@ func (this *Applier) ValidateOrDropExistingTables() error {
 // that is difficult to identify.
 func (this *Applier) AttemptInstantDDL() error {
 	query := this.generateInstantDDLQuery()
-	this.migrationContext.Log.Infof("INSTANT DDL query is: %s", query)
+	this.log.Infof("INSTANT DDL query is: %s", query)
 
 	// Reuse cut-over-lock-timeout from regular migration process to reduce risk
 	// in situations where there may be long-running transactions.
 	tableLockTimeoutSeconds := this.migrationContext.CutOverLockTimeoutSeconds * 2
-	this.migrationContext.Log.Infof("Setting LOCK timeout as %d seconds", tableLockTimeoutSeconds)
+	this.log.Infof("Setting LOCK timeout as %d seconds", tableLockTimeoutSeconds)
 	lockTimeoutQuery := fmt.Sprintf(`set /* gh-ost */ session lock_wait_timeout:=%d`, tableLockTimeoutSeconds)
 	if _, err := this.db.Exec(lockTimeoutQuery); err != nil {
 		return err
@@ -270,21 +324,50 @@ func (this *Applier) AttemptInstantDDL() error {
 	return err
 }
 
-// CreateGhostTable creates the ghost table on the applier host
-func (this *Applier) CreateGhostTable() error {
-	query := fmt.Sprintf(`create /* gh-ost */ table %s.%s like %s.%s`,
-		sql.EscapeName(this.migrationContext.DatabaseName),
-		sql.EscapeName(this.migrationContext.GetGhostTableName()),
+// buildCreateGhostTableQuery returns the statement creating the ghost table. On a cross-server move, the
+// original table does not exist on the target server, and so the ghost table is created from the original
+// table's `show create table` output rather than by `create table ... like ...`.
+func (this *Applier) buildCreateGhostTableQuery() (string, error) {
+	if !this.migrationContext.IsCrossServerMove() {
+		return fmt.Sprintf(`create /* gh-ost */ table %s.%s like %s.%s`,
+			sql.EscapeName(this.migrationContext.DatabaseName),
+			sql.EscapeName(this.migrationContext.GetGhostTableName()),
+			sql.EscapeName(this.migrationContext.DatabaseName),
+			sql.EscapeName(this.migrationContext.OriginalTableName),
+		), nil
+	}
+	query := fmt.Sprintf(`show /* gh-ost */ create table %s.%s`,
 		sql.EscapeName(this.migrationContext.DatabaseName),
 		sql.EscapeName(this.migrationContext.OriginalTableName),
 	)
-	this.migrationContext.Log.Infof("Creating ghost table %s.%s",
+	var tableName, createTableStatement string
+	if err := this.db.QueryRow(query).Scan(&tableName, &createTableStatement); err != nil {
+		return "", err
+	}
+	createTablePrefix := fmt.Sprintf("CREATE TABLE %s", sql.EscapeName(this.migrationContext.OriginalTableName))
+	if !strings.HasPrefix(createTableStatement, createTablePrefix) {
+		return "", fmt.Errorf("Unexpected create table statement for %s: %s", sql.EscapeName(this.migrationContext.OriginalTableName), createTableStatement)
+	}
+	return fmt.Sprintf(`create /* gh-ost */ table %s.%s%s`,
+		sql.EscapeName(this.migrationContext.DatabaseName),
+		sql.EscapeName(this.migrationContext.GetGhostTableName()),
+		strings.TrimPrefix(createTableStatement, createTablePrefix),
+	), nil
+}
+
+// CreateGhostTable creates the ghost table on the applier host, or on the target host of a cross-server move
+func (this *Applier) CreateGhostTable() error {
+	query, err := this.buildCreateGhostTableQuery()
+	if err != nil {
+		return err
+	}
+	this.log.Infof("Creating ghost table %s.%s",
 		sql.EscapeName(this.migrationContext.DatabaseName),
 		sql.EscapeName(this.migrationContext.GetGhostTableName()),
 	)
 
-	err := func() error {
-		tx, err := this.db.Begin()
+	err = func() error {
+		tx, err := this.targetDB.Begin()
 		if err != nil {
 			return err
 		}
@@ -299,7 +382,7 @@ func (this *Applier) CreateGhostTable() error {
 		if _, err := tx.Exec(query); err != nil {
 			return err
 		}
-		this.migrationContext.Log.Infof("Ghost table created")
+		this.log.Infof("Ghost table created")
 		if err := tx.Commit(); err != nil {
 			// Neither SET SESSION nor ALTER are really transactional, so strictly speaking
 			// there's no need to commit; but let's do this the legit way anyway.
@@ -318,14 +401,14 @@ func (this *Applier) AlterGhost() error {
 		sql.EscapeName(this.migrationContext.GetGhostTableName()),
 		this.migrationContext.AlterStatementOptions,
 	)
-	this.migrationContext.Log.Infof("Altering ghost table %s.%s",
+	this.log.Infof("Altering ghost table %s.%s",
 		sql.EscapeName(this.migrationContext.DatabaseName),
 		sql.EscapeName(this.migrationContext.GetGhostTableName()),
 	)
-	this.migrationContext.Log.Debugf("ALTER statement: %s", query)
+	this.log.Debugf("ALTER statement: %s", query)
 
 	err := func() error {
-		tx, err := this.db.Begin()
+		tx, err := this.targetDB.Begin()
 		if err != nil {
 			return err
 		}
@@ -340,7 +423,7 @@ func (this *Applier) AlterGhost() error {
 		if _, err := tx.Exec(query); err != nil {
 			return err
 		}
-		this.migrationContext.Log.Infof("Ghost table altered")
+		this.log.Infof("Ghost table altered")
 		if err := tx.Commit(); err != nil {
 			// Neither SET SESSION nor ALTER are really transactional, so strictly speaking
 			// there's no need to commit; but let's do this the legit way anyway.
@@ -359,15 +442,15 @@ func (this *Applier) AlterGhostAutoIncrement() error {
 		sql.EscapeName(this.migrationContext.GetGhostTableName()),
 		this.migrationContext.OriginalTableAutoIncrement,
 	)
-	this.migrationContext.Log.Infof("Altering ghost table AUTO_INCREMENT value %s.%s",
+	this.log.Infof("Altering ghost table AUTO_INCREMENT value %s.%s",
 		sql.EscapeName(this.migrationContext.DatabaseName),
 		sql.EscapeName(this.migrationContext.GetGhostTableName()),
 	)
-	this.migrationContext.Log.Debugf("AUTO_INCREMENT ALTER statement: %s", query)
-	if _, err := sqlutils.ExecNoPrepare(this.db, query); err != nil {
+	this.log.Debugf("AUTO_INCREMENT ALTER statement: %s", query)
+	if _, err := sqlutils.ExecNoPrepare(this.targetDB, query); err != nil {
 		return err
 	}
-	this.migrationContext.Log.Infof("Ghost table AUTO_INCREMENT altered")
+	this.log.Infof("Ghost table AUTO_INCREMENT altered")
 	return nil
 }
 
@@ -388,31 +471,31 @@ func (this *Applier) CreateChangelogTable() error {
 		sql.EscapeName(this.migrationContext.GetChangelogTableName()),
 		GhostChangelogTableComment,
 	)
-	this.migrationContext.Log.Infof("Creating changelog table %s.%s",
+	this.log.Infof("Creating changelog table %s.%s",
 		sql.EscapeName(this.migrationContext.DatabaseName),
 		sql.EscapeName(this.migrationContext.GetChangelogTableName()),
 	)
 	if _, err := sqlutils.ExecNoPrepare(this.db, query); err != nil {
 		return err
 	}
-	this.migrationContext.Log.Infof("Changelog table created")
+	this.log.Infof("Changelog table created")
 	return nil
 }
 
-// dropTable drops a given table on the applied host
-func (this *Applier) dropTable(tableName string) error {
+// dropTable drops a given table on the given host
+func (this *Applier) dropTable(db *gosql.DB, tableName string) error {
 	query := fmt.Sprintf(`drop /* gh-ost */ table if exists %s.%s`,
 		sql.EscapeName(this.migrationContext.DatabaseName),
 		sql.EscapeName(tableName),
 	)
-	this.migrationContext.Log.Infof("Dropping table %s.%s",
+	this.log.Infof("Dropping table %s.%s",
 		sql.EscapeName(this.migrationContext.DatabaseName),
 		sql.EscapeName(tableName),
 	)
-	if _, err := sqlutils.ExecNoPrepare(this.db, query); err != nil {
+	if _, err := sqlutils.ExecNoPrepare(db, query); err != nil {
 		return err
 	}
-	this.migrationContext.Log.Infof("Table dropped")
+	this.log.Infof("Table dropped")
 	return nil
 }
 
@@ -422,11 +505,11 @@ func (this *Applier) DropTriggersFromGhost() error {
 		for _, trigger := range this.migrationContext.Triggers {
 			triggerName := this.migrationContext.GetGhostTriggerName(trigger.Name)
 			query := fmt.Sprintf("drop trigger if exists %s", sql.EscapeName(triggerName))
-			_, err := sqlutils.ExecNoPrepare(this.db, query)
+			_, err := sqlutils.ExecNoPrepare(this.targetDB, query)
 			if err != nil {
 				return err
 			}
-			this.migrationContext.Log.Infof("Trigger '%s' dropped", triggerName)
+			this.log.Infof("Trigger '%s' dropped", triggerName)
 		}
 	}
 	return nil
@@ -446,16 +529,16 @@ func (this *Applier) createTriggers(tableName string) error {
 				sql.EscapeName(tableName),
 				trigger.Statement,
 			)
-			this.migrationContext.Log.Infof("Createing trigger %s on %s.%s",
+			this.log.Infof("Createing trigger %s on %s.%s",
 				sql.EscapeName(triggerName),
 				sql.EscapeName(this.migrationContext.DatabaseName),
 				sql.EscapeName(tableName),
 			)
-			if _, err := sqlutils.ExecNoPrepare(this.db, query); err != nil {
+			if _, err := sqlutils.ExecNoPrepare(this.targetDB, query); err != nil {
 				return err
 			}
 		}
-		this.migrationContext.Log.Infof("Triggers created on %s", tableName)
+		this.log.Infof("Triggers created on %s", tableName)
 	}
 	return nil
 }
@@ -468,17 +551,17 @@ func (this *Applier) CreateTriggersOnGhost() error {
 
 // DropChangelogTable drops the changelog table on the applier host
 func (this *Applier) DropChangelogTable() error {
-	return this.dropTable(this.migrationContext.GetChangelogTableName())
+	return this.dropTable(this.db, this.migrationContext.GetChangelogTableName())
 }
 
 // DropOldTable drops the _Old table on the applier host
 func (this *Applier) DropOldTable() error {
-	return this.dropTable(this.migrationContext.GetOldTableName())
+	return this.dropTable(this.db, this.migrationContext.GetOldTableName())
 }
 
-// DropGhostTable drops the ghost table on the applier host
+// DropGhostTable drops the ghost table on the applier host, or on the target host of a cross-server move
 func (this *Applier) DropGhostTable() error {
-	return this.dropTable(this.migrationContext.GetGhostTableName())
+	return this.dropTable(this.targetDB, this.migrationContext.GetGhostTableName())
 }
 
 // WriteChangelog writes a value to the changelog table.
@@ -524,13 +607,19 @@ func (this *Applier) WriteChangelogState(value string) (string, error) {
 func (this *Applier) InitiateHeartbeat() {
 	var numSuccessiveFailures int64
 	injectHeartbeat := func() error {
+		this.heartbeatMutex.Lock()
+		defer this.heartbeatMutex.Unlock()
+
+		if atomic.LoadInt64(&this.heartbeatStopped) > 0 {
+			return nil
+		}
 		if atomic.LoadInt64(&this.migrationContext.HibernateUntil) > 0 {
 			return nil
 		}
 		if _, err := this.WriteChangelog("heartbeat", time.Now().Format(time.RFC3339Nano)); err != nil {
 			numSuccessiveFailures++
 			if numSuccessiveFailures > this.migrationContext.MaxRetries() {
-				return this.migrationContext.Log.Errore(err)
+				return this.log.Errore(err)
 			}
 		} else {
 			numSuccessiveFailures = 0
@@ -542,7 +631,7 @@ func (this *Applier) InitiateHeartbeat() {
 	ticker := time.NewTicker(time.Duration(this.migrationContext.HeartbeatIntervalMilliseconds) * time.Millisecond)
 	defer ticker.Stop()
 	for range ticker.C {
-		if atomic.LoadInt64(&this.finishedMigrating) > 0 {
+		if atomic.LoadInt64(&this.finishedMigrating) > 0 || atomic.LoadInt64(&this.heartbeatStopped) > 0 {
 			return
 		}
 		// Generally speaking, we would issue a goroutine, but I'd actually rather
@@ -557,6 +646,14 @@ func (this *Applier) InitiateHeartbeat() {
 	}
 }
 
+// StopHeartbeat stops writing heartbeats to the changelog table, waiting out one being written
+func (this *Applier) StopHeartbeat() {
+	atomic.StoreInt64(&this.heartbeatStopped, 1)
+
+	this.heartbeatMutex.Lock()
+	defer this.heartbeatMutex.Unlock()
+}
+
 // ExecuteThrottleQuery executes the `--throttle-query` and returns its results.
 func (this *Applier) ExecuteThrottleQuery() (int64, error) {
 	throttleQuery := this.migrationContext.GetThrottleQuery()
@@ -566,14 +663,14 @@ func (this *Applier) ExecuteThrottleQuery() (int64, error) {
 	}
 	var result int64
 	if err := this.db.QueryRow(throttleQuery).Scan(&result); err != nil {
-		return 0, this.migrationContext.Log.Errore(err)
+		return 0, this.log.Errore(err)
 	}
 	return result, nil
 }
 
 // readMigrationMinValues returns the minimum values to be iterated on rowcopy
 func (this *Applier) readMigrationMinValues(tx *gosql.Tx, uniqueKey *sql.UniqueKey) error {
-	this.migrationContext.Log.Debugf("Reading migration range according to key: %s", uniqueKey.Name)
+	this.log.Debugf("Reading migration range according to key: %s", uniqueKey.Name)
 	query, err := sql.BuildUniqueKeyMinValuesPreparedQuery(this.migrationContext.DatabaseName, this.migrationContext.OriginalTableName, uniqueKey)
 	if err != nil {
 		return err
@@ -591,14 +688,14 @@ func (this *Applier) readMigrationMinValues(tx *gosql.Tx, uniqueKey *sql.UniqueK
 			return err
 		}
 	}
-	this.migrationContext.Log.Infof("Migration min values: [%s]", this.migrationContext.MigrationRangeMinValues)
+	this.log.Infof("Migration min values: [%s]", this.migrationContext.MigrationRangeMinValues)
 
 	return rows.Err()
 }
 
 // readMigrationMaxValues returns the maximum values to be iterated on rowcopy
 func (this *Applier) readMigrationMaxValues(tx *gosql.Tx, uniqueKey *sql.UniqueKey) error {
-	this.migrationContext.Log.Debugf("Reading migration range according to key: %s", uniqueKey.Name)
+	this.log.Debugf("Reading migration range according to key: %s", uniqueKey.Name)
 	query, err := sql.BuildUniqueKeyMaxValuesPreparedQuery(this.migrationContext.DatabaseName, this.migrationContext.OriginalTableName, uniqueKey)
 	if err != nil {
 		return err
@@ -616,7 +713,7 @@ func (this *Applier) readMigrationMaxValues(tx *gosql.Tx, uniqueKey *sql.UniqueK
 			return err
 		}
 	}
-	this.migrationContext.Log.Infof("Migration max values: [%s]", this.migrationContext.MigrationRangeMaxValues)
+	this.log.Infof("Migration max values: [%s]", this.migrationContext.MigrationRangeMaxValues)
 
 	return rows.Err()
 }
@@ -709,10 +806,11 @@ func (this *Applier) CalculateNextIterationRangeEndValues() (hasFurtherRange boo
 		}
 		if hasFurtherRange {
 			this.migrationContext.MigrationIterationRangeMaxValues = iterationRangeMaxValues
+			this.migrationContext.SetCurrentChunkRange(this.migrationContext.MigrationIterationRangeMinValues, iterationRangeMaxValues)
 			return hasFurtherRange, expectedRowCount, nil
 		}
 	}
-	this.migrationContext.Log.Debugf("Iteration complete: no further range to iterate")
+	this.log.Debugf("Iteration complete: no further range to iterate")
 	return hasFurtherRange, expectedRowCount, nil
 }
 
@@ -722,12 +820,27 @@ func (this *Applier) ApplyIterationInsertQuery() (chunkSize int64, rowsAffected
 	startTime := time.Now()
 	chunkSize = atomic.LoadInt64(&this.migrationContext.ChunkSize)
 
+	if this.migrationContext.IsCrossServerMove() {
+		if rowsAffected, err = this.copyIterationRangeToTarget(); err != nil {
+			return chunkSize, rowsAffected, duration, err
+		}
+		duration = time.Since(startTime)
+		this.log.Debugf(
+			"Copied range to target: [%s]..[%s]; iteration: %d; chunk-size: %d",
+			this.migrationContext.MigrationIterationRangeMinValues,
+			this.migrationContext.MigrationIterationRangeMaxValues,
+			this.migrationContext.GetIteration(),
+			chunkSize)
+		return chunkSize, rowsAffected, duration, nil
+	}
+
 	query, explodedArgs, err := sql.BuildRangeInsertPreparedQuery(
 		this.migrationContext.DatabaseName,
 		this.migrationContext.OriginalTableName,
 		this.migrationContext.GetGhostTableName(),
 		this.migrationContext.SharedColumns.Names(),
 		this.migrationContext.MappedSharedColumns.Names(),
+		this.migrationContext.ColumnExpressions,
 		this.migrationContext.UniqueKey.Name,
 		&this.migrationContext.UniqueKey.Columns,
 		this.migrationContext.MigrationIterationRangeMinValues.AbstractValues(),
@@ -760,33 +873,9 @@ func (this *Applier) ApplyIterationInsertQuery() (chunkSize int64, rowsAffected
 		}
 
 		if this.migrationContext.PanicOnWarnings {
-			//nolint:execinquery
-			rows, err := tx.Query("SHOW WARNINGS")
-			if err != nil {
-				return nil, err
-			}
-			defer rows.Close()
-			if err = rows.Err(); err != nil {
+			if err := this.readMigrationLastInsertSQLWarnings(tx); err != nil {
 				return nil, err
 			}
-
-			var sqlWarnings []string
-			for rows.Next() {
-				var level, message string
-				var code int
-				if err := rows.Scan(&level, &code, &message); err != nil {
-					this.migrationContext.Log.Warningf("Failed to read SHOW WARNINGS row")
-					continue
-				}
-				// Duplicate warnings are formatted differently across mysql versions, hence the optional table name prefix
-				migrationUniqueKeyExpression := fmt.Sprintf("for key '(%s\\.)?%s'", this.migrationContext.GetGhostTableName(), this.migrationContext.UniqueKey.NameInGhostTable)
-				matched, _ := regexp.MatchString(migrationUniqueKeyExpression, message)
-				if strings.Contains(message, "Duplicate entry") && matched {
-					continue
-				}
-				sqlWarnings = append(sqlWarnings, fmt.Sprintf("%s: %s (%d)", level, message, code))
-			}
-			this.migrationContext.MigrationLastInsertSQLWarnings = sqlWarnings
 		}
 
 		if err := tx.Commit(); err != nil {
@@ -800,7 +889,7 @@ func (this *Applier) ApplyIterationInsertQuery() (chunkSize int64, rowsAffected
 	}
 	rowsAffected, _ = sqlResult.RowsAffected()
 	duration = time.Since(startTime)
-	this.migrationContext.Log.Debugf(
+	this.log.Debugf(
 		"Issued INSERT on range: [%s]..[%s]; iteration: %d; chunk-size: %d",
 		this.migrationContext.MigrationIterationRangeMinValues,
 		this.migrationContext.MigrationIterationRangeMaxValues,
@@ -809,13 +898,162 @@ func (this *Applier) ApplyIterationInsertQuery() (chunkSize int64, rowsAffected
 	return chunkSize, rowsAffected, duration, nil
 }
 
+// readMigrationLastInsertSQLWarnings reads the warnings of a row copy insert, ignoring the expected
+// duplicate entry warnings on the migration unique key.
+func (this *Applier) readMigrationLastInsertSQLWarnings(tx *gosql.Tx) error {
+	//nolint:execinquery
+	rows, err := tx.Query("SHOW WARNINGS")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	var sqlWarnings []string
+	for rows.Next() {
+		var level, message string
+		var code int
+		if err := rows.Scan(&level, &code, &message); err != nil {
+			this.log.Warningf("Failed to read SHOW WARNINGS row")
+			continue
+		}
+		// Duplicate warnings are formatted differently across mysql versions, hence the optional table name prefix
+		migrationUniqueKeyExpression := fmt.Sprintf("for key '(%s\\.)?%s'", this.migrationContext.GetGhostTableName(), this.migrationContext.UniqueKey.NameInGhostTable)
+		matched, _ := regexp.MatchString(migrationUniqueKeyExpression, message)
+		if strings.Contains(message, "Duplicate entry") && matched {
+			continue
+		}
+		sqlWarnings = append(sqlWarnings, fmt.Sprintf("%s: %s (%d)", level, message, code))
+	}
+	this.migrationContext.MigrationLastInsertSQLWarnings = sqlWarnings
+	return nil
+}
+
+// readRowCopyValues scans the current row into arguments for a write onto the target server. Textual values
+// are passed on as strings, so that the target converts them into its column's charset. Binary values are
+// passed on as bytes.
+func readRowCopyValues(rows *gosql.Rows, columnTypes []*gosql.ColumnType) ([]interface{}, error) {
+	values := make([]interface{}, len(columnTypes))
+	valuePointers := make([]interface{}, len(columnTypes))
+	for i := range values {
+		valuePointers[i] = &values[i]
+	}
+	if err := rows.Scan(valuePointers...); err != nil {
+		return nil, err
+	}
+	for i, value := range values {
+		bytes, ok := value.([]byte)
+		if !ok {
+			continue
+		}
+		switch strings.ToUpper(columnTypes[i].DatabaseTypeName()) {
+		case "BINARY", "VARBINARY", "TINYBLOB", "BLOB", "MEDIUMBLOB", "LONGBLOB", "BIT", "GEOMETRY":
+			continue
+		}
+		values[i] = string(bytes)
+	}
+	return values, nil
+}
+
+// copyIterationRangeToTarget copies the current iteration's range of rows on a cross-server move:
+// rows are read from the original table on the applier, and are inserted onto the ghost table on the target.
+// The read holds shared locks on the original rows until the target write completes, so that any binlog
+// event on these rows is only applied after the rows are copied, just as with `insert ... select`.
+func (this *Applier) copyIterationRangeToTarget() (rowsAffected int64, err error) {
+	selectQuery, ghostColumns, explodedArgs, err := sql.BuildRangeSelectPreparedQuery(
+		this.migrationContext.DatabaseName,
+		this.migrationContext.OriginalTableName,
+		this.migrationContext.SharedColumns.Names(),
+		this.migrationContext.MappedSharedColumns.Names(),
+		this.migrationContext.ColumnExpressions,
+		this.migrationContext.UniqueKey.Name,
+		&this.migrationContext.UniqueKey.Columns,
+		this.migrationContext.MigrationIterationRangeMinValues.AbstractValues(),
+		this.migrationContext.MigrationIterationRangeMaxValues.AbstractValues(),
+		this.migrationContext.GetIteration() == 0,
+		this.migrationContext.IsTransactionalTable(),
+		strings.HasPrefix(this.migrationContext.ApplierMySQLVersion, "8."),
+	)
+	if err != nil {
+		return rowsAffected, err
+	}
+	sessionQuery := fmt.Sprintf("SET /* gh-ost */ SESSION time_zone = '+00:00', %s", this.generateSqlModeQuery())
+
+	sourceTx, err := this.db.Begin()
+	if err != nil {
+		return rowsAffected, err
+	}
+	defer sourceTx.Rollback()
+	if _, err := sourceTx.Exec(sessionQuery); err != nil {
+		return rowsAffected, err
+	}
+
+	var rowsArgs []interface{}
+	rowsCount := 0
+	err = func() error {
+		rows, err := sourceTx.Query(selectQuery, explodedArgs...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		columnTypes, err := rows.ColumnTypes()
+		if err != nil {
+			return err
+		}
+		for rows.Next() {
+			values, err := readRowCopyValues(rows, columnTypes)
+			if err != nil {
+				return err
+			}
+			rowsArgs = append(rowsArgs, values...)
+			rowsCount++
+		}
+		return rows.Err()
+	}()
+	if err != nil {
+		return rowsAffected, err
+	}
+	if rowsCount == 0 {
+		return rowsAffected, sourceTx.Commit()
+	}
+
+	insertQuery, err := sql.BuildMultiRowInsertPreparedQuery(this.migrationContext.DatabaseName, this.migrationContext.GetGhostTableName(), ghostColumns, rowsCount)
+	if err != nil {
+		return rowsAffected, err
+	}
+	targetTx, err := this.targetDB.Begin()
+	if err != nil {
+		return rowsAffected, err
+	}
+	defer targetTx.Rollback()
+	if _, err := targetTx.Exec(sessionQuery); err != nil {
+		return rowsAffected, err
+	}
+	result, err := targetTx.Exec(insertQuery, rowsArgs...)
+	if err != nil {
+		return rowsAffected, err
+	}
+	if this.migrationContext.PanicOnWarnings {
+		if err := this.readMigrationLastInsertSQLWarnings(targetTx); err != nil {
+			return rowsAffected, err
+		}
+	}
+	if err := targetTx.Commit(); err != nil {
+		return rowsAffected, err
+	}
+	rowsAffected, _ = result.RowsAffected()
+	return rowsAffected, sourceTx.Commit()
+}
+
 // LockOriginalTable places a write lock on the original table
 func (this *Applier) LockOriginalTable() error {
 	query := fmt.Sprintf(`lock /* gh-ost */ tables %s.%s write`,
 		sql.EscapeName(this.migrationContext.DatabaseName),
 		sql.EscapeName(this.migrationContext.OriginalTableName),
 	)
-	this.migrationContext.Log.Infof("Locking %s.%s",
+	this.log.Infof("Locking %s.%s",
 		sql.EscapeName(this.migrationContext.DatabaseName),
 		sql.EscapeName(this.migrationContext.OriginalTableName),
 	)
@@ -823,18 +1061,87 @@ func (this *Applier) LockOriginalTable() error {
 	if _, err := sqlutils.ExecNoPrepare(this.singletonDB, query); err != nil {
 		return err
 	}
-	this.migrationContext.Log.Infof("Table locked")
+	this.log.Infof("Table locked")
+	return nil
+}
+
+// LockOriginalAndGhostTables locks both original and ghost tables for write, on the singleton session.
+// Until UnlockOriginalAndGhostTables, DML events are applied via that session, as no other session
+// may access the ghost table.
+func (this *Applier) LockOriginalAndGhostTables() error {
+	this.lockingSessionApplyMutex.Lock()
+	defer this.lockingSessionApplyMutex.Unlock()
+
+	// The session applies DML events, hence is set up like any session applying them
+	sessionQuery := fmt.Sprintf(`set /* gh-ost */ session time_zone = '+00:00', lock_wait_timeout = %d, %s`,
+		this.migrationContext.CutOverLockTimeoutSeconds,
+		this.generateSqlModeQuery(),
+	)
+	if _, err := sqlutils.ExecNoPrepare(this.singletonDB, sessionQuery); err != nil {
+		return err
+	}
+	query := fmt.Sprintf(`lock /* gh-ost */ tables %s.%s write, %s.%s write`,
+		sql.EscapeName(this.migrationContext.DatabaseName),
+		sql.EscapeName(this.migrationContext.OriginalTableName),
+		sql.EscapeName(this.migrationContext.DatabaseName),
+		sql.EscapeName(this.migrationContext.GetGhostTableName()),
+	)
+	this.log.Infof("Locking %s.%s, %s.%s",
+		sql.EscapeName(this.migrationContext.DatabaseName),
+		sql.EscapeName(this.migrationContext.OriginalTableName),
+		sql.EscapeName(this.migrationContext.DatabaseName),
+		sql.EscapeName(this.migrationContext.GetGhostTableName()),
+	)
+	this.migrationContext.LockTablesStartTime = time.Now()
+	if _, err := sqlutils.ExecNoPrepare(this.singletonDB, query); err != nil {
+		return err
+	}
+	atomic.StoreInt64(&this.lockingSessionApply, 1)
+	this.log.Infof("Tables locked")
+	return nil
+}
+
+// UnlockOriginalAndGhostTables reverts LockOriginalAndGhostTables
+func (this *Applier) UnlockOriginalAndGhostTables() error {
+	this.lockingSessionApplyMutex.Lock()
+	defer this.lockingSessionApplyMutex.Unlock()
+
+	atomic.StoreInt64(&this.lockingSessionApply, 0)
+	return this.UnlockTables()
+}
+
+// RenameTablesUnderLock atomically swaps the original and ghost tables, via the singleton session
+// which holds both locked. MySQL allows for this as of 8.0.13. Queries blocked on the original table
+// resume on the new table, once the tables are unlocked.
+func (this *Applier) RenameTablesUnderLock() error {
+	query := fmt.Sprintf(`rename /* gh-ost */ table %s.%s to %s.%s, %s.%s to %s.%s`,
+		sql.EscapeName(this.migrationContext.DatabaseName),
+		sql.EscapeName(this.migrationContext.OriginalTableName),
+		sql.EscapeName(this.migrationContext.DatabaseName),
+		sql.EscapeName(this.migrationContext.GetOldTableName()),
+		sql.EscapeName(this.migrationContext.DatabaseName),
+		sql.EscapeName(this.migrationContext.GetGhostTableName()),
+		sql.EscapeName(this.migrationContext.DatabaseName),
+		sql.EscapeName(this.migrationContext.OriginalTableName),
+	)
+	this.log.Infof("Renaming tables under lock")
+	this.migrationContext.RenameTablesStartTime = time.Now()
+	if _, err := sqlutils.ExecNoPrepare(this.singletonDB, query); err != nil {
+		return err
+	}
+	this.migrationContext.RenameTablesEndTime = time.Now()
+	this.log.Infof("Tables renamed")
 	return nil
 }
 
 // UnlockTables makes tea. No wait, it unlocks tables.
 func (this *Applier) UnlockTables() error {
 	query := `unlock /* gh-ost */ tables`
-	this.migrationContext.Log.Infof("Unlocking tables")
+	this.log.Infof("Unlocking tables")
 	if _, err := sqlutils.ExecNoPrepare(this.singletonDB, query); err != nil {
 		return err
 	}
-	this.migrationContext.Log.Infof("Tables unlocked")
+	this.log.Infof("Tables unlocked")
 	return nil
 }
 
@@ -848,7 +1155,7 @@ func (this *Applier) SwapTablesQuickAndBumpy() error {
 		sql.EscapeName(this.migrationContext.OriginalTableName),
 		sql.EscapeName(this.migrationContext.GetOldTableName()),
 	)
-	this.migrationContext.Log.Infof("Renaming original table")
+	this.log.Infof("Renaming original table")
 	this.migrationContext.RenameTablesStartTime = time.Now()
 	if _, err := sqlutils.ExecNoPrepare(this.singletonDB, query); err != nil {
 		return err
@@ -858,13 +1165,64 @@ func (this *Applier) SwapTablesQuickAndBumpy() error {
 		sql.EscapeName(this.migrationContext.GetGhostTableName()),
 		sql.EscapeName(this.migrationContext.OriginalTableName),
 	)
-	this.migrationContext.Log.Infof("Renaming ghost table")
+	this.log.Infof("Renaming ghost table")
 	if _, err := sqlutils.ExecNoPrepare(this.db, query); err != nil {
 		return err
 	}
 	this.migrationContext.RenameTablesEndTime = time.Now()
 
-	this.migrationContext.Log.Infof("Tables renamed")
+	this.log.Infof("Tables renamed")
+	return nil
+}
+
+// RenameOriginalTableAway renames the original table to the _old table, using the session which holds
+// the lock on the original table. This is how a cross-server move blocks writes to the original table
+// for good: once renamed, the table is still there to roll back to, but no longer serves the application.
+func (this *Applier) RenameOriginalTableAway() error {
+	query := fmt.Sprintf(`alter /* gh-ost */ table %s.%s rename %s`,
+		sql.EscapeName(this.migrationContext.DatabaseName),
+		sql.EscapeName(this.migrationContext.OriginalTableName),
+		sql.EscapeName(this.migrationContext.GetOldTableName()),
+	)
+	this.log.Infof("Renaming original table away")
+	this.migrationContext.RenameTablesStartTime = time.Now()
+	if _, err := sqlutils.ExecNoPrepare(this.singletonDB, query); err != nil {
+		return err
+	}
+	this.migrationContext.RenameTablesEndTime = time.Now()
+	this.log.Infof("Original table renamed to %s", sql.EscapeName(this.migrationContext.GetOldTableName()))
+	return nil
+}
+
+// RestoreOriginalTable reverts RenameOriginalTableAway, using the same locking session
+func (this *Applier) RestoreOriginalTable() error {
+	query := fmt.Sprintf(`alter /* gh-ost */ table %s.%s rename %s`,
+		sql.EscapeName(this.migrationContext.DatabaseName),
+		sql.EscapeName(this.migrationContext.GetOldTableName()),
+		sql.EscapeName(this.migrationContext.OriginalTableName),
+	)
+	this.log.Infof("Renaming back original table")
+	if _, err := sqlutils.ExecNoPrepare(this.singletonDB, query); err != nil {
+		return this.log.Errore(err)
+	}
+	this.log.Infof("Original table restored")
+	return nil
+}
+
+// RenameGhostOnTarget renames the ghost table on the target server of a cross-server move
+// into the original table's name
+func (this *Applier) RenameGhostOnTarget() error {
+	query := fmt.Sprintf(`rename /* gh-ost */ table %s.%s to %s.%s`,
+		sql.EscapeName(this.migrationContext.DatabaseName),
+		sql.EscapeName(this.migrationContext.GetGhostTableName()),
+		sql.EscapeName(this.migrationContext.DatabaseName),
+		sql.EscapeName(this.migrationContext.OriginalTableName),
+	)
+	this.log.Infof("Renaming ghost table on target %+v", *this.targetConnectionConfig.ImpliedKey)
+	if _, err := sqlutils.ExecNoPrepare(this.targetDB, query); err != nil {
+		return err
+	}
+	this.log.Infof("Ghost table renamed to %s on target", sql.EscapeName(this.migrationContext.OriginalTableName))
 	return nil
 }
 
@@ -883,7 +1241,7 @@ func (this *Applier) RenameTablesRollback() (renameError error) {
 		sql.EscapeName(this.migrationContext.DatabaseName),
 		sql.EscapeName(this.migrationContext.OriginalTableName),
 	)
-	this.migrationContext.Log.Infof("Renaming back both tables")
+	this.log.Infof("Renaming back both tables")
 	if _, err := sqlutils.ExecNoPrepare(this.db, query); err == nil {
 		return nil
 	}
@@ -894,7 +1252,7 @@ func (this *Applier) RenameTablesRollback() (renameError error) {
 		sql.EscapeName(this.migrationContext.DatabaseName),
 		sql.EscapeName(this.migrationContext.GetGhostTableName()),
 	)
-	this.migrationContext.Log.Infof("Renaming back to ghost table")
+	this.log.Infof("Renaming back to ghost table")
 	if _, err := sqlutils.ExecNoPrepare(this.db, query); err != nil {
 		renameError = err
 	}
@@ -904,11 +1262,11 @@ func (this *Applier) RenameTablesRollback() (renameError error) {
 		sql.EscapeName(this.migrationContext.DatabaseName),
 		sql.EscapeName(this.migrationContext.OriginalTableName),
 	)
-	this.migrationContext.Log.Infof("Renaming back to original table")
+	this.log.Infof("Renaming back to original table")
 	if _, err := sqlutils.ExecNoPrepare(this.db, query); err != nil {
 		renameError = err
 	}
-	return this.migrationContext.Log.Errore(renameError)
+	return this.log.Errore(renameError)
 }
 
 // StopSlaveIOThread is applicable with --test-on-replica; it stops the IO thread, duh.
@@ -917,11 +1275,11 @@ func (this *Applier) RenameTablesRollback() (renameError error) {
 func (this *Applier) StopSlaveIOThread() error {
 	replicaTerm := mysql.ReplicaTermFor(this.migrationContext.ApplierMySQLVersion, `slave`)
 	query := fmt.Sprintf("stop /* gh-ost */ %s io_thread", replicaTerm)
-	this.migrationContext.Log.Infof("Stopping replication IO thread")
+	this.log.Infof("Stopping replication IO thread")
 	if _, err := sqlutils.ExecNoPrepare(this.db, query); err != nil {
 		return err
 	}
-	this.migrationContext.Log.Infof("Replication IO thread stopped")
+	this.log.Infof("Replication IO thread stopped")
 	return nil
 }
 
@@ -929,11 +1287,11 @@ func (this *Applier) StopSlaveIOThread() error {
 func (this *Applier) StartSlaveIOThread() error {
 	replicaTerm := mysql.ReplicaTermFor(this.migrationContext.ApplierMySQLVersion, `slave`)
 	query := fmt.Sprintf("start /* gh-ost */ %s io_thread", replicaTerm)
-	this.migrationContext.Log.Infof("Starting replication IO thread")
+	this.log.Infof("Starting replication IO thread")
 	if _, err := sqlutils.ExecNoPrepare(this.db, query); err != nil {
 		return err
 	}
-	this.migrationContext.Log.Infof("Replication IO thread started")
+	this.log.Infof("Replication IO thread started")
 	return nil
 }
 
@@ -941,11 +1299,11 @@ func (this *Applier) StartSlaveIOThread() error {
 func (this *Applier) StopSlaveSQLThread() error {
 	replicaTerm := mysql.ReplicaTermFor(this.migrationContext.ApplierMySQLVersion, `slave`)
 	query := fmt.Sprintf("stop /* gh-ost */ %s sql_thread", replicaTerm)
-	this.migrationContext.Log.Infof("Verifying SQL thread is stopped")
+	this.log.Infof("Verifying SQL thread is stopped")
 	if _, err := sqlutils.ExecNoPrepare(this.db, query); err != nil {
 		return err
 	}
-	this.migrationContext.Log.Infof("SQL thread stopped")
+	this.log.Infof("SQL thread stopped")
 	return nil
 }
 
@@ -953,11 +1311,11 @@ func (this *Applier) StopSlaveSQLThread() error {
 func (this *Applier) StartSlaveSQLThread() error {
 	replicaTerm := mysql.ReplicaTermFor(this.migrationContext.ApplierMySQLVersion, `slave`)
 	query := fmt.Sprintf("start /* gh-ost */ %s sql_thread", replicaTerm)
-	this.migrationContext.Log.Infof("Verifying SQL thread is running")
+	this.log.Infof("Verifying SQL thread is running")
 	if _, err := sqlutils.ExecNoPrepare(this.db, query); err != nil {
 		return err
 	}
-	this.migrationContext.Log.Infof("SQL thread started")
+	this.log.Infof("SQL thread started")
 	return nil
 }
 
@@ -974,7 +1332,7 @@ func (this *Applier) StopReplication() error {
 	if err != nil {
 		return err
 	}
-	this.migrationContext.Log.Infof("Replication IO thread at %+v. SQL thread is at %+v", *readBinlogCoordinates, *executeBinlogCoordinates)
+	this.log.Infof("Replication IO thread at %+v. SQL thread is at %+v", *readBinlogCoordinates, *executeBinlogCoordinates)
 	return nil
 }
 
@@ -986,7 +1344,7 @@ func (this *Applier) StartReplication() error {
 	if err := this.StartSlaveSQLThread(); err != nil {
 		return err
 	}
-	this.migrationContext.Log.Infof("Replication started")
+	this.log.Infof("Replication started")
 	return nil
 }
 
@@ -1000,7 +1358,7 @@ func (this *Applier) ExpectUsedLock(sessionId int64) error {
 	var result int64
 	query := `select /* gh-ost */ is_used_lock(?)`
 	lockName := this.GetSessionLockName(sessionId)
-	this.migrationContext.Log.Infof("Checking session lock: %s", lockName)
+	this.log.Infof("Checking session lock: %s", lockName)
 	if err := this.db.QueryRow(query, lockName).Scan(&result); err != nil || result != sessionId {
 		return fmt.Errorf("Session lock %s expected to be found but wasn't", lockName)
 	}
@@ -1032,12 +1390,98 @@ func (this *Applier) ExpectProcess(sessionId int64, stateHint, infoHint string)
 	return nil
 }
 
+// MetadataLocksInstrumented checks that performance_schema instruments metadata locks, which is
+// where sessions blocking the cut-over are found.
+func (this *Applier) MetadataLocksInstrumented() (instrumented bool, err error) {
+	query := `
+		select /* gh-ost */ count(*)
+		from
+			performance_schema.setup_instruments
+		where
+			name = 'wait/lock/metadata/sql/mdl'
+			and enabled = 'YES'`
+	var count int64
+	if err := this.db.QueryRow(query).Scan(&count); err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// ReadCutOverBlockers reads the sessions which block, or would block, the cut-over from locking
+// the original table: sessions holding metadata locks on the table, along with their process and
+// their transaction, if any. The migration's own sessions, told by their connection attribute, and
+// the server's own threads, such as replication threads, are exempt: these are never to be killed.
+func (this *Applier) ReadCutOverBlockers(duringCutOver bool) (blockers []*CutOverBlocker, err error) {
+	locks := []*CutOverBlocker{}
+	query := `
+		select /* gh-ost */
+			processlist.id,
+			ifnull(processlist.user, '') as user,
+			ifnull(processlist.host, '') as host,
+			ifnull(processlist.command, '') as command,
+			ifnull(processlist.time, 0) as time,
+			ifnull(processlist.info, '') as info,
+			ifnull(timestampdiff(second, innodb_trx.trx_started, now()), -1) as trx_seconds,
+			metadata_locks.lock_type,
+			metadata_locks.lock_status,
+			(
+				threads.type != 'FOREGROUND'
+				or processlist.user = 'system user'
+				or exists (
+					select 1
+					from performance_schema.session_connect_attrs
+					where
+						session_connect_attrs.processlist_id = processlist.id
+						and session_connect_attrs.attr_name = ?
+						and session_connect_attrs.attr_value = ?
+				)
+			) as is_exempt
+		from
+			performance_schema.metadata_locks
+			join performance_schema.threads on (threads.thread_id = metadata_locks.owner_thread_id)
+			join information_schema.processlist on (processlist.id = threads.processlist_id)
+			left join information_schema.innodb_trx on (innodb_trx.trx_mysql_thread_id = processlist.id)
+		where
+			metadata_locks.object_type = 'TABLE'
+			and metadata_locks.object_schema = ?
+			and metadata_locks.object_name = ?
+			and processlist.id != connection_id()`
+	err = sqlutils.QueryRowsMap(this.db, query, func(m sqlutils.RowMap) error {
+		locks = append(locks, &CutOverBlocker{
+			SessionId:          m.GetInt64("id"),
+			User:               m.GetString("user"),
+			Host:               m.GetString("host"),
+			Command:            m.GetString("command"),
+			Query:              m.GetString("info"),
+			QuerySeconds:       m.GetInt64("time"),
+			TransactionSeconds: m.GetInt64("trx_seconds"),
+			LockType:           m.GetString("lock_type"),
+			LockStatus:         m.GetString("lock_status"),
+			Exempt:             m.GetBool("is_exempt"),
+		})
+		return nil
+	}, mysql.MigrationConnectionAttribute, this.migrationContext.Uuid, this.migrationContext.DatabaseName, this.migrationContext.OriginalTableName)
+	if err != nil {
+		return nil, err
+	}
+	return findCutOverBlockers(locks, duringCutOver), nil
+}
+
+// KillSession kills a session, ending its transaction, if any, and releasing its locks
+func (this *Applier) KillSession(sessionId int64) error {
+	query := fmt.Sprintf(`kill /* gh-ost */ %d`, sessionId)
+	if _, err := sqlutils.ExecNoPrepare(this.db, query); err != nil {
+		return err
+	}
+	return nil
+}
+
 // DropAtomicCutOverSentryTableIfExists checks if the "old" table name
 // happens to be a cut-over magic table; if so, it drops it.
 func (this *Applier) DropAtomicCutOverSentryTableIfExists() error {
-	this.migrationContext.Log.Infof("Looking for magic cut-over table")
+	this.log.Infof("Looking for magic cut-over table")
 	tableName := this.migrationContext.GetOldTableName()
-	rowMap := this.showTableStatus(tableName)
+	rowMap := this.showTableStatus(this.db, tableName)
 	if rowMap == nil {
 		// Table does not exist
 		return nil
@@ -1045,8 +1489,8 @@ func (this *Applier) DropAtomicCutOverSentryTableIfExists() error {
 	if rowMap["Comment"].String != atomicCutOverMagicHint {
 		return fmt.Errorf("Expected magic comment on %s, did not find it", tableName)
 	}
-	this.migrationContext.Log.Infof("Dropping magic cut-over table")
-	return this.dropTable(tableName)
+	this.log.Infof("Dropping magic cut-over table")
+	return this.dropTable(this.db, tableName)
 }
 
 // CreateAtomicCutOverSentryTable
@@ -1065,14 +1509,14 @@ func (this *Applier) CreateAtomicCutOverSentryTable() error {
 		this.migrationContext.TableEngine,
 		atomicCutOverMagicHint,
 	)
-	this.migrationContext.Log.Infof("Creating magic cut-over table %s.%s",
+	this.log.Infof("Creating magic cut-over table %s.%s",
 		sql.EscapeName(this.migrationContext.DatabaseName),
 		sql.EscapeName(tableName),
 	)
 	if _, err := sqlutils.ExecNoPrepare(this.db, query); err != nil {
 		return err
 	}
-	this.migrationContext.Log.Infof("Magic cut-over table created")
+	this.log.Infof("Magic cut-over table created")
 
 	return nil
 }
@@ -1081,7 +1525,7 @@ func (this *Applier) CreateAtomicCutOverSentryTable() error {
 // time an unresponsive (but still connected) gh-ost process can hold the cut-over lock.
 func (this *Applier) InitAtomicCutOverWaitTimeout(tx *gosql.Tx) error {
 	cutOverWaitTimeoutSeconds := this.migrationContext.CutOverLockTimeoutSeconds * 3
-	this.migrationContext.Log.Infof("Setting cut-over idle timeout as %d seconds", cutOverWaitTimeoutSeconds)
+	this.log.Infof("Setting cut-over idle timeout as %d seconds", cutOverWaitTimeoutSeconds)
 	query := fmt.Sprintf(`set /* gh-ost */ session wait_timeout:=%d`, cutOverWaitTimeoutSeconds)
 	_, err := tx.Exec(query)
 	return err
@@ -1089,10 +1533,10 @@ func (this *Applier) InitAtomicCutOverWaitTimeout(tx *gosql.Tx) error {
 
 // RevertAtomicCutOverWaitTimeout restores the original wait_timeout for the applier session post-cut-over.
 func (this *Applier) RevertAtomicCutOverWaitTimeout() {
-	this.migrationContext.Log.Infof("Reverting cut-over idle timeout to %d seconds", this.migrationContext.ApplierWaitTimeout)
+	this.log.Infof("Reverting cut-over idle timeout to %d seconds", this.migrationContext.ApplierWaitTimeout)
 	query := fmt.Sprintf(`set /* gh-ost */ session wait_timeout:=%d`, this.migrationContext.ApplierWaitTimeout)
 	if _, err := sqlutils.ExecNoPrepare(this.db, query); err != nil {
-		this.migrationContext.Log.Errorf("Failed to restore applier wait_timeout to %d seconds: %v",
+		this.log.Errorf("Failed to restore applier wait_timeout to %d seconds: %v",
 			this.migrationContext.ApplierWaitTimeout, err,
 		)
 	}
@@ -1123,7 +1567,7 @@ func (this *Applier) AtomicCutOverMagicLock(sessionIdChan chan int64, tableLocke
 	lockResult := 0
 	query := `select /* gh-ost */ get_lock(?, 0)`
 	lockName := this.GetSessionLockName(sessionId)
-	this.migrationContext.Log.Infof("Grabbing voluntary lock: %s", lockName)
+	this.log.Infof("Grabbing voluntary lock: %s", lockName)
 	if err := tx.QueryRow(query, lockName).Scan(&lockResult); err != nil || lockResult != 1 {
 		err := fmt.Errorf("Unable to acquire lock %s", lockName)
 		tableLocked <- err
@@ -1131,7 +1575,7 @@ func (this *Applier) AtomicCutOverMagicLock(sessionIdChan chan int64, tableLocke
 	}
 
 	tableLockTimeoutSeconds := this.migrationContext.CutOverLockTimeoutSeconds * 2
-	this.migrationContext.Log.Infof("Setting LOCK timeout as %d seconds", tableLockTimeoutSeconds)
+	this.log.Infof("Setting LOCK timeout as %d seconds", tableLockTimeoutSeconds)
 	query = fmt.Sprintf(`set /* gh-ost */ session lock_wait_timeout:=%d`, tableLockTimeoutSeconds)
 	if _, err := tx.Exec(query); err != nil {
 		tableLocked <- err
@@ -1155,7 +1599,7 @@ func (this *Applier) AtomicCutOverMagicLock(sessionIdChan chan int64, tableLocke
 		sql.EscapeName(this.migrationContext.DatabaseName),
 		sql.EscapeName(this.migrationContext.GetOldTableName()),
 	)
-	this.migrationContext.Log.Infof("Locking %s.%s, %s.%s",
+	this.log.Infof("Locking %s.%s, %s.%s",
 		sql.EscapeName(this.migrationContext.DatabaseName),
 		sql.EscapeName(this.migrationContext.OriginalTableName),
 		sql.EscapeName(this.migrationContext.DatabaseName),
@@ -1166,7 +1610,7 @@ func (this *Applier) AtomicCutOverMagicLock(sessionIdChan chan int64, tableLocke
 		tableLocked <- err
 		return err
 	}
-	this.migrationContext.Log.Infof("Tables locked")
+	this.log.Infof("Tables locked")
 	tableLocked <- nil // No error.
 
 	// From this point on, we are committed to UNLOCK TABLES. No matter what happens,
@@ -1175,23 +1619,23 @@ func (this *Applier) AtomicCutOverMagicLock(sessionIdChan chan int64, tableLocke
 	// The cut-over phase will proceed to apply remaining backlog onto ghost table,
 	// and issue RENAME. We wait here until told to proceed.
 	<-okToUnlockTable
-	this.migrationContext.Log.Infof("Will now proceed to drop magic table and unlock tables")
+	this.log.Infof("Will now proceed to drop magic table and unlock tables")
 
 	// The magic table is here because we locked it. And we are the only ones allowed to drop it.
 	// And in fact, we will:
-	this.migrationContext.Log.Infof("Dropping magic cut-over table")
+	this.log.Infof("Dropping magic cut-over table")
 	query = fmt.Sprintf(`drop /* gh-ost */ table if exists %s.%s`,
 		sql.EscapeName(this.migrationContext.DatabaseName),
 		sql.EscapeName(this.migrationContext.GetOldTableName()),
 	)
 
 	if _, err := tx.Exec(query); err != nil {
-		this.migrationContext.Log.Errore(err)
+		this.log.Errore(err)
 		// We DO NOT return here because we must `UNLOCK TABLES`!
 	}
 
 	// Tables still locked
-	this.migrationContext.Log.Infof("Releasing lock from %s.%s, %s.%s",
+	this.log.Infof("Releasing lock from %s.%s, %s.%s",
 		sql.EscapeName(this.migrationContext.DatabaseName),
 		sql.EscapeName(this.migrationContext.OriginalTableName),
 		sql.EscapeName(this.migrationContext.DatabaseName),
@@ -1200,9 +1644,9 @@ func (this *Applier) AtomicCutOverMagicLock(sessionIdChan chan int64, tableLocke
 	query = `unlock /* gh-ost */ tables`
 	if _, err := tx.Exec(query); err != nil {
 		tableUnlocked <- err
-		return this.migrationContext.Log.Errore(err)
+		return this.log.Errore(err)
 	}
-	this.migrationContext.Log.Infof("Tables unlocked")
+	this.log.Infof("Tables unlocked")
 	tableUnlocked <- nil
 	return nil
 }
@@ -1224,7 +1668,7 @@ func (this *Applier) AtomicCutoverRename(sessionIdChan chan int64, tablesRenamed
 	}
 	sessionIdChan <- sessionId
 
-	this.migrationContext.Log.Infof("Setting RENAME timeout as %d seconds", this.migrationContext.CutOverLockTimeoutSeconds)
+	this.log.Infof("Setting RENAME timeout as %d seconds", this.migrationContext.CutOverLockTimeoutSeconds)
 	query := fmt.Sprintf(`set /* gh-ost */ session lock_wait_timeout:=%d`, this.migrationContext.CutOverLockTimeoutSeconds)
 	if _, err := tx.Exec(query); err != nil {
 		return err
@@ -1240,13 +1684,13 @@ func (this *Applier) AtomicCutoverRename(sessionIdChan chan int64, tablesRenamed
 		sql.EscapeName(this.migrationContext.DatabaseName),
 		sql.EscapeName(this.migrationContext.OriginalTableName),
 	)
-	this.migrationContext.Log.Infof("Issuing and expecting this to block: %s", query)
+	this.log.Infof("Issuing and expecting this to block: %s", query)
 	if _, err := tx.Exec(query); err != nil {
 		tablesRenamed <- err
-		return this.migrationContext.Log.Errore(err)
+		return this.log.Errore(err)
 	}
 	tablesRenamed <- nil
-	this.migrationContext.Log.Infof("Tables renamed")
+	this.log.Infof("Tables renamed")
 	return nil
 }
 
@@ -1258,6 +1702,211 @@ func (this *Applier) ShowStatusVariable(variableName string) (result int64, err
 	return result, nil
 }
 
+// ReadInnoDBMetric reads the value of a counter in information_schema.innodb_metrics, such as
+// trx_rseg_history_len or log_lsn_checkpoint_age. As a special case, dirty_pages_pct is the percentage
+// of dirty pages in the buffer pool, per status variables.
+func (this *Applier) ReadInnoDBMetric(metricName string) (result int64, err error) {
+	if strings.EqualFold(metricName, dirtyPagesPctInnoDBMetric) {
+		dirtyPages, err := this.ShowStatusVariable("Innodb_buffer_pool_pages_dirty")
+		if err != nil {
+			return 0, err
+		}
+		totalPages, err := this.ShowStatusVariable("Innodb_buffer_pool_pages_total")
+		if err != nil || totalPages == 0 {
+			return 0, err
+		}
+		return dirtyPages * 100 / totalPages, nil
+	}
+
+	query := `select /* gh-ost */ count, status from information_schema.innodb_metrics where name = ?`
+	var status string
+	if err := this.db.QueryRow(query, metricName).Scan(&result, &status); err == gosql.ErrNoRows {
+		return 0, fmt.Errorf("unknown InnoDB metric")
+	} else if err != nil {
+		return 0, err
+	}
+	if status != "enabled" {
+		return 0, fmt.Errorf("InnoDB metric is %s; enable it via innodb_monitor_enable", status)
+	}
+	return result, nil
+}
+
+// ValidateInnoDBMetrics verifies the given --max-innodb-metrics are known, and enabled, on the server
+// they are read from. A metric which cannot be read would otherwise have the migration throttle forever.
+func (this *Applier) ValidateInnoDBMetrics(maxInnoDBMetrics base.LoadMap) error {
+	if len(maxInnoDBMetrics) == 0 {
+		return nil
+	}
+	statuses := map[string]string{}
+	query := `select /* gh-ost */ name, status from information_schema.innodb_metrics`
+	err := sqlutils.QueryRowsMap(this.db, query, func(m sqlutils.RowMap) error {
+		statuses[strings.ToLower(m.GetString("name"))] = m.GetString("status")
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return checkInnoDBMetricsStatus(maxInnoDBMetrics, statuses)
+}
+
+// checkInnoDBMetricsStatus checks each metric is found, and enabled, among the given statuses of
+// information_schema.innodb_metrics, keyed by lowercase name
+func checkInnoDBMetricsStatus(maxInnoDBMetrics base.LoadMap, statuses map[string]string) error {
+	metricNames := []string{}
+	for metricName := range maxInnoDBMetrics {
+		metricNames = append(metricNames, metricName)
+	}
+	sort.Strings(metricNames)
+	for _, metricName := range metricNames {
+		if strings.EqualFold(metricName, dirtyPagesPctInnoDBMetric) {
+			continue
+		}
+		status, found := statuses[strings.ToLower(metricName)]
+		if !found {
+			return fmt.Errorf("--max-innodb-metrics: unknown InnoDB metric %s", metricName)
+		}
+		if status != "enabled" {
+			return fmt.Errorf("--max-innodb-metrics: InnoDB metric %s is %s; enable it via innodb_monitor_enable", metricName, status)
+		}
+	}
+	return nil
+}
+
+// ReadGroupReplicationQueue reads the longest transaction queue across members of the Group Replication
+// cluster: either of transactions awaiting certification, or of certified transactions awaiting to be
+// applied. Either grows when a member cannot keep up, and leads to flow control throttling all writes.
+func (this *Applier) ReadGroupReplicationQueue() (memberKey string, queue int64, err error) {
+	query := `
+		select /* gh-ost */
+			concat(m.member_host, ':', m.member_port) as member_key,
+			greatest(s.count_transactions_in_queue, s.count_transactions_remote_in_applier_queue) as queue
+		from
+			performance_schema.replication_group_member_stats s
+			join performance_schema.replication_group_members m using (member_id)
+		order by
+			queue desc
+		limit 1
+	`
+	err = this.db.QueryRow(query).Scan(&memberKey, &queue)
+	return memberKey, queue, err
+}
+
+// ReadGaleraMemberKeys reads the members of the Galera cluster, per wsrep_incoming_addresses
+func (this *Applier) ReadGaleraMemberKeys() (memberKeys []mysql.InstanceKey, err error) {
+	var variableName, incomingAddresses string
+	query := `show /* gh-ost */ global status like 'wsrep_incoming_addresses'`
+	if err := this.db.QueryRow(query).Scan(&variableName, &incomingAddresses); err != nil {
+		return memberKeys, err
+	}
+	for _, address := range strings.Split(incomingAddresses, ",") {
+		if address = strings.TrimSpace(address); address == "" {
+			continue
+		}
+		memberKey, err := mysql.ParseInstanceKey(address)
+		if err != nil {
+			return memberKeys, err
+		}
+		memberKeys = append(memberKeys, *memberKey)
+	}
+	return memberKeys, nil
+}
+
+// readTableLayout reads the size of the given table, and what its rows and indexes consist of
+func (this *Applier) readTableLayout(db *gosql.DB, tableName string) (layout *tableLayout, err error) {
+	layout = newTableLayout()
+	query := `
+		select /* gh-ost */
+			ifnull(data_length, 0), ifnull(index_length, 0)
+		from
+			information_schema.tables
+		where
+			table_schema = ? and table_name = ?
+	`
+	if err := db.QueryRow(query, this.migrationContext.DatabaseName, tableName).Scan(&layout.dataLength, &layout.indexLength); err != nil {
+		return nil, err
+	}
+	// With file-per-table, the tablespace's allocated size accounts for fragmentation, and is not
+	// subject to stale statistics. The table is missing from innodb_tablespaces prior to 8.0.
+	query = `select /* gh-ost */ ifnull(allocated_size, 0) from information_schema.innodb_tablespaces where name = ?`
+	var allocatedSize int64
+	if err := db.QueryRow(query, fmt.Sprintf("%s/%s", this.migrationContext.DatabaseName, tableName)).Scan(&allocatedSize); err == nil && allocatedSize > layout.dataLength+layout.indexLength {
+		layout.dataLength = allocatedSize - layout.indexLength
+	}
+
+	query = `
+		select /* gh-ost */
+			column_name, data_type, ifnull(character_octet_length, 0) as character_octet_length,
+			ifnull(numeric_precision, 0) as numeric_precision, extra
+		from
+			information_schema.columns
+		where
+			table_schema = ? and table_name = ?
+	`
+	err = sqlutils.QueryRowsMap(db, query, func(m sqlutils.RowMap) error {
+		if strings.Contains(strings.ToUpper(m.GetString("extra")), "VIRTUAL") {
+			// Virtual columns are not stored
+			return nil
+		}
+		layout.columnWidths[strings.ToLower(m.GetString("column_name"))] = columnWidth(m.GetString("data_type"), m.GetInt64("character_octet_length"), m.GetInt64("numeric_precision"))
+		return nil
+	}, this.migrationContext.DatabaseName, tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	query = `
+		select /* gh-ost */
+			index_name, column_name
+		from
+			information_schema.statistics
+		where
+			table_schema = ? and table_name = ? and column_name is not null
+		order by
+			index_name, seq_in_index
+	`
+	indexes := map[string][]string{}
+	indexNames := []string{}
+	err = sqlutils.QueryRowsMap(db, query, func(m sqlutils.RowMap) error {
+		indexName := m.GetString("index_name")
+		if _, found := indexes[indexName]; !found {
+			indexNames = append(indexNames, indexName)
+		}
+		indexes[indexName] = append(indexes[indexName], m.GetString("column_name"))
+		return nil
+	}, this.migrationContext.DatabaseName, tableName)
+	if err != nil {
+		return nil, err
+	}
+	for _, indexName := range indexNames {
+		if indexName == "PRIMARY" {
+			layout.primaryKey = indexes[indexName]
+		} else {
+			layout.secondaryIndexes = append(layout.secondaryIndexes, indexes[indexName])
+		}
+	}
+	return layout, nil
+}
+
+// EstimateGhostTableSize estimates the size of the ghost table once all rows are copied, by how the
+// alter changes the original table's rows and indexes
+func (this *Applier) EstimateGhostTableSize() (originalSize int64, ghostSize int64, err error) {
+	originalLayout, err := this.readTableLayout(this.db, this.migrationContext.OriginalTableName)
+	if err != nil {
+		return 0, 0, err
+	}
+	ghostLayout, err := this.readTableLayout(this.targetDB, this.migrationContext.GetGhostTableName())
+	if err != nil {
+		return 0, 0, err
+	}
+	return originalLayout.dataLength + originalLayout.indexLength, estimateTableSize(originalLayout, ghostLayout), nil
+}
+
+// ReadFreeDiskSpace runs --free-disk-space-query on the server holding the ghost table
+func (this *Applier) ReadFreeDiskSpace() (freeBytes int64, err error) {
+	err = this.targetDB.QueryRow(this.migrationContext.FreeDiskSpaceQuery).Scan(&freeBytes)
+	return freeBytes, err
+}
+
 // updateModifiesUniqueKeyColumns checks whether a UPDATE DML event actually
 // modifies values of the migration's unique key (the iterated key). This will call
 // for special handling.
@@ -1307,13 +1956,40 @@ func (this *Applier) buildDMLEventQuery(dmlEvent *binlog.BinlogDMLEvent) []*dmlB
 	return []*dmlBuildResult{newDmlBuildResultError(fmt.Errorf("Unknown dml event type: %+v", dmlEvent.DML))}
 }
 
+// applyDMLEventQueriesOnLockingSession applies DML queries onto the _ghost_ table via the singleton
+// session, while it holds the tables locked. There is no transaction, as beginning one would implicitly
+// release the locks; statements are rather applied one by one, which is safe given no other session
+// can access the ghost table meanwhile, and given the statements are idempotent upon retry.
+func (this *Applier) applyDMLEventQueriesOnLockingSession(dmlEvents [](*binlog.BinlogDMLEvent), totalDelta *int64) error {
+	for _, dmlEvent := range dmlEvents {
+		for _, buildResult := range this.buildDMLEventQuery(dmlEvent) {
+			if buildResult.err != nil {
+				return buildResult.err
+			}
+			res, err := sqlutils.ExecNoPrepare(this.singletonDB, buildResult.query, buildResult.args...)
+			if err != nil {
+				return fmt.Errorf("%w; query=%s; args=%+v", err, buildResult.query, buildResult.args)
+			}
+			rowsAffected, _ := res.RowsAffected()
+			*totalDelta += buildResult.rowsDelta * rowsAffected
+		}
+	}
+	return nil
+}
+
 // ApplyDMLEventQueries applies multiple DML queries onto the _ghost_ table
 func (this *Applier) ApplyDMLEventQueries(dmlEvents [](*binlog.BinlogDMLEvent)) error {
 	var totalDelta int64
 	ctx := context.Background()
 
+	this.lockingSessionApplyMutex.Lock()
+	defer this.lockingSessionApplyMutex.Unlock()
+
 	err := func() error {
-		conn, err := this.db.Conn(ctx)
+		if atomic.LoadInt64(&this.lockingSessionApply) > 0 {
+			return this.applyDMLEventQueriesOnLockingSession(dmlEvents, &totalDelta)
+		}
+		conn, err := this.targetDB.Conn(ctx)
 		if err != nil {
 			return err
 		}
@@ -1392,20 +2068,23 @@ func (this *Applier) ApplyDMLEventQueries(dmlEvents [](*binlog.BinlogDMLEvent))
 	}()
 
 	if err != nil {
-		return this.migrationContext.Log.Errore(err)
+		return this.log.Errore(err)
 	}
 	// no error
 	atomic.AddInt64(&this.migrationContext.TotalDMLEventsApplied, int64(len(dmlEvents)))
 	if this.migrationContext.CountTableRows {
 		atomic.AddInt64(&this.migrationContext.RowsDeltaEstimate, totalDelta)
 	}
-	this.migrationContext.Log.Debugf("ApplyDMLEventQueries() applied %d events in one transaction", len(dmlEvents))
+	this.log.Debugf("ApplyDMLEventQueries() applied %d events in one transaction", len(dmlEvents))
 	return nil
 }
 
 func (this *Applier) Teardown() {
-	this.migrationContext.Log.Debugf("Tearing down...")
+	this.log.Debugf("Tearing down...")
 	this.db.Close()
 	this.singletonDB.Close()
+	if this.migrationContext.IsCrossServerMove() && this.targetDB != nil {
+		this.targetDB.Close()
+	}
 	atomic.StoreInt64(&this.finishedMigrating, 1)
 }
@@ -181,6 +181,24 @@ func TestApplierBuildDMLEventQuery(t *testing.T) {
 	})
 }
 
+func TestCheckInnoDBMetricsStatus(t *testing.T) {
+	statuses := map[string]string{
+		"trx_rseg_history_len":   "enabled",
+		"log_lsn_checkpoint_age": "disabled",
+	}
+	maxInnoDBMetrics, err := base.ParseLoadMap("trx_rseg_history_len=1000000,dirty_pages_pct=75")
+	require.NoError(t, err)
+	require.NoError(t, checkInnoDBMetricsStatus(maxInnoDBMetrics, statuses))
+
+	maxInnoDBMetrics, err = base.ParseLoadMap("TRX_RSEG_HISTORY_LEN=1000000,trx_rseg_histroy_len=1000000")
+	require.NoError(t, err)
+	require.EqualError(t, checkInnoDBMetricsStatus(maxInnoDBMetrics, statuses), "--max-innodb-metrics: unknown InnoDB metric trx_rseg_histroy_len")
+
+	maxInnoDBMetrics, err = base.ParseLoadMap("log_lsn_checkpoint_age=1000000000")
+	require.NoError(t, err)
+	require.EqualError(t, checkInnoDBMetricsStatus(maxInnoDBMetrics, statuses), "--max-innodb-metrics: InnoDB metric log_lsn_checkpoint_age is disabled; enable it via innodb_monitor_enable")
+}
+
 func TestApplierInstantDDL(t *testing.T) {
 	migrationContext := base.NewMigrationContext()
 	migrationContext.DatabaseName = "test"
@@ -348,6 +366,178 @@ func (suite *ApplierTestSuite) TestApplyDMLEventQueries() {
 	suite.Require().Equal(int64(0), migrationContext.RowsDeltaEstimate)
 }
 
+func (suite *ApplierTestSuite) TestLockOriginalAndGhostTablesAndRename() {
+	ctx := context.Background()
+
+	var err error
+
+	_, err = suite.db.ExecContext(ctx, "CREATE TABLE test.testing (id INT PRIMARY KEY, item_id INT);")
+	suite.Require().NoError(err)
+
+	_, err = suite.db.ExecContext(ctx, "CREATE TABLE test._testing_gho (id INT PRIMARY KEY, item_id INT, note VARCHAR(32));")
+	suite.Require().NoError(err)
+
+	connectionConfig, err := GetConnectionConfig(ctx, suite.mysqlContainer)
+	suite.Require().NoError(err)
+
+	migrationContext := base.NewMigrationContext()
+	migrationContext.ApplierConnectionConfig = connectionConfig
+	migrationContext.DatabaseName = "test"
+	migrationContext.SkipPortValidation = true
+	migrationContext.OriginalTableName = "testing"
+	migrationContext.SetConnectionConfig("innodb")
+
+	migrationContext.OriginalTableColumns = sql.NewColumnList([]string{"id", "item_id"})
+	migrationContext.SharedColumns = sql.NewColumnList([]string{"id", "item_id"})
+	migrationContext.MappedSharedColumns = sql.NewColumnList([]string{"id", "item_id"})
+	migrationContext.UniqueKey = &sql.UniqueKey{
+		Name:    "primary_key",
+		Columns: *sql.NewColumnList([]string{"id"}),
+	}
+
+	applier := NewApplier(migrationContext)
+	suite.Require().NoError(applier.prepareQueries())
+	defer applier.Teardown()
+
+	err = applier.InitDBConnections()
+	suite.Require().NoError(err)
+
+	err = applier.LockOriginalAndGhostTables()
+	suite.Require().NoError(err)
+
+	// While locked, events are applied via the locking session
+	dmlEvents := []*binlog.BinlogDMLEvent{
+		{
+			DatabaseName:    "test",
+			TableName:       "testing",
+			DML:             binlog.InsertDML,
+			NewColumnValues: sql.ToColumnValues([]interface{}{123456, 42}),
+		},
+	}
+	err = applier.ApplyDMLEventQueries(dmlEvents)
+	suite.Require().NoError(err)
+
+	err = applier.RenameTablesUnderLock()
+	suite.Require().NoError(err)
+
+	err = applier.UnlockOriginalAndGhostTables()
+	suite.Require().NoError(err)
+
+	// The ghost table, along with the applied event, now goes by the original table's name
+	var id, itemId int
+	err = suite.db.QueryRow("SELECT id, item_id FROM test.testing WHERE note IS NULL").Scan(&id, &itemId)
+	suite.Require().NoError(err)
+	suite.Require().Equal(123456, id)
+	suite.Require().Equal(42, itemId)
+
+	var tableName string
+	err = suite.db.QueryRow("SHOW TABLES IN test LIKE '_testing_del'").Scan(&tableName)
+	suite.Require().NoError(err)
+	suite.Require().Equal("_testing_del", tableName)
+
+	// With tables unlocked, events are applied as usual
+	dmlEvents[0].NewColumnValues = sql.ToColumnValues([]interface{}{123457, 43})
+	suite.Require().NoError(applier.ApplyDMLEventQueries(dmlEvents))
+	suite.Require().Equal(int64(2), migrationContext.TotalDMLEventsApplied)
+}
+
+func (suite *ApplierTestSuite) TestReadCutOverBlockersAndKillSession() {
+	ctx := context.Background()
+
+	_, err := suite.db.ExecContext(ctx, "CREATE TABLE test.testing (id INT PRIMARY KEY, item_id INT);")
+	suite.Require().NoError(err)
+
+	connectionConfig, err := GetConnectionConfig(ctx, suite.mysqlContainer)
+	suite.Require().NoError(err)
+
+	migrationContext := base.NewMigrationContext()
+	migrationContext.ApplierConnectionConfig = connectionConfig
+	migrationContext.DatabaseName = "test"
+	migrationContext.SkipPortValidation = true
+	migrationContext.OriginalTableName = "testing"
+	migrationContext.SetConnectionConfig("innodb")
+
+	applier := NewApplier(migrationContext)
+	defer applier.Teardown()
+
+	err = applier.InitDBConnections()
+	suite.Require().NoError(err)
+
+	instrumented, err := applier.MetadataLocksInstrumented()
+	suite.Require().NoError(err)
+	suite.Require().True(instrumented)
+
+	blockers, err := applier.ReadCutOverBlockers(false)
+	suite.Require().NoError(err)
+	suite.Require().Empty(blockers)
+
+	// A transaction which read the table holds a metadata lock on it until it completes
+	conn, err := suite.db.Conn(ctx)
+	suite.Require().NoError(err)
+	defer conn.Close()
+
+	var sessionId int64
+	suite.Require().NoError(conn.QueryRowContext(ctx, "SELECT CONNECTION_ID()").Scan(&sessionId))
+	_, err = conn.ExecContext(ctx, "BEGIN")
+	suite.Require().NoError(err)
+	_, err = conn.ExecContext(ctx, "SELECT * FROM test.testing")
+	suite.Require().NoError(err)
+
+	blockers, err = applier.ReadCutOverBlockers(false)
+	suite.Require().NoError(err)
+	suite.Require().Len(blockers, 1)
+	suite.Require().Equal(sessionId, blockers[0].SessionId)
+	suite.Require().Equal("SHARED_READ", blockers[0].LockType)
+	suite.Require().GreaterOrEqual(blockers[0].TransactionSeconds, int64(0))
+
+	// No cut-over waits on a lock, hence nothing is blocked
+	blockers, err = applier.ReadCutOverBlockers(true)
+	suite.Require().NoError(err)
+	suite.Require().Empty(blockers)
+
+	suite.Require().NoError(applier.KillSession(sessionId))
+
+	blockers, err = applier.ReadCutOverBlockers(false)
+	suite.Require().NoError(err)
+	suite.Require().Empty(blockers)
+}
+
+func (suite *ApplierTestSuite) TestReadInnoDBMetric() {
+	ctx := context.Background()
+
+	connectionConfig, err := GetConnectionConfig(ctx, suite.mysqlContainer)
+	suite.Require().NoError(err)
+
+	migrationContext := base.NewMigrationContext()
+	migrationContext.ApplierConnectionConfig = connectionConfig
+	migrationContext.DatabaseName = "test"
+	migrationContext.SkipPortValidation = true
+	migrationContext.OriginalTableName = "testing"
+	migrationContext.SetConnectionConfig("innodb")
+
+	applier := NewApplier(migrationContext)
+	defer applier.Teardown()
+
+	err = applier.InitDBConnections()
+	suite.Require().NoError(err)
+
+	historyLength, err := applier.ReadInnoDBMetric("trx_rseg_history_len")
+	suite.Require().NoError(err)
+	suite.Require().GreaterOrEqual(historyLength, int64(0))
+
+	dirtyPagesPct, err := applier.ReadInnoDBMetric("dirty_pages_pct")
+	suite.Require().NoError(err)
+	suite.Require().GreaterOrEqual(dirtyPagesPct, int64(0))
+	suite.Require().LessOrEqual(dirtyPagesPct, int64(100))
+
+	_, err = applier.ReadInnoDBMetric("no_such_metric")
+	suite.Require().Error(err)
+
+	// Disabled by default
+	_, err = applier.ReadInnoDBMetric("dml_inserts")
+	suite.Require().Error(err)
+}
+
 func (suite *ApplierTestSuite) TestValidateOrDropExistingTables() {
 	ctx := context.Background()
 
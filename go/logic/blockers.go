@@ -0,0 +1,100 @@
+/*
+   Copyright 2022 GitHub Inc.
+	 See https://github.com/github/gh-ost/blob/master/LICENSE
+*/
+
+package logic
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	maxCutOverBlockerQueryLength = 256
+	cutOverLockQueryHint         = "lock /* gh-ost */ tables"
+)
+
+// CutOverBlocker is a session holding a metadata lock on the original table. While it holds the
+// lock, the cut-over cannot lock the table, and queries on the table queue behind the cut-over.
+type CutOverBlocker struct {
+	SessionId          int64
+	User               string
+	Host               string
+	Command            string
+	Query              string
+	QuerySeconds       int64
+	TransactionSeconds int64 // -1 when the session is not in a transaction
+	LockType           string
+	LockStatus         string
+	// Exempt sessions are never blockers: these are gh-ost's own sessions, and the server's own
+	// threads, such as replication threads
+	Exempt bool
+}
+
+// Age is how long the session has been holding its lock, at the least: the lock was taken no later
+// than the session's transaction began, or its current command did.
+func (this *CutOverBlocker) Age() int64 {
+	if this.TransactionSeconds > this.QuerySeconds {
+		return this.TransactionSeconds
+	}
+	return this.QuerySeconds
+}
+
+func (this *CutOverBlocker) String() string {
+	description := fmt.Sprintf("session %d (%s@%s, %s for %ds", this.SessionId, this.User, this.Host, this.Command, this.QuerySeconds)
+	if this.TransactionSeconds >= 0 {
+		description = fmt.Sprintf("%s, in transaction for %ds", description, this.TransactionSeconds)
+	}
+	description = fmt.Sprintf("%s, holding %s lock)", description, this.LockType)
+	if query := this.Query; query != "" {
+		if len(query) > maxCutOverBlockerQueryLength {
+			query = query[:maxCutOverBlockerQueryLength] + "..."
+		}
+		description = fmt.Sprintf("%s: %s", description, strings.Join(strings.Fields(query), " "))
+	}
+	return description
+}
+
+// findCutOverBlockers picks the sessions blocking the cut-over out of the metadata locks on the
+// original table. Ahead of a cut-over attempt, these are all sessions holding a lock, but exempt
+// ones. During an attempt, these are the sessions holding a lock while the cut-over waits on its
+// own: once the cut-over holds the table, the sessions holding locks are gh-ost's own.
+func findCutOverBlockers(locks []*CutOverBlocker, duringCutOver bool) (blockers []*CutOverBlocker) {
+	var cutOverSessionId int64
+	for _, lock := range locks {
+		if lock.LockStatus == "PENDING" && strings.Contains(lock.Query, cutOverLockQueryHint) {
+			cutOverSessionId = lock.SessionId
+		}
+	}
+	if duringCutOver && cutOverSessionId == 0 {
+		return blockers
+	}
+	blockersMap := map[int64]*CutOverBlocker{}
+	for _, lock := range locks {
+		if lock.LockStatus != "GRANTED" || lock.SessionId == cutOverSessionId || lock.Exempt {
+			continue
+		}
+		if blocker, found := blockersMap[lock.SessionId]; found {
+			if !strings.Contains(blocker.LockType, lock.LockType) {
+				blocker.LockType = fmt.Sprintf("%s,%s", blocker.LockType, lock.LockType)
+			}
+			continue
+		}
+		blocker := *lock
+		blockersMap[lock.SessionId] = &blocker
+		blockers = append(blockers, &blocker)
+	}
+	return blockers
+}
+
+// filterCutOverBlockersByAge returns the blockers which have been holding their lock for at least
+// the given number of seconds.
+func filterCutOverBlockersByAge(blockers []*CutOverBlocker, minAgeSeconds int64) (filtered []*CutOverBlocker) {
+	for _, blocker := range blockers {
+		if blocker.Age() >= minAgeSeconds {
+			filtered = append(filtered, blocker)
+		}
+	}
+	return filtered
+}
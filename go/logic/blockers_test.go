@@ -0,0 +1,90 @@
+/*
+   Copyright 2022 GitHub Inc.
+	 See https://github.com/github/gh-ost/blob/master/LICENSE
+*/
+
+package logic
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCutOverBlockerAge(t *testing.T) {
+	require.Equal(t, int64(7), (&CutOverBlocker{QuerySeconds: 7, TransactionSeconds: -1}).Age())
+	require.Equal(t, int64(30), (&CutOverBlocker{QuerySeconds: 7, TransactionSeconds: 30}).Age())
+}
+
+func TestCutOverBlockerString(t *testing.T) {
+	blocker := &CutOverBlocker{
+		SessionId:          17,
+		User:               "app",
+		Host:               "10.0.0.1:4567",
+		Command:            "Query",
+		Query:              "select *\n\tfrom tbl",
+		QuerySeconds:       7,
+		TransactionSeconds: 30,
+		LockType:           "SHARED_READ",
+	}
+	require.Equal(t, "session 17 (app@10.0.0.1:4567, Query for 7s, in transaction for 30s, holding SHARED_READ lock): select * from tbl", blocker.String())
+
+	blocker = &CutOverBlocker{
+		SessionId:          17,
+		Command:            "Sleep",
+		Query:              strings.Repeat("x", maxCutOverBlockerQueryLength+1),
+		TransactionSeconds: -1,
+		LockType:           "SHARED_READ",
+	}
+	require.True(t, strings.HasSuffix(blocker.String(), "x..."))
+	require.NotContains(t, blocker.String(), "in transaction")
+}
+
+func TestFindCutOverBlockers(t *testing.T) {
+	locks := []*CutOverBlocker{
+		{SessionId: 1, LockType: "SHARED_READ", LockStatus: "GRANTED"},
+		{SessionId: 1, LockType: "SHARED_WRITE", LockStatus: "GRANTED"},
+		{SessionId: 2, LockType: "SHARED_READ", LockStatus: "GRANTED"},
+		{SessionId: 3, LockType: "SHARED_WRITE", LockStatus: "PENDING", Query: "insert into tbl values (1)"},
+		// A replication thread, or a session of gh-ost's own
+		{SessionId: 5, LockType: "SHARED_WRITE", LockStatus: "GRANTED", Exempt: true},
+	}
+
+	t.Run("ahead of cut-over", func(t *testing.T) {
+		blockers := findCutOverBlockers(locks, false)
+		require.Len(t, blockers, 2)
+		require.Equal(t, int64(1), blockers[0].SessionId)
+		require.Equal(t, "SHARED_READ,SHARED_WRITE", blockers[0].LockType)
+		require.Equal(t, int64(2), blockers[1].SessionId)
+		// Input is left intact
+		require.Equal(t, "SHARED_READ", locks[0].LockType)
+	})
+
+	t.Run("cut-over not waiting", func(t *testing.T) {
+		require.Empty(t, findCutOverBlockers(locks, true))
+	})
+
+	t.Run("cut-over waiting", func(t *testing.T) {
+		cutOverLocks := append(locks,
+			&CutOverBlocker{SessionId: 4, LockType: "SHARED_NO_READ_WRITE", LockStatus: "PENDING", Query: "lock /* gh-ost */ tables `test`.`tbl` write", Exempt: true},
+		)
+		blockers := findCutOverBlockers(cutOverLocks, true)
+		require.Len(t, blockers, 2)
+		require.Equal(t, int64(1), blockers[0].SessionId)
+		require.Equal(t, int64(2), blockers[1].SessionId)
+	})
+}
+
+func TestFilterCutOverBlockersByAge(t *testing.T) {
+	blockers := []*CutOverBlocker{
+		{SessionId: 1, QuerySeconds: 3, TransactionSeconds: -1},
+		{SessionId: 2, QuerySeconds: 0, TransactionSeconds: 12},
+		{SessionId: 3, QuerySeconds: 10, TransactionSeconds: 10},
+	}
+	filtered := filterCutOverBlockersByAge(blockers, 10)
+	require.Len(t, filtered, 2)
+	require.Equal(t, int64(2), filtered[0].SessionId)
+	require.Equal(t, int64(3), filtered[1].SessionId)
+	require.Len(t, filterCutOverBlockersByAge(blockers, 0), 3)
+}
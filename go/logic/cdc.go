@@ -0,0 +1,392 @@
+/*
+   Copyright 2022 GitHub Inc.
+	 See https://github.com/github/gh-ost/blob/master/LICENSE
+*/
+
+package logic
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+
+	"github.com/github/gh-ost/go/base"
+	"github.com/github/gh-ost/go/binlog"
+	"github.com/github/gh-ost/go/mysql"
+	"github.com/github/gh-ost/go/sql"
+)
+
+const (
+	cdcCheckpointInterval        = time.Second
+	cdcStatusIntervalCheckpoints = 60
+)
+
+// CDCEvent is a single row change, as written onto the CDC export sink; one JSON line per event.
+// Row is the ordinal of the row within the binary log rows event found at Coordinates, such that
+// coordinates and row uniquely identify an event.
+type CDCEvent struct {
+	Coordinates string                 `json:"coordinates"`
+	Row         int                    `json:"row"`
+	Database    string                 `json:"database"`
+	Table       string                 `json:"table"`
+	Type        string                 `json:"type"`
+	Before      map[string]interface{} `json:"before,omitempty"`
+	After       map[string]interface{} `json:"after,omitempty"`
+}
+
+// NewCDCEvent creates a CDC event from a binlog entry. Column values are keyed by the names of the
+// given columns, which are expected to be the table's columns, in order.
+func NewCDCEvent(entry *binlog.BinlogEntry, row int, columns *sql.ColumnList) (*CDCEvent, error) {
+	dmlEvent := entry.DmlEvent
+	event := &CDCEvent{
+		Coordinates: entry.Coordinates.DisplayString(),
+		Row:         row,
+		Database:    dmlEvent.DatabaseName,
+		Table:       dmlEvent.TableName,
+		Type:        strings.ToLower(string(dmlEvent.DML)),
+	}
+	var err error
+	if event.Before, err = toCDCImage(dmlEvent.WhereColumnValues, columns); err != nil {
+		return nil, err
+	}
+	if event.After, err = toCDCImage(dmlEvent.NewColumnValues, columns); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+// toCDCImage maps a row image onto column names. Character strings which are not valid UTF-8,
+// typically binary column values, are returned as bytes, which JSON encodes as base64.
+func toCDCImage(columnValues *sql.ColumnValues, columns *sql.ColumnList) (map[string]interface{}, error) {
+	if columnValues == nil {
+		return nil, nil
+	}
+	values := columnValues.AbstractValues()
+	if len(values) != columns.Len() {
+		return nil, fmt.Errorf("Row image has %d values, while table has %d columns. Was the table altered?", len(values), columns.Len())
+	}
+	image := make(map[string]interface{}, len(values))
+	for i, column := range columns.Columns() {
+		value := column.ConvertToText(values[i])
+		if s, ok := value.(string); ok && !utf8.ValidString(s) {
+			value = []byte(s)
+		}
+		image[column.Name] = value
+	}
+	return image, nil
+}
+
+// openCDCSink opens the sink given by `--cdc-export`: `-` for standard output, `unix:<path>` for
+// a unix socket, which is connected to, or otherwise a file path, which is appended to.
+func openCDCSink(sink string) (io.WriteCloser, *os.File, error) {
+	if sink == "-" {
+		return nopWriteCloser{os.Stdout}, nil, nil
+	}
+	if strings.HasPrefix(sink, "unix:") {
+		conn, err := net.Dial("unix", strings.TrimPrefix(sink, "unix:"))
+		return conn, nil, err
+	}
+	file, err := os.OpenFile(sink, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0640)
+	if err != nil {
+		return nil, nil, err
+	}
+	return file, file, nil
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+// readCDCCheckpoint reads the coordinates found in the checkpoint file. It returns nil when the
+// file does not exist or is empty, in which case there is nothing to resume from.
+func readCDCCheckpoint(checkpointFile string) (*mysql.BinlogCoordinates, error) {
+	content, err := os.ReadFile(checkpointFile)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	checkpoint := strings.TrimSpace(string(content))
+	if checkpoint == "" {
+		return nil, nil
+	}
+	return mysql.ParseBinlogCoordinates(checkpoint)
+}
+
+// writeCDCCheckpoint atomically replaces the checkpoint file with the given coordinates
+func writeCDCCheckpoint(checkpointFile string, coordinates mysql.BinlogCoordinates) error {
+	tmpFile := filepath.Join(filepath.Dir(checkpointFile), fmt.Sprintf(".%s.tmp", filepath.Base(checkpointFile)))
+	if err := os.WriteFile(tmpFile, []byte(coordinates.DisplayString()+"\n"), 0640); err != nil {
+		return err
+	}
+	return os.Rename(tmpFile, checkpointFile)
+}
+
+// CDCExporter streams the changes made to the original table onto a sink, as JSON lines. It does not
+// migrate the table: no ghost table is created and no rows are copied. Delivery is at-least-once:
+// the checkpoint only ever covers rows events of which all rows were exported, and so a restarted
+// export may repeat the last few events.
+type CDCExporter struct {
+	migrationContext *base.MigrationContext
+	inspector        *Inspector
+	eventsStreamer   *EventsStreamer
+	columns          *sql.ColumnList
+
+	sink      io.WriteCloser
+	sinkFile  *os.File
+	sinkMutex *sync.Mutex
+
+	pendingCoordinates    mysql.BinlogCoordinates
+	pendingRow            int
+	checkpointCoordinates mysql.BinlogCoordinates
+	checkpointMutex       *sync.Mutex
+
+	exportedEventsCount int64
+	stopped             int64
+	exportDone          chan error
+}
+
+func NewCDCExporter(migrationContext *base.MigrationContext) *CDCExporter {
+	return &CDCExporter{
+		migrationContext: migrationContext,
+		sinkMutex:        &sync.Mutex{},
+		checkpointMutex:  &sync.Mutex{},
+		exportDone:       make(chan error, 1),
+	}
+}
+
+// Export runs the CDC export. It blocks until the export is stopped or fails.
+func (this *CDCExporter) Export() (err error) {
+	this.migrationContext.Log.Infof("Exporting changes of %s.%s onto %s",
+		sql.EscapeName(this.migrationContext.DatabaseName),
+		sql.EscapeName(this.migrationContext.OriginalTableName),
+		this.migrationContext.CDCExportSink,
+	)
+	defer this.teardown()
+
+	if err := this.initiateInspector(); err != nil {
+		return err
+	}
+	if this.sink, this.sinkFile, err = openCDCSink(this.migrationContext.CDCExportSink); err != nil {
+		return err
+	}
+	if err := this.initiateStreaming(); err != nil {
+		return err
+	}
+	go this.checkpointPeriodically()
+
+	err = <-this.exportDone
+	this.migrationContext.Log.Infof("Exported %d events", atomic.LoadInt64(&this.exportedEventsCount))
+	return err
+}
+
+// Stop gracefully stops a running export
+func (this *CDCExporter) Stop() {
+	this.abort(nil)
+}
+
+// abort ends the export; only the first call has effect
+func (this *CDCExporter) abort(err error) {
+	select {
+	case this.exportDone <- err:
+	default:
+	}
+}
+
+func (this *CDCExporter) isStopped() bool {
+	return atomic.LoadInt64(&this.stopped) > 0
+}
+
+func (this *CDCExporter) initiateInspector() (err error) {
+	this.inspector = NewInspector(this.migrationContext)
+	if err := this.inspector.InitDBConnections(); err != nil {
+		return err
+	}
+	if err := this.inspector.validateTable(); err != nil {
+		return err
+	}
+	if this.columns, _, err = mysql.GetTableColumns(this.inspector.db, this.migrationContext.DatabaseName, this.migrationContext.OriginalTableName); err != nil {
+		return err
+	}
+	return this.inspector.applyColumnTypes(this.migrationContext.DatabaseName, this.migrationContext.OriginalTableName, this.columns)
+}
+
+func (this *CDCExporter) initiateStreaming() error {
+	this.eventsStreamer = NewEventsStreamer(this.migrationContext)
+	checkpoint, err := this.readCheckpoint()
+	if err != nil {
+		return err
+	}
+	if checkpoint != nil {
+		this.migrationContext.Log.Infof("Resuming export past checkpoint %s", checkpoint.DisplayString())
+		this.checkpointCoordinates = *checkpoint
+		err = this.eventsStreamer.InitDBConnectionsAt(*checkpoint)
+	} else {
+		err = this.eventsStreamer.InitDBConnections()
+	}
+	if err != nil {
+		return err
+	}
+	if err := this.eventsStreamer.AddEntryListener(
+		false,
+		this.migrationContext.DatabaseName,
+		this.migrationContext.OriginalTableName,
+		func(entry *binlog.BinlogEntry) error {
+			if err := this.onBinlogEntry(entry); err != nil {
+				this.abort(err)
+				return err
+			}
+			return nil
+		},
+	); err != nil {
+		return err
+	}
+
+	go func() {
+		this.migrationContext.Log.Debugf("Beginning streaming")
+		if err := this.eventsStreamer.StreamEvents(this.isStopped); err != nil {
+			this.abort(err)
+		}
+		this.migrationContext.Log.Debugf("Done streaming")
+	}()
+	return nil
+}
+
+// onBinlogEntry exports a single row change. As rows of a rows event share the event's coordinates,
+// seeing new coordinates means all rows of the previous event were exported, and so the previous
+// event may be checkpointed.
+func (this *CDCExporter) onBinlogEntry(entry *binlog.BinlogEntry) error {
+	if this.isStopped() {
+		return nil
+	}
+	if entry.Coordinates.Equals(&this.pendingCoordinates) {
+		this.pendingRow++
+	} else {
+		this.setCheckpointCoordinates(this.pendingCoordinates)
+		this.pendingCoordinates = entry.Coordinates
+		this.pendingRow = 0
+	}
+	event, err := NewCDCEvent(entry, this.pendingRow, this.columns)
+	if err != nil {
+		return err
+	}
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	this.sinkMutex.Lock()
+	defer this.sinkMutex.Unlock()
+	if this.isStopped() {
+		return nil
+	}
+	if _, err := this.sink.Write(line); err != nil {
+		return err
+	}
+	atomic.AddInt64(&this.exportedEventsCount, 1)
+	return nil
+}
+
+func (this *CDCExporter) setCheckpointCoordinates(coordinates mysql.BinlogCoordinates) {
+	if coordinates.IsEmpty() {
+		return
+	}
+	this.checkpointMutex.Lock()
+	defer this.checkpointMutex.Unlock()
+	this.checkpointCoordinates = coordinates
+}
+
+func (this *CDCExporter) getCheckpointCoordinates() mysql.BinlogCoordinates {
+	this.checkpointMutex.Lock()
+	defer this.checkpointMutex.Unlock()
+	return this.checkpointCoordinates
+}
+
+func (this *CDCExporter) readCheckpoint() (*mysql.BinlogCoordinates, error) {
+	if this.migrationContext.CDCCheckpointFile == "" {
+		return nil, nil
+	}
+	return readCDCCheckpoint(this.migrationContext.CDCCheckpointFile)
+}
+
+// writeCheckpoint persists the checkpoint coordinates. A file sink is synced beforehand, so that the
+// checkpoint never runs ahead of what is durably exported.
+func (this *CDCExporter) writeCheckpoint(lastWritten *mysql.BinlogCoordinates) (written *mysql.BinlogCoordinates, err error) {
+	if this.migrationContext.CDCCheckpointFile == "" {
+		return lastWritten, nil
+	}
+	coordinates := this.getCheckpointCoordinates()
+	if coordinates.IsEmpty() || coordinates.Equals(lastWritten) {
+		return lastWritten, nil
+	}
+	if this.sinkFile != nil {
+		if err := this.sinkFile.Sync(); err != nil {
+			return lastWritten, err
+		}
+	}
+	if err := writeCDCCheckpoint(this.migrationContext.CDCCheckpointFile, coordinates); err != nil {
+		return lastWritten, err
+	}
+	return &coordinates, nil
+}
+
+func (this *CDCExporter) checkpointPeriodically() {
+	var lastWritten *mysql.BinlogCoordinates
+	ticker := time.NewTicker(cdcCheckpointInterval)
+	defer ticker.Stop()
+	for ticks := 1; ; ticks++ {
+		<-ticker.C
+		if this.isStopped() {
+			return
+		}
+		var err error
+		this.sinkMutex.Lock()
+		lastWritten, err = this.writeCheckpoint(lastWritten)
+		this.sinkMutex.Unlock()
+		if err != nil {
+			this.abort(err)
+			return
+		}
+		if ticks%cdcStatusIntervalCheckpoints == 0 {
+			this.migrationContext.Log.Infof("Exported %d events; streamer at %+v; checkpoint at %+v",
+				atomic.LoadInt64(&this.exportedEventsCount), *this.eventsStreamer.GetCurrentBinlogCoordinates(), this.getCheckpointCoordinates(),
+			)
+		}
+	}
+}
+
+func (this *CDCExporter) teardown() {
+	this.sinkMutex.Lock()
+	atomic.StoreInt64(&this.stopped, 1)
+	this.sinkMutex.Unlock()
+
+	if this.eventsStreamer != nil && this.eventsStreamer.binlogReader != nil {
+		this.eventsStreamer.Close()
+	}
+	if this.sink != nil {
+		if _, err := this.writeCheckpoint(nil); err != nil {
+			this.migrationContext.Log.Errore(err)
+		}
+		if err := this.sink.Close(); err != nil {
+			this.migrationContext.Log.Errore(err)
+		}
+	}
+	if this.eventsStreamer != nil && this.eventsStreamer.db != nil {
+		this.eventsStreamer.Teardown()
+	}
+	if this.inspector != nil {
+		this.inspector.Teardown()
+	}
+}
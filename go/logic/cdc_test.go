@@ -0,0 +1,136 @@
+/*
+   Copyright 2022 GitHub Inc.
+	 See https://github.com/github/gh-ost/blob/master/LICENSE
+*/
+
+package logic
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/github/gh-ost/go/base"
+	"github.com/github/gh-ost/go/binlog"
+	"github.com/github/gh-ost/go/mysql"
+	"github.com/github/gh-ost/go/sql"
+)
+
+func newTestCDCEntry(logPos int64, dml binlog.EventDML, whereValues, newValues []interface{}) *binlog.BinlogEntry {
+	entry := binlog.NewBinlogEntryAt(mysql.BinlogCoordinates{LogFile: "mysql-bin.000017", LogPos: logPos})
+	entry.DmlEvent = binlog.NewBinlogDMLEvent("test", "tbl", dml)
+	if whereValues != nil {
+		entry.DmlEvent.WhereColumnValues = sql.ToColumnValues(whereValues)
+	}
+	if newValues != nil {
+		entry.DmlEvent.NewColumnValues = sql.ToColumnValues(newValues)
+	}
+	return entry
+}
+
+func TestNewCDCEvent(t *testing.T) {
+	columns := sql.NewColumnList([]string{"id", "name", "data"})
+	columns.GetColumn("name").Charset = "utf8mb4"
+
+	t.Run("insert", func(t *testing.T) {
+		entry := newTestCDCEntry(1234, binlog.InsertDML, nil, []interface{}{int64(7), "gh-ost", nil})
+		event, err := NewCDCEvent(entry, 0, columns)
+		require.NoError(t, err)
+
+		line, err := json.Marshal(event)
+		require.NoError(t, err)
+		require.Equal(t, `{"coordinates":"mysql-bin.000017:1234","row":0,"database":"test","table":"tbl","type":"insert","after":{"data":null,"id":7,"name":"gh-ost"}}`, string(line))
+	})
+
+	t.Run("update", func(t *testing.T) {
+		entry := newTestCDCEntry(1234, binlog.UpdateDML, []interface{}{int64(7), "before", nil}, []interface{}{int64(7), "after", nil})
+		event, err := NewCDCEvent(entry, 2, columns)
+		require.NoError(t, err)
+		require.Equal(t, "update", event.Type)
+		require.Equal(t, 2, event.Row)
+		require.Equal(t, "before", event.Before["name"])
+		require.Equal(t, "after", event.After["name"])
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		entry := newTestCDCEntry(1234, binlog.DeleteDML, []interface{}{int64(7), "gh-ost", nil}, nil)
+		event, err := NewCDCEvent(entry, 0, columns)
+		require.NoError(t, err)
+		require.Equal(t, "delete", event.Type)
+		require.Equal(t, int64(7), event.Before["id"])
+		require.Nil(t, event.After)
+	})
+
+	t.Run("binary", func(t *testing.T) {
+		entry := newTestCDCEntry(1234, binlog.InsertDML, nil, []interface{}{int64(7), "gh-ost", string([]byte{0xff, 0x00})})
+		event, err := NewCDCEvent(entry, 0, columns)
+		require.NoError(t, err)
+		require.Equal(t, []byte{0xff, 0x00}, event.After["data"])
+	})
+
+	t.Run("columns mismatch", func(t *testing.T) {
+		entry := newTestCDCEntry(1234, binlog.InsertDML, nil, []interface{}{int64(7), "gh-ost"})
+		_, err := NewCDCEvent(entry, 0, columns)
+		require.Error(t, err)
+	})
+}
+
+func TestCDCCheckpoint(t *testing.T) {
+	dir := t.TempDir()
+	checkpointFile := filepath.Join(dir, "checkpoint")
+
+	coordinates, err := readCDCCheckpoint(checkpointFile)
+	require.NoError(t, err)
+	require.Nil(t, coordinates)
+
+	require.NoError(t, writeCDCCheckpoint(checkpointFile, mysql.BinlogCoordinates{LogFile: "mysql-bin.000017", LogPos: 1234}))
+	coordinates, err = readCDCCheckpoint(checkpointFile)
+	require.NoError(t, err)
+	require.Equal(t, "mysql-bin.000017:1234", coordinates.DisplayString())
+
+	require.NoError(t, os.WriteFile(checkpointFile, []byte("garbage"), 0640))
+	_, err = readCDCCheckpoint(checkpointFile)
+	require.Error(t, err)
+}
+
+func TestCDCExporterOnBinlogEntry(t *testing.T) {
+	migrationContext := base.NewMigrationContext()
+	exporter := NewCDCExporter(migrationContext)
+	exporter.columns = sql.NewColumnList([]string{"id"})
+	sink := &bytes.Buffer{}
+	exporter.sink = nopWriteCloser{sink}
+
+	require.NoError(t, exporter.onBinlogEntry(newTestCDCEntry(100, binlog.InsertDML, nil, []interface{}{int64(1)})))
+	require.NoError(t, exporter.onBinlogEntry(newTestCDCEntry(100, binlog.InsertDML, nil, []interface{}{int64(2)})))
+	// The rows event at 100 may not be checkpointed while its rows may still be coming
+	checkpoint := exporter.getCheckpointCoordinates()
+	require.True(t, checkpoint.IsEmpty())
+
+	require.NoError(t, exporter.onBinlogEntry(newTestCDCEntry(200, binlog.DeleteDML, []interface{}{int64(1)}, nil)))
+	checkpoint = exporter.getCheckpointCoordinates()
+	require.Equal(t, "mysql-bin.000017:100", checkpoint.DisplayString())
+
+	lines := strings.Split(strings.TrimSpace(sink.String()), "\n")
+	require.Len(t, lines, 3)
+	rows := []int{}
+	for _, line := range lines {
+		event := &CDCEvent{}
+		require.NoError(t, json.Unmarshal([]byte(line), event))
+		rows = append(rows, event.Row)
+	}
+	require.Equal(t, []int{0, 1, 0}, rows)
+
+	dir := t.TempDir()
+	migrationContext.CDCCheckpointFile = filepath.Join(dir, "checkpoint")
+	written, err := exporter.writeCheckpoint(nil)
+	require.NoError(t, err)
+	require.Equal(t, "mysql-bin.000017:100", written.DisplayString())
+	content, err := os.ReadFile(migrationContext.CDCCheckpointFile)
+	require.NoError(t, err)
+	require.Equal(t, "mysql-bin.000017:100\n", string(content))
+}
@@ -0,0 +1,105 @@
+/*
+   Copyright 2022 GitHub Inc.
+	 See https://github.com/github/gh-ost/blob/master/LICENSE
+*/
+
+package logic
+
+import (
+	"strings"
+)
+
+const bytesPerMB = 1024 * 1024
+
+// nominalVariableColumnWidth is the width assumed for values of variable length columns, which
+// are typically much shorter than their maximum length, or stored off-page
+const nominalVariableColumnWidth = 256
+
+// tableLayout describes what a table's rows and indexes consist of, along with the table's size.
+// The size of a table under another layout is estimated by comparing the two layouts.
+type tableLayout struct {
+	dataLength       int64
+	indexLength      int64
+	columnWidths     map[string]int64
+	primaryKey       []string
+	secondaryIndexes [][]string
+}
+
+func newTableLayout() *tableLayout {
+	return &tableLayout{columnWidths: make(map[string]int64)}
+}
+
+// columnWidth estimates the number of bytes a column takes in a row, per information_schema.COLUMNS
+func columnWidth(dataType string, characterOctetLength int64, numericPrecision int64) int64 {
+	switch strings.ToLower(dataType) {
+	case "tinyint", "year":
+		return 1
+	case "smallint", "enum":
+		return 2
+	case "mediumint", "date", "time":
+		return 3
+	case "int", "integer", "float", "timestamp":
+		return 4
+	case "datetime":
+		return 5
+	case "bigint", "double", "real", "set":
+		return 8
+	case "decimal", "numeric":
+		return numericPrecision/2 + 1
+	case "bit":
+		return (numericPrecision + 7) / 8
+	case "char", "binary":
+		return characterOctetLength
+	case "varchar", "varbinary":
+		if characterOctetLength < nominalVariableColumnWidth {
+			return characterOctetLength/2 + 2
+		}
+		return nominalVariableColumnWidth
+	default:
+		// text, blob, json, geometry types
+		return nominalVariableColumnWidth
+	}
+}
+
+func (this *tableLayout) columnsWidth(columns []string) (width int64) {
+	for _, column := range columns {
+		width += this.columnWidths[strings.ToLower(column)]
+	}
+	return width
+}
+
+// rowWidth is the width of a row in the clustered index
+func (this *tableLayout) rowWidth() (width int64) {
+	for _, columnWidth := range this.columnWidths {
+		width += columnWidth
+	}
+	return width
+}
+
+// secondaryIndexesWidth is the width of the entries of a row in all secondary indexes, each of
+// which holds the index columns along with the primary key
+func (this *tableLayout) secondaryIndexesWidth() (width int64) {
+	primaryKeyWidth := this.columnsWidth(this.primaryKey)
+	for _, index := range this.secondaryIndexes {
+		width += this.columnsWidth(index) + primaryKeyWidth
+	}
+	return width
+}
+
+// estimateTableSize estimates the size of a table holding the rows of the original table under the
+// given layout, such as the ghost table once all rows are copied. The data and indexes of the
+// original table are scaled by how the width of rows, and of index entries, change.
+func estimateTableSize(original, layout *tableLayout) int64 {
+	dataLength := float64(original.dataLength)
+	if originalWidth := original.rowWidth(); originalWidth > 0 {
+		dataLength = dataLength * float64(layout.rowWidth()) / float64(originalWidth)
+	}
+	indexLength := float64(original.indexLength)
+	if originalWidth := original.secondaryIndexesWidth(); originalWidth > 0 {
+		indexLength = indexLength * float64(layout.secondaryIndexesWidth()) / float64(originalWidth)
+	} else if rowWidth := layout.rowWidth(); rowWidth > 0 {
+		// The original table has no secondary indexes to compare with
+		indexLength = dataLength * float64(layout.secondaryIndexesWidth()) / float64(rowWidth)
+	}
+	return int64(dataLength + indexLength)
+}
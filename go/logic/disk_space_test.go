@@ -0,0 +1,46 @@
+/*
+   Copyright 2022 GitHub Inc.
+	 See https://github.com/github/gh-ost/blob/master/LICENSE
+*/
+
+package logic
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestColumnWidth(t *testing.T) {
+	require.Equal(t, int64(4), columnWidth("int", 0, 10))
+	require.Equal(t, int64(8), columnWidth("BIGINT", 0, 19))
+	require.Equal(t, int64(6), columnWidth("decimal", 0, 10))
+	require.Equal(t, int64(40), columnWidth("char", 40, 0))
+	require.Equal(t, int64(22), columnWidth("varchar", 40, 0))
+	require.Equal(t, int64(nominalVariableColumnWidth), columnWidth("varchar", 1020, 0))
+	require.Equal(t, int64(nominalVariableColumnWidth), columnWidth("longtext", 4294967295, 0))
+}
+
+func TestEstimateTableSize(t *testing.T) {
+	original := newTableLayout()
+	original.dataLength = 1000 * bytesPerMB
+	original.indexLength = 400 * bytesPerMB
+	original.columnWidths = map[string]int64{"id": 8, "name": 24, "created_at": 8}
+	original.primaryKey = []string{"id"}
+	original.secondaryIndexes = [][]string{{"name"}}
+
+	// An unchanged layout keeps the same size
+	require.Equal(t, int64(1400*bytesPerMB), estimateTableSize(original, original))
+
+	ghost := newTableLayout()
+	ghost.columnWidths = map[string]int64{"id": 8, "name": 24, "created_at": 8, "updated_at": 8, "flags": 32}
+	ghost.primaryKey = []string{"id"}
+	ghost.secondaryIndexes = [][]string{{"name"}, {"created_at"}}
+	// Rows double in width; index entries grow from 32 to 48 bytes
+	require.Equal(t, int64(2600*bytesPerMB), estimateTableSize(original, ghost))
+
+	// With no secondary indexes to compare with, index size is relative to row size
+	original.secondaryIndexes = nil
+	original.indexLength = 0
+	require.Equal(t, int64(3200*bytesPerMB), estimateTableSize(original, ghost))
+}
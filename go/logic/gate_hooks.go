@@ -0,0 +1,145 @@
+/*
+   Copyright 2022 GitHub Inc.
+	 See https://github.com/github/gh-ost/blob/master/LICENSE
+*/
+
+package logic
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"time"
+
+	"github.com/github/gh-ost/go/base"
+)
+
+const (
+	gateBeforeRowCopy = base.GateHookPrefix + "before-row-copy"
+	gateBeforeCutOver = base.GateHookPrefix + "before-cut-over"
+
+	defaultGateWaitSeconds = 60
+)
+
+type GateDecision string
+
+const (
+	GateProceed GateDecision = "proceed"
+	GateWait    GateDecision = "wait"
+	GateAbort   GateDecision = "abort"
+)
+
+// GateResponse is what a gating hook responds with: JSON, printed on stdout by a gating executable,
+// or as the body of a webhook's response, such as:
+//
+//	{"decision": "wait", "wait_seconds": 300, "message": "change freeze until 14:00"}
+//
+// An empty response means proceed.
+type GateResponse struct {
+	Decision    GateDecision `json:"decision"`
+	WaitSeconds int64        `json:"wait_seconds"`
+	Message     string       `json:"message"`
+}
+
+func parseGateResponse(output []byte) (*GateResponse, error) {
+	response := &GateResponse{Decision: GateProceed}
+	if len(bytes.TrimSpace(output)) == 0 {
+		return response, nil
+	}
+	if err := json.Unmarshal(output, response); err != nil {
+		return nil, fmt.Errorf("Cannot parse gate response %q: %+v", output, err)
+	}
+	switch response.Decision {
+	case GateProceed, GateAbort:
+	case GateWait:
+		if response.WaitSeconds <= 0 {
+			response.WaitSeconds = defaultGateWaitSeconds
+		}
+	default:
+		return nil, fmt.Errorf("Unknown gate decision %q; expected proceed, wait or abort", response.Decision)
+	}
+	return response, nil
+}
+
+// WaitDuration is how long to wait before asking the gate again
+func (this *GateResponse) WaitDuration() time.Duration {
+	return time.Duration(this.WaitSeconds) * time.Second
+}
+
+// combine merges the responses of several gates: any abort wins, then the longest wait
+func (this *GateResponse) combine(other *GateResponse) *GateResponse {
+	switch {
+	case this.Decision == GateAbort:
+		return this
+	case other.Decision == GateAbort:
+		return other
+	case other.Decision == GateWait && (this.Decision != GateWait || other.WaitSeconds > this.WaitSeconds):
+		return other
+	default:
+		return this
+	}
+}
+
+// executeGateHook executes a gating executable, which responds on stdout. Its stderr is printed to
+// the configured writer.
+func (this *HooksExecutor) executeGateHook(hook string, extraVariables ...hookVariable) (*GateResponse, error) {
+	cmd := exec.Command(hook)
+	cmd.Env = this.applyEnvironmentVariables(extraVariables...)
+	cmd.Stderr = this.writer
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, this.log.Errore(err)
+	}
+	return parseGateResponse(output)
+}
+
+// askGate asks all gating executables and webhooks of the given gate whether to proceed, and combines
+// their responses. A gate which fails to respond fails, unless it is a webhook to ignore failures of.
+func (this *HooksExecutor) askGate(gate string, extraVariables ...hookVariable) (*GateResponse, error) {
+	combined := &GateResponse{Decision: GateProceed}
+	hooks, err := this.detectHooks(gate)
+	if err != nil {
+		return nil, err
+	}
+	for _, hook := range hooks {
+		this.log.Infof("executing %+v gate: %+v", gate, hook)
+		response, err := this.executeGateHook(hook, extraVariables...)
+		if err != nil {
+			return nil, err
+		}
+		combined = combined.combine(response)
+	}
+	for _, webhook := range this.migrationContext.HookWebhooks {
+		if !webhook.Matches(gate) {
+			continue
+		}
+		this.log.Infof("posting %+v gate to webhook: %+v", gate, webhook.RedactedURL())
+		output, err := this.postWebhook(webhook, gate, extraVariables...)
+		var response *GateResponse
+		if err == nil {
+			response, err = parseGateResponse(output)
+		}
+		if err != nil {
+			if webhook.OnFailure == base.IgnoreHookWebhookFailure {
+				this.log.Errore(err)
+				continue
+			}
+			return nil, err
+		}
+		combined = combined.combine(response)
+	}
+	return combined, nil
+}
+
+func (this *HooksExecutor) askGateBeforeRowCopy(attempt int64) (*GateResponse, error) {
+	v := hookVariable{"GH_OST_GATE_ATTEMPT", attempt}
+	return this.askGate(gateBeforeRowCopy, v)
+}
+
+func (this *HooksExecutor) askGateBeforeCutOver(attempt int64) (*GateResponse, error) {
+	v1 := hookVariable{"GH_OST_GATE_ATTEMPT", attempt}
+	v2 := hookVariable{"GH_OST_CUT_OVER_TYPE", this.migrationContext.CutOverType.String()}
+	return this.askGate(gateBeforeCutOver, v1, v2)
+}
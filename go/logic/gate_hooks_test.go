@@ -0,0 +1,131 @@
+/*
+   Copyright 2022 GitHub Inc.
+	 See https://github.com/github/gh-ost/blob/master/LICENSE
+*/
+
+package logic
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/github/gh-ost/go/base"
+)
+
+func TestParseGateResponse(t *testing.T) {
+	for _, output := range []string{" \n", "{}"} {
+		response, err := parseGateResponse([]byte(output))
+		require.NoError(t, err)
+		require.Equal(t, GateProceed, response.Decision)
+	}
+	{
+		response, err := parseGateResponse([]byte(`{"decision": "wait", "wait_seconds": 300, "message": "change freeze"}`))
+		require.NoError(t, err)
+		require.Equal(t, GateWait, response.Decision)
+		require.Equal(t, 5*time.Minute, response.WaitDuration())
+		require.Equal(t, "change freeze", response.Message)
+	}
+	{
+		response, err := parseGateResponse([]byte(`{"decision": "wait"}`))
+		require.NoError(t, err)
+		require.Equal(t, int64(defaultGateWaitSeconds), response.WaitSeconds)
+	}
+	{
+		response, err := parseGateResponse([]byte(`{"decision": "abort", "message": "incident in progress"}`))
+		require.NoError(t, err)
+		require.Equal(t, GateAbort, response.Decision)
+	}
+	for _, output := range []string{`{"decision": "postpone"}`, `{"decision": ""}`, `proceed`} {
+		_, err := parseGateResponse([]byte(output))
+		require.Error(t, err, output)
+	}
+}
+
+func TestGateResponseCombine(t *testing.T) {
+	proceed := &GateResponse{Decision: GateProceed}
+	shortWait := &GateResponse{Decision: GateWait, WaitSeconds: 10}
+	longWait := &GateResponse{Decision: GateWait, WaitSeconds: 60}
+	abort := &GateResponse{Decision: GateAbort}
+
+	require.Equal(t, proceed, proceed.combine(proceed))
+	require.Equal(t, shortWait, proceed.combine(shortWait))
+	require.Equal(t, longWait, shortWait.combine(longWait))
+	require.Equal(t, longWait, longWait.combine(shortWait))
+	require.Equal(t, abort, longWait.combine(abort))
+	require.Equal(t, abort, abort.combine(proceed))
+}
+
+func TestHooksExecutorAskGate(t *testing.T) {
+	migrationContext := base.NewMigrationContext()
+	migrationContext.CutOverType = base.CutOverAtomic
+	migrationContext.HooksPath = t.TempDir()
+	hooksExecutor := NewHooksExecutor(migrationContext)
+	hooksExecutor.writer = io.Discard
+
+	writeGate := func(name string, script string) {
+		require.NoError(t, os.WriteFile(filepath.Join(migrationContext.HooksPath, name), []byte("#!/bin/sh\n"+script), 0777))
+	}
+
+	t.Run("no gates", func(t *testing.T) {
+		response, err := hooksExecutor.askGateBeforeCutOver(1)
+		require.NoError(t, err)
+		require.Equal(t, GateProceed, response.Decision)
+	})
+
+	t.Run("executables", func(t *testing.T) {
+		writeGate(gateBeforeCutOver, `echo '{"decision": "wait", "wait_seconds": 30, "message": "attempt '$GH_OST_GATE_ATTEMPT' '$GH_OST_CUT_OVER_TYPE'"}'`)
+		response, err := hooksExecutor.askGateBeforeCutOver(2)
+		require.NoError(t, err)
+		require.Equal(t, GateWait, response.Decision)
+		require.Equal(t, int64(30), response.WaitSeconds)
+		require.Equal(t, "attempt 2 atomic", response.Message)
+
+		writeGate(gateBeforeCutOver+"-freeze", `echo '{"decision": "abort", "message": "change freeze"}'`)
+		response, err = hooksExecutor.askGateBeforeCutOver(3)
+		require.NoError(t, err)
+		require.Equal(t, GateAbort, response.Decision)
+		require.Equal(t, "change freeze", response.Message)
+
+		// Gates are only asked about their own stage
+		response, err = hooksExecutor.askGateBeforeRowCopy(1)
+		require.NoError(t, err)
+		require.Equal(t, GateProceed, response.Decision)
+
+		writeGate(gateBeforeRowCopy, "exit 1")
+		_, err = hooksExecutor.askGateBeforeRowCopy(1)
+		require.Error(t, err)
+	})
+
+	t.Run("webhooks", func(t *testing.T) {
+		migrationContext.HooksPath = ""
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"decision": "wait", "wait_seconds": 120}`)
+		}))
+		defer server.Close()
+
+		// Catch-all webhooks are not gates
+		require.NoError(t, migrationContext.ReadHookWebhooks(fmt.Sprintf("* %s", server.URL)))
+		response, err := hooksExecutor.askGateBeforeRowCopy(1)
+		require.NoError(t, err)
+		require.Equal(t, GateProceed, response.Decision)
+
+		require.NoError(t, migrationContext.ReadHookWebhooks(fmt.Sprintf("%s %s", gateBeforeRowCopy, server.URL)))
+		response, err = hooksExecutor.askGateBeforeRowCopy(1)
+		require.NoError(t, err)
+		require.Equal(t, GateWait, response.Decision)
+		require.Equal(t, 2*time.Minute, response.WaitDuration())
+
+		require.NoError(t, migrationContext.ReadHookWebhooks(fmt.Sprintf("%s %s/missing on-failure=ignore; %s %s", gateBeforeRowCopy, "http://127.0.0.1:1", gateBeforeRowCopy, server.URL)))
+		response, err = hooksExecutor.askGateBeforeRowCopy(1)
+		require.NoError(t, err)
+		require.Equal(t, GateWait, response.Decision)
+	})
+}
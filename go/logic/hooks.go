@@ -6,83 +6,159 @@
 package logic
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
 	"io"
+	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 	"sync/atomic"
+	"time"
 
 	"github.com/github/gh-ost/go/base"
-	"github.com/openark/golib/log"
 )
 
 const (
-	onStartup            = "gh-ost-on-startup"
-	onValidated          = "gh-ost-on-validated"
-	onRowCountComplete   = "gh-ost-on-rowcount-complete"
-	onBeforeRowCopy      = "gh-ost-on-before-row-copy"
-	onRowCopyComplete    = "gh-ost-on-row-copy-complete"
-	onBeginPostponed     = "gh-ost-on-begin-postponed"
-	onBeforeCutOver      = "gh-ost-on-before-cut-over"
-	onInteractiveCommand = "gh-ost-on-interactive-command"
-	onSuccess            = "gh-ost-on-success"
-	onFailure            = "gh-ost-on-failure"
-	onStatus             = "gh-ost-on-status"
-	onStopReplication    = "gh-ost-on-stop-replication"
-	onStartReplication   = "gh-ost-on-start-replication"
+	onStartup                = "gh-ost-on-startup"
+	onValidated              = "gh-ost-on-validated"
+	onRowCountComplete       = "gh-ost-on-rowcount-complete"
+	onBeforeRowCopy          = "gh-ost-on-before-row-copy"
+	onRowCopyComplete        = "gh-ost-on-row-copy-complete"
+	onBeginPostponed         = "gh-ost-on-begin-postponed"
+	onBeforeCutOver          = "gh-ost-on-before-cut-over"
+	onInteractiveCommand     = "gh-ost-on-interactive-command"
+	onSuccess                = "gh-ost-on-success"
+	onFailure                = "gh-ost-on-failure"
+	onStatus                 = "gh-ost-on-status"
+	onStopReplication        = "gh-ost-on-stop-replication"
+	onStartReplication       = "gh-ost-on-start-replication"
+	onBinlogRetentionWarning = "gh-ost-on-binlog-retention-warning"
+	onDiskSpaceCritical      = "gh-ost-on-disk-space-critical"
+	onThrottleBegin          = "gh-ost-on-throttle-begin"
+	onThrottleEnd            = "gh-ost-on-throttle-end"
+	onCriticalLoadHibernate  = "gh-ost-on-critical-load-hibernate"
+	onCutOverAttempt         = "gh-ost-on-cut-over-attempt"
+	onCutOverFailure         = "gh-ost-on-cut-over-failure"
+	onRenameRollback         = "gh-ost-on-rename-rollback"
+	onStreamerReconnect      = "gh-ost-on-streamer-reconnect"
+	onGhostTableCaughtUp     = "gh-ost-on-ghost-table-caught-up"
 )
 
+const webhookRetryInterval = time.Second
+
 type HooksExecutor struct {
-	migrationContext *base.MigrationContext
-	writer           io.Writer
+	migrationContext     *base.MigrationContext
+	log                  base.Logger
+	writer               io.Writer
+	httpClient           *http.Client
+	webhookRetryInterval time.Duration
 }
 
 func NewHooksExecutor(migrationContext *base.MigrationContext) *HooksExecutor {
 	return &HooksExecutor{
-		migrationContext: migrationContext,
-		writer:           os.Stderr,
+		migrationContext:     migrationContext,
+		log:                  migrationContext.ComponentLog("hooks"),
+		writer:               os.Stderr,
+		httpClient:           &http.Client{},
+		webhookRetryInterval: webhookRetryInterval,
+	}
+}
+
+// hookVariable is a piece of migration state handed to hooks: as an environment variable to hook
+// executables, and as a JSON field to webhooks
+type hookVariable struct {
+	name  string
+	value interface{}
+}
+
+func (this hookVariable) String() string {
+	if value, ok := this.value.(float64); ok {
+		return fmt.Sprintf("%s=%f", this.name, value)
+	}
+	return fmt.Sprintf("%s=%v", this.name, this.value)
+}
+
+// quotedHookValue is a string value which is single quoted as an environment variable, e.g.
+// GH_OST_COMMAND='throttle', but not in webhook payloads
+type quotedHookValue string
+
+func (this quotedHookValue) String() string {
+	return fmt.Sprintf("'%s'", string(this))
+}
+
+// jsonName is the name of the variable in webhook payloads, e.g. copied_rows for GH_OST_COPIED_ROWS
+func (this hookVariable) jsonName() string {
+	return strings.ToLower(strings.TrimPrefix(this.name, "GH_OST_"))
+}
+
+func (this *HooksExecutor) hookVariables() []hookVariable {
+	return []hookVariable{
+		{"GH_OST_DATABASE_NAME", this.migrationContext.DatabaseName},
+		{"GH_OST_TABLE_NAME", this.migrationContext.OriginalTableName},
+		{"GH_OST_GHOST_TABLE_NAME", this.migrationContext.GetGhostTableName()},
+		{"GH_OST_OLD_TABLE_NAME", this.migrationContext.GetOldTableName()},
+		{"GH_OST_DDL", this.migrationContext.AlterStatement},
+		{"GH_OST_ELAPSED_SECONDS", this.migrationContext.ElapsedTime().Seconds()},
+		{"GH_OST_ELAPSED_COPY_SECONDS", this.migrationContext.ElapsedRowCopyTime().Seconds()},
+		{"GH_OST_ESTIMATED_ROWS", atomic.LoadInt64(&this.migrationContext.RowsEstimate) + atomic.LoadInt64(&this.migrationContext.RowsDeltaEstimate)},
+		{"GH_OST_COPIED_ROWS", this.migrationContext.GetTotalRowsCopied()},
+		{"GH_OST_SHADOW_VERIFY_MISMATCHES", atomic.LoadInt64(&this.migrationContext.ShadowVerifyMismatchesCount)},
+		{"GH_OST_MIGRATED_HOST", this.migrationContext.GetApplierHostname()},
+		{"GH_OST_INSPECTED_HOST", this.migrationContext.GetInspectorHostname()},
+		{"GH_OST_TARGET_HOST", this.migrationContext.GetTargetHostname()},
+		{"GH_OST_EXECUTING_HOST", this.migrationContext.Hostname},
+		{"GH_OST_INSPECTED_LAG", this.migrationContext.GetCurrentLagDuration().Seconds()},
+		{"GH_OST_HEARTBEAT_LAG", this.migrationContext.TimeSinceLastHeartbeatOnChangelog().Seconds()},
+		{"GH_OST_APPLY_LAG", this.migrationContext.GetApplyLag().Seconds()},
+		{"GH_OST_BINLOG_SECONDS_UNTIL_PURGE", atomic.LoadInt64(&this.migrationContext.BinlogSecondsUntilPurge)},
+		{"GH_OST_FREE_DISK_SPACE_BYTES", atomic.LoadInt64(&this.migrationContext.FreeDiskSpaceBytes)},
+		{"GH_OST_GHOST_TABLE_SIZE_ESTIMATE_BYTES", atomic.LoadInt64(&this.migrationContext.GhostTableSizeEstimate)},
+		{"GH_OST_PROGRESS", this.migrationContext.GetProgressPct()},
+		{"GH_OST_ETA_SECONDS", this.migrationContext.GetETASeconds()},
+		{"GH_OST_HOOKS_HINT", this.migrationContext.HooksHintMessage},
+		{"GH_OST_HOOKS_HINT_OWNER", this.migrationContext.HooksHintOwner},
+		{"GH_OST_HOOKS_HINT_TOKEN", this.migrationContext.HooksHintToken},
+		{"GH_OST_DRY_RUN", this.migrationContext.Noop},
 	}
 }
 
-func (this *HooksExecutor) applyEnvironmentVariables(extraVariables ...string) []string {
+func (this *HooksExecutor) applyEnvironmentVariables(extraVariables ...hookVariable) []string {
 	env := os.Environ()
-	env = append(env, fmt.Sprintf("GH_OST_DATABASE_NAME=%s", this.migrationContext.DatabaseName))
-	env = append(env, fmt.Sprintf("GH_OST_TABLE_NAME=%s", this.migrationContext.OriginalTableName))
-	env = append(env, fmt.Sprintf("GH_OST_GHOST_TABLE_NAME=%s", this.migrationContext.GetGhostTableName()))
-	env = append(env, fmt.Sprintf("GH_OST_OLD_TABLE_NAME=%s", this.migrationContext.GetOldTableName()))
-	env = append(env, fmt.Sprintf("GH_OST_DDL=%s", this.migrationContext.AlterStatement))
-	env = append(env, fmt.Sprintf("GH_OST_ELAPSED_SECONDS=%f", this.migrationContext.ElapsedTime().Seconds()))
-	env = append(env, fmt.Sprintf("GH_OST_ELAPSED_COPY_SECONDS=%f", this.migrationContext.ElapsedRowCopyTime().Seconds()))
-	estimatedRows := atomic.LoadInt64(&this.migrationContext.RowsEstimate) + atomic.LoadInt64(&this.migrationContext.RowsDeltaEstimate)
-	env = append(env, fmt.Sprintf("GH_OST_ESTIMATED_ROWS=%d", estimatedRows))
-	totalRowsCopied := this.migrationContext.GetTotalRowsCopied()
-	env = append(env, fmt.Sprintf("GH_OST_COPIED_ROWS=%d", totalRowsCopied))
-	env = append(env, fmt.Sprintf("GH_OST_MIGRATED_HOST=%s", this.migrationContext.GetApplierHostname()))
-	env = append(env, fmt.Sprintf("GH_OST_INSPECTED_HOST=%s", this.migrationContext.GetInspectorHostname()))
-	env = append(env, fmt.Sprintf("GH_OST_EXECUTING_HOST=%s", this.migrationContext.Hostname))
-	env = append(env, fmt.Sprintf("GH_OST_INSPECTED_LAG=%f", this.migrationContext.GetCurrentLagDuration().Seconds()))
-	env = append(env, fmt.Sprintf("GH_OST_HEARTBEAT_LAG=%f", this.migrationContext.TimeSinceLastHeartbeatOnChangelog().Seconds()))
-	env = append(env, fmt.Sprintf("GH_OST_PROGRESS=%f", this.migrationContext.GetProgressPct()))
-	env = append(env, fmt.Sprintf("GH_OST_ETA_SECONDS=%d", this.migrationContext.GetETASeconds()))
-	env = append(env, fmt.Sprintf("GH_OST_HOOKS_HINT=%s", this.migrationContext.HooksHintMessage))
-	env = append(env, fmt.Sprintf("GH_OST_HOOKS_HINT_OWNER=%s", this.migrationContext.HooksHintOwner))
-	env = append(env, fmt.Sprintf("GH_OST_HOOKS_HINT_TOKEN=%s", this.migrationContext.HooksHintToken))
-	env = append(env, fmt.Sprintf("GH_OST_DRY_RUN=%t", this.migrationContext.Noop))
-
-	env = append(env, extraVariables...)
+	for _, variable := range this.hookVariables() {
+		env = append(env, variable.String())
+	}
+	for _, variable := range extraVariables {
+		env = append(env, variable.String())
+	}
 	return env
 }
 
+// webhookPayload is the JSON body posted to webhooks: the hook variables, along with the name of
+// the hook and the UUID of the migration. Numbers are posted as JSON numbers.
+func (this *HooksExecutor) webhookPayload(hook string, extraVariables ...hookVariable) map[string]interface{} {
+	payload := map[string]interface{}{
+		"hook": hook,
+		"uuid": this.migrationContext.Uuid,
+	}
+	for _, variable := range append(this.hookVariables(), extraVariables...) {
+		payload[variable.jsonName()] = variable.value
+	}
+	return payload
+}
+
 // executeHook executes a command, and sets relevant environment variables
 // combined output & error are printed to the configured writer.
-func (this *HooksExecutor) executeHook(hook string, extraVariables ...string) error {
+func (this *HooksExecutor) executeHook(hook string, extraVariables ...hookVariable) error {
 	cmd := exec.Command(hook)
 	cmd.Env = this.applyEnvironmentVariables(extraVariables...)
 
 	combinedOutput, err := cmd.CombinedOutput()
 	fmt.Fprintln(this.writer, string(combinedOutput))
-	return log.Errore(err)
+	return this.log.Errore(err)
 }
 
 func (this *HooksExecutor) detectHooks(baseName string) (hooks []string, err error) {
@@ -94,20 +170,77 @@ func (this *HooksExecutor) detectHooks(baseName string) (hooks []string, err err
 	return hooks, err
 }
 
-func (this *HooksExecutor) executeHooks(baseName string, extraVariables ...string) error {
+func (this *HooksExecutor) executeHooks(baseName string, extraVariables ...hookVariable) error {
 	hooks, err := this.detectHooks(baseName)
 	if err != nil {
 		return err
 	}
 	for _, hook := range hooks {
-		log.Infof("executing %+v hook: %+v", baseName, hook)
+		this.log.Infof("executing %+v hook: %+v", baseName, hook)
 		if err := this.executeHook(hook, extraVariables...); err != nil {
 			return err
 		}
 	}
+	for _, webhook := range this.migrationContext.HookWebhooks {
+		if !webhook.Matches(baseName) {
+			continue
+		}
+		this.log.Infof("posting %+v hook to webhook: %+v", baseName, webhook.RedactedURL())
+		if _, err := this.postWebhook(webhook, baseName, extraVariables...); err != nil {
+			if webhook.OnFailure == base.IgnoreHookWebhookFailure {
+				this.log.Errore(err)
+				continue
+			}
+			return err
+		}
+	}
 	return nil
 }
 
+// postWebhook posts the hook's payload to a webhook, retrying as configured, and returns the
+// response body. Any 2xx response counts as success.
+func (this *HooksExecutor) postWebhook(webhook *base.HookWebhook, hook string, extraVariables ...hookVariable) (response []byte, err error) {
+	payload, err := json.Marshal(this.webhookPayload(hook, extraVariables...))
+	if err != nil {
+		return nil, err
+	}
+	for attempt := int64(0); attempt <= webhook.Retries; attempt++ {
+		if attempt > 0 {
+			this.log.Warningf("%+v webhook failed: %+v; retrying", hook, err)
+			time.Sleep(this.webhookRetryInterval)
+		}
+		if response, err = this.postWebhookPayload(webhook, payload); err == nil {
+			return response, nil
+		}
+	}
+	return nil, fmt.Errorf("%+v webhook failed after %d attempts: %+v", hook, webhook.Retries+1, err)
+}
+
+func (this *HooksExecutor) postWebhookPayload(webhook *base.HookWebhook, payload []byte) (response []byte, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), webhook.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := this.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if response, err = io.ReadAll(resp.Body); err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("webhook responded with HTTP status %d", resp.StatusCode)
+	}
+	return response, nil
+}
+
 func (this *HooksExecutor) onStartup() error {
 	return this.executeHooks(onStartup)
 }
@@ -136,7 +269,7 @@ func (this *HooksExecutor) onBeforeCutOver() error {
 }
 
 func (this *HooksExecutor) onInteractiveCommand(command string) error {
-	v := fmt.Sprintf("GH_OST_COMMAND='%s'", command)
+	v := hookVariable{"GH_OST_COMMAND", quotedHookValue(command)}
 	return this.executeHooks(onInteractiveCommand, v)
 }
 
@@ -149,10 +282,69 @@ func (this *HooksExecutor) onFailure() error {
 }
 
 func (this *HooksExecutor) onStatus(statusMessage string) error {
-	v := fmt.Sprintf("GH_OST_STATUS='%s'", statusMessage)
+	v := hookVariable{"GH_OST_STATUS", quotedHookValue(statusMessage)}
 	return this.executeHooks(onStatus, v)
 }
 
+func (this *HooksExecutor) onBinlogRetentionWarning(logFile string) error {
+	v := hookVariable{"GH_OST_BINLOG_FILE", logFile}
+	return this.executeHooks(onBinlogRetentionWarning, v)
+}
+
+func (this *HooksExecutor) onDiskSpaceCritical() error {
+	return this.executeHooks(onDiskSpaceCritical)
+}
+
+func (this *HooksExecutor) onThrottleBegin(reason string) error {
+	v := hookVariable{"GH_OST_THROTTLE_REASON", reason}
+	return this.executeHooks(onThrottleBegin, v)
+}
+
+func (this *HooksExecutor) onThrottleEnd(reason string, throttledDuration time.Duration) error {
+	v1 := hookVariable{"GH_OST_THROTTLE_REASON", reason}
+	v2 := hookVariable{"GH_OST_THROTTLE_SECONDS", throttledDuration.Seconds()}
+	return this.executeHooks(onThrottleEnd, v1, v2)
+}
+
+func (this *HooksExecutor) onCriticalLoadHibernate(criticalLoad string, hibernateDuration time.Duration) error {
+	v1 := hookVariable{"GH_OST_CRITICAL_LOAD", criticalLoad}
+	v2 := hookVariable{"GH_OST_HIBERNATE_SECONDS", int64(hibernateDuration.Seconds())}
+	return this.executeHooks(onCriticalLoadHibernate, v1, v2)
+}
+
+func (this *HooksExecutor) onCutOverAttempt(attempt int64) error {
+	v1 := hookVariable{"GH_OST_CUT_OVER_ATTEMPT", attempt}
+	v2 := hookVariable{"GH_OST_CUT_OVER_TYPE", this.migrationContext.CutOverType.String()}
+	return this.executeHooks(onCutOverAttempt, v1, v2)
+}
+
+func (this *HooksExecutor) onCutOverFailure(attempt int64, cutOverError error) error {
+	v1 := hookVariable{"GH_OST_CUT_OVER_ATTEMPT", attempt}
+	v2 := hookVariable{"GH_OST_CUT_OVER_TYPE", this.migrationContext.CutOverType.String()}
+	v3 := hookVariable{"GH_OST_CUT_OVER_ERROR", fmt.Sprintf("%s", cutOverError)}
+	return this.executeHooks(onCutOverFailure, v1, v2, v3)
+}
+
+func (this *HooksExecutor) onRenameRollback(rollbackError error) error {
+	v := hookVariable{"GH_OST_ROLLBACK_ERROR", ""}
+	if rollbackError != nil {
+		v.value = rollbackError.Error()
+	}
+	return this.executeHooks(onRenameRollback, v)
+}
+
+func (this *HooksExecutor) onStreamerReconnect(streamerError error, coordinates string, successiveFailures int64) error {
+	v1 := hookVariable{"GH_OST_STREAMER_ERROR", fmt.Sprintf("%s", streamerError)}
+	v2 := hookVariable{"GH_OST_STREAMER_COORDINATES", coordinates}
+	v3 := hookVariable{"GH_OST_STREAMER_SUCCESSIVE_FAILURES", successiveFailures}
+	return this.executeHooks(onStreamerReconnect, v1, v2, v3)
+}
+
+func (this *HooksExecutor) onGhostTableCaughtUp(catchUpDuration time.Duration) error {
+	v := hookVariable{"GH_OST_CATCH_UP_SECONDS", catchUpDuration.Seconds()}
+	return this.executeHooks(onGhostTableCaughtUp, v)
+}
+
 func (this *HooksExecutor) onStopReplication() error {
 	return this.executeHooks(onStopReplication)
 }
@@ -8,11 +8,16 @@ package logic
 import (
 	"bufio"
 	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -73,7 +78,7 @@ func TestHooksExecutorExecuteHooks(t *testing.T) {
 
 		var buf bytes.Buffer
 		hooksExecutor.writer = &buf
-		require.Nil(t, hooksExecutor.executeHooks("success-hook", "TEST="+t.Name()))
+		require.Nil(t, hooksExecutor.executeHooks("success-hook", hookVariable{"TEST", t.Name()}))
 
 		scanner := bufio.NewScanner(&buf)
 		for scanner.Scan() {
@@ -111,3 +116,120 @@ func TestHooksExecutorExecuteHooks(t *testing.T) {
 		}
 	})
 }
+
+func TestHooksExecutorWebhooks(t *testing.T) {
+	migrationContext := base.NewMigrationContext()
+	migrationContext.DatabaseName = "test"
+	migrationContext.OriginalTableName = "tablename"
+	migrationContext.TotalRowsCopied = 123456
+	hooksExecutor := NewHooksExecutor(migrationContext)
+	hooksExecutor.webhookRetryInterval = time.Millisecond
+
+	var requests int64
+	var failures int64
+	payloads := make(chan map[string]interface{}, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&requests, 1)
+		if atomic.AddInt64(&failures, -1) >= 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		require.Equal(t, http.MethodPost, r.Method)
+		require.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		payload := map[string]interface{}{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+		payloads <- payload
+	}))
+	defer server.Close()
+
+	t.Run("success", func(t *testing.T) {
+		require.NoError(t, migrationContext.ReadHookWebhooks(fmt.Sprintf("gh-ost-on-status %s; gh-ost-on-success %s/ignored", server.URL, server.URL)))
+		atomic.StoreInt64(&requests, 0)
+		require.NoError(t, hooksExecutor.onStatus("copying rows"))
+		require.Equal(t, int64(1), atomic.LoadInt64(&requests))
+
+		payload := <-payloads
+		require.Equal(t, "gh-ost-on-status", payload["hook"])
+		require.Equal(t, migrationContext.Uuid, payload["uuid"])
+		require.Equal(t, "test", payload["database_name"])
+		require.Equal(t, float64(123456), payload["copied_rows"])
+		require.Equal(t, false, payload["dry_run"])
+		require.Equal(t, "copying rows", payload["status"])
+	})
+
+	t.Run("numbers", func(t *testing.T) {
+		require.NoError(t, migrationContext.ReadHookWebhooks(fmt.Sprintf("* %s", server.URL)))
+		migrationContext.CutOverType = base.CutOverCrossServer
+		require.NoError(t, hooksExecutor.onCutOverFailure(3, errors.New("lock wait timeout")))
+
+		payload := <-payloads
+		require.Equal(t, float64(3), payload["cut_over_attempt"])
+		require.Equal(t, "cross-server", payload["cut_over_type"])
+		require.Equal(t, "lock wait timeout", payload["cut_over_error"])
+
+		require.NoError(t, hooksExecutor.onThrottleEnd("max-lag-millis", 1500*time.Millisecond))
+		payload = <-payloads
+		require.Equal(t, 1.5, payload["throttle_seconds"])
+		require.Equal(t, "max-lag-millis", payload["throttle_reason"])
+	})
+
+	t.Run("retries", func(t *testing.T) {
+		require.NoError(t, migrationContext.ReadHookWebhooks(fmt.Sprintf("* %s retries=2", server.URL)))
+		atomic.StoreInt64(&requests, 0)
+		atomic.StoreInt64(&failures, 2)
+		require.NoError(t, hooksExecutor.onSuccess())
+		require.Equal(t, int64(3), atomic.LoadInt64(&requests))
+		require.Equal(t, "gh-ost-on-success", (<-payloads)["hook"])
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		require.NoError(t, migrationContext.ReadHookWebhooks(fmt.Sprintf("* %s retries=1", server.URL)))
+		atomic.StoreInt64(&failures, 2)
+		require.Error(t, hooksExecutor.onSuccess())
+
+		require.NoError(t, migrationContext.ReadHookWebhooks(fmt.Sprintf("* %s on-failure=ignore", server.URL)))
+		atomic.StoreInt64(&failures, 1)
+		require.NoError(t, hooksExecutor.onSuccess())
+	})
+}
+
+func TestHooksExecutorLifecycleHooks(t *testing.T) {
+	migrationContext := base.NewMigrationContext()
+	migrationContext.CutOverType = base.CutOverLockAndRename
+	migrationContext.HooksPath = t.TempDir()
+	hooksExecutor := NewHooksExecutor(migrationContext)
+
+	for _, hook := range []string{onThrottleEnd, onCutOverFailure, onRenameRollback} {
+		require.NoError(t, os.WriteFile(filepath.Join(migrationContext.HooksPath, hook), []byte("#!/bin/sh\nenv | grep ^GH_OST_ | grep -v ^GH_OST_HOOKS_HINT"), 0777))
+	}
+	hookEnv := func(runHook func() error) map[string]string {
+		var buf bytes.Buffer
+		hooksExecutor.writer = &buf
+		require.NoError(t, runHook())
+		env := map[string]string{}
+		scanner := bufio.NewScanner(&buf)
+		for scanner.Scan() {
+			if name, value, ok := strings.Cut(scanner.Text(), "="); ok {
+				env[name] = value
+			}
+		}
+		return env
+	}
+
+	env := hookEnv(func() error { return hooksExecutor.onThrottleEnd("max-lag-millis 2.5s", 90*time.Second) })
+	require.Equal(t, "max-lag-millis 2.5s", env["GH_OST_THROTTLE_REASON"])
+	require.Equal(t, "90.000000", env["GH_OST_THROTTLE_SECONDS"])
+
+	env = hookEnv(func() error { return hooksExecutor.onCutOverFailure(3, fmt.Errorf("lock wait timeout exceeded")) })
+	require.Equal(t, "3", env["GH_OST_CUT_OVER_ATTEMPT"])
+	require.Equal(t, "lock-and-rename", env["GH_OST_CUT_OVER_TYPE"])
+	require.Equal(t, "lock wait timeout exceeded", env["GH_OST_CUT_OVER_ERROR"])
+
+	env = hookEnv(func() error { return hooksExecutor.onRenameRollback(nil) })
+	require.Contains(t, env, "GH_OST_ROLLBACK_ERROR")
+	require.Equal(t, "", env["GH_OST_ROLLBACK_ERROR"])
+
+	// Hooks not found in --hooks-path are not executed
+	env = hookEnv(func() error { return hooksExecutor.onThrottleBegin("max-lag-millis 2.5s") })
+	require.Empty(t, env)
+}
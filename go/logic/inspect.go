@@ -33,25 +33,76 @@ type Inspector struct {
 	dbVersion           string
 	informationSchemaDb *gosql.DB
 	migrationContext    *base.MigrationContext
+	log                 base.Logger
 	name                string
+
+	// binlogRotations maps binary logs to the time they were found rotated; see recordBinlogRotations
+	binlogRotations map[string]time.Time
+
+	// ghostTableInspector examines the ghost table. It is nil unless the ghost table lives on
+	// another server, as is the case with a cross-server move.
+	ghostTableInspector *Inspector
 }
 
 func NewInspector(migrationContext *base.MigrationContext) *Inspector {
 	return &Inspector{
 		connectionConfig: migrationContext.InspectorConnectionConfig,
 		migrationContext: migrationContext,
+		log:              migrationContext.ComponentLog("inspector"),
 		name:             "inspector",
 	}
 }
 
+// NewTargetInspector creates an inspector of the target server of a cross-server move. It is only
+// used for examining the ghost table.
+func NewTargetInspector(migrationContext *base.MigrationContext) *Inspector {
+	return &Inspector{
+		connectionConfig: migrationContext.TargetConnectionConfig,
+		migrationContext: migrationContext,
+		log:              migrationContext.ComponentLog("inspector"),
+		name:             "target inspector",
+	}
+}
+
+// InitTargetDBConnections connects a target inspector. Unlike InitDBConnections, it makes no
+// replication related validations, as the target server is not where binary logs are read from.
+func (this *Inspector) InitTargetDBConnections() (err error) {
+	if err := this.connectionConfig.RefreshCredentials(); err != nil {
+		return err
+	}
+	targetUri := this.connectionConfig.GetDBUri(this.migrationContext.DatabaseName)
+	if this.db, _, err = mysql.GetDB(this.migrationContext.Uuid, targetUri, this.connectionConfig); err != nil {
+		return err
+	}
+	informationSchemaUri := this.connectionConfig.GetDBUri("information_schema")
+	if this.informationSchemaDb, _, err = mysql.GetDB(this.migrationContext.Uuid, informationSchemaUri, this.connectionConfig); err != nil {
+		return err
+	}
+	if this.dbVersion, err = base.ValidateConnection(this.db, this.connectionConfig, this.migrationContext, this.name); err != nil {
+		return err
+	}
+	return nil
+}
+
+// getGhostTableInspector returns the inspector by which to examine the ghost table
+func (this *Inspector) getGhostTableInspector() *Inspector {
+	if this.ghostTableInspector != nil {
+		return this.ghostTableInspector
+	}
+	return this
+}
+
 func (this *Inspector) InitDBConnections() (err error) {
+	if err := this.connectionConfig.RefreshCredentials(); err != nil {
+		return err
+	}
 	inspectorUri := this.connectionConfig.GetDBUri(this.migrationContext.DatabaseName)
-	if this.db, _, err = mysql.GetDB(this.migrationContext.Uuid, inspectorUri); err != nil {
+	if this.db, _, err = mysql.GetDB(this.migrationContext.Uuid, inspectorUri, this.connectionConfig); err != nil {
 		return err
 	}
 
 	informationSchemaUri := this.connectionConfig.GetDBUri("information_schema")
-	if this.informationSchemaDb, _, err = mysql.GetDB(this.migrationContext.Uuid, informationSchemaUri); err != nil {
+	if this.informationSchemaDb, _, err = mysql.GetDB(this.migrationContext.Uuid, informationSchemaUri, this.connectionConfig); err != nil {
 		return err
 	}
 
@@ -70,13 +121,16 @@ func (this *Inspector) InitDBConnections() (err error) {
 	if err := this.validateGrants(); err != nil {
 		return err
 	}
+	if err := this.detectReplicationCluster(); err != nil {
+		return err
+	}
 	if err := this.validateBinlogs(); err != nil {
 		return err
 	}
 	if err := this.applyBinlogFormat(); err != nil {
 		return err
 	}
-	this.migrationContext.Log.Infof("Inspector initiated on %+v, version %+v", this.connectionConfig.ImpliedKey, this.migrationContext.InspectorMySQLVersion)
+	this.log.Infof("Inspector initiated on %+v, version %+v", this.connectionConfig.ImpliedKey, this.migrationContext.InspectorMySQLVersion)
 	return nil
 }
 
@@ -108,6 +162,9 @@ func (this *Inspector) InspectTableColumnsAndUniqueKeys(tableName string) (colum
 	if err != nil {
 		return columns, virtualColumns, uniqueKeys, err
 	}
+	if virtualColumns.Len() > 0 {
+		this.log.Debugf("%s has generated columns: %s", sql.EscapeName(tableName), virtualColumns)
+	}
 
 	return columns, virtualColumns, uniqueKeys, nil
 }
@@ -133,7 +190,7 @@ func (this *Inspector) inspectOriginalAndGhostTables() (err error) {
 		return fmt.Errorf("It seems like table structure is not identical between master and replica. This scenario is not supported.")
 	}
 
-	this.migrationContext.GhostTableColumns, this.migrationContext.GhostTableVirtualColumns, this.migrationContext.GhostTableUniqueKeys, err = this.InspectTableColumnsAndUniqueKeys(this.migrationContext.GetGhostTableName())
+	this.migrationContext.GhostTableColumns, this.migrationContext.GhostTableVirtualColumns, this.migrationContext.GhostTableUniqueKeys, err = this.getGhostTableInspector().InspectTableColumnsAndUniqueKeys(this.migrationContext.GetGhostTableName())
 	if err != nil {
 		return err
 	}
@@ -145,14 +202,14 @@ func (this *Inspector) inspectOriginalAndGhostTables() (err error) {
 			switch column.Type {
 			case sql.FloatColumnType:
 				{
-					this.migrationContext.Log.Warningf("Will not use %+v as shared key due to FLOAT data type", sharedUniqueKey.Name)
+					this.log.Warningf("Will not use %+v as shared key due to FLOAT data type", sharedUniqueKey.Name)
 					uniqueKeyIsValid = false
 				}
 			case sql.JSONColumnType:
 				{
 					// Noteworthy that at this time MySQL does not allow JSON indexing anyhow, but this code
 					// will remain in place to potentially handle the future case where JSON is supported in indexes.
-					this.migrationContext.Log.Warningf("Will not use %+v as shared key due to JSON data type", sharedUniqueKey.Name)
+					this.log.Warningf("Will not use %+v as shared key due to JSON data type", sharedUniqueKey.Name)
 					uniqueKeyIsValid = false
 				}
 			}
@@ -165,24 +222,24 @@ func (this *Inspector) inspectOriginalAndGhostTables() (err error) {
 	if this.migrationContext.UniqueKey == nil {
 		return fmt.Errorf("No shared unique key can be found after ALTER! Bailing out")
 	}
-	this.migrationContext.Log.Infof("Chosen shared unique key is %s", this.migrationContext.UniqueKey.Name)
+	this.log.Infof("Chosen shared unique key is %s", this.migrationContext.UniqueKey.Name)
 	if this.migrationContext.UniqueKey.HasNullable {
 		if this.migrationContext.NullableUniqueKeyAllowed {
-			this.migrationContext.Log.Warningf("Chosen key (%s) has nullable columns. You have supplied with --allow-nullable-unique-key and so this migration proceeds. As long as there aren't NULL values in this key's column, migration should be fine. NULL values will corrupt migration's data", this.migrationContext.UniqueKey)
+			this.log.Warningf("Chosen key (%s) has nullable columns. You have supplied with --allow-nullable-unique-key and so this migration proceeds. As long as there aren't NULL values in this key's column, migration should be fine. NULL values will corrupt migration's data", this.migrationContext.UniqueKey)
 		} else {
 			return fmt.Errorf("Chosen key (%s) has nullable columns. Bailing out. To force this operation to continue, supply --allow-nullable-unique-key flag. Only do so if you are certain there are no actual NULL values in this key. As long as there aren't, migration should be fine. NULL values in columns of this key will corrupt migration's data", this.migrationContext.UniqueKey)
 		}
 	}
 
 	this.migrationContext.SharedColumns, this.migrationContext.MappedSharedColumns = this.getSharedColumns(this.migrationContext.OriginalTableColumns, this.migrationContext.GhostTableColumns, this.migrationContext.OriginalTableVirtualColumns, this.migrationContext.GhostTableVirtualColumns, this.migrationContext.ColumnRenameMap)
-	this.migrationContext.Log.Infof("Shared columns are %s", this.migrationContext.SharedColumns)
+	this.log.Infof("Shared columns are %s", this.migrationContext.SharedColumns)
 	// By fact that a non-empty unique key exists we also know the shared columns are non-empty
 
 	// This additional step looks at which columns are unsigned. We could have merged this within
 	// the `getTableColumns()` function, but it's a later patch and introduces some complexity; I feel
 	// comfortable in doing this as a separate step.
 	this.applyColumnTypes(this.migrationContext.DatabaseName, this.migrationContext.OriginalTableName, this.migrationContext.OriginalTableColumns, this.migrationContext.SharedColumns, &this.migrationContext.UniqueKey.Columns)
-	this.applyColumnTypes(this.migrationContext.DatabaseName, this.migrationContext.GetGhostTableName(), this.migrationContext.GhostTableColumns, this.migrationContext.MappedSharedColumns)
+	this.getGhostTableInspector().applyColumnTypes(this.migrationContext.DatabaseName, this.migrationContext.GetGhostTableName(), this.migrationContext.GhostTableColumns, this.migrationContext.MappedSharedColumns)
 
 	for i := range this.migrationContext.SharedColumns.Columns() {
 		column := this.migrationContext.SharedColumns.Columns()[i]
@@ -208,7 +265,48 @@ func (this *Inspector) inspectOriginalAndGhostTables() (err error) {
 			return fmt.Errorf("No support at this time for converting a column from DATETIME to TIMESTAMP that is also part of the chosen unique key. Column: %s, key: %s", column.Name, this.migrationContext.UniqueKey.Name)
 		}
 	}
+	if err := this.validateColumnExpressions(); err != nil {
+		return err
+	}
+
+	return nil
+}
 
+// validateColumnExpressions makes sure each `--column-expression` populates an existing, non-virtual,
+// non-key ghost column, and that the expression itself is valid when evaluated over the original table.
+func (this *Inspector) validateColumnExpressions() error {
+	for columnName, expression := range this.migrationContext.ColumnExpressions {
+		var ghostColumn *sql.Column
+		for _, column := range this.migrationContext.GhostTableColumns.Columns() {
+			if strings.EqualFold(column.Name, columnName) {
+				ghostColumn = &column
+				break
+			}
+		}
+		if ghostColumn == nil {
+			return fmt.Errorf("Column expression given for %s, but no such column exists on ghost table", sql.EscapeName(columnName))
+		}
+		if this.migrationContext.GhostTableVirtualColumns.GetColumn(ghostColumn.Name) != nil {
+			return fmt.Errorf("Column expression given for %s, which is a generated column on ghost table", sql.EscapeName(columnName))
+		}
+		for i, mappedColumn := range this.migrationContext.MappedSharedColumns.Columns() {
+			if !strings.EqualFold(mappedColumn.Name, ghostColumn.Name) {
+				continue
+			}
+			if this.migrationContext.UniqueKey.Columns.GetColumn(this.migrationContext.SharedColumns.Columns()[i].Name) != nil {
+				return fmt.Errorf("Column expression given for %s, which is part of the chosen unique key %s", sql.EscapeName(columnName), this.migrationContext.UniqueKey.Name)
+			}
+		}
+		query := fmt.Sprintf(`select /* gh-ost */ (%s) from %s.%s limit 0`,
+			expression,
+			sql.EscapeName(this.migrationContext.DatabaseName),
+			sql.EscapeName(this.migrationContext.OriginalTableName),
+		)
+		if _, err := this.db.Exec(query); err != nil {
+			return this.log.Errorf("Invalid column expression for %s: %s; error: %+v", sql.EscapeName(columnName), expression, err)
+		}
+		this.log.Infof("Column %s will be populated by expression: %s", sql.EscapeName(columnName), expression)
+	}
 	return nil
 }
 
@@ -265,19 +363,19 @@ func (this *Inspector) validateGrants() error {
 	this.migrationContext.HasSuperPrivilege = foundSuper
 
 	if foundAll {
-		this.migrationContext.Log.Infof("User has ALL privileges")
+		this.log.Infof("User has ALL privileges")
 		return nil
 	}
 	if foundSuper && foundReplicationSlave && foundDBAll {
-		this.migrationContext.Log.Infof("User has SUPER, REPLICATION SLAVE privileges, and has ALL privileges on %s.*", sql.EscapeName(this.migrationContext.DatabaseName))
+		this.log.Infof("User has SUPER, REPLICATION SLAVE privileges, and has ALL privileges on %s.*", sql.EscapeName(this.migrationContext.DatabaseName))
 		return nil
 	}
 	if foundReplicationClient && foundReplicationSlave && foundDBAll {
-		this.migrationContext.Log.Infof("User has REPLICATION CLIENT, REPLICATION SLAVE privileges, and has ALL privileges on %s.*", sql.EscapeName(this.migrationContext.DatabaseName))
+		this.log.Infof("User has REPLICATION CLIENT, REPLICATION SLAVE privileges, and has ALL privileges on %s.*", sql.EscapeName(this.migrationContext.DatabaseName))
 		return nil
 	}
-	this.migrationContext.Log.Debugf("Privileges: Super: %t, REPLICATION CLIENT: %t, REPLICATION SLAVE: %t, ALL on *.*: %t, ALL on %s.*: %t", foundSuper, foundReplicationClient, foundReplicationSlave, foundAll, sql.EscapeName(this.migrationContext.DatabaseName), foundDBAll)
-	return this.migrationContext.Log.Errorf("User has insufficient privileges for migration. Needed: SUPER|REPLICATION CLIENT, REPLICATION SLAVE and ALL on %s.*", sql.EscapeName(this.migrationContext.DatabaseName))
+	this.log.Debugf("Privileges: Super: %t, REPLICATION CLIENT: %t, REPLICATION SLAVE: %t, ALL on *.*: %t, ALL on %s.*: %t", foundSuper, foundReplicationClient, foundReplicationSlave, foundAll, sql.EscapeName(this.migrationContext.DatabaseName), foundDBAll)
+	return this.log.Errorf("User has insufficient privileges for migration. Needed: SUPER|REPLICATION CLIENT, REPLICATION SLAVE and ALL on %s.*", sql.EscapeName(this.migrationContext.DatabaseName))
 }
 
 // restartReplication is required so that we are _certain_ the binlog format and
@@ -285,7 +383,7 @@ func (this *Inspector) validateGrants() error {
 // It is entirely possible, for example, that the replication is using 'STATEMENT'
 // binlog format even as the variable says 'ROW'
 func (this *Inspector) restartReplication() error {
-	this.migrationContext.Log.Infof("Restarting replication on %s to make sure binlog settings apply to replication thread", this.connectionConfig.Key.String())
+	this.log.Infof("Restarting replication on %s to make sure binlog settings apply to replication thread", this.connectionConfig.Key.String())
 
 	masterKey, _ := mysql.GetMasterKeyFromSlaveStatus(this.dbVersion, this.connectionConfig)
 	if masterKey == nil {
@@ -317,11 +415,11 @@ func (this *Inspector) restartReplication() error {
 		if time.Since(startTime) > startReplicationMaxWait {
 			return fmt.Errorf("Replication did not restart within the maximum wait time of %s", startReplicationMaxWait)
 		}
-		this.migrationContext.Log.Debugf("Replication not yet restarted, waiting...")
+		this.log.Debugf("Replication not yet restarted, waiting...")
 		time.Sleep(startReplicationPostWait)
 	}
 
-	this.migrationContext.Log.Debugf("Replication restarted")
+	this.log.Debugf("Replication restarted")
 	return nil
 }
 
@@ -365,7 +463,7 @@ func (this *Inspector) applyBinlogFormat() error {
 		if err := this.restartReplication(); err != nil {
 			return err
 		}
-		this.migrationContext.Log.Debugf("'ROW' binlog format applied")
+		this.log.Debugf("'ROW' binlog format applied")
 		return nil
 	}
 	// We already have RBR, no explicit switch
@@ -385,6 +483,9 @@ func (this *Inspector) validateBinlogs() error {
 		return err
 	}
 	if !hasBinaryLogs {
+		if this.migrationContext.ReplicationCluster == base.GaleraCluster {
+			return fmt.Errorf("%s must have binary logs enabled. Galera does not require them, but gh-ost reads the changes to the migrated table from them", this.connectionConfig.Key.String())
+		}
 		return fmt.Errorf("%s must have binary logs enabled", this.connectionConfig.Key.String())
 	}
 	if this.migrationContext.RequiresBinlogFormatChange() {
@@ -403,7 +504,7 @@ func (this *Inspector) validateBinlogs() error {
 		if countReplicas > 0 {
 			return fmt.Errorf("%s has %s binlog_format, but I'm too scared to change it to ROW because it has replicas. Bailing out", this.connectionConfig.Key.String(), this.migrationContext.OriginalBinlogFormat)
 		}
-		this.migrationContext.Log.Infof("%s has %s binlog_format. I will change it to ROW, and will NOT change it back, even in the event of failure.", this.connectionConfig.Key.String(), this.migrationContext.OriginalBinlogFormat)
+		this.log.Infof("%s has %s binlog_format. I will change it to ROW, and will NOT change it back, even in the event of failure.", this.connectionConfig.Key.String(), this.migrationContext.OriginalBinlogFormat)
 	}
 	query = `select /* gh-ost */ @@global.binlog_row_image`
 	if err := this.db.QueryRow(query).Scan(&this.migrationContext.OriginalBinlogRowImage); err != nil {
@@ -414,7 +515,7 @@ func (this *Inspector) validateBinlogs() error {
 		return fmt.Errorf("%s has '%s' binlog_row_image, and only 'FULL' is supported. This operation cannot proceed. You may `set global binlog_row_image='full'` and try again", this.connectionConfig.Key.String(), this.migrationContext.OriginalBinlogRowImage)
 	}
 
-	this.migrationContext.Log.Infof("binary logs validated on %s", this.connectionConfig.Key.String())
+	this.log.Infof("binary logs validated on %s", this.connectionConfig.Key.String())
 	return nil
 }
 
@@ -427,12 +528,12 @@ func (this *Inspector) validateLogSlaveUpdates() error {
 	}
 
 	if logSlaveUpdates {
-		this.migrationContext.Log.Infof("log_slave_updates validated on %s", this.connectionConfig.Key.String())
+		this.log.Infof("log_slave_updates validated on %s", this.connectionConfig.Key.String())
 		return nil
 	}
 
 	if this.migrationContext.IsTungsten {
-		this.migrationContext.Log.Warningf("log_slave_updates not found on %s, but --tungsten provided, so I'm proceeding", this.connectionConfig.Key.String())
+		this.log.Warningf("log_slave_updates not found on %s, but --tungsten provided, so I'm proceeding", this.connectionConfig.Key.String())
 		return nil
 	}
 
@@ -441,10 +542,14 @@ func (this *Inspector) validateLogSlaveUpdates() error {
 	}
 
 	if this.migrationContext.InspectorIsAlsoApplier() {
-		this.migrationContext.Log.Warningf("log_slave_updates not found on %s, but executing directly on master, so I'm proceeding", this.connectionConfig.Key.String())
+		this.log.Warningf("log_slave_updates not found on %s, but executing directly on master, so I'm proceeding", this.connectionConfig.Key.String())
 		return nil
 	}
 
+	if this.migrationContext.ReplicationCluster == base.GaleraCluster {
+		return fmt.Errorf("%s must have log_slave_updates enabled, for writes on %+v to reach its binary logs through Galera replication", this.connectionConfig.Key.String(), this.migrationContext.ApplierConnectionConfig.Key)
+	}
+
 	return fmt.Errorf("%s must have log_slave_updates enabled for executing migration", this.connectionConfig.Key.String())
 }
 
@@ -468,17 +573,17 @@ func (this *Inspector) validateTable() error {
 		return err
 	}
 	if !tableFound {
-		return this.migrationContext.Log.Errorf("Cannot find table %s.%s!", sql.EscapeName(this.migrationContext.DatabaseName), sql.EscapeName(this.migrationContext.OriginalTableName))
+		return this.log.Errorf("Cannot find table %s.%s!", sql.EscapeName(this.migrationContext.DatabaseName), sql.EscapeName(this.migrationContext.OriginalTableName))
 	}
-	this.migrationContext.Log.Infof("Table found. Engine=%s", this.migrationContext.TableEngine)
-	this.migrationContext.Log.Debugf("Estimated number of rows via STATUS: %d", this.migrationContext.RowsEstimate)
+	this.log.Infof("Table found. Engine=%s", this.migrationContext.TableEngine)
+	this.log.Debugf("Estimated number of rows via STATUS: %d", this.migrationContext.RowsEstimate)
 	return nil
 }
 
 // validateTableForeignKeys makes sure no foreign keys exist on the migrated table
 func (this *Inspector) validateTableForeignKeys(allowChildForeignKeys bool) error {
 	if this.migrationContext.SkipForeignKeyChecks {
-		this.migrationContext.Log.Warning("--skip-foreign-key-checks provided: will not check for foreign keys")
+		this.log.Warning("--skip-foreign-key-checks provided: will not check for foreign keys")
 		return nil
 	}
 	query := `
@@ -514,16 +619,16 @@ func (this *Inspector) validateTableForeignKeys(allowChildForeignKeys bool) erro
 		return err
 	}
 	if numParentForeignKeys > 0 {
-		return this.migrationContext.Log.Errorf("Found %d parent-side foreign keys on %s.%s. Parent-side foreign keys are not supported. Bailing out", numParentForeignKeys, sql.EscapeName(this.migrationContext.DatabaseName), sql.EscapeName(this.migrationContext.OriginalTableName))
+		return this.log.Errorf("Found %d parent-side foreign keys on %s.%s. Parent-side foreign keys are not supported. Bailing out", numParentForeignKeys, sql.EscapeName(this.migrationContext.DatabaseName), sql.EscapeName(this.migrationContext.OriginalTableName))
 	}
 	if numChildForeignKeys > 0 {
 		if allowChildForeignKeys {
-			this.migrationContext.Log.Debugf("Foreign keys found and will be dropped, as per given --discard-foreign-keys flag")
+			this.log.Debugf("Foreign keys found and will be dropped, as per given --discard-foreign-keys flag")
 			return nil
 		}
-		return this.migrationContext.Log.Errorf("Found %d child-side foreign keys on %s.%s. Child-side foreign keys are not supported. Bailing out", numChildForeignKeys, sql.EscapeName(this.migrationContext.DatabaseName), sql.EscapeName(this.migrationContext.OriginalTableName))
+		return this.log.Errorf("Found %d child-side foreign keys on %s.%s. Child-side foreign keys are not supported. Bailing out", numChildForeignKeys, sql.EscapeName(this.migrationContext.DatabaseName), sql.EscapeName(this.migrationContext.OriginalTableName))
 	}
-	this.migrationContext.Log.Debugf("Validated no foreign keys exist on table")
+	this.log.Debugf("Validated no foreign keys exist on table")
 	return nil
 }
 
@@ -550,7 +655,7 @@ func (this *Inspector) validateTableTriggers() error {
 	}
 	if numTriggers > 0 {
 		if this.migrationContext.IncludeTriggers {
-			this.migrationContext.Log.Infof("Found %d triggers on %s.%s.", numTriggers, sql.EscapeName(this.migrationContext.DatabaseName), sql.EscapeName(this.migrationContext.OriginalTableName))
+			this.log.Infof("Found %d triggers on %s.%s.", numTriggers, sql.EscapeName(this.migrationContext.DatabaseName), sql.EscapeName(this.migrationContext.OriginalTableName))
 			this.migrationContext.Triggers, err = mysql.GetTriggers(this.db, this.migrationContext.DatabaseName, this.migrationContext.OriginalTableName)
 			if err != nil {
 				return err
@@ -563,9 +668,9 @@ func (this *Inspector) validateTableTriggers() error {
 			}
 			return nil
 		}
-		return this.migrationContext.Log.Errorf("Found triggers on %s.%s. Tables with triggers are supported only when using \"include-triggers\" flag. Bailing out", sql.EscapeName(this.migrationContext.DatabaseName), sql.EscapeName(this.migrationContext.OriginalTableName))
+		return this.log.Errorf("Found triggers on %s.%s. Tables with triggers are supported only when using \"include-triggers\" flag. Bailing out", sql.EscapeName(this.migrationContext.DatabaseName), sql.EscapeName(this.migrationContext.OriginalTableName))
 	}
-	this.migrationContext.Log.Debugf("Validated no triggers exist on table")
+	this.log.Debugf("Validated no triggers exist on table")
 	return nil
 }
 
@@ -592,7 +697,7 @@ func (this *Inspector) validateGhostTriggersDontExist() error {
 			}
 		}
 		if len(foundTriggers) > 0 {
-			return this.migrationContext.Log.Errorf("Found gh-ost triggers (%s). Please use a different suffix or drop them. Bailing out", strings.Join(foundTriggers, ","))
+			return this.log.Errorf("Found gh-ost triggers (%s). Please use a different suffix or drop them. Bailing out", strings.Join(foundTriggers, ","))
 		}
 	}
 
@@ -609,7 +714,7 @@ func (this *Inspector) validateGhostTriggersLength() error {
 			}
 		}
 		if len(foundTriggers) > 0 {
-			return this.migrationContext.Log.Errorf("Gh-ost triggers (%s) length > %d characters. Bailing out", strings.Join(foundTriggers, ","), mysql.MaxTableNameLength)
+			return this.log.Errorf("Gh-ost triggers (%s) length > %d characters. Bailing out", strings.Join(foundTriggers, ","), mysql.MaxTableNameLength)
 		}
 	}
 	return nil
@@ -631,9 +736,9 @@ func (this *Inspector) estimateTableRowsViaExplain() error {
 		return err
 	}
 	if !outputFound {
-		return this.migrationContext.Log.Errorf("Cannot run EXPLAIN on %s.%s!", sql.EscapeName(this.migrationContext.DatabaseName), sql.EscapeName(this.migrationContext.OriginalTableName))
+		return this.log.Errorf("Cannot run EXPLAIN on %s.%s!", sql.EscapeName(this.migrationContext.DatabaseName), sql.EscapeName(this.migrationContext.OriginalTableName))
 	}
-	this.migrationContext.Log.Infof("Estimated number of rows via EXPLAIN: %d", this.migrationContext.RowsEstimate)
+	this.log.Infof("Estimated number of rows via EXPLAIN: %d", this.migrationContext.RowsEstimate)
 	return nil
 }
 
@@ -642,7 +747,7 @@ func (this *Inspector) CountTableRows(ctx context.Context) error {
 	atomic.StoreInt64(&this.migrationContext.CountingRowsFlag, 1)
 	defer atomic.StoreInt64(&this.migrationContext.CountingRowsFlag, 0)
 
-	this.migrationContext.Log.Infof("As instructed, I'm issuing a SELECT COUNT(*) on the table. This may take a while")
+	this.log.Infof("As instructed, I'm issuing a SELECT COUNT(*) on the table. This may take a while")
 
 	conn, err := this.db.Conn(ctx)
 	if err != nil {
@@ -659,7 +764,7 @@ func (this *Inspector) CountTableRows(ctx context.Context) error {
 	var rowsEstimate int64
 	if err := conn.QueryRowContext(ctx, query).Scan(&rowsEstimate); err != nil {
 		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
-			this.migrationContext.Log.Infof("exact row count cancelled (%s), likely because I'm about to cut over. I'm going to kill that query.", ctx.Err())
+			this.log.Infof("exact row count cancelled (%s), likely because I'm about to cut over. I'm going to kill that query.", ctx.Err())
 			return mysql.Kill(this.db, connectionID)
 		}
 		return err
@@ -672,7 +777,7 @@ func (this *Inspector) CountTableRows(ctx context.Context) error {
 	atomic.StoreInt64(&this.migrationContext.RowsEstimate, rowsEstimate)
 	this.migrationContext.UsedRowsEstimateMethod = base.CountRowsEstimate
 
-	this.migrationContext.Log.Infof("Exact number of rows via COUNT: %d", rowsEstimate)
+	this.log.Infof("Exact number of rows via COUNT: %d", rowsEstimate)
 
 	return nil
 }
@@ -697,6 +802,7 @@ func (this *Inspector) applyColumnTypes(databaseName, tableName string, columnsL
 				continue
 			}
 
+			column.MySQLType = columnType
 			if strings.Contains(columnType, "unsigned") {
 				column.IsUnsigned = true
 			}
@@ -830,7 +936,7 @@ func (this *Inspector) getCandidateUniqueKeys(tableName string) (uniqueKeys [](*
 	if err != nil {
 		return uniqueKeys, err
 	}
-	this.migrationContext.Log.Debugf("Potential unique keys in %+v: %+v", tableName, uniqueKeys)
+	this.log.Debugf("Potential unique keys in %+v: %+v", tableName, uniqueKeys)
 	return uniqueKeys, nil
 }
 
@@ -926,10 +1032,177 @@ func (this *Inspector) readChangelogState(hint string) (string, error) {
 	return result, err
 }
 
+// detectReplicationCluster checks whether the inspected server is a member of a Group Replication
+// or a Galera cluster. Such members replicate from one another outside of the classic replication
+// topology, and thus call for their own validations and throttling.
+func (this *Inspector) detectReplicationCluster() error {
+	var galeraClusterSize int64
+	err := sqlutils.QueryRowsMap(this.db, `show /* gh-ost */ global status like 'wsrep_cluster_size'`, func(rowMap sqlutils.RowMap) error {
+		galeraClusterSize = rowMap.GetInt64("Value")
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if galeraClusterSize > 0 {
+		this.migrationContext.ReplicationCluster = base.GaleraCluster
+		this.log.Infof("%s is a member of a Galera cluster of %d members", this.connectionConfig.Key.String(), galeraClusterSize)
+		return nil
+	}
+
+	query := `select /* gh-ost */ count(*) from performance_schema.replication_group_members where member_state = 'ONLINE'`
+	var groupReplicationMembers int64
+	if err := this.db.QueryRow(query).Scan(&groupReplicationMembers); err != nil {
+		// performance_schema is disabled, or the server predates Group Replication
+		this.log.Debugf("Cannot read Group Replication members on %s: %+v", this.connectionConfig.Key.String(), err)
+		return nil
+	}
+	if groupReplicationMembers > 0 {
+		this.migrationContext.ReplicationCluster = base.GroupReplicationCluster
+		this.log.Infof("%s is a member of a Group Replication cluster of %d online members", this.connectionConfig.Key.String(), groupReplicationMembers)
+	}
+	return nil
+}
+
+// getGroupReplicationPrimaryConnectionConfig returns the connection config of the group's primary in
+// single-primary mode. In multi-primary mode, any member may apply the migration; that member is the
+// inspected server itself.
+func (this *Inspector) getGroupReplicationPrimaryConnectionConfig() (applierConfig *mysql.ConnectionConfig, err error) {
+	query := `
+		select /* gh-ost */
+			member_host,
+			member_port,
+			member_id = @@global.server_uuid as is_self
+		from
+			performance_schema.replication_group_members
+		where
+			member_role = 'PRIMARY'
+			and member_state = 'ONLINE'
+	`
+	var primaryKeys []mysql.InstanceKey
+	isSelf := false
+	err = sqlutils.QueryRowsMap(this.db, query, func(rowMap sqlutils.RowMap) error {
+		primaryKeys = append(primaryKeys, mysql.InstanceKey{Hostname: rowMap.GetString("member_host"), Port: rowMap.GetInt("member_port")})
+		isSelf = rowMap.GetBool("is_self")
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case len(primaryKeys) == 0:
+		return nil, fmt.Errorf("Cannot find an online primary in the Group Replication cluster of %s", this.connectionConfig.Key.String())
+	case len(primaryKeys) > 1:
+		this.log.Infof("Group Replication cluster is in multi-primary mode; applying on %s", this.connectionConfig.Key.String())
+		return this.connectionConfig, nil
+	case isSelf:
+		return this.connectionConfig, nil
+	}
+	applierConfig = this.connectionConfig.DuplicateCredentials(primaryKeys[0])
+	if err := applierConfig.RegisterTLSConfig(); err != nil {
+		return nil, err
+	}
+	return applierConfig, nil
+}
+
 func (this *Inspector) getMasterConnectionConfig() (applierConfig *mysql.ConnectionConfig, err error) {
-	this.migrationContext.Log.Infof("Recursively searching for replication master")
+	switch this.migrationContext.ReplicationCluster {
+	case base.GroupReplicationCluster:
+		// Members replicate via the group_replication_applier channel, which has no master to follow
+		this.log.Infof("Searching for Group Replication primary")
+		return this.getGroupReplicationPrimaryConnectionConfig()
+	case base.GaleraCluster:
+		// All members are writable, and none replicates from another
+		return this.connectionConfig, nil
+	}
+	this.log.Infof("Recursively searching for replication master")
 	visitedKeys := mysql.NewInstanceKeyMap()
-	return mysql.GetMasterConnectionConfigSafe(this.dbVersion, this.connectionConfig, visitedKeys, this.migrationContext.AllowedMasterMaster)
+	applierConfig, err = mysql.GetMasterConnectionConfigSafe(this.dbVersion, this.connectionConfig, visitedKeys, this.migrationContext.AllowedMasterMaster)
+	if err != nil {
+		return nil, err
+	}
+	this.log.Debugf("%s of %+v is %+v", mysql.ReplicaTermFor(this.dbVersion, "master"), this.connectionConfig.Key, applierConfig.Key)
+	return applierConfig, nil
+}
+
+// readBinlogExpireSeconds reads the time after which binary logs expire on the inspected server, or 0
+// when they do not
+func (this *Inspector) readBinlogExpireSeconds() (expireSeconds int64, err error) {
+	err = this.db.QueryRow(`select /* gh-ost */ @@global.binlog_expire_logs_seconds`).Scan(&expireSeconds)
+	if err == nil && expireSeconds > 0 {
+		return expireSeconds, nil
+	}
+	// 5.7 only supports expire_logs_days, which 8.0 deprecates in favor of binlog_expire_logs_seconds
+	var expireDays float64
+	if daysErr := this.db.QueryRow(`select /* gh-ost */ @@global.expire_logs_days`).Scan(&expireDays); daysErr != nil {
+		return expireSeconds, err
+	}
+	return int64(expireDays * 24 * 60 * 60), nil
+}
+
+// readBinaryLogs lists the binary logs on the inspected server, oldest first
+func (this *Inspector) readBinaryLogs() (logFiles []string, err error) {
+	err = sqlutils.QueryRowsMap(this.db, `show /* gh-ost */ binary logs`, func(rowMap sqlutils.RowMap) error {
+		logFiles = append(logFiles, rowMap.GetString("Log_name"))
+		return nil
+	})
+	return logFiles, err
+}
+
+// recordBinlogRotations records the time at which binary logs were first found followed by another;
+// that is, the time by which they were rotated. Rotations which took place before the first
+// check are unknown, and recorded as the zero time.
+func (this *Inspector) recordBinlogRotations(logFiles []string, now time.Time) {
+	initialized := this.binlogRotations != nil
+	rotations := make(map[string]time.Time)
+	for i := 0; i < len(logFiles)-1; i++ {
+		rotatedAt, found := this.binlogRotations[logFiles[i]]
+		if !found && initialized {
+			rotatedAt = now
+		}
+		rotations[logFiles[i]] = rotatedAt
+	}
+	this.binlogRotations = rotations
+}
+
+// estimateBinlogPurge estimates how long until the given binary log, from which events written at
+// eventTime are being read, expires and is purged. A binary log expires some time after it is
+// rotated, and it is rotated after the events it holds are written. purgeExpected is false when
+// binary logs do not expire, or when the given log is yet to be rotated. An error is returned when
+// the given log has already been purged.
+func (this *Inspector) estimateBinlogPurge(logFile string, eventTime time.Time) (untilPurge time.Duration, purgeExpected bool, err error) {
+	expireSeconds, err := this.readBinlogExpireSeconds()
+	if err != nil {
+		return untilPurge, false, err
+	}
+	logFiles, err := this.readBinaryLogs()
+	if err != nil {
+		return untilPurge, false, err
+	}
+	now := time.Now()
+	this.recordBinlogRotations(logFiles, now)
+
+	logFileIndex := -1
+	for i, existingLogFile := range logFiles {
+		if existingLogFile == logFile {
+			logFileIndex = i
+		}
+	}
+	if logFileIndex < 0 {
+		return untilPurge, true, fmt.Errorf("binary log %s has been purged from %s", logFile, this.connectionConfig.Key.String())
+	}
+	if expireSeconds == 0 || logFileIndex == len(logFiles)-1 {
+		return untilPurge, false, nil
+	}
+	rotatedAt := this.binlogRotations[logFile]
+	if eventTime.After(rotatedAt) {
+		rotatedAt = eventTime
+	}
+	if rotatedAt.IsZero() {
+		// No events read yet, nor was the rotation observed
+		return untilPurge, false, nil
+	}
+	return rotatedAt.Add(time.Duration(expireSeconds) * time.Second).Sub(now), true, nil
 }
 
 func (this *Inspector) getReplicationLag() (replicationLag time.Duration, err error) {
@@ -943,4 +1216,7 @@ func (this *Inspector) getReplicationLag() (replicationLag time.Duration, err er
 func (this *Inspector) Teardown() {
 	this.db.Close()
 	this.informationSchemaDb.Close()
+	if this.ghostTableInspector != nil {
+		this.ghostTableInspector.Teardown()
+	}
 }
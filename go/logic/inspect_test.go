@@ -7,6 +7,7 @@ package logic
 
 import (
 	"testing"
+	"time"
 
 	"github.com/github/gh-ost/go/sql"
 	"github.com/stretchr/testify/require"
@@ -30,3 +31,25 @@ func TestInspectGetSharedUniqueKeys(t *testing.T) {
 	require.Equal(t, "id,org_id", sharedUniqKeys[1].Columns.String())
 	require.Equal(t, "id", sharedUniqKeys[2].Columns.String())
 }
+
+func TestInspectRecordBinlogRotations(t *testing.T) {
+	inspector := &Inspector{}
+	startTime := time.Now()
+
+	// Rotations prior to the first check are unknown
+	inspector.recordBinlogRotations([]string{"mysql-bin.000001", "mysql-bin.000002"}, startTime)
+	require.Len(t, inspector.binlogRotations, 1)
+	require.True(t, inspector.binlogRotations["mysql-bin.000001"].IsZero())
+
+	rotationTime := startTime.Add(time.Minute)
+	inspector.recordBinlogRotations([]string{"mysql-bin.000001", "mysql-bin.000002", "mysql-bin.000003"}, rotationTime)
+	require.Len(t, inspector.binlogRotations, 2)
+	require.True(t, inspector.binlogRotations["mysql-bin.000001"].IsZero())
+	require.Equal(t, rotationTime, inspector.binlogRotations["mysql-bin.000002"])
+
+	// Purged binary logs are forgotten, and rotations are recorded once
+	inspector.recordBinlogRotations([]string{"mysql-bin.000002", "mysql-bin.000003", "mysql-bin.000004"}, rotationTime.Add(time.Minute))
+	require.Len(t, inspector.binlogRotations, 2)
+	require.Equal(t, rotationTime, inspector.binlogRotations["mysql-bin.000002"])
+	require.Equal(t, rotationTime.Add(time.Minute), inspector.binlogRotations["mysql-bin.000003"])
+}
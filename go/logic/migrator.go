@@ -7,12 +7,14 @@ package logic
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"math"
 	"os"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -66,6 +68,14 @@ const (
 	ForcePrintStatusRule                        = iota
 	ForcePrintStatusOnlyRule                    = iota
 	ForcePrintStatusAndHintRule                 = iota
+	// ForcePrintStatusJSONRule prints the status as a JSON object, onto the given writers only
+	ForcePrintStatusJSONRule = iota
+)
+
+const (
+	cutOverIdle int64 = iota
+	cutOverAttempting
+	cutOverAbandoned
 )
 
 // Migrator is the main schema migration flow manager.
@@ -77,8 +87,11 @@ type Migrator struct {
 	eventsStreamer   *EventsStreamer
 	server           *Server
 	throttler        *Throttler
+	shadowVerifier   *ShadowVerifier
+	spillQueue       *SpillQueue
 	hooksExecutor    *HooksExecutor
 	migrationContext *base.MigrationContext
+	log              base.Logger
 
 	firstThrottlingCollected   chan bool
 	ghostTableMigrated         chan bool
@@ -93,7 +106,31 @@ type Migrator struct {
 
 	handledChangelogStates map[string]bool
 
+	cutOverBlockersDetectable bool
+	// cutOverState is cutOverAttempting throughout a cut-over attempt, and cutOverAbandoned once
+	// the migration aborts ahead of the cut-over, e.g. as the --cut-over-deadline passed, after
+	// which no attempt is made
+	cutOverState int64
+	// cutOverAttempts counts the cut-over attempts made
+	cutOverAttempts int64
+	// dropTablesOnAbort is set when the migration aborts on --cut-over-deadline or on critical free
+	// disk space: the ghost and changelog tables are then dropped as the migration tears down, once
+	// writes onto them stopped
+	dropTablesOnAbort int64
+	// writeFuncsDone is closed once executeWriteFuncs returns, when started
+	writeFuncsDone chan struct{}
+
 	finishedMigrating int64
+
+	// exitOnPanicAbort has the process exit upon PanicAbort. When unset, as for embedded
+	// migrations, the migration is aborted instead, and Migrate returns the error.
+	exitOnPanicAbort bool
+	// aborted is closed once the migration is aborted; abortError is why
+	aborted    chan struct{}
+	abortOnce  *sync.Once
+	abortError error
+	// migrated is closed as Migrate returns
+	migrated chan struct{}
 }
 
 func NewMigrator(context *base.MigrationContext, appVersion string) *Migrator {
@@ -101,6 +138,7 @@ func NewMigrator(context *base.MigrationContext, appVersion string) *Migrator {
 		appVersion:                 appVersion,
 		hooksExecutor:              NewHooksExecutor(context),
 		migrationContext:           context,
+		log:                        context.ComponentLog("migrator"),
 		parser:                     sql.NewAlterTableParser(),
 		ghostTableMigrated:         make(chan bool),
 		firstThrottlingCollected:   make(chan bool, 3),
@@ -111,10 +149,44 @@ func NewMigrator(context *base.MigrationContext, appVersion string) *Migrator {
 		applyEventsQueue:       make(chan *applyEventStruct, base.MaxEventsBatchSize),
 		handledChangelogStates: make(map[string]bool),
 		finishedMigrating:      0,
+
+		exitOnPanicAbort: true,
+		aborted:          make(chan struct{}),
+		abortOnce:        &sync.Once{},
+		migrated:         make(chan struct{}),
 	}
 	return migrator
 }
 
+// NewEmbeddedMigrator creates a migrator for running within another program: rather than have the
+// process exit upon panic, it aborts the migration, and Migrate returns the error.
+func NewEmbeddedMigrator(context *base.MigrationContext, appVersion string) *Migrator {
+	migrator := NewMigrator(context, appVersion)
+	migrator.exitOnPanicAbort = false
+	return migrator
+}
+
+// Abort aborts the migration: Migrate tears down and returns the given error as soon as it reaches
+// a point it can stop at, such as in between chunks, retries or cut-over attempts. Only the first
+// abort counts.
+func (this *Migrator) Abort(err error) {
+	this.abortOnce.Do(func() {
+		this.abortError = err
+		close(this.aborted)
+		this.migrationContext.ClosePanicAbort()
+	})
+}
+
+// abortedError returns the error the migration was aborted with, or nil when it was not aborted
+func (this *Migrator) abortedError() error {
+	select {
+	case <-this.aborted:
+		return this.abortError
+	default:
+		return nil
+	}
+}
+
 // sleepWhileTrue sleeps indefinitely until the given function returns 'false'
 // (or fails with error)
 func (this *Migrator) sleepWhileTrue(operation func() (bool, error)) error {
@@ -126,10 +198,55 @@ func (this *Migrator) sleepWhileTrue(operation func() (bool, error)) error {
 		if !shouldSleep {
 			return nil
 		}
+		if err := this.abortedError(); err != nil {
+			return err
+		}
 		time.Sleep(time.Second)
 	}
 }
 
+// gateAbortError is returned when a gating hook says to abort the migration
+type gateAbortError struct {
+	gate    string
+	message string
+}
+
+func (this *gateAbortError) Error() string {
+	return fmt.Sprintf("%s gate aborted the migration: %s", this.gate, this.message)
+}
+
+// passGate asks the gating hooks of the given gate whether to proceed, asking again for as long as
+// they say to wait. onWait, if given, is called before each wait.
+func (this *Migrator) passGate(gate string, askGate func(attempt int64) (*GateResponse, error), onWait func()) error {
+	for attempt := int64(1); ; attempt++ {
+		response, err := askGate(attempt)
+		if err != nil {
+			return err
+		}
+		switch response.Decision {
+		case GateProceed:
+			if attempt > 1 {
+				this.log.Infof("%s gate says proceed: %s", gate, response.Message)
+			}
+			return nil
+		case GateAbort:
+			return &gateAbortError{gate: gate, message: response.Message}
+		}
+		this.log.Infof("%s gate says wait %+v: %s", gate, response.WaitDuration(), response.Message)
+		if onWait != nil {
+			onWait()
+		}
+		if atomic.LoadInt64(&this.finishedMigrating) > 0 {
+			return fmt.Errorf("%s gate: migration finished while waiting", gate)
+		}
+		select {
+		case <-time.After(response.WaitDuration()):
+		case <-this.aborted:
+			return this.abortError
+		}
+	}
+}
+
 // retryOperation attempts up to `count` attempts at running given function,
 // exiting as soon as it returns with non-error.
 func (this *Migrator) retryOperation(operation func() error, notFatalHint ...bool) (err error) {
@@ -139,14 +256,21 @@ func (this *Migrator) retryOperation(operation func() error, notFatalHint ...boo
 			// sleep after previous iteration
 			RetrySleepFn(1 * time.Second)
 		}
+		if err := this.abortedError(); err != nil {
+			return err
+		}
 		err = operation()
 		if err == nil {
 			return nil
 		}
+		if abortErr := this.abortedError(); abortErr != nil {
+			// No use retrying an aborted migration
+			return abortErr
+		}
 		// there's an error. Let's try again.
 	}
 	if len(notFatalHint) == 0 {
-		this.migrationContext.PanicAbort <- err
+		this.migrationContext.SendPanicAbort(err)
 	}
 	return err
 }
@@ -168,32 +292,47 @@ func (this *Migrator) retryOperationWithExponentialBackoff(operation func() erro
 		if i != 0 {
 			RetrySleepFn(time.Duration(interval) * time.Second)
 		}
+		if err := this.abortedError(); err != nil {
+			return err
+		}
 		err = operation()
 		if err == nil {
 			return nil
 		}
+		if abortErr := this.abortedError(); abortErr != nil {
+			// No use retrying an aborted migration
+			return abortErr
+		}
 	}
 	if len(notFatalHint) == 0 {
-		this.migrationContext.PanicAbort <- err
+		this.migrationContext.SendPanicAbort(err)
 	}
 	return err
 }
 
 // consumeRowCopyComplete blocks on the rowCopyComplete channel once, and then
 // consumes and drops any further incoming events that may be left hanging.
-func (this *Migrator) consumeRowCopyComplete() {
-	if err := <-this.rowCopyComplete; err != nil {
-		this.migrationContext.PanicAbort <- err
+// It returns an error when row copy fails, or the migration is aborted, meanwhile.
+func (this *Migrator) consumeRowCopyComplete() error {
+	select {
+	case err := <-this.rowCopyComplete:
+		if err != nil {
+			this.migrationContext.SendPanicAbort(err)
+			return err
+		}
+	case <-this.aborted:
+		return this.abortError
 	}
 	atomic.StoreInt64(&this.rowCopyCompleteFlag, 1)
 	this.migrationContext.MarkRowCopyEndTime()
 	go func() {
 		for err := range this.rowCopyComplete {
 			if err != nil {
-				this.migrationContext.PanicAbort <- err
+				this.migrationContext.SendPanicAbort(err)
 			}
 		}
 	}()
+	return nil
 }
 
 func (this *Migrator) canStopStreaming() bool {
@@ -216,7 +355,7 @@ func (this *Migrator) onChangelogEvent(dmlEvent *binlog.BinlogDMLEvent) (err err
 func (this *Migrator) onChangelogStateEvent(dmlEvent *binlog.BinlogDMLEvent) (err error) {
 	changelogStateString := dmlEvent.NewColumnValues.StringColumn(3)
 	changelogState := ReadChangelogState(changelogStateString)
-	this.migrationContext.Log.Infof("Intercepted changelog state %s", changelogState)
+	this.log.Infof("Intercepted changelog state %s", changelogState)
 	switch changelogState {
 	case Migrated, ReadMigrationRangeValues:
 		// no-op event
@@ -233,12 +372,14 @@ func (this *Migrator) onChangelogStateEvent(dmlEvent *binlog.BinlogDMLEvent) (er
 		// So as not to create a potential deadlock, we write this func to applyEventsQueue
 		// asynchronously, understanding it doesn't really matter.
 		go func() {
-			this.applyEventsQueue <- newApplyEventStructByFunc(&applyEventFunc)
+			if err := this.enqueueApplyEvent(newApplyEventStructByFunc(&applyEventFunc)); err != nil {
+				this.migrationContext.SendPanicAbort(err)
+			}
 		}()
 	default:
 		return fmt.Errorf("Unknown changelog state: %+v", changelogState)
 	}
-	this.migrationContext.Log.Infof("Handled changelog state %s", changelogState)
+	this.log.Infof("Handled changelog state %s", changelogState)
 	return nil
 }
 
@@ -247,7 +388,7 @@ func (this *Migrator) onChangelogHeartbeatEvent(dmlEvent *binlog.BinlogDMLEvent)
 
 	heartbeatTime, err := time.Parse(time.RFC3339Nano, changelogHeartbeatString)
 	if err != nil {
-		return this.migrationContext.Log.Errore(err)
+		return this.log.Errore(err)
 	} else {
 		this.migrationContext.SetLastHeartbeatOnChangelogTime(heartbeatTime)
 		return nil
@@ -256,8 +397,25 @@ func (this *Migrator) onChangelogHeartbeatEvent(dmlEvent *binlog.BinlogDMLEvent)
 
 // listenOnPanicAbort aborts on abort request
 func (this *Migrator) listenOnPanicAbort() {
-	err := <-this.migrationContext.PanicAbort
-	this.migrationContext.Log.Fatale(err)
+	select {
+	case err := <-this.migrationContext.PanicAbort:
+		if this.exitOnPanicAbort {
+			this.log.Fatale(err)
+		}
+		this.log.Errore(err)
+		this.Abort(err)
+	case <-this.aborted:
+	case <-this.migrated:
+		return
+	}
+	// Further panics, as of goroutines failing while the migration aborts, are dropped
+	for {
+		select {
+		case <-this.migrationContext.PanicAbort:
+		case <-this.migrated:
+			return
+		}
+	}
 }
 
 // validateAlterStatement validates the `alter` statement meets criteria.
@@ -273,7 +431,7 @@ func (this *Migrator) validateAlterStatement() (err error) {
 		if !this.migrationContext.ApproveRenamedColumns {
 			return fmt.Errorf("gh-ost believes the ALTER statement renames columns, as follows: %v; as precaution, you are asked to confirm gh-ost is correct, and provide with `--approve-renamed-columns`, and we're all happy. Or you can skip renamed columns via `--skip-renamed-columns`, in which case column data may be lost", this.parser.GetNonTrivialRenames())
 		}
-		this.migrationContext.Log.Infof("Alter statement has column(s) renamed. gh-ost finds the following renames: %v; --approve-renamed-columns is given and so migration proceeds.", this.parser.GetNonTrivialRenames())
+		this.log.Infof("Alter statement has column(s) renamed. gh-ost finds the following renames: %v; --approve-renamed-columns is given and so migration proceeds.", this.parser.GetNonTrivialRenames())
 	}
 	this.migrationContext.DroppedColumnsMap = this.parser.DroppedColumnsMap()
 	return nil
@@ -285,7 +443,7 @@ func (this *Migrator) countTableRows() (err error) {
 		return nil
 	}
 	if this.migrationContext.Noop {
-		this.migrationContext.Log.Debugf("Noop operation; not really counting table rows")
+		this.log.Debugf("Noop operation; not really counting table rows")
 		return nil
 	}
 
@@ -304,7 +462,7 @@ func (this *Migrator) countTableRows() (err error) {
 		rowCountContext, rowCountCancel := context.WithCancel(context.Background())
 		this.migrationContext.SetCountTableRowsCancelFunc(rowCountCancel)
 
-		this.migrationContext.Log.Infof("As instructed, counting rows in the background; meanwhile I will use an estimated count, and will update it later on")
+		this.log.Infof("As instructed, counting rows in the background; meanwhile I will use an estimated count, and will update it later on")
 		go countRowsFunc(rowCountContext)
 
 		// and we ignore errors, because this turns to be a background job
@@ -317,9 +475,9 @@ func (this *Migrator) createFlagFiles() (err error) {
 	if this.migrationContext.PostponeCutOverFlagFile != "" {
 		if !base.FileExists(this.migrationContext.PostponeCutOverFlagFile) {
 			if err := base.TouchFile(this.migrationContext.PostponeCutOverFlagFile); err != nil {
-				return this.migrationContext.Log.Errorf("--postpone-cut-over-flag-file indicated by gh-ost is unable to create said file: %s", err.Error())
+				return this.log.Errorf("--postpone-cut-over-flag-file indicated by gh-ost is unable to create said file: %s", err.Error())
 			}
-			this.migrationContext.Log.Infof("Created postpone-cut-over-flag-file: %s", this.migrationContext.PostponeCutOverFlagFile)
+			this.log.Infof("Created postpone-cut-over-flag-file: %s", this.migrationContext.PostponeCutOverFlagFile)
 		}
 	}
 	return nil
@@ -327,12 +485,14 @@ func (this *Migrator) createFlagFiles() (err error) {
 
 // Migrate executes the complete migration logic. This is *the* major gh-ost function.
 func (this *Migrator) Migrate() (err error) {
-	this.migrationContext.Log.Infof("Migrating %s.%s", sql.EscapeName(this.migrationContext.DatabaseName), sql.EscapeName(this.migrationContext.OriginalTableName))
+	this.log.Infof("Migrating %s.%s", sql.EscapeName(this.migrationContext.DatabaseName), sql.EscapeName(this.migrationContext.OriginalTableName))
 	this.migrationContext.StartTime = time.Now()
 	if this.migrationContext.Hostname, err = os.Hostname(); err != nil {
 		return err
 	}
 
+	defer close(this.migrated)
+	defer this.migrationContext.ClosePanicAbort()
 	go this.listenOnPanicAbort()
 
 	if err := this.hooksExecutor.onStartup(); err != nil {
@@ -349,6 +509,7 @@ func (this *Migrator) Migrate() (err error) {
 	//   so we don't leave things hanging around
 	defer this.teardown()
 
+	this.migrationContext.SetPhase(base.InspectionPhase)
 	if err := this.initiateInspector(); err != nil {
 		return err
 	}
@@ -365,9 +526,9 @@ func (this *Migrator) Migrate() (err error) {
 	// Attempt to do this if AttemptInstantDDL is set.
 	if this.migrationContext.AttemptInstantDDL {
 		if this.migrationContext.Noop {
-			this.migrationContext.Log.Debugf("Noop operation; not really attempting instant DDL")
+			this.log.Debugf("Noop operation; not really attempting instant DDL")
 		} else {
-			this.migrationContext.Log.Infof("Attempting to execute alter with ALGORITHM=INSTANT")
+			this.log.Infof("Attempting to execute alter with ALGORITHM=INSTANT")
 			if err := this.applier.AttemptInstantDDL(); err == nil {
 				if err := this.finalCleanup(); err != nil {
 					return nil
@@ -375,18 +536,23 @@ func (this *Migrator) Migrate() (err error) {
 				if err := this.hooksExecutor.onSuccess(); err != nil {
 					return err
 				}
-				this.migrationContext.Log.Infof("Success! table %s.%s migrated instantly", sql.EscapeName(this.migrationContext.DatabaseName), sql.EscapeName(this.migrationContext.OriginalTableName))
+				this.migrationContext.SetPhase(base.DonePhase)
+				this.log.Infof("Success! table %s.%s migrated instantly", sql.EscapeName(this.migrationContext.DatabaseName), sql.EscapeName(this.migrationContext.OriginalTableName))
 				return nil
 			} else {
-				this.migrationContext.Log.Infof("ALGORITHM=INSTANT not supported for this operation, proceeding with original algorithm: %s", err)
+				this.log.Infof("ALGORITHM=INSTANT not supported for this operation, proceeding with original algorithm: %s", err)
 			}
 		}
 	}
 
 	initialLag, _ := this.inspector.getReplicationLag()
-	this.migrationContext.Log.Infof("Waiting for ghost table to be migrated. Current lag is %+v", initialLag)
-	<-this.ghostTableMigrated
-	this.migrationContext.Log.Debugf("ghost table migrated")
+	this.log.Infof("Waiting for ghost table to be migrated. Current lag is %+v", initialLag)
+	select {
+	case <-this.ghostTableMigrated:
+	case <-this.aborted:
+		return this.abortError
+	}
+	this.log.Debugf("ghost table migrated")
 	// Yay! We now know the Ghost and Changelog tables are good to examine!
 	// When running on replica, this means the replica has those tables. When running
 	// on master this is always true, of course, and yet it also implies this knowledge
@@ -398,6 +564,9 @@ func (this *Migrator) Migrate() (err error) {
 	if err := this.applier.prepareQueries(); err != nil {
 		return err
 	}
+	if err := this.initiateShadowVerifier(); err != nil {
+		return err
+	}
 	// Validation complete! We're good to execute this migration
 	if err := this.hooksExecutor.onValidated(); err != nil {
 		return err
@@ -407,6 +576,7 @@ func (this *Migrator) Migrate() (err error) {
 		return err
 	}
 	defer this.server.RemoveSocketFile()
+	defer this.server.CloseAuditLog()
 
 	if err := this.countTableRows(); err != nil {
 		return err
@@ -418,31 +588,56 @@ func (this *Migrator) Migrate() (err error) {
 		return err
 	}
 
+	if err := this.checkDiskSpace(); err != nil {
+		return err
+	}
 	this.initiateThrottler()
 
+	this.migrationContext.SetPhase(base.RowCopyPhase)
 	if err := this.hooksExecutor.onBeforeRowCopy(); err != nil {
 		return err
 	}
+	this.writeFuncsDone = make(chan struct{})
 	go this.executeWriteFuncs()
+	// DML events are applied while the gate holds row copy
+	if err := this.passGate(gateBeforeRowCopy, this.hooksExecutor.askGateBeforeRowCopy, nil); err != nil {
+		return err
+	}
 	go this.iterateChunks()
+	if this.shadowVerifier != nil {
+		go this.shadowVerifier.Run()
+	}
 	this.migrationContext.MarkRowCopyStartTime()
 	go this.initiateStatus()
+	go this.watchCutOverDeadline()
+	go this.guardBinlogRetention()
+	go this.guardDiskSpace()
 
-	this.migrationContext.Log.Debugf("Operating until row copy is complete")
-	this.consumeRowCopyComplete()
-	this.migrationContext.Log.Infof("Row copy complete")
+	this.log.Debugf("Operating until row copy is complete")
+	if err := this.consumeRowCopyComplete(); err != nil {
+		return err
+	}
+	this.log.Infof("Row copy complete")
+	this.migrationContext.SetPhase(base.CutOverPhase)
+	if this.shadowVerifier != nil {
+		this.shadowVerifier.MarkRowCopyComplete()
+	}
 	if err := this.hooksExecutor.onRowCopyComplete(); err != nil {
 		return err
 	}
+	go this.watchGhostTableCatchUp(time.Now())
 	this.printStatus(ForcePrintStatusRule)
 
 	if this.migrationContext.IsCountingTableRows() {
-		this.migrationContext.Log.Info("stopping query for exact row count, because that can accidentally lock out the cut over")
+		this.log.Info("stopping query for exact row count, because that can accidentally lock out the cut over")
 		this.migrationContext.CancelTableRowsCount()
 	}
 	if err := this.hooksExecutor.onBeforeCutOver(); err != nil {
 		return err
 	}
+	if this.shadowVerifier != nil {
+		this.shadowVerifier.Teardown()
+	}
 	var retrier func(func() error, ...bool) error
 	if this.migrationContext.CutOverExponentialBackoff {
 		retrier = this.retryOperationWithExponentialBackoff
@@ -460,7 +655,8 @@ func (this *Migrator) Migrate() (err error) {
 	if err := this.hooksExecutor.onSuccess(); err != nil {
 		return err
 	}
-	this.migrationContext.Log.Infof("Done migrating %s.%s", sql.EscapeName(this.migrationContext.DatabaseName), sql.EscapeName(this.migrationContext.OriginalTableName))
+	this.migrationContext.SetPhase(base.DonePhase)
+	this.log.Infof("Done migrating %s.%s", sql.EscapeName(this.migrationContext.DatabaseName), sql.EscapeName(this.migrationContext.OriginalTableName))
 	return nil
 }
 
@@ -473,7 +669,10 @@ func (this *Migrator) ExecOnFailureHook() (err error) {
 func (this *Migrator) handleCutOverResult(cutOverError error) (err error) {
 	if this.migrationContext.TestOnReplica {
 		// We're merely testing, we don't want to keep this state. Rollback the renames as possible
-		this.applier.RenameTablesRollback()
+		rollbackErr := this.applier.RenameTablesRollback()
+		if err := this.hooksExecutor.onRenameRollback(rollbackErr); err != nil {
+			this.log.Errore(err)
+		}
 	}
 	if cutOverError == nil {
 		return nil
@@ -486,14 +685,14 @@ func (this *Migrator) handleCutOverResult(cutOverError error) (err error) {
 		// and swap the tables.
 		// The difference is that we will later swap the tables back.
 		if err := this.hooksExecutor.onStartReplication(); err != nil {
-			return this.migrationContext.Log.Errore(err)
+			return this.log.Errore(err)
 		}
 		if this.migrationContext.TestOnReplicaSkipReplicaStop {
-			this.migrationContext.Log.Warningf("--test-on-replica-skip-replica-stop enabled, we are not starting replication.")
+			this.log.Warningf("--test-on-replica-skip-replica-stop enabled, we are not starting replication.")
 		} else {
-			this.migrationContext.Log.Debugf("testing on replica. Starting replication IO thread after cut-over failure")
+			this.log.Debugf("testing on replica. Starting replication IO thread after cut-over failure")
 			if err := this.retryOperation(this.applier.StartReplication); err != nil {
-				return this.migrationContext.Log.Errore(err)
+				return this.log.Errore(err)
 			}
 		}
 	}
@@ -504,23 +703,37 @@ func (this *Migrator) handleCutOverResult(cutOverError error) (err error) {
 // type (on replica? atomic? safe?)
 func (this *Migrator) cutOver() (err error) {
 	if this.migrationContext.Noop {
-		this.migrationContext.Log.Debugf("Noop operation; not really swapping tables")
+		this.log.Debugf("Noop operation; not really swapping tables")
 		return nil
 	}
 	this.migrationContext.MarkPointOfInterest()
 	this.throttler.throttle(func() {
-		this.migrationContext.Log.Debugf("throttling before swapping tables")
+		this.log.Debugf("throttling before swapping tables")
 	})
 
 	this.migrationContext.MarkPointOfInterest()
-	this.migrationContext.Log.Debugf("checking for cut-over postpone")
+	this.log.Debugf("checking for cut-over postpone")
 	this.sleepWhileTrue(
 		func() (bool, error) {
 			heartbeatLag := this.migrationContext.TimeSinceLastHeartbeatOnChangelog()
 			maxLagMillisecondsThrottle := time.Duration(atomic.LoadInt64(&this.migrationContext.MaxLagMillisecondsThrottleThreshold)) * time.Millisecond
 			cutOverLockTimeout := time.Duration(this.migrationContext.CutOverLockTimeoutSeconds) * time.Second
 			if heartbeatLag > maxLagMillisecondsThrottle || heartbeatLag > cutOverLockTimeout {
-				this.migrationContext.Log.Debugf("current HeartbeatLag (%.2fs) is too high, it needs to be less than both --max-lag-millis (%.2fs) and --cut-over-lock-timeout-seconds (%.2fs) to continue", heartbeatLag.Seconds(), maxLagMillisecondsThrottle.Seconds(), cutOverLockTimeout.Seconds())
+				this.log.Debugf("current HeartbeatLag (%.2fs) is too high, it needs to be less than both --max-lag-millis (%.2fs) and --cut-over-lock-timeout-seconds (%.2fs) to continue", heartbeatLag.Seconds(), maxLagMillisecondsThrottle.Seconds(), cutOverLockTimeout.Seconds())
+				return true, nil
+			}
+			if window := this.migrationContext.GetCutOverWindow(); window != nil && !window.Contains(time.Now()) {
+				if atomic.LoadInt64(&this.migrationContext.UserCommandedUnpostponeFlag) > 0 {
+					atomic.StoreInt64(&this.migrationContext.UserCommandedUnpostponeFlag, 0)
+					return false, nil
+				}
+				if atomic.LoadInt64(&this.migrationContext.IsPostponingCutOver) == 0 {
+					this.log.Infof("Postponing cut-over until cut-over window %s opens at %s", window, window.NextStart(time.Now()).Format(time.RFC3339))
+					if err := this.hooksExecutor.onBeginPostponed(); err != nil {
+						return true, err
+					}
+				}
+				atomic.StoreInt64(&this.migrationContext.IsPostponingCutOver, 1)
 				return true, nil
 			}
 			if this.migrationContext.PostponeCutOverFlagFile == "" {
@@ -545,7 +758,39 @@ func (this *Migrator) cutOver() (err error) {
 	)
 	atomic.StoreInt64(&this.migrationContext.IsPostponingCutOver, 0)
 	this.migrationContext.MarkPointOfInterest()
-	this.migrationContext.Log.Debugf("checking for cut-over postpone: complete")
+	this.log.Debugf("checking for cut-over postpone: complete")
+
+	err = this.passGate(gateBeforeCutOver, this.hooksExecutor.askGateBeforeCutOver, func() {
+		atomic.StoreInt64(&this.migrationContext.IsPostponingCutOver, 1)
+	})
+	atomic.StoreInt64(&this.migrationContext.IsPostponingCutOver, 0)
+	if _, aborted := err.(*gateAbortError); aborted && atomic.CompareAndSwapInt64(&this.cutOverState, cutOverIdle, cutOverAbandoned) {
+		// No further attempts. The migration tears down as on any other failure
+		this.Abort(err)
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := this.handleCutOverBlockers(); err != nil {
+		return err
+	}
+	if !atomic.CompareAndSwapInt64(&this.cutOverState, cutOverIdle, cutOverAttempting) {
+		return fmt.Errorf("Cut-over abandoned; not attempting cut-over")
+	}
+	cutOverAttempt := atomic.AddInt64(&this.cutOverAttempts, 1)
+	defer func() {
+		if err != nil {
+			atomic.StoreInt64(&this.cutOverState, cutOverIdle)
+			if hookErr := this.hooksExecutor.onCutOverFailure(cutOverAttempt, err); hookErr != nil {
+				this.log.Errore(hookErr)
+			}
+		}
+	}()
+	if err := this.hooksExecutor.onCutOverAttempt(cutOverAttempt); err != nil {
+		return err
+	}
 
 	if this.migrationContext.TestOnReplica {
 		// With `--test-on-replica` we stop replication thread, and then proceed to use
@@ -556,15 +801,23 @@ func (this *Migrator) cutOver() (err error) {
 			return err
 		}
 		if this.migrationContext.TestOnReplicaSkipReplicaStop {
-			this.migrationContext.Log.Warningf("--test-on-replica-skip-replica-stop enabled, we are not stopping replication.")
+			this.log.Warningf("--test-on-replica-skip-replica-stop enabled, we are not stopping replication.")
 		} else {
-			this.migrationContext.Log.Debugf("testing on replica. Stopping replication IO thread")
+			this.log.Debugf("testing on replica. Stopping replication IO thread")
 			if err := this.retryOperation(this.applier.StopReplication); err != nil {
 				return err
 			}
 		}
 	}
 
+	stopMonitoringCutOverBlockers := this.monitorCutOverBlockers()
+	defer stopMonitoringCutOverBlockers()
+
+	if this.migrationContext.IsCrossServerMove() {
+		err = this.cutOverCrossServer()
+		this.handleCutOverResult(err)
+		return err
+	}
 	switch this.migrationContext.CutOverType {
 	case base.CutOverAtomic:
 		// Atomic solution: we use low timeout and multiple attempts. But for
@@ -572,13 +825,307 @@ func (this *Migrator) cutOver() (err error) {
 		err = this.atomicCutOver()
 	case base.CutOverTwoStep:
 		err = this.cutOverTwoStep()
+	case base.CutOverLockAndRename:
+		err = this.cutOverLockAndRename()
 	default:
-		return this.migrationContext.Log.Fatalf("Unknown cut-over type: %d; should never get here!", this.migrationContext.CutOverType)
+		return this.log.Errorf("Unknown cut-over type: %d; should never get here!", this.migrationContext.CutOverType)
 	}
 	this.handleCutOverResult(err)
 	return err
 }
 
+// checkDiskSpace estimates the size of the ghost table once all rows are copied. Given
+// --free-disk-space-query, it verifies that the server holding the ghost table has the space
+// for it, while keeping above the free space thresholds.
+func (this *Migrator) checkDiskSpace() error {
+	originalSize, ghostSize, err := this.applier.EstimateGhostTableSize()
+	if err != nil {
+		this.log.Warningf("Cannot estimate ghost table size: %+v", err)
+	} else {
+		atomic.StoreInt64(&this.migrationContext.GhostTableSizeEstimate, ghostSize)
+		this.log.Infof("Original table size is %dMB; ghost table estimated to grow to %dMB", originalSize/bytesPerMB, ghostSize/bytesPerMB)
+	}
+	if this.migrationContext.FreeDiskSpaceQuery == "" {
+		return nil
+	}
+	freeSpace, err := this.applier.ReadFreeDiskSpace()
+	if err != nil {
+		return fmt.Errorf("Error reading --free-disk-space-query: %+v", err)
+	}
+	atomic.StoreInt64(&this.migrationContext.FreeDiskSpaceBytes, freeSpace)
+	requiredFreeSpace := this.migrationContext.MinFreeDiskSpaceMB * bytesPerMB
+	if criticalFreeSpace := this.migrationContext.CriticalFreeDiskSpaceMB * bytesPerMB; criticalFreeSpace > requiredFreeSpace {
+		requiredFreeSpace = criticalFreeSpace
+	}
+	if freeSpace-atomic.LoadInt64(&this.migrationContext.GhostTableSizeEstimate) < requiredFreeSpace {
+		return fmt.Errorf("Not enough disk space for the ghost table: %dMB free, ghost table estimated at %dMB, and %dMB must remain free", freeSpace/bytesPerMB, ghostSize/bytesPerMB, requiredFreeSpace/bytesPerMB)
+	}
+	this.log.Infof("Disk space validated: %dMB free", freeSpace/bytesPerMB)
+	return nil
+}
+
+// guardDiskSpace periodically reads the free disk space on the server holding the ghost table,
+// which the throttler compares against --min-free-disk-space-mb. Should free space drop below
+// --critical-free-disk-space-mb, it fires the gh-ost-on-disk-space-critical hook, drops the ghost
+// table to release its space, and aborts.
+func (this *Migrator) guardDiskSpace() {
+	if this.migrationContext.FreeDiskSpaceQuery == "" {
+		return
+	}
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+	for range ticker.C {
+		if atomic.LoadInt64(&this.finishedMigrating) > 0 {
+			return
+		}
+		freeSpace, err := this.applier.ReadFreeDiskSpace()
+		if err != nil {
+			this.log.Warningf("Error reading --free-disk-space-query: %+v", err)
+			atomic.StoreInt64(&this.migrationContext.FreeDiskSpaceBytes, -1)
+			continue
+		}
+		atomic.StoreInt64(&this.migrationContext.FreeDiskSpaceBytes, freeSpace)
+		criticalFreeSpace := this.migrationContext.CriticalFreeDiskSpaceMB * bytesPerMB
+		if criticalFreeSpace <= 0 || freeSpace >= criticalFreeSpace {
+			continue
+		}
+		if !atomic.CompareAndSwapInt64(&this.cutOverState, cutOverIdle, cutOverAbandoned) {
+			// Past the cut-over, the ghost table is no longer to be dropped
+			continue
+		}
+		this.abortOnCriticalDiskSpace(freeSpace)
+		return
+	}
+}
+
+// abortOnCriticalDiskSpace aborts the migration. The ghost and changelog tables are dropped to release
+// their space as the migration tears down, once row copy and events apply stopped.
+func (this *Migrator) abortOnCriticalDiskSpace(freeSpace int64) {
+	this.log.Errorf("Free disk space %dMB below --critical-free-disk-space-mb. Aborting migration and dropping ghost and changelog tables", freeSpace/bytesPerMB)
+	if err := this.hooksExecutor.onDiskSpaceCritical(); err != nil {
+		this.log.Errore(err)
+	}
+	atomic.StoreInt64(&this.dropTablesOnAbort, 1)
+	this.Abort(fmt.Errorf("Free disk space %dMB below --critical-free-disk-space-mb; migration aborted", freeSpace/bytesPerMB))
+}
+
+// watchGhostTableCatchUp fires the gh-ost-on-ghost-table-caught-up hook once the applier, which
+// may lag behind the binary log throughout row copy, catches up after row copy completes.
+// The applier is deemed caught up once the apply lag drops below a second.
+func (this *Migrator) watchGhostTableCatchUp(rowCopyCompleteTime time.Time) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		if atomic.LoadInt64(&this.finishedMigrating) > 0 {
+			return
+		}
+		if this.migrationContext.GetApplyLag() < time.Second {
+			catchUpDuration := time.Since(rowCopyCompleteTime)
+			this.log.Infof("Ghost table caught up with the binary log, %+v after row copy completed", catchUpDuration)
+			if err := this.hooksExecutor.onGhostTableCaughtUp(catchUpDuration); err != nil {
+				this.log.Errore(err)
+			}
+			return
+		}
+		<-ticker.C
+	}
+}
+
+// guardBinlogRetention periodically estimates how long until the binary log being read is purged
+// from the inspected server. Should the streamer then need to reconnect, it could not resume, and
+// the migration could not complete. As the estimate approaches --binlog-retention-guard-seconds,
+// it warns, fires the gh-ost-on-binlog-retention-warning hook, and with
+// --binlog-retention-override-forced-throttle, lets the throttler override forced throttling.
+func (this *Migrator) guardBinlogRetention() {
+	if this.migrationContext.BinlogRetentionGuardSeconds <= 0 {
+		return
+	}
+	guard := time.Duration(this.migrationContext.BinlogRetentionGuardSeconds) * time.Second
+	check := func() {
+		logFile := this.eventsStreamer.GetCurrentBinlogCoordinates().LogFile
+		untilPurge, purgeExpected, err := this.inspector.estimateBinlogPurge(logFile, this.eventsStreamer.GetCurrentEventTime())
+		if err != nil && !purgeExpected {
+			this.log.Warningf("Cannot estimate binary log retention: %+v", err)
+			return
+		}
+		if !purgeExpected {
+			atomic.StoreInt64(&this.migrationContext.BinlogSecondsUntilPurge, -1)
+		} else {
+			atomic.StoreInt64(&this.migrationContext.BinlogSecondsUntilPurge, int64(untilPurge.Seconds()))
+		}
+		breached := purgeExpected && untilPurge < guard
+		if !breached {
+			if atomic.CompareAndSwapInt64(&this.migrationContext.BinlogRetentionGuardBreached, 1, 0) {
+				this.log.Infof("Binary log %s no longer expected to be purged within %+v", logFile, guard)
+			}
+			return
+		}
+		if !atomic.CompareAndSwapInt64(&this.migrationContext.BinlogRetentionGuardBreached, 0, 1) {
+			return
+		}
+		if err != nil {
+			this.log.Errorf("%+v. The migration cannot recover should the binlog streamer need to reconnect", err)
+		} else {
+			this.log.Warningf("Binary log %s expected to be purged in %+v, within --binlog-retention-guard-seconds", logFile, untilPurge.Round(time.Second))
+		}
+		if this.migrationContext.BinlogRetentionOverrideForcedThrottle {
+			this.log.Warningf("Overriding forced throttling, per --binlog-retention-override-forced-throttle")
+		}
+		if err := this.hooksExecutor.onBinlogRetentionWarning(logFile); err != nil {
+			this.log.Errore(err)
+		}
+	}
+
+	ticker := time.NewTicker(10 * time.Second)
+	defer ticker.Stop()
+	for range ticker.C {
+		if atomic.LoadInt64(&this.finishedMigrating) > 0 {
+			return
+		}
+		check()
+	}
+}
+
+// watchCutOverDeadline aborts the migration once the --cut-over-deadline passes without the
+// cut-over having completed. An ongoing cut-over attempt is let through: the deadline is enforced
+// only between attempts.
+func (this *Migrator) watchCutOverDeadline() {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for range ticker.C {
+		if atomic.LoadInt64(&this.finishedMigrating) > 0 {
+			return
+		}
+		deadline := this.migrationContext.GetCutOverDeadline()
+		if deadline.IsZero() || time.Now().Before(deadline) {
+			continue
+		}
+		if !atomic.CompareAndSwapInt64(&this.cutOverState, cutOverIdle, cutOverAbandoned) {
+			continue
+		}
+		this.abortOnCutOverDeadline(deadline)
+		return
+	}
+}
+
+// abortOnCutOverDeadline aborts the migration. The ghost and changelog tables are dropped as the
+// migration tears down, once row copy and events apply stopped. The original table is left untouched.
+func (this *Migrator) abortOnCutOverDeadline(deadline time.Time) {
+	this.log.Errorf("Cut-over deadline %s passed. Aborting migration and dropping ghost and changelog tables", deadline.Format(time.RFC3339))
+	atomic.StoreInt64(&this.dropTablesOnAbort, 1)
+	this.Abort(fmt.Errorf("Cut-over did not complete by --cut-over-deadline %s; migration aborted", deadline.Format(time.RFC3339)))
+}
+
+// handleCutOverBlockers looks, ahead of a cut-over attempt, for long transactions holding locks on
+// the original table, which would block the attempt. Per --cut-over-blockers, these are logged,
+// killed, or waited for.
+func (this *Migrator) handleCutOverBlockers() error {
+	if !this.cutOverBlockersDetectable {
+		return nil
+	}
+	readLongBlockers := func() ([]*CutOverBlocker, error) {
+		blockers, err := this.applier.ReadCutOverBlockers(false)
+		if err != nil {
+			return nil, err
+		}
+		return filterCutOverBlockersByAge(blockers, this.migrationContext.CutOverBlockersMinAgeSeconds), nil
+	}
+
+	if this.migrationContext.CutOverBlockersPolicy == base.CutOverBlockersPostpone {
+		loggedBlockers := map[int64]bool{}
+		defer atomic.StoreInt64(&this.migrationContext.IsPostponingCutOver, 0)
+		return this.sleepWhileTrue(
+			func() (bool, error) {
+				if atomic.LoadInt64(&this.migrationContext.UserCommandedUnpostponeFlag) > 0 {
+					atomic.StoreInt64(&this.migrationContext.UserCommandedUnpostponeFlag, 0)
+					return false, nil
+				}
+				blockers, err := readLongBlockers()
+				if err != nil {
+					this.log.Errore(err)
+					return false, nil
+				}
+				if len(blockers) == 0 {
+					return false, nil
+				}
+				for _, blocker := range blockers {
+					if !loggedBlockers[blocker.SessionId] {
+						loggedBlockers[blocker.SessionId] = true
+						this.log.Infof("Postponing cut-over while a long transaction holds a lock on %s: %s", sql.EscapeName(this.migrationContext.OriginalTableName), blocker)
+					}
+				}
+				atomic.StoreInt64(&this.migrationContext.IsPostponingCutOver, 1)
+				return true, nil
+			},
+		)
+	}
+
+	blockers, err := readLongBlockers()
+	if err != nil {
+		this.log.Errore(err)
+		return nil
+	}
+	for _, blocker := range blockers {
+		this.log.Warningf("Long transaction holds a lock on %s, and may block the cut-over: %s", sql.EscapeName(this.migrationContext.OriginalTableName), blocker)
+	}
+	if this.migrationContext.CutOverBlockersPolicy == base.CutOverBlockersKill {
+		this.killCutOverBlockers(blockers, map[int64]bool{})
+	}
+	return nil
+}
+
+// monitorCutOverBlockers polls, throughout a cut-over attempt, for sessions blocking the attempt
+// from locking the original table, until the returned function is called. Blockers are logged and,
+// per --cut-over-blockers=kill, killed.
+func (this *Migrator) monitorCutOverBlockers() (stop func()) {
+	if !this.cutOverBlockersDetectable {
+		return func() {}
+	}
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		loggedBlockers := map[int64]bool{}
+		killedBlockers := map[int64]bool{}
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+			blockers, err := this.applier.ReadCutOverBlockers(true)
+			if err != nil {
+				this.log.Errore(err)
+				continue
+			}
+			for _, blocker := range blockers {
+				if !loggedBlockers[blocker.SessionId] {
+					loggedBlockers[blocker.SessionId] = true
+					this.log.Warningf("Cut-over is blocked by %s", blocker)
+				}
+			}
+			if this.migrationContext.CutOverBlockersPolicy == base.CutOverBlockersKill {
+				this.killCutOverBlockers(filterCutOverBlockersByAge(blockers, this.migrationContext.CutOverBlockersMinAgeSeconds), killedBlockers)
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// killCutOverBlockers kills the given blockers, unless already killed
+func (this *Migrator) killCutOverBlockers(blockers []*CutOverBlocker, killedBlockers map[int64]bool) {
+	for _, blocker := range blockers {
+		if killedBlockers[blocker.SessionId] {
+			continue
+		}
+		killedBlockers[blocker.SessionId] = true
+		this.log.Warningf("Killing %s", blocker)
+		if err := this.applier.KillSession(blocker.SessionId); err != nil {
+			this.log.Errore(err)
+		}
+	}
+}
+
 // Inject the "AllEventsUpToLockProcessed" state hint, wait for it to appear in the binary logs,
 // make sure the queue is drained.
 func (this *Migrator) waitForEventsUpToLock() (err error) {
@@ -588,32 +1135,32 @@ func (this *Migrator) waitForEventsUpToLock() (err error) {
 	waitForEventsUpToLockStartTime := time.Now()
 
 	allEventsUpToLockProcessedChallenge := fmt.Sprintf("%s:%d", string(AllEventsUpToLockProcessed), waitForEventsUpToLockStartTime.UnixNano())
-	this.migrationContext.Log.Infof("Writing changelog state: %+v", allEventsUpToLockProcessedChallenge)
+	this.log.Infof("Writing changelog state: %+v", allEventsUpToLockProcessedChallenge)
 	if _, err := this.applier.WriteChangelogState(allEventsUpToLockProcessedChallenge); err != nil {
 		return err
 	}
-	this.migrationContext.Log.Infof("Waiting for events up to lock")
+	this.log.Infof("Waiting for events up to lock")
 	atomic.StoreInt64(&this.migrationContext.AllEventsUpToLockProcessedInjectedFlag, 1)
 	for found := false; !found; {
 		select {
 		case <-timeout.C:
 			{
-				return this.migrationContext.Log.Errorf("Timeout while waiting for events up to lock")
+				return this.log.Errorf("Timeout while waiting for events up to lock")
 			}
 		case state := <-this.allEventsUpToLockProcessed:
 			{
 				if state == allEventsUpToLockProcessedChallenge {
-					this.migrationContext.Log.Infof("Waiting for events up to lock: got %s", state)
+					this.log.Infof("Waiting for events up to lock: got %s", state)
 					found = true
 				} else {
-					this.migrationContext.Log.Infof("Waiting for events up to lock: skipping %s", state)
+					this.log.Infof("Waiting for events up to lock: skipping %s", state)
 				}
 			}
 		}
 	}
 	waitForEventsUpToLockDuration := time.Since(waitForEventsUpToLockStartTime)
 
-	this.migrationContext.Log.Infof("Done waiting for events up to lock; duration=%+v", waitForEventsUpToLockDuration)
+	this.log.Infof("Done waiting for events up to lock; duration=%+v", waitForEventsUpToLockDuration)
 	this.printStatus(ForcePrintStatusAndHintRule)
 
 	return nil
@@ -650,7 +1197,90 @@ func (this *Migrator) cutOverTwoStep() (err error) {
 
 	lockAndRenameDuration := this.migrationContext.RenameTablesEndTime.Sub(this.migrationContext.LockTablesStartTime)
 	renameDuration := this.migrationContext.RenameTablesEndTime.Sub(this.migrationContext.RenameTablesStartTime)
-	this.migrationContext.Log.Debugf("Lock & rename duration: %s (rename only: %s). During this time, queries on %s were locked or failing", lockAndRenameDuration, renameDuration, sql.EscapeName(this.migrationContext.OriginalTableName))
+	this.log.Debugf("Lock & rename duration: %s (rename only: %s). During this time, queries on %s were locked or failing", lockAndRenameDuration, renameDuration, sql.EscapeName(this.migrationContext.OriginalTableName))
+	return nil
+}
+
+// cutOverCrossServer completes a cross-server move. It locks the original table, applies what's left
+// of the DML events onto the target, then renames the original table away so that it takes no further
+// writes, and renames the ghost table on the target into the original table's name.
+// Unlike the other cut-over types, there is no point in time where a single server switches tables:
+// applications are expected to point at the target server, e.g. via `onSuccess` hook.
+func (this *Migrator) cutOverCrossServer() (err error) {
+	atomic.StoreInt64(&this.migrationContext.InCutOverCriticalSectionFlag, 1)
+	defer atomic.StoreInt64(&this.migrationContext.InCutOverCriticalSectionFlag, 0)
+	atomic.StoreInt64(&this.migrationContext.AllEventsUpToLockProcessedInjectedFlag, 0)
+
+	if err := this.retryOperation(this.applier.LockOriginalTable); err != nil {
+		return err
+	}
+	unlockOnError := func(err error) error {
+		this.applier.UnlockTables()
+		return err
+	}
+	if err := this.waitForEventsUpToLock(); err != nil {
+		return unlockOnError(err)
+	}
+	if this.migrationContext.IncludeTriggers && len(this.migrationContext.Triggers) > 0 {
+		if err := this.retryOperation(this.applier.CreateTriggersOnGhost); err != nil {
+			return unlockOnError(err)
+		}
+	}
+	if err := this.applier.RenameOriginalTableAway(); err != nil {
+		return unlockOnError(err)
+	}
+	if this.migrationContext.TargetSkipRename {
+		this.log.Infof("--target-skip-rename given. Ghost table %s remains by its name on target", sql.EscapeName(this.migrationContext.GetGhostTableName()))
+	} else if err := this.applier.RenameGhostOnTarget(); err != nil {
+		rollbackErr := this.applier.RestoreOriginalTable()
+		err = unlockOnError(err)
+		// The hook runs once the original table is unlocked
+		if hookErr := this.hooksExecutor.onRenameRollback(rollbackErr); hookErr != nil {
+			this.log.Errore(hookErr)
+		}
+		return err
+	}
+	if err := this.retryOperation(this.applier.UnlockTables); err != nil {
+		return err
+	}
+
+	lockAndRenameDuration := this.migrationContext.RenameTablesEndTime.Sub(this.migrationContext.LockTablesStartTime)
+	this.log.Debugf("Lock & rename duration: %s. During this time, queries on %s were locked", lockAndRenameDuration, sql.EscapeName(this.migrationContext.OriginalTableName))
+	this.log.Infof("Table %s moved to %+v", sql.EscapeName(this.migrationContext.OriginalTableName), *this.migrationContext.TargetConnectionConfig.ImpliedKey)
+	return nil
+}
+
+// cutOverLockAndRename locks both original and ghost tables, applies what's left of the DML events,
+// then swaps the tables via RENAME TABLE in the very session holding the locks, which MySQL allows
+// as of 8.0.13. Queries on the original table are blocked throughout, never failing.
+// Unlike atomicCutOver, it needs no sentry table, magic lock, nor polling of the process list.
+func (this *Migrator) cutOverLockAndRename() (err error) {
+	atomic.StoreInt64(&this.migrationContext.InCutOverCriticalSectionFlag, 1)
+	defer atomic.StoreInt64(&this.migrationContext.InCutOverCriticalSectionFlag, 0)
+	atomic.StoreInt64(&this.migrationContext.AllEventsUpToLockProcessedInjectedFlag, 0)
+
+	if err := this.applier.LockOriginalAndGhostTables(); err != nil {
+		this.applier.UnlockOriginalAndGhostTables()
+		return this.log.Errore(err)
+	}
+	unlockOnError := func(err error) error {
+		this.applier.UnlockOriginalAndGhostTables()
+		return this.log.Errore(err)
+	}
+	// At this point we know the original table is locked.
+	// We know any newly incoming DML on original table is blocked.
+	if err := this.waitForEventsUpToLock(); err != nil {
+		return unlockOnError(err)
+	}
+	if err := this.applier.RenameTablesUnderLock(); err != nil {
+		return unlockOnError(err)
+	}
+	if err := this.applier.UnlockOriginalAndGhostTables(); err != nil {
+		return this.log.Errore(err)
+	}
+
+	lockAndRenameDuration := this.migrationContext.RenameTablesEndTime.Sub(this.migrationContext.LockTablesStartTime)
+	this.log.Infof("Lock & rename duration: %s. During this time, queries on %s were blocked", lockAndRenameDuration, sql.EscapeName(this.migrationContext.OriginalTableName))
 	return nil
 }
 
@@ -671,24 +1301,24 @@ func (this *Migrator) atomicCutOver() (err error) {
 	tableUnlocked := make(chan error, 2)
 	go func() {
 		if err := this.applier.AtomicCutOverMagicLock(lockOriginalSessionIdChan, tableLocked, okToUnlockTable, tableUnlocked); err != nil {
-			this.migrationContext.Log.Errore(err)
+			this.log.Errore(err)
 		}
 	}()
 	if err := <-tableLocked; err != nil {
-		return this.migrationContext.Log.Errore(err)
+		return this.log.Errore(err)
 	}
 	lockOriginalSessionId := <-lockOriginalSessionIdChan
-	this.migrationContext.Log.Infof("Session locking original & magic tables is %+v", lockOriginalSessionId)
+	this.log.Infof("Session locking original & magic tables is %+v", lockOriginalSessionId)
 	// At this point we know the original table is locked.
 	// We know any newly incoming DML on original table is blocked.
 	if err := this.waitForEventsUpToLock(); err != nil {
-		return this.migrationContext.Log.Errore(err)
+		return this.log.Errore(err)
 	}
 
 	// If we need to create triggers we need to do it here (only create part)
 	if this.migrationContext.IncludeTriggers && len(this.migrationContext.Triggers) > 0 {
 		if err := this.applier.CreateTriggersOnGhost(); err != nil {
-			this.migrationContext.Log.Errore(err)
+			this.log.Errore(err)
 		}
 	}
 
@@ -707,7 +1337,7 @@ func (this *Migrator) atomicCutOver() (err error) {
 		}
 	}()
 	renameSessionId := <-renameSessionIdChan
-	this.migrationContext.Log.Infof("Session renaming tables is %+v", renameSessionId)
+	this.log.Infof("Session renaming tables is %+v", renameSessionId)
 
 	waitForRename := func() error {
 		if atomic.LoadInt64(&tableRenameKnownToHaveFailed) == 1 {
@@ -724,13 +1354,13 @@ func (this *Migrator) atomicCutOver() (err error) {
 		return err
 	}
 	if atomic.LoadInt64(&tableRenameKnownToHaveFailed) == 0 {
-		this.migrationContext.Log.Infof("Found atomic RENAME to be blocking, as expected. Double checking the lock is still in place (though I don't strictly have to)")
+		this.log.Infof("Found atomic RENAME to be blocking, as expected. Double checking the lock is still in place (though I don't strictly have to)")
 	}
 	if err := this.applier.ExpectUsedLock(lockOriginalSessionId); err != nil {
 		// Abort operation. Just make sure to drop the magic table.
-		return this.migrationContext.Log.Errore(err)
+		return this.log.Errore(err)
 	}
-	this.migrationContext.Log.Infof("Connection holding lock on original table still exists")
+	this.log.Infof("Connection holding lock on original table still exists")
 
 	// Now that we've found the RENAME blocking, AND the locking connection still alive,
 	// we know it is safe to proceed to release the lock
@@ -739,16 +1369,16 @@ func (this *Migrator) atomicCutOver() (err error) {
 	// BAM! magic table dropped, original table lock is released
 	// -> RENAME released -> queries on original are unblocked.
 	if err := <-tableUnlocked; err != nil {
-		return this.migrationContext.Log.Errore(err)
+		return this.log.Errore(err)
 	}
 	if err := <-tablesRenamed; err != nil {
-		return this.migrationContext.Log.Errore(err)
+		return this.log.Errore(err)
 	}
 	this.migrationContext.RenameTablesEndTime = time.Now()
 
 	// ooh nice! We're actually truly and thankfully done
 	lockAndRenameDuration := this.migrationContext.RenameTablesEndTime.Sub(this.migrationContext.LockTablesStartTime)
-	this.migrationContext.Log.Infof("Lock & rename duration: %s. During this time, queries on %s were blocked", lockAndRenameDuration, sql.EscapeName(this.migrationContext.OriginalTableName))
+	this.log.Infof("Lock & rename duration: %s. During this time, queries on %s were blocked", lockAndRenameDuration, sql.EscapeName(this.migrationContext.OriginalTableName))
 	return nil
 }
 
@@ -757,7 +1387,10 @@ func (this *Migrator) initiateServer() (err error) {
 	var f printStatusFunc = func(rule PrintStatusRule, writer io.Writer) {
 		this.printStatus(rule, writer)
 	}
-	this.server = NewServer(this.migrationContext, this.hooksExecutor, f)
+	this.server = NewServer(this.migrationContext, this.applier, this.hooksExecutor, f)
+	if err := this.server.OpenAuditLog(); err != nil {
+		return err
+	}
 	if err := this.server.BindSocketFile(); err != nil {
 		return err
 	}
@@ -794,7 +1427,7 @@ func (this *Migrator) initiateInspector() (err error) {
 		if this.migrationContext.ApplierConnectionConfig, err = this.inspector.getMasterConnectionConfig(); err != nil {
 			return err
 		}
-		this.migrationContext.Log.Infof("Master found to be %+v", *this.migrationContext.ApplierConnectionConfig.ImpliedKey)
+		this.log.Infof("Master found to be %+v", *this.migrationContext.ApplierConnectionConfig.ImpliedKey)
 	} else {
 		// Forced master host.
 		key, err := mysql.ParseInstanceKey(this.migrationContext.AssumeMasterHostname)
@@ -807,18 +1440,23 @@ func (this *Migrator) initiateInspector() (err error) {
 		}
 		if this.migrationContext.CliMasterPassword != "" {
 			this.migrationContext.ApplierConnectionConfig.Password = this.migrationContext.CliMasterPassword
+			// Explicit master credentials are not overridden by the credential helper
+			this.migrationContext.ApplierConnectionConfig.CredentialHelper = ""
 		}
 		if err := this.migrationContext.ApplierConnectionConfig.RegisterTLSConfig(); err != nil {
 			return err
 		}
-		this.migrationContext.Log.Infof("Master forced to be %+v", *this.migrationContext.ApplierConnectionConfig.ImpliedKey)
+		this.log.Infof("Master forced to be %+v", *this.migrationContext.ApplierConnectionConfig.ImpliedKey)
 	}
 	// validate configs
+	if err := this.validateReplicationClusterConfigs(); err != nil {
+		return err
+	}
 	if this.migrationContext.TestOnReplica || this.migrationContext.MigrateOnReplica {
 		if this.migrationContext.InspectorIsAlsoApplier() {
 			return fmt.Errorf("Instructed to --test-on-replica or --migrate-on-replica, but the server we connect to doesn't seem to be a replica")
 		}
-		this.migrationContext.Log.Infof("--test-on-replica or --migrate-on-replica given. Will not execute on master %+v but rather on replica %+v itself",
+		this.log.Infof("--test-on-replica or --migrate-on-replica given. Will not execute on master %+v but rather on replica %+v itself",
 			*this.migrationContext.ApplierConnectionConfig.ImpliedKey, *this.migrationContext.InspectorConnectionConfig.ImpliedKey,
 		)
 		this.migrationContext.ApplierConnectionConfig = this.migrationContext.InspectorConnectionConfig.Duplicate()
@@ -831,10 +1469,58 @@ func (this *Migrator) initiateInspector() (err error) {
 	if err := this.inspector.validateLogSlaveUpdates(); err != nil {
 		return err
 	}
+	if this.migrationContext.IsCrossServerMove() {
+		return this.initiateTargetInspector()
+	}
 
 	return nil
 }
 
+// validateReplicationClusterConfigs checks that cluster-specific configs match the cluster, if any,
+// which the inspected server is a member of
+func (this *Migrator) validateReplicationClusterConfigs() error {
+	replicationCluster := this.migrationContext.ReplicationCluster
+	if replicationCluster != base.NoReplicationCluster && (this.migrationContext.TestOnReplica || this.migrationContext.MigrateOnReplica) {
+		return fmt.Errorf("--test-on-replica and --migrate-on-replica are unsupported on a %s cluster, whose members do not replicate via replication threads gh-ost may stop", replicationCluster)
+	}
+	if this.migrationContext.MaxGroupReplicationQueue > 0 && replicationCluster != base.GroupReplicationCluster {
+		return fmt.Errorf("--max-group-replication-queue given, but %+v is not a Group Replication member", this.migrationContext.InspectorConnectionConfig.Key)
+	}
+	if (this.migrationContext.MaxGaleraRecvQueue > 0 || this.migrationContext.MaxGaleraFlowControlPaused > 0) && replicationCluster != base.GaleraCluster {
+		return fmt.Errorf("--max-galera-recv-queue or --max-galera-flow-control-paused given, but %+v is not a Galera member", this.migrationContext.InspectorConnectionConfig.Key)
+	}
+	return nil
+}
+
+// initiateTargetInspector sets up the connection to the target server of a cross-server move,
+// and the inspector by which the ghost table is examined on that server.
+func (this *Migrator) initiateTargetInspector() (err error) {
+	key, err := mysql.ParseInstanceKey(this.migrationContext.TargetHostname)
+	if err != nil {
+		return err
+	}
+	this.migrationContext.TargetConnectionConfig = this.migrationContext.ApplierConnectionConfig.DuplicateCredentials(*key)
+	if this.migrationContext.CliTargetUser != "" {
+		this.migrationContext.TargetConnectionConfig.User = this.migrationContext.CliTargetUser
+	}
+	if this.migrationContext.CliTargetPassword != "" {
+		this.migrationContext.TargetConnectionConfig.Password = this.migrationContext.CliTargetPassword
+		this.migrationContext.TargetConnectionConfig.CredentialHelper = ""
+	}
+	if err := this.migrationContext.TargetConnectionConfig.RegisterTLSConfig(); err != nil {
+		return err
+	}
+	if this.migrationContext.TargetConnectionConfig.Equals(this.migrationContext.ApplierConnectionConfig) {
+		return fmt.Errorf("--target-host %+v is the master itself. A cross-server move requires a different target server", *key)
+	}
+	this.inspector.ghostTableInspector = NewTargetInspector(this.migrationContext)
+	if err := this.inspector.ghostTableInspector.InitTargetDBConnections(); err != nil {
+		return err
+	}
+	this.log.Infof("Moving table to target %+v", *this.migrationContext.TargetConnectionConfig.ImpliedKey)
+	return nil
+}
+
 // initiateStatus sets and activates the printStatus() ticker
 func (this *Migrator) initiateStatus() {
 	this.printStatus(ForcePrintStatusAndHintRule)
@@ -872,6 +1558,11 @@ func (this *Migrator) printMigrationStatusHint(writers ...io.Writer) {
 		*this.inspector.connectionConfig.ImpliedKey,
 		this.migrationContext.Hostname,
 	)
+	if this.migrationContext.IsCrossServerMove() {
+		fmt.Fprintf(w, "# Moving table to target %+v\n",
+			*this.applier.targetConnectionConfig.ImpliedKey,
+		)
+	}
 	fmt.Fprintf(w, "# Migration started at %+v\n",
 		this.migrationContext.StartTime.Format(time.RubyDate),
 	)
@@ -885,6 +1576,51 @@ func (this *Migrator) printMigrationStatusHint(writers ...io.Writer) {
 		criticalLoad.String(),
 		this.migrationContext.GetNiceRatio(),
 	)
+	if maxApplyLagMillis := atomic.LoadInt64(&this.migrationContext.MaxApplyLagMillisecondsThrottleThreshold); maxApplyLagMillis > 0 {
+		fmt.Fprintf(w, "# max-apply-lag-millis: %+vms\n", maxApplyLagMillis)
+	}
+	if ghostSize := atomic.LoadInt64(&this.migrationContext.GhostTableSizeEstimate); ghostSize > 0 || this.migrationContext.FreeDiskSpaceQuery != "" {
+		freeSpace := "unknown"
+		if freeBytes := atomic.LoadInt64(&this.migrationContext.FreeDiskSpaceBytes); freeBytes >= 0 {
+			freeSpace = fmt.Sprintf("%dMB", freeBytes/bytesPerMB)
+		}
+		fmt.Fprintf(w, "# disk space: ghost table estimated at %dMB; free: %s; min-free-disk-space-mb: %d; critical-free-disk-space-mb: %d\n",
+			ghostSize/bytesPerMB,
+			freeSpace,
+			this.migrationContext.MinFreeDiskSpaceMB,
+			this.migrationContext.CriticalFreeDiskSpaceMB,
+		)
+	}
+	if this.migrationContext.BinlogRetentionGuardSeconds > 0 {
+		if secondsUntilPurge := atomic.LoadInt64(&this.migrationContext.BinlogSecondsUntilPurge); secondsUntilPurge >= 0 {
+			breachedIndicator := ""
+			if atomic.LoadInt64(&this.migrationContext.BinlogRetentionGuardBreached) > 0 {
+				breachedIndicator = " [within guard]"
+			}
+			fmt.Fprintf(w, "# binlog retention: binary log being read expected to be purged in %+v%s\n",
+				time.Duration(secondsUntilPurge)*time.Second,
+				breachedIndicator,
+			)
+		}
+	}
+	switch this.migrationContext.ReplicationCluster {
+	case base.GroupReplicationCluster:
+		fmt.Fprintf(w, "# replication cluster: %s; max-group-replication-queue: %d\n",
+			this.migrationContext.ReplicationCluster,
+			this.migrationContext.MaxGroupReplicationQueue,
+		)
+	case base.GaleraCluster:
+		fmt.Fprintf(w, "# replication cluster: %s; max-galera-recv-queue: %d; max-galera-flow-control-paused: %.2f\n",
+			this.migrationContext.ReplicationCluster,
+			this.migrationContext.MaxGaleraRecvQueue,
+			this.migrationContext.MaxGaleraFlowControlPaused,
+		)
+	}
+	if maxInnoDBMetrics := this.migrationContext.GetMaxInnoDBMetrics(); len(maxInnoDBMetrics) > 0 {
+		fmt.Fprintf(w, "# max-innodb-metrics: %s\n",
+			maxInnoDBMetrics.String(),
+		)
+	}
 	if this.migrationContext.ThrottleFlagFile != "" {
 		setIndicator := ""
 		if base.FileExists(this.migrationContext.ThrottleFlagFile) {
@@ -913,7 +1649,19 @@ func (this *Migrator) printMigrationStatusHint(writers ...io.Writer) {
 			throttleControlReplicaKeys.Len(),
 		)
 	}
+	if this.migrationContext.DiscoverThrottleControlReplicas {
+		fmt.Fprintf(w, "# throttle-control-replicas discovered: %+v\n",
+			this.migrationContext.GetDiscoveredThrottleControlReplicaKeys().ToCommaDelimitedList(),
+		)
+	}
 
+	if throttleSchedule := this.migrationContext.GetThrottleSchedule(); throttleSchedule != nil {
+		activeEntry := "none"
+		if entry := this.migrationContext.GetActiveThrottleScheduleEntry(); entry != nil {
+			activeEntry = entry.String()
+		}
+		fmt.Fprintf(w, "# throttle-schedule: %s; active entry: %s\n", throttleSchedule, activeEntry)
+	}
 	if this.migrationContext.PostponeCutOverFlagFile != "" {
 		setIndicator := ""
 		if base.FileExists(this.migrationContext.PostponeCutOverFlagFile) {
@@ -923,6 +1671,30 @@ func (this *Migrator) printMigrationStatusHint(writers ...io.Writer) {
 			this.migrationContext.PostponeCutOverFlagFile, setIndicator,
 		)
 	}
+	if window := this.migrationContext.GetCutOverWindow(); window != nil {
+		windowState := "open"
+		if now := time.Now(); !window.Contains(now) {
+			windowState = fmt.Sprintf("closed; opens at %s", window.NextStart(now).Format(time.RFC3339))
+		}
+		fmt.Fprintf(w, "# cut-over-window: %s [%s]\n", window, windowState)
+	}
+	if deadline := this.migrationContext.GetCutOverDeadline(); !deadline.IsZero() {
+		fmt.Fprintf(w, "# cut-over-deadline: %s\n", deadline.Format(time.RFC3339))
+	}
+	if this.shadowVerifier != nil {
+		fmt.Fprintf(w, "# Shadow verification: every %+vs; verified rows: %+v; mismatches: %+v\n",
+			this.migrationContext.ShadowVerifyIntervalSeconds,
+			atomic.LoadInt64(&this.migrationContext.ShadowVerifiedRowsCount),
+			atomic.LoadInt64(&this.migrationContext.ShadowVerifyMismatchesCount),
+		)
+	}
+	if this.spillQueue != nil {
+		spilledDepth, spilledBytes := this.spillQueue.Backlog()
+		fmt.Fprintf(w, "# DML backlog spills to %s, up to %dMB; spilled events: %d (%.1fMB)\n",
+			this.spillQueue.dir, this.migrationContext.DMLBacklogSpillMaxMB,
+			spilledDepth, float64(spilledBytes)/bytesPerMB,
+		)
+	}
 	if this.migrationContext.PanicFlagFile != "" {
 		fmt.Fprintf(w, "# panic-flag-file: %+v\n",
 			this.migrationContext.PanicFlagFile,
@@ -997,6 +1769,10 @@ func (this *Migrator) getMigrationStateAndETA(rowsEstimate int64) (state, eta st
 		state = "postponing cut-over"
 	} else if isThrottled, throttleReason, _ := this.migrationContext.IsThrottled(); isThrottled {
 		state = fmt.Sprintf("throttled, %s", throttleReason)
+	} else if this.migrationContext.IsRowCopyPaused() {
+		state = fmt.Sprintf("paused row copy, throttle-schedule %s", this.migrationContext.GetActiveThrottleScheduleEntry())
+	} else if this.migrationContext.IsApplyLagExceeded() {
+		state = fmt.Sprintf("throttled row copy, apply-lag %.2fs > %dms", this.migrationContext.GetApplyLag().Seconds(), atomic.LoadInt64(&this.migrationContext.MaxApplyLagMillisecondsThrottleThreshold))
 	}
 	return state, eta, etaDuration
 }
@@ -1035,6 +1811,100 @@ func (this *Migrator) shouldPrintMigrationStatusHint(rule PrintStatusRule, elaps
 	return shouldPrint
 }
 
+// getRowsEstimate returns the estimated number of rows to copy
+func (this *Migrator) getRowsEstimate(totalRowsCopied int64) int64 {
+	if atomic.LoadInt64(&this.rowCopyCompleteFlag) == 1 {
+		// Done copying rows. The totalRowsCopied value is the de-facto number of rows,
+		// and there is no further need to keep updating the value.
+		return totalRowsCopied
+	}
+	return atomic.LoadInt64(&this.migrationContext.RowsEstimate) + atomic.LoadInt64(&this.migrationContext.RowsDeltaEstimate)
+}
+
+// statusFields returns the fields of the status line, as logged by loggers which support fields
+func (this *Migrator) statusFields(totalRowsCopied, rowsEstimate int64, progressPct float64, state, eta string) base.LogFields {
+	elapsedTime := this.migrationContext.ElapsedTime()
+	var spilledDepth, spilledBytes int64
+	if this.spillQueue != nil {
+		spilledDepth, spilledBytes = this.spillQueue.Backlog()
+	}
+	var currentBinlogCoordinates mysql.BinlogCoordinates
+	if this.eventsStreamer != nil {
+		currentBinlogCoordinates = *this.eventsStreamer.GetCurrentBinlogCoordinates()
+	}
+	return base.LogFields{
+		"copied_rows":           totalRowsCopied,
+		"estimated_rows":        rowsEstimate,
+		"progress_pct":          progressPct,
+		"applied_events":        atomic.LoadInt64(&this.migrationContext.TotalDMLEventsApplied),
+		"backlog":               len(this.applyEventsQueue),
+		"backlog_capacity":      cap(this.applyEventsQueue),
+		"spilled_events":        spilledDepth,
+		"spilled_bytes":         spilledBytes,
+		"elapsed_seconds":       elapsedTime.Seconds(),
+		"copy_elapsed_seconds":  this.migrationContext.ElapsedRowCopyTime().Seconds(),
+		"binlog_file":           currentBinlogCoordinates.LogFile,
+		"binlog_pos":            currentBinlogCoordinates.LogPos,
+		"lag_seconds":           this.migrationContext.GetCurrentLagDuration().Seconds(),
+		"heartbeat_lag_seconds": this.migrationContext.TimeSinceLastHeartbeatOnChangelog().Seconds(),
+		"apply_lag_seconds":     this.migrationContext.GetApplyLag().Seconds(),
+		"state":                 state,
+		"eta":                   eta,
+		"eta_seconds":           this.migrationContext.GetETASeconds(),
+	}
+}
+
+// printStatusJSON writes the status, along with what `gh-ost top` shows, as a JSON object
+func (this *Migrator) printStatusJSON(writers ...io.Writer) {
+	totalRowsCopied := this.migrationContext.GetTotalRowsCopied()
+	rowsEstimate := this.getRowsEstimate(totalRowsCopied)
+	state, eta, _ := this.getMigrationStateAndETA(rowsEstimate)
+	fields := this.statusFields(totalRowsCopied, rowsEstimate, this.getProgressPercent(rowsEstimate), state, eta)
+
+	fields["database"] = this.migrationContext.DatabaseName
+	fields["table"] = this.migrationContext.OriginalTableName
+	fields["phase"] = this.migrationContext.GetPhase()
+	fields["rows_per_second"] = atomic.LoadInt64(&this.migrationContext.EtaRowsPerSecond)
+	fields["chunk_size"] = atomic.LoadInt64(&this.migrationContext.ChunkSize)
+	fields["nice_ratio"] = this.migrationContext.GetNiceRatio()
+	fields["postponing_cut_over"] = atomic.LoadInt64(&this.migrationContext.IsPostponingCutOver) > 0
+	throttled, throttleReason, _ := this.migrationContext.IsThrottled()
+	fields["throttled"] = throttled
+	fields["throttle_reason"] = throttleReason
+
+	throttleHistory := []base.LogFields{}
+	for _, throttleEvent := range this.migrationContext.GetThrottleHistory() {
+		throttleHistory = append(throttleHistory, base.LogFields{
+			"time":      throttleEvent.Time.Format(time.RFC3339),
+			"throttled": throttleEvent.Throttled,
+			"reason":    throttleEvent.Reason,
+		})
+	}
+	fields["throttle_history"] = throttleHistory
+
+	controlReplicas := []base.LogFields{}
+	for _, lagResult := range this.migrationContext.GetControlReplicasLagResults() {
+		controlReplica := base.LogFields{
+			"replica":     lagResult.Key.StringCode(),
+			"lag_seconds": lagResult.Lag.Seconds(),
+		}
+		if lagResult.Err != nil {
+			controlReplica["error"] = lagResult.Err.Error()
+		}
+		controlReplicas = append(controlReplicas, controlReplica)
+	}
+	fields["control_replicas"] = controlReplicas
+
+	chunkRangeMin, chunkRangeMax := this.migrationContext.GetCurrentChunkRange()
+	fields["chunk_range_min"] = chunkRangeMin
+	fields["chunk_range_max"] = chunkRangeMax
+	fields["iteration"] = this.migrationContext.GetIteration()
+
+	if err := json.NewEncoder(io.MultiWriter(writers...)).Encode(fields); err != nil {
+		this.log.Errore(err)
+	}
+}
+
 // printStatus prints the progress status, and optionally additionally detailed
 // dump of configuration.
 // `rule` indicates the type of output expected.
@@ -1044,17 +1914,16 @@ func (this *Migrator) printStatus(rule PrintStatusRule, writers ...io.Writer) {
 	if rule == NoPrintStatusRule {
 		return
 	}
+	if rule == ForcePrintStatusJSONRule {
+		this.printStatusJSON(writers...)
+		return
+	}
 	writers = append(writers, os.Stdout)
 
 	elapsedTime := this.migrationContext.ElapsedTime()
 	elapsedSeconds := int64(elapsedTime.Seconds())
 	totalRowsCopied := this.migrationContext.GetTotalRowsCopied()
-	rowsEstimate := atomic.LoadInt64(&this.migrationContext.RowsEstimate) + atomic.LoadInt64(&this.migrationContext.RowsDeltaEstimate)
-	if atomic.LoadInt64(&this.rowCopyCompleteFlag) == 1 {
-		// Done copying rows. The totalRowsCopied value is the de-facto number of rows,
-		// and there is no further need to keep updating the value.
-		rowsEstimate = totalRowsCopied
-	}
+	rowsEstimate := this.getRowsEstimate(totalRowsCopied)
 
 	// we take the opportunity to update migration context with progressPct
 	progressPct := this.getProgressPercent(rowsEstimate)
@@ -1068,6 +1937,18 @@ func (this *Migrator) printStatus(rule PrintStatusRule, writers ...io.Writer) {
 	// Get state + ETA
 	state, eta, etaDuration := this.getMigrationStateAndETA(rowsEstimate)
 	this.migrationContext.SetETADuration(etaDuration)
+	this.migrationContext.NotifyProgress(base.MigrationProgress{
+		CopiedRows:    totalRowsCopied,
+		EstimatedRows: rowsEstimate,
+		ProgressPct:   progressPct,
+		AppliedEvents: atomic.LoadInt64(&this.migrationContext.TotalDMLEventsApplied),
+		Backlog:       len(this.applyEventsQueue),
+		Elapsed:       elapsedTime,
+		CopyElapsed:   this.migrationContext.ElapsedRowCopyTime(),
+		Lag:           this.migrationContext.GetCurrentLagDuration(),
+		State:         state,
+		ETA:           etaDuration,
+	})
 
 	if !this.shouldPrintStatus(rule, elapsedSeconds, etaDuration) {
 		return
@@ -1075,14 +1956,21 @@ func (this *Migrator) printStatus(rule PrintStatusRule, writers ...io.Writer) {
 
 	currentBinlogCoordinates := *this.eventsStreamer.GetCurrentBinlogCoordinates()
 
-	status := fmt.Sprintf("Copy: %d/%d %.1f%%; Applied: %d; Backlog: %d/%d; Time: %+v(total), %+v(copy); streamer: %+v; Lag: %.2fs, HeartbeatLag: %.2fs, State: %s; ETA: %s",
+	backlog := fmt.Sprintf("%d/%d", len(this.applyEventsQueue), cap(this.applyEventsQueue))
+	var spilledDepth, spilledBytes int64
+	if this.spillQueue != nil {
+		spilledDepth, spilledBytes = this.spillQueue.Backlog()
+		backlog = fmt.Sprintf("%s, spilled: %d (%.1fMB)", backlog, spilledDepth, float64(spilledBytes)/bytesPerMB)
+	}
+	status := fmt.Sprintf("Copy: %d/%d %.1f%%; Applied: %d; Backlog: %s; Time: %+v(total), %+v(copy); streamer: %+v; Lag: %.2fs, HeartbeatLag: %.2fs, ApplyLag: %.2fs, State: %s; ETA: %s",
 		totalRowsCopied, rowsEstimate, progressPct,
 		atomic.LoadInt64(&this.migrationContext.TotalDMLEventsApplied),
-		len(this.applyEventsQueue), cap(this.applyEventsQueue),
+		backlog,
 		base.PrettifyDurationOutput(elapsedTime), base.PrettifyDurationOutput(this.migrationContext.ElapsedRowCopyTime()),
 		currentBinlogCoordinates,
 		this.migrationContext.GetCurrentLagDuration().Seconds(),
 		this.migrationContext.TimeSinceLastHeartbeatOnChangelog().Seconds(),
+		this.migrationContext.GetApplyLag().Seconds(),
 		state,
 		eta,
 	)
@@ -1093,13 +1981,17 @@ func (this *Migrator) printStatus(rule PrintStatusRule, writers ...io.Writer) {
 	w := io.MultiWriter(writers...)
 	fmt.Fprintln(w, status)
 
-	// This "hack" is required here because the underlying logging library
-	// github.com/outbrain/golib/log provides two functions Info and Infof; but the arguments of
-	// both these functions are eventually redirected to the same function, which internally calls
-	// fmt.Sprintf. So, the argument of every function called on the DefaultLogger object
-	// migrationContext.Log will eventually pass through fmt.Sprintf, and thus the '%' character
-	// needs to be escaped.
-	this.migrationContext.Log.Info(strings.Replace(status, "%", "%%", 1))
+	if fieldsLogger, ok := this.log.(base.FieldsLogger); ok {
+		fieldsLogger.InfoFields("Status", this.statusFields(totalRowsCopied, rowsEstimate, progressPct, state, eta))
+	} else {
+		// This "hack" is required here because the underlying logging library
+		// github.com/outbrain/golib/log provides two functions Info and Infof; but the arguments of
+		// both these functions are eventually redirected to the same function, which internally calls
+		// fmt.Sprintf. So, the argument of every function called on the DefaultLogger object
+		// migrationContext.Log will eventually pass through fmt.Sprintf, and thus the '%' character
+		// needs to be escaped.
+		this.log.Info(strings.Replace(status, "%", "%%", 1))
+	}
 
 	hooksStatusIntervalSec := this.migrationContext.HooksStatusIntervalSec
 	if hooksStatusIntervalSec > 0 && elapsedSeconds%hooksStatusIntervalSec == 0 {
@@ -1123,12 +2015,12 @@ func (this *Migrator) initiateStreaming() error {
 	)
 
 	go func() {
-		this.migrationContext.Log.Debugf("Beginning streaming")
+		this.log.Debugf("Beginning streaming")
 		err := this.eventsStreamer.StreamEvents(this.canStopStreaming)
 		if err != nil {
-			this.migrationContext.PanicAbort <- err
+			this.migrationContext.SendPanicAbort(err)
 		}
-		this.migrationContext.Log.Debugf("Done streaming")
+		this.log.Debugf("Done streaming")
 	}()
 
 	go func() {
@@ -1147,50 +2039,165 @@ func (this *Migrator) initiateStreaming() error {
 // addDMLEventsListener begins listening for binlog events on the original table,
 // and creates & enqueues a write task per such event.
 func (this *Migrator) addDMLEventsListener() error {
-	err := this.eventsStreamer.AddListener(
+	if this.migrationContext.DMLBacklogSpillDir != "" {
+		spillQueue, err := NewSpillQueue(this.migrationContext, this.applyEventsQueue)
+		if err != nil {
+			return err
+		}
+		this.spillQueue = spillQueue
+		this.log.Infof("DML backlog spills to %s", spillQueue.dir)
+		go func() {
+			if err := this.spillQueue.Drain(); err != nil {
+				this.migrationContext.SendPanicAbort(err)
+			}
+		}()
+	}
+	err := this.eventsStreamer.AddEntryListener(
 		false,
 		this.migrationContext.DatabaseName,
 		this.migrationContext.OriginalTableName,
-		func(dmlEvent *binlog.BinlogDMLEvent) error {
-			this.applyEventsQueue <- newApplyEventStructByDML(dmlEvent)
+		func(entry *binlog.BinlogEntry) error {
+			this.migrationContext.AddPendingApplyEvent(entry.Timestamp)
+			if err := this.enqueueApplyEvent(newApplyEventStructByDML(entry.DmlEvent)); err != nil {
+				this.migrationContext.SendPanicAbort(err)
+				return err
+			}
 			return nil
 		},
 	)
 	return err
 }
 
+// enqueueApplyEvent enqueues an event to apply, via the spill queue if there is one
+func (this *Migrator) enqueueApplyEvent(eventStruct *applyEventStruct) error {
+	if this.spillQueue != nil {
+		return this.spillQueue.Push(eventStruct)
+	}
+	select {
+	case this.applyEventsQueue <- eventStruct:
+	case <-this.migrated:
+		// Events are no longer applied
+	}
+	return nil
+}
+
 // initiateThrottler kicks in the throttling collection and the throttling checks.
 func (this *Migrator) initiateThrottler() {
 	this.throttler = NewThrottler(this.migrationContext, this.applier, this.inspector, this.appVersion)
 
 	go this.throttler.initiateThrottlerCollection(this.firstThrottlingCollected)
-	this.migrationContext.Log.Infof("Waiting for first throttle metrics to be collected")
+	this.log.Infof("Waiting for first throttle metrics to be collected")
 	<-this.firstThrottlingCollected // replication lag
 	<-this.firstThrottlingCollected // HTTP status
 	<-this.firstThrottlingCollected // other, general metrics
-	this.migrationContext.Log.Infof("First throttle metrics collected")
+	this.log.Infof("First throttle metrics collected")
 	go this.throttler.initiateThrottlerChecks()
 }
 
+// initiateShadowVerifier sets up continuous verification of applied DML events, if requested
+func (this *Migrator) initiateShadowVerifier() error {
+	if this.migrationContext.ShadowVerifyIntervalSeconds <= 0 {
+		return nil
+	}
+	this.shadowVerifier = NewShadowVerifier(this.migrationContext, this.applier)
+	if err := this.shadowVerifier.prepareQueries(); err != nil {
+		return err
+	}
+	this.log.Infof("Shadow verification enabled: verifying up to %d rows every %d seconds",
+		this.migrationContext.ShadowVerifySampleSize, this.migrationContext.ShadowVerifyIntervalSeconds,
+	)
+	return nil
+}
+
+// cutOverServer returns the server which renames the ghost table into place at cut-over, and its
+// MySQL version: the target server on a cross-server move, and the applier otherwise.
+func (this *Migrator) cutOverServer() (name string, version string) {
+	if this.migrationContext.IsCrossServerMove() {
+		return "target", this.migrationContext.TargetMySQLVersion
+	}
+	return "applier", this.migrationContext.ApplierMySQLVersion
+}
+
+// resolveCutOverType picks the cut-over type when none was explicitly given, and otherwise validates
+// the one given, by the server which performs the cut-over.
+func (this *Migrator) resolveCutOverType() error {
+	serverName, serverVersion := this.cutOverServer()
+	renameUnderLockSupported := mysql.IsMySQLVersionAtLeast(serverVersion, mysql.RenameUnderLockTablesMinVersion)
+	// Ghost triggers are created by another session, which would be blocked by the lock on the ghost table
+	includesTriggers := this.migrationContext.IncludeTriggers && len(this.migrationContext.Triggers) > 0
+	switch this.migrationContext.CutOverType {
+	case base.CutOverAuto:
+		if this.migrationContext.IsCrossServerMove() {
+			break
+		}
+		if renameUnderLockSupported && !includesTriggers {
+			this.migrationContext.CutOverType = base.CutOverLockAndRename
+			this.log.Infof("The %s runs %s; cut-over will rename tables under lock", serverName, serverVersion)
+		} else {
+			this.migrationContext.CutOverType = base.CutOverAtomic
+		}
+	case base.CutOverLockAndRename:
+		if !renameUnderLockSupported {
+			return fmt.Errorf("--cut-over=lock-and-rename requires MySQL %s or newer; the %s runs %s", mysql.RenameUnderLockTablesMinVersion, serverName, serverVersion)
+		}
+		if includesTriggers {
+			return fmt.Errorf("--cut-over=lock-and-rename does not support --include-triggers")
+		}
+	}
+	if this.migrationContext.IsCrossServerMove() {
+		// The table is moved by its own cut-over, whichever type was given
+		this.migrationContext.CutOverType = base.CutOverCrossServer
+	}
+	this.log.Infof("Cut-over type: %s", this.migrationContext.CutOverType)
+	return nil
+}
+
+// initiateCutOverBlockersDetection checks that sessions blocking the cut-over can be identified,
+// which requires performance_schema to instrument metadata locks.
+func (this *Migrator) initiateCutOverBlockersDetection() error {
+	instrumented, err := this.applier.MetadataLocksInstrumented()
+	if err != nil {
+		this.log.Errore(err)
+	}
+	if instrumented {
+		this.cutOverBlockersDetectable = true
+		return nil
+	}
+	if this.migrationContext.CutOverBlockersPolicy != base.CutOverBlockersLog {
+		return fmt.Errorf("--cut-over-blockers requires performance_schema with the wait/lock/metadata/sql/mdl instrument enabled")
+	}
+	this.log.Warningf("performance_schema does not instrument metadata locks (wait/lock/metadata/sql/mdl). Sessions blocking the cut-over will not be identified")
+	return nil
+}
+
 func (this *Migrator) initiateApplier() error {
 	this.applier = NewApplier(this.migrationContext)
 	if err := this.applier.InitDBConnections(); err != nil {
 		return err
 	}
+	if err := this.resolveCutOverType(); err != nil {
+		return err
+	}
+	if err := this.applier.ValidateInnoDBMetrics(this.migrationContext.GetMaxInnoDBMetrics()); err != nil {
+		return this.log.Errore(err)
+	}
+	if err := this.initiateCutOverBlockersDetection(); err != nil {
+		return err
+	}
 	if err := this.applier.ValidateOrDropExistingTables(); err != nil {
 		return err
 	}
 	if err := this.applier.CreateChangelogTable(); err != nil {
-		this.migrationContext.Log.Errorf("Unable to create changelog table, see further error details. Perhaps a previous migration failed without dropping the table? OR is there a running migration? Bailing out")
+		this.log.Errorf("Unable to create changelog table, see further error details. Perhaps a previous migration failed without dropping the table? OR is there a running migration? Bailing out")
 		return err
 	}
 	if err := this.applier.CreateGhostTable(); err != nil {
-		this.migrationContext.Log.Errorf("Unable to create ghost table, see further error details. Perhaps a previous migration failed without dropping the table? Bailing out")
+		this.log.Errorf("Unable to create ghost table, see further error details. Perhaps a previous migration failed without dropping the table? Bailing out")
 		return err
 	}
 
 	if err := this.applier.AlterGhost(); err != nil {
-		this.migrationContext.Log.Errorf("Unable to ALTER ghost table, see further error details. Bailing out")
+		this.log.Errorf("Unable to ALTER ghost table, see further error details. Bailing out")
 		return err
 	}
 
@@ -1198,7 +2205,7 @@ func (this *Migrator) initiateApplier() error {
 		// Original table has AUTO_INCREMENT value and the -alter statement does not indicate any override,
 		// so we should copy AUTO_INCREMENT value onto our ghost table.
 		if err := this.applier.AlterGhostAutoIncrement(); err != nil {
-			this.migrationContext.Log.Errorf("Unable to ALTER ghost table AUTO_INCREMENT value, see further error details. Bailing out")
+			this.log.Errorf("Unable to ALTER ghost table AUTO_INCREMENT value, see further error details. Bailing out")
 			return err
 		}
 	}
@@ -1211,15 +2218,18 @@ func (this *Migrator) initiateApplier() error {
 // a chunk of rows onto the ghost table.
 func (this *Migrator) iterateChunks() error {
 	terminateRowIteration := func(err error) error {
-		this.rowCopyComplete <- err
-		return this.migrationContext.Log.Errore(err)
+		select {
+		case this.rowCopyComplete <- err:
+		case <-this.aborted:
+		}
+		return this.log.Errore(err)
 	}
 	if this.migrationContext.Noop {
-		this.migrationContext.Log.Debugf("Noop operation; not really copying data")
+		this.log.Debugf("Noop operation; not really copying data")
 		return terminateRowIteration(nil)
 	}
 	if this.migrationContext.MigrationRangeMinValues == nil {
-		this.migrationContext.Log.Debugf("No rows found in table. Rowcopy will be implicitly empty")
+		this.log.Debugf("No rows found in table. Rowcopy will be implicitly empty")
 		return terminateRowIteration(nil)
 	}
 
@@ -1273,7 +2283,7 @@ func (this *Migrator) iterateChunks() error {
 				if this.migrationContext.PanicOnWarnings {
 					if len(this.migrationContext.MigrationLastInsertSQLWarnings) > 0 {
 						for _, warning := range this.migrationContext.MigrationLastInsertSQLWarnings {
-							this.migrationContext.Log.Infof("ApplyIterationInsertQuery has SQL warnings! %s", warning)
+							this.log.Infof("ApplyIterationInsertQuery has SQL warnings! %s", warning)
 						}
 						if expectedRangeSize != rowsAffected {
 							joinedWarnings := strings.Join(this.migrationContext.MigrationLastInsertSQLWarnings, "; ")
@@ -1292,7 +2302,11 @@ func (this *Migrator) iterateChunks() error {
 			return nil
 		}
 		// Enqueue copy operation; to be executed by executeWriteFuncs()
-		this.copyRowsQueue <- copyRowsFunc
+		select {
+		case this.copyRowsQueue <- copyRowsFunc:
+		case <-this.aborted:
+			return this.abortError
+		}
 	}
 }
 
@@ -1300,7 +2314,7 @@ func (this *Migrator) onApplyEventStruct(eventStruct *applyEventStruct) error {
 	handleNonDMLEventStruct := func(eventStruct *applyEventStruct) error {
 		if eventStruct.writeFunc != nil {
 			if err := this.retryOperation(*eventStruct.writeFunc); err != nil {
-				return this.migrationContext.Log.Errore(err)
+				return this.log.Errore(err)
 			}
 		}
 		return nil
@@ -1334,13 +2348,17 @@ func (this *Migrator) onApplyEventStruct(eventStruct *applyEventStruct) error {
 			return this.applier.ApplyDMLEventQueries(dmlEvents)
 		}
 		if err := this.retryOperation(applyEventFunc); err != nil {
-			return this.migrationContext.Log.Errore(err)
+			return this.log.Errore(err)
+		}
+		this.migrationContext.RemoveAppliedEvents(int64(len(dmlEvents)))
+		if this.shadowVerifier != nil {
+			this.shadowVerifier.Record(dmlEvents)
 		}
 		if nonDmlStructToApply != nil {
 			// We pulled DML events from the queue, and then we hit a non-DML event. Wait!
 			// We need to handle it!
 			if err := handleNonDMLEventStruct(nonDmlStructToApply); err != nil {
-				return this.migrationContext.Log.Errore(err)
+				return this.log.Errore(err)
 			}
 		}
 	}
@@ -1351,12 +2369,15 @@ func (this *Migrator) onApplyEventStruct(eventStruct *applyEventStruct) error {
 // This is where the ghost table gets the data. The function fills the data single-threaded.
 // Both event backlog and rowcopy events are polled; the backlog events have precedence.
 func (this *Migrator) executeWriteFuncs() error {
+	if this.writeFuncsDone != nil {
+		defer close(this.writeFuncsDone)
+	}
 	if this.migrationContext.Noop {
-		this.migrationContext.Log.Debugf("Noop operation; not really executing write funcs")
+		this.log.Debugf("Noop operation; not really executing write funcs")
 		return nil
 	}
 	for {
-		if atomic.LoadInt64(&this.finishedMigrating) > 0 {
+		if atomic.LoadInt64(&this.finishedMigrating) > 0 || this.abortedError() != nil {
 			return nil
 		}
 
@@ -1373,13 +2394,23 @@ func (this *Migrator) executeWriteFuncs() error {
 			}
 		default:
 			{
+				if this.migrationContext.IsApplyLagExceeded() {
+					// The applier is too far behind the binary log: row copy waits for it to catch up
+					time.Sleep(250 * time.Millisecond)
+					continue
+				}
+				if this.migrationContext.IsRowCopyPaused() {
+					// A throttle schedule pause window: row copy waits it out, events are still applied
+					time.Sleep(250 * time.Millisecond)
+					continue
+				}
 				select {
 				case copyRowsFunc := <-this.copyRowsQueue:
 					{
 						copyRowsStartTime := time.Now()
 						// Retries are handled within the copyRowsFunc
 						if err := copyRowsFunc(); err != nil {
-							return this.migrationContext.Log.Errore(err)
+							return this.log.Errore(err)
 						}
 						if niceRatio := this.migrationContext.GetNiceRatio(); niceRatio > 0 {
 							copyRowsDuration := time.Since(copyRowsStartTime)
@@ -1392,7 +2423,7 @@ func (this *Migrator) executeWriteFuncs() error {
 					{
 						// Hmmmmm... nothing in the queue; no events, but also no row copy.
 						// This is possible upon load. Let's just sleep it over.
-						this.migrationContext.Log.Debugf("Getting nothing in the write queue. Sleeping...")
+						this.log.Debugf("Getting nothing in the write queue. Sleeping...")
 						time.Sleep(time.Second)
 					}
 				}
@@ -1404,22 +2435,23 @@ func (this *Migrator) executeWriteFuncs() error {
 // finalCleanup takes actions at very end of migration, dropping tables etc.
 func (this *Migrator) finalCleanup() error {
 	atomic.StoreInt64(&this.migrationContext.CleanupImminentFlag, 1)
+	this.migrationContext.SetPhase(base.CleanupPhase)
 
-	this.migrationContext.Log.Infof("Writing changelog state: %+v", Migrated)
+	this.log.Infof("Writing changelog state: %+v", Migrated)
 	if _, err := this.applier.WriteChangelogState(string(Migrated)); err != nil {
 		return err
 	}
 
 	if this.migrationContext.Noop {
-		if createTableStatement, err := this.inspector.showCreateTable(this.migrationContext.GetGhostTableName()); err == nil {
-			this.migrationContext.Log.Infof("New table structure follows")
+		if createTableStatement, err := this.inspector.getGhostTableInspector().showCreateTable(this.migrationContext.GetGhostTableName()); err == nil {
+			this.log.Infof("New table structure follows")
 			fmt.Println(createTableStatement)
 		} else {
-			this.migrationContext.Log.Errore(err)
+			this.log.Errore(err)
 		}
 	}
 	if err := this.eventsStreamer.Close(); err != nil {
-		this.migrationContext.Log.Errore(err)
+		this.log.Errore(err)
 	}
 
 	if err := this.retryOperation(this.applier.DropChangelogTable); err != nil {
@@ -1431,8 +2463,8 @@ func (this *Migrator) finalCleanup() error {
 		}
 	} else {
 		if !this.migrationContext.Noop {
-			this.migrationContext.Log.Infof("Am not dropping old table because I want this operation to be as live as possible. If you insist I should do it, please add `--ok-to-drop-table` next time. But I prefer you do not. To drop the old table, issue:")
-			this.migrationContext.Log.Infof("-- drop table %s.%s", sql.EscapeName(this.migrationContext.DatabaseName), sql.EscapeName(this.migrationContext.GetOldTableName()))
+			this.log.Infof("Am not dropping old table because I want this operation to be as live as possible. If you insist I should do it, please add `--ok-to-drop-table` next time. But I prefer you do not. To drop the old table, issue:")
+			this.log.Infof("-- drop table %s.%s", sql.EscapeName(this.migrationContext.DatabaseName), sql.EscapeName(this.migrationContext.GetOldTableName()))
 		}
 	}
 	if this.migrationContext.Noop {
@@ -1444,26 +2476,60 @@ func (this *Migrator) finalCleanup() error {
 	return nil
 }
 
+// dropAbortedTables drops the ghost and changelog tables when so requested by an abort. Writes onto
+// these tables are stopped first: row copy and events apply, then the heartbeat.
+func (this *Migrator) dropAbortedTables() {
+	if atomic.LoadInt64(&this.dropTablesOnAbort) == 0 || this.applier == nil {
+		return
+	}
+	if this.writeFuncsDone != nil {
+		<-this.writeFuncsDone
+	}
+	this.applier.StopHeartbeat()
+	if err := this.applier.DropGhostTable(); err != nil {
+		this.log.Errore(err)
+	}
+	if err := this.applier.DropChangelogTable(); err != nil {
+		this.log.Errore(err)
+	}
+}
+
 func (this *Migrator) teardown() {
 	atomic.StoreInt64(&this.finishedMigrating, 1)
+	this.dropAbortedTables()
+
+	if this.server != nil {
+		this.log.Infof("Tearing down server")
+		this.server.Close()
+	}
 
 	if this.inspector != nil {
-		this.migrationContext.Log.Infof("Tearing down inspector")
+		this.log.Infof("Tearing down inspector")
 		this.inspector.Teardown()
 	}
 
 	if this.applier != nil {
-		this.migrationContext.Log.Infof("Tearing down applier")
+		this.log.Infof("Tearing down applier")
 		this.applier.Teardown()
 	}
 
 	if this.eventsStreamer != nil {
-		this.migrationContext.Log.Infof("Tearing down streamer")
+		this.log.Infof("Tearing down streamer")
 		this.eventsStreamer.Teardown()
 	}
 
 	if this.throttler != nil {
-		this.migrationContext.Log.Infof("Tearing down throttler")
+		this.log.Infof("Tearing down throttler")
 		this.throttler.Teardown()
 	}
+
+	if this.shadowVerifier != nil {
+		this.log.Infof("Tearing down shadow verifier")
+		this.shadowVerifier.Teardown()
+	}
+
+	if this.spillQueue != nil {
+		this.log.Infof("Tearing down spill queue")
+		this.spillQueue.Teardown()
+	}
 }
@@ -6,9 +6,14 @@
 package logic
 
 import (
+	"bytes"
 	"context"
 	gosql "database/sql"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
+	"net"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -26,6 +31,7 @@ import (
 
 	"github.com/github/gh-ost/go/base"
 	"github.com/github/gh-ost/go/binlog"
+	"github.com/github/gh-ost/go/mysql"
 	"github.com/github/gh-ost/go/sql"
 )
 
@@ -202,6 +208,40 @@ func TestMigratorGetProgressPercent(t *testing.T) {
 	}
 }
 
+func TestMigratorPrintStatusJSON(t *testing.T) {
+	migrationContext := base.NewMigrationContext()
+	migrationContext.DatabaseName = "test"
+	migrationContext.OriginalTableName = "t1"
+	migrationContext.TotalRowsCopied = 250
+	migrationContext.RowsEstimate = 1000
+	migrationContext.SetPhase(base.RowCopyPhase)
+	migrationContext.SetThrottled(true, "flag-file", base.NoThrottleReasonHint)
+	migrationContext.SetControlReplicasLagResults([]mysql.ReplicationLagResult{
+		{Key: mysql.InstanceKey{Hostname: "replica1", Port: 3306}, Lag: 1500 * time.Millisecond},
+		{Key: mysql.InstanceKey{Hostname: "replica2", Port: 3306}, Err: errors.New("unreachable")},
+	})
+	migrationContext.SetCurrentChunkRange(sql.ToColumnValues([]interface{}{1}), sql.ToColumnValues([]interface{}{1000}))
+	migrator := NewMigrator(migrationContext, "1.2.3")
+
+	var output bytes.Buffer
+	migrator.printStatus(ForcePrintStatusJSONRule, &output)
+	status := map[string]interface{}{}
+	require.NoError(t, json.Unmarshal(output.Bytes(), &status))
+	require.Equal(t, "test", status["database"])
+	require.Equal(t, "row-copy", status["phase"])
+	require.Equal(t, float64(250), status["copied_rows"])
+	require.Equal(t, float64(25), status["progress_pct"])
+	require.Equal(t, "throttled, flag-file", status["state"])
+	require.Equal(t, true, status["throttled"])
+	require.Equal(t, "1", status["chunk_range_min"])
+	require.Equal(t, "1000", status["chunk_range_max"])
+	require.Len(t, status["throttle_history"], 1)
+	require.Equal(t, []interface{}{
+		map[string]interface{}{"replica": "replica1:3306", "lag_seconds": 1.5},
+		map[string]interface{}{"replica": "replica2:3306", "lag_seconds": float64(0), "error": "unreachable"},
+	}, status["control_replicas"])
+}
+
 func TestMigratorGetMigrationStateAndETA(t *testing.T) {
 	migrationContext := base.NewMigrationContext()
 	migrator := NewMigrator(migrationContext, "1.2.3")
@@ -379,6 +419,47 @@ func (suite *MigratorTestSuite) TestFoo() {
 	suite.Require().Equal("_testing_del", tableName)
 }
 
+func (suite *MigratorTestSuite) TestEmbeddedMigrationsOnSameTCPPort() {
+	ctx := context.Background()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	suite.Require().NoError(err)
+	port := listener.Addr().(*net.TCPAddr).Port
+	suite.Require().NoError(listener.Close())
+
+	connectionConfig, err := GetConnectionConfig(ctx, suite.mysqlContainer)
+	suite.Require().NoError(err)
+
+	for _, tableName := range []string{"testing1", "testing2"} {
+		_, err := suite.db.ExecContext(ctx, fmt.Sprintf("CREATE TABLE test.%s (id INT PRIMARY KEY, name VARCHAR(64))", tableName))
+		suite.Require().NoError(err)
+
+		migrationContext := base.NewMigrationContext()
+		migrationContext.AllowedRunningOnMaster = true
+		migrationContext.ApplierConnectionConfig = connectionConfig
+		migrationContext.InspectorConnectionConfig = connectionConfig
+		migrationContext.DatabaseName = "test"
+		migrationContext.SkipPortValidation = true
+		migrationContext.OriginalTableName = tableName
+		migrationContext.SetConnectionConfig("innodb")
+		migrationContext.AlterStatementOptions = "ADD COLUMN foobar varchar(255), ENGINE=InnoDB"
+		migrationContext.ReplicaServerId = 99999
+		migrationContext.HeartbeatIntervalMilliseconds = 100
+		migrationContext.ThrottleHTTPIntervalMillis = 100
+		migrationContext.ThrottleHTTPTimeoutMillis = 1000
+		migrationContext.ServeSocketFile = filepath.Join(suite.T().TempDir(), "gh-ost.sock")
+		migrationContext.ServeTCPPort = int64(port)
+
+		migrator := NewEmbeddedMigrator(migrationContext, "0.0.0")
+		suite.Require().NoError(migrator.Migrate())
+
+		// The migration has released its socket file and TCP port
+		suite.Require().NoFileExists(migrationContext.ServeSocketFile)
+		_, err = net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+		suite.Require().Error(err)
+	}
+}
+
 func TestMigratorRetry(t *testing.T) {
 	oldRetrySleepFn := RetrySleepFn
 	defer func() { RetrySleepFn = oldRetrySleepFn }()
@@ -445,6 +526,172 @@ func TestMigratorRetryWithExponentialBackoff(t *testing.T) {
 	assert.Equal(t, tries, 100)
 }
 
+func TestMigratorAbort(t *testing.T) {
+	migrationContext := base.NewMigrationContext()
+	migrationContext.SetDefaultNumRetries(100)
+	migrator := NewEmbeddedMigrator(migrationContext, "1.2.3")
+	require.NoError(t, migrator.abortedError())
+
+	go migrator.listenOnPanicAbort()
+	abortErr := errors.New("row copy failed")
+	migrationContext.PanicAbort <- abortErr
+	<-migrator.aborted
+	require.Equal(t, abortErr, migrator.abortedError())
+
+	// Later aborts, and panics, do not override the first
+	migrator.Abort(errors.New("canceled"))
+	migrationContext.PanicAbort <- errors.New("streamer failed")
+	require.Equal(t, abortErr, migrator.abortedError())
+
+	tries := 0
+	require.Equal(t, abortErr, migrator.retryOperation(func() error {
+		tries++
+		return nil
+	}))
+	require.Zero(t, tries)
+	require.Equal(t, abortErr, migrator.sleepWhileTrue(func() (bool, error) { return true, nil }))
+	require.Equal(t, abortErr, migrator.consumeRowCopyComplete())
+
+	close(migrator.migrated)
+}
+
+func TestMigratorAbortOnCutOverDeadline(t *testing.T) {
+	migrationContext := base.NewMigrationContext()
+	migrator := NewMigrator(migrationContext, "1.2.3")
+	go migrator.listenOnPanicAbort()
+
+	deadline := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	migrator.abortOnCutOverDeadline(deadline)
+	require.ErrorContains(t, migrator.abortedError(), "--cut-over-deadline 2024-01-01T12:00:00Z")
+	require.Equal(t, int64(1), atomic.LoadInt64(&migrator.dropTablesOnAbort))
+	// Tables are left for teardown to drop, rather than dropped under running writes
+	require.Zero(t, atomic.LoadInt64(&migrationContext.ThrottleCommandedByUser))
+
+	// Writes failing as the migration aborts do not have the process exit
+	migrationContext.SendPanicAbort(errors.New("row copy failed"))
+	require.True(t, migrationContext.IsPanicAbortClosed())
+
+	close(migrator.migrated)
+}
+
+func TestMigratorCutOverGateAbort(t *testing.T) {
+	migrationContext := base.NewMigrationContext()
+	migrationContext.SetDefaultNumRetries(60)
+	migrationContext.HooksPath = t.TempDir()
+	migrationContext.SetLastHeartbeatOnChangelogTime(time.Now())
+	require.NoError(t, os.WriteFile(filepath.Join(migrationContext.HooksPath, gateBeforeCutOver), []byte("#!/bin/sh\necho '{\"decision\": \"abort\", \"message\": \"change freeze\"}'"), 0777))
+	migrator := NewMigrator(migrationContext, "1.2.3")
+	migrator.hooksExecutor.writer = io.Discard
+	migrator.throttler = NewThrottler(migrationContext, nil, nil, "1.2.3")
+	go migrator.listenOnPanicAbort()
+
+	var sleeps int
+	defer func(retrySleepFn func(time.Duration)) { RetrySleepFn = retrySleepFn }(RetrySleepFn)
+	RetrySleepFn = func(time.Duration) { sleeps++ }
+
+	err := migrator.retryOperation(migrator.cutOver)
+	require.ErrorContains(t, err, "before-cut-over gate aborted the migration: change freeze")
+	// The migration aborts, tearing down as on any failure, rather than have the process exit
+	require.Equal(t, err, migrator.abortedError())
+	require.True(t, migrationContext.IsPanicAbortClosed())
+	// No further attempts are made
+	require.Zero(t, sleeps)
+	require.Equal(t, cutOverAbandoned, atomic.LoadInt64(&migrator.cutOverState))
+
+	close(migrator.migrated)
+}
+
+func TestMigratorAbortOnCriticalDiskSpace(t *testing.T) {
+	migrationContext := base.NewMigrationContext()
+	migrator := NewMigrator(migrationContext, "1.2.3")
+	go migrator.listenOnPanicAbort()
+
+	migrator.abortOnCriticalDiskSpace(50 * bytesPerMB)
+	require.ErrorContains(t, migrator.abortedError(), "Free disk space 50MB below --critical-free-disk-space-mb")
+	require.Equal(t, int64(1), atomic.LoadInt64(&migrator.dropTablesOnAbort))
+	require.Zero(t, atomic.LoadInt64(&migrationContext.ThrottleCommandedByUser))
+
+	close(migrator.migrated)
+}
+
+func TestMigratorExecuteWriteFuncsPauseWindow(t *testing.T) {
+	migrationContext := base.NewMigrationContext()
+	migrationContext.ThrottleScheduleTimezone = "UTC"
+	migrator := NewMigrator(migrationContext, "1.2.3")
+	migrator.throttler = NewThrottler(migrationContext, nil, nil, "1.2.3")
+	require.NoError(t, migrationContext.ReadThrottleSchedule("00:00-24:00 pause"))
+	migrator.throttler.applyThrottleSchedule()
+	require.True(t, migrationContext.IsRowCopyPaused())
+
+	// A pause window does not throttle the migration as a whole
+	isThrottled, _, _ := migrationContext.IsThrottled()
+	require.False(t, isThrottled)
+
+	var rowsCopied int64
+	go func() {
+		migrator.copyRowsQueue <- func() error {
+			atomic.AddInt64(&rowsCopied, 1)
+			return nil
+		}
+	}()
+	eventApplied := make(chan struct{})
+	var writeFunc tableWriteFunc = func() error {
+		close(eventApplied)
+		return nil
+	}
+	migrator.applyEventsQueue <- newApplyEventStructByFunc(&writeFunc)
+
+	done := make(chan error)
+	go func() { done <- migrator.executeWriteFuncs() }()
+	select {
+	case <-eventApplied:
+	case <-time.After(time.Second):
+		t.Fatal("event not applied during pause window")
+	}
+	time.Sleep(500 * time.Millisecond)
+	require.Zero(t, atomic.LoadInt64(&rowsCopied))
+
+	// Row copy resumes once the window no longer applies
+	require.NoError(t, migrationContext.ReadThrottleSchedule(""))
+	migrator.throttler.applyThrottleSchedule()
+	require.Eventually(t, func() bool {
+		return atomic.LoadInt64(&rowsCopied) == 1
+	}, 2*time.Second, 10*time.Millisecond)
+
+	atomic.StoreInt64(&migrator.finishedMigrating, 1)
+	require.NoError(t, <-done)
+}
+
+func TestMigratorResolveCutOverType(t *testing.T) {
+	migrationContext := base.NewMigrationContext()
+	migrationContext.ApplierMySQLVersion = "8.0.40"
+	migrationContext.CutOverType = base.CutOverAuto
+	migrator := NewMigrator(migrationContext, "1.2.3")
+	require.NoError(t, migrator.resolveCutOverType())
+	require.Equal(t, base.CutOverLockAndRename, migrationContext.CutOverType)
+
+	migrationContext.ApplierMySQLVersion = "5.7.44-log"
+	migrationContext.CutOverType = base.CutOverAuto
+	require.NoError(t, migrator.resolveCutOverType())
+	require.Equal(t, base.CutOverAtomic, migrationContext.CutOverType)
+
+	// On a cross-server move, the target renames the ghost table into place; its version is what counts
+	migrationContext.TargetHostname = "target"
+	migrationContext.ApplierMySQLVersion = "8.0.40"
+	migrationContext.TargetMySQLVersion = "5.7.44-log"
+	migrationContext.CutOverType = base.CutOverLockAndRename
+	require.ErrorContains(t, migrator.resolveCutOverType(), "requires MySQL 8.0.13 or newer; the target runs 5.7.44-log")
+
+	migrationContext.ApplierMySQLVersion = "5.7.44-log"
+	migrationContext.TargetMySQLVersion = "8.0.40"
+	require.NoError(t, migrator.resolveCutOverType())
+	require.Equal(t, base.CutOverCrossServer, migrationContext.CutOverType)
+
+	migrationContext.CutOverType = base.CutOverAuto
+	require.NoError(t, migrator.resolveCutOverType())
+	require.Equal(t, base.CutOverCrossServer, migrationContext.CutOverType)
+}
+
 func TestMigrator(t *testing.T) {
 	suite.Run(t, new(MigratorTestSuite))
 }
@@ -9,6 +9,7 @@ import (
 	"bufio"
 	"bytes"
 	"compress/gzip"
+	"crypto/tls"
 	"encoding/base64"
 	"errors"
 	"fmt"
@@ -19,6 +20,7 @@ import (
 	"runtime/pprof"
 	"strconv"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -33,19 +35,90 @@ var (
 
 type printStatusFunc func(PrintStatusRule, io.Writer)
 
+const (
+	// CtlCommandPrefix prefixes commands sent by `gh-ost ctl`. The server strips it, and ends its
+	// reply with a CtlReplyOK or CtlReplyError line, such that the client tells errors apart.
+	CtlCommandPrefix = "ctl "
+	CtlReplyOK       = "# ctl: ok"
+	CtlReplyError    = "# ctl: error"
+)
+
+// InteractiveCommand is a command the server responds to, as listed by the help command
+type InteractiveCommand struct {
+	Name        string
+	Usage       string
+	Description string
+	Aliases     []string
+	// ReadOnly commands may be run by read-only clients, as may Queryable ones when given "?"
+	ReadOnly  bool
+	Queryable bool
+}
+
+// InteractiveCommands are the commands the server responds to
+var InteractiveCommands = []InteractiveCommand{
+	{Name: "status", Usage: "status", Description: "Print a detailed status message", Aliases: []string{"info"}, ReadOnly: true},
+	{Name: "sup", Usage: "sup", Description: "Print a short status message", ReadOnly: true},
+	{Name: "status-json", Usage: "status-json", Description: "Print the status as a JSON object, as read by gh-ost top", ReadOnly: true},
+	{Name: "cpu-profile", Usage: "cpu-profile=<options>", Description: "Print a base64-encoded runtime/pprof CPU profile using a duration, default: 30s. Comma-separated options 'gzip' and/or 'block' (blocked profile) may follow the profile duration"},
+	{Name: "coordinates", Usage: "coordinates", Description: "Print the currently inspected coordinates", ReadOnly: true},
+	{Name: "applier", Usage: "applier", Description: "Print the hostname of the applier", ReadOnly: true},
+	{Name: "inspector", Usage: "inspector", Description: "Print the hostname of the inspector", ReadOnly: true},
+	{Name: "chunk-size", Usage: "chunk-size=<newsize>", Description: "Set a new chunk-size", Queryable: true},
+	{Name: "dml-batch-size", Usage: "dml-batch-size=<newsize>", Description: "Set a new dml-batch-size", Queryable: true},
+	{Name: "nice-ratio", Usage: "nice-ratio=<ratio>", Description: "Set a new nice-ratio, immediate sleep after each row-copy operation, float (examples: 0 is aggressive, 0.7 adds 70% runtime, 1.0 doubles runtime, 2.0 triples runtime, ...)", Queryable: true},
+	{Name: "critical-load", Usage: "critical-load=<load>", Description: "Set a new set of max-load thresholds", Queryable: true},
+	{Name: "max-lag-millis", Usage: "max-lag-millis=<max-lag>", Description: "Set a new replication lag threshold", Queryable: true},
+	{Name: "max-apply-lag-millis", Usage: "max-apply-lag-millis=<max-lag>", Description: "Set a new apply lag threshold, beyond which row copy is throttled; 0 to disable", Queryable: true},
+	{Name: "replication-lag-query", Usage: "replication-lag-query=<query>", Description: "Set a new query that determines replication lag (no quotes)", Queryable: true},
+	{Name: "max-load", Usage: "max-load=<load>", Description: "Set a new set of max-load thresholds", Queryable: true},
+	{Name: "max-innodb-metrics", Usage: "max-innodb-metrics=<metrics>", Description: "Set a new set of InnoDB metrics thresholds", Queryable: true},
+	{Name: "throttle-query", Usage: "throttle-query=<query>", Description: "Set a new throttle-query (no quotes)", Queryable: true},
+	{Name: "throttle-http", Usage: "throttle-http=<URL>", Description: "Set a new throttle URL", Queryable: true},
+	{Name: "throttle-control-replicas", Usage: "throttle-control-replicas=<replicas>", Description: "Set a new comma delimited list of throttle control replicas", Queryable: true},
+	{Name: "throttle-schedule", Usage: "throttle-schedule=<schedule>", Description: "Set a new throttle schedule, e.g. 'mon-fri 08:00-20:00 nice-ratio=1'; empty to remove the schedule", Queryable: true},
+	{Name: "throttle", Usage: "throttle", Description: "Force throttling", Aliases: []string{"pause", "suspend"}},
+	{Name: "no-throttle", Usage: "no-throttle", Description: "End forced throttling (other throttling may still apply)", Aliases: []string{"unthrottle", "resume", "continue"}},
+	{Name: "unpostpone", Usage: "unpostpone", Description: "Bail out a cut-over postpone; proceed to cut-over", Aliases: []string{"no-postpone", "cut-over"}},
+	{Name: "cut-over-window", Usage: "cut-over-window=<schedule>", Description: "Set a new cut-over window, e.g. 'mon-fri 01:00-05:00'; empty to allow cut-over at any time", Queryable: true},
+	{Name: "cut-over-deadline", Usage: "cut-over-deadline=<time|duration>", Description: "Set a new cut-over deadline, RFC3339 time or duration from now; empty to remove the deadline", Queryable: true},
+	{Name: "panic", Usage: "panic", Description: "panic and quit without cleanup"},
+	{Name: "help", Usage: "help", Description: "This message", ReadOnly: true},
+}
+
+// lookupInteractiveCommand returns the command of the given name or alias
+func lookupInteractiveCommand(name string) (InteractiveCommand, bool) {
+	for _, interactiveCommand := range InteractiveCommands {
+		if interactiveCommand.Name == name {
+			return interactiveCommand, true
+		}
+		for _, alias := range interactiveCommand.Aliases {
+			if alias == name {
+				return interactiveCommand, true
+			}
+		}
+	}
+	return InteractiveCommand{}, false
+}
+
 // Server listens for requests on a socket file or via TCP
 type Server struct {
 	migrationContext *base.MigrationContext
+	log              base.Logger
+	applier          *Applier
 	unixListener     net.Listener
 	tcpListener      net.Listener
 	hooksExecutor    *HooksExecutor
 	printStatus      printStatusFunc
 	isCPUProfiling   int64
+	auditLog         *os.File
+	auditLogMutex    sync.Mutex
 }
 
-func NewServer(migrationContext *base.MigrationContext, hooksExecutor *HooksExecutor, printStatus printStatusFunc) *Server {
+func NewServer(migrationContext *base.MigrationContext, applier *Applier, hooksExecutor *HooksExecutor, printStatus printStatusFunc) *Server {
 	return &Server{
 		migrationContext: migrationContext,
+		log:              migrationContext.ComponentLog("server"),
+		applier:          applier,
 		hooksExecutor:    hooksExecutor,
 		printStatus:      printStatus,
 	}
@@ -95,7 +168,7 @@ func (this *Server) runCPUProfile(args string) (io.Reader, error) {
 
 	time.Sleep(duration)
 	pprof.StopCPUProfile()
-	this.migrationContext.Log.Infof("Captured %d byte runtime/pprof CPU profile (gzip=%v)", buf.Len(), useGzip)
+	this.log.Infof("Captured %d byte runtime/pprof CPU profile (gzip=%v)", buf.Len(), useGzip)
 	return &buf, nil
 }
 
@@ -110,12 +183,12 @@ func (this *Server) BindSocketFile() (err error) {
 	if err != nil {
 		return err
 	}
-	this.migrationContext.Log.Infof("Listening on unix socket file: %s", this.migrationContext.ServeSocketFile)
+	this.log.Infof("Listening on unix socket file: %s", this.migrationContext.ServeSocketFile)
 	return nil
 }
 
 func (this *Server) RemoveSocketFile() (err error) {
-	this.migrationContext.Log.Infof("Removing socket file: %s", this.migrationContext.ServeSocketFile)
+	this.log.Infof("Removing socket file: %s", this.migrationContext.ServeSocketFile)
 	return os.Remove(this.migrationContext.ServeSocketFile)
 }
 
@@ -123,21 +196,39 @@ func (this *Server) BindTCPPort() (err error) {
 	if this.migrationContext.ServeTCPPort == 0 {
 		return nil
 	}
+	var tlsConfig *tls.Config
+	if this.migrationContext.ServeTCPTLSCertFile != "" {
+		if tlsConfig, err = this.tcpTLSConfig(); err != nil {
+			return err
+		}
+	}
 	this.tcpListener, err = net.Listen("tcp", fmt.Sprintf(":%d", this.migrationContext.ServeTCPPort))
 	if err != nil {
 		return err
 	}
-	this.migrationContext.Log.Infof("Listening on tcp port: %d", this.migrationContext.ServeTCPPort)
+	if tlsConfig != nil {
+		this.tcpListener = tls.NewListener(this.tcpListener, tlsConfig)
+		this.log.Infof("Listening on tcp port: %d, over TLS", this.migrationContext.ServeTCPPort)
+	} else {
+		this.log.Infof("Listening on tcp port: %d", this.migrationContext.ServeTCPPort)
+	}
 	return nil
 }
 
-// Serve begins listening & serving on whichever device was configured
+// Serve begins listening & serving on whichever device was configured. It serves until Close.
 func (this *Server) Serve() (err error) {
 	go func() {
+		if this.unixListener == nil {
+			return
+		}
 		for {
 			conn, err := this.unixListener.Accept()
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
 			if err != nil {
-				this.migrationContext.Log.Errore(err)
+				this.log.Errore(err)
+				continue
 			}
 			go this.handleConnection(conn)
 		}
@@ -148,16 +239,35 @@ func (this *Server) Serve() (err error) {
 		}
 		for {
 			conn, err := this.tcpListener.Accept()
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
 			if err != nil {
-				this.migrationContext.Log.Errore(err)
+				this.log.Errore(err)
+				continue
 			}
-			go this.handleConnection(conn)
+			go this.handleTCPConnection(conn)
 		}
 	}()
 
 	return nil
 }
 
+// Close stops serving, releasing the socket file and the TCP port, such that another migration
+// in this process may bind them
+func (this *Server) Close() {
+	if this.unixListener != nil {
+		if err := this.unixListener.Close(); err != nil {
+			this.log.Errore(err)
+		}
+	}
+	if this.tcpListener != nil {
+		if err := this.tcpListener.Close(); err != nil {
+			this.log.Errore(err)
+		}
+	}
+}
+
 func (this *Server) handleConnection(conn net.Conn) (err error) {
 	if conn != nil {
 		defer conn.Close()
@@ -166,24 +276,35 @@ func (this *Server) handleConnection(conn net.Conn) (err error) {
 	if err != nil {
 		return err
 	}
-	return this.onServerCommand(string(command), bufio.NewWriter(conn))
+	// Whoever may write to the socket file may run any command
+	client := &serverClient{peer: this.migrationContext.ServeSocketFile, role: base.AdminServerRole}
+	return this.onServerCommand(client, string(command), bufio.NewWriter(conn))
 }
 
 // onServerCommand responds to a user's interactive command
-func (this *Server) onServerCommand(command string, writer *bufio.Writer) (err error) {
+func (this *Server) onServerCommand(client *serverClient, command string, writer *bufio.Writer) (err error) {
 	defer writer.Flush()
 
-	printStatusRule, err := this.applyServerCommand(command, writer)
+	command, fromCtl := strings.CutPrefix(command, CtlCommandPrefix)
+	printStatusRule, err := this.applyServerCommand(client, command, writer)
+	this.audit(client, command, err)
 	if err == nil {
 		this.printStatus(printStatusRule, writer)
 	} else {
 		fmt.Fprintf(writer, "%s\n", err.Error())
 	}
-	return this.migrationContext.Log.Errore(err)
+	if fromCtl {
+		if err == nil {
+			fmt.Fprintln(writer, CtlReplyOK)
+		} else {
+			fmt.Fprintln(writer, CtlReplyError)
+		}
+	}
+	return this.log.Errore(err)
 }
 
 // applyServerCommand parses and executes commands by user
-func (this *Server) applyServerCommand(command string, writer *bufio.Writer) (printStatusRule PrintStatusRule, err error) {
+func (this *Server) applyServerCommand(client *serverClient, command string, writer *bufio.Writer) (printStatusRule PrintStatusRule, err error) {
 	tokens := strings.SplitN(command, "=", 2)
 	command = strings.TrimSpace(tokens[0])
 	arg := ""
@@ -196,6 +317,10 @@ func (this *Server) applyServerCommand(command string, writer *bufio.Writer) (pr
 	argIsQuestion := (arg == "?")
 	throttleHint := "# Note: you may only throttle for as long as your binary logs are not purged"
 
+	if err := client.authorize(command, argIsQuestion); err != nil {
+		return NoPrintStatusRule, &serverAuthorizationError{err: err}
+	}
+
 	if err := this.hooksExecutor.onInteractiveCommand(command); err != nil {
 		return NoPrintStatusRule, err
 	}
@@ -203,39 +328,26 @@ func (this *Server) applyServerCommand(command string, writer *bufio.Writer) (pr
 	switch command {
 	case "help":
 		{
-			fmt.Fprint(writer, `available commands:
-status                               # Print a detailed status message
-sup                                  # Print a short status message
-cpu-profile=<options>                # Print a base64-encoded runtime/pprof CPU profile using a duration, default: 30s. Comma-separated options 'gzip' and/or 'block' (blocked profile) may follow the profile duration
-coordinates                          # Print the currently inspected coordinates
-applier                              # Print the hostname of the applier
-inspector                            # Print the hostname of the inspector
-chunk-size=<newsize>                 # Set a new chunk-size
-dml-batch-size=<newsize>             # Set a new dml-batch-size
-nice-ratio=<ratio>                   # Set a new nice-ratio, immediate sleep after each row-copy operation, float (examples: 0 is aggressive, 0.7 adds 70% runtime, 1.0 doubles runtime, 2.0 triples runtime, ...)
-critical-load=<load>                 # Set a new set of max-load thresholds
-max-lag-millis=<max-lag>             # Set a new replication lag threshold
-replication-lag-query=<query>        # Set a new query that determines replication lag (no quotes)
-max-load=<load>                      # Set a new set of max-load thresholds
-throttle-query=<query>               # Set a new throttle-query (no quotes)
-throttle-http=<URL>                  # Set a new throttle URL
-throttle-control-replicas=<replicas> # Set a new comma delimited list of throttle control replicas
-throttle                             # Force throttling
-no-throttle                          # End forced throttling (other throttling may still apply)
-unpostpone                           # Bail out a cut-over postpone; proceed to cut-over
-panic                                # panic and quit without cleanup
-help                                 # This message
-- use '?' (question mark) as argument to get info rather than set. e.g. "max-load=?" will just print out current max-load.
-`)
+			fmt.Fprintln(writer, "available commands:")
+			for _, interactiveCommand := range InteractiveCommands {
+				fmt.Fprintf(writer, "%-37s# %s\n", interactiveCommand.Usage, interactiveCommand.Description)
+			}
+			fmt.Fprintln(writer, `- use '?' (question mark) as argument to get info rather than set. e.g. "max-load=?" will just print out current max-load.`)
 		}
 	case "sup":
 		return ForcePrintStatusOnlyRule, nil
 	case "info", "status":
 		return ForcePrintStatusAndHintRule, nil
+	case "status-json":
+		return ForcePrintStatusJSONRule, nil
 	case "cpu-profile":
 		cpuProfile, err := this.runCPUProfile(arg)
 		if err == nil {
-			fmt.Fprint(base64.NewEncoder(base64.StdEncoding, writer), cpuProfile)
+			encoder := base64.NewEncoder(base64.StdEncoding, writer)
+			if _, err = io.Copy(encoder, cpuProfile); err == nil {
+				err = encoder.Close()
+			}
+			fmt.Fprintln(writer)
 		}
 		return NoPrintStatusRule, err
 	case "coordinates":
@@ -301,6 +413,19 @@ help                                 # This message
 				return ForcePrintStatusAndHintRule, nil
 			}
 		}
+	case "max-apply-lag-millis":
+		{
+			if argIsQuestion {
+				fmt.Fprintf(writer, "%+v\n", atomic.LoadInt64(&this.migrationContext.MaxApplyLagMillisecondsThrottleThreshold))
+				return NoPrintStatusRule, nil
+			}
+			if maxApplyLagMillis, err := strconv.Atoi(arg); err != nil {
+				return NoPrintStatusRule, err
+			} else {
+				this.migrationContext.SetMaxApplyLagMillisecondsThrottleThreshold(int64(maxApplyLagMillis))
+				return ForcePrintStatusAndHintRule, nil
+			}
+		}
 	case "replication-lag-query":
 		{
 			return NoPrintStatusRule, fmt.Errorf("replication-lag-query is deprecated. gh-ost uses an internal, subsecond resolution query")
@@ -330,6 +455,27 @@ help                                 # This message
 			}
 			return ForcePrintStatusAndHintRule, nil
 		}
+	case "max-innodb-metrics":
+		{
+			if argIsQuestion {
+				maxInnoDBMetrics := this.migrationContext.GetMaxInnoDBMetrics()
+				fmt.Fprintf(writer, "%s\n", maxInnoDBMetrics.String())
+				return NoPrintStatusRule, nil
+			}
+			maxInnoDBMetrics, err := base.ParseLoadMap(arg)
+			if err != nil {
+				return NoPrintStatusRule, err
+			}
+			if this.applier != nil {
+				if err := this.applier.ValidateInnoDBMetrics(maxInnoDBMetrics); err != nil {
+					return NoPrintStatusRule, err
+				}
+			}
+			if err := this.migrationContext.ReadMaxInnoDBMetrics(arg); err != nil {
+				return NoPrintStatusRule, err
+			}
+			return ForcePrintStatusAndHintRule, nil
+		}
 	case "critical-load":
 		{
 			if argIsQuestion {
@@ -374,6 +520,21 @@ help                                 # This message
 			fmt.Fprintf(writer, "%s\n", this.migrationContext.GetThrottleControlReplicaKeys().ToCommaDelimitedList())
 			return ForcePrintStatusAndHintRule, nil
 		}
+	case "throttle-schedule":
+		{
+			if argIsQuestion {
+				if throttleSchedule := this.migrationContext.GetThrottleSchedule(); throttleSchedule != nil {
+					fmt.Fprintf(writer, "%s\n", throttleSchedule)
+				} else {
+					fmt.Fprintln(writer, "")
+				}
+				return NoPrintStatusRule, nil
+			}
+			if err := this.migrationContext.ReadThrottleSchedule(arg); err != nil {
+				return NoPrintStatusRule, err
+			}
+			return ForcePrintStatusAndHintRule, nil
+		}
 	case "throttle", "pause", "suspend":
 		{
 			if arg != "" && arg != this.migrationContext.OriginalTableName {
@@ -381,6 +542,10 @@ help                                 # This message
 				err := fmt.Errorf("User commanded 'throttle' on %s, but migrated table is %s; ignoring request.", arg, this.migrationContext.OriginalTableName)
 				return NoPrintStatusRule, err
 			}
+			if this.migrationContext.BinlogRetentionOverrideForcedThrottle && atomic.LoadInt64(&this.migrationContext.BinlogRetentionGuardBreached) > 0 {
+				err := fmt.Errorf("Refusing to throttle: binary log being read is expected to be purged within --binlog-retention-guard-seconds")
+				return NoPrintStatusRule, err
+			}
 			atomic.StoreInt64(&this.migrationContext.ThrottleCommandedByUser, 1)
 			fmt.Fprintln(writer, throttleHint)
 			return ForcePrintStatusAndHintRule, nil
@@ -414,6 +579,36 @@ help                                 # This message
 			fmt.Fprintf(writer, "You may only invoke this when gh-ost is actively postponing migration. At this time it is not.\n")
 			return NoPrintStatusRule, nil
 		}
+	case "cut-over-window":
+		{
+			if argIsQuestion {
+				if window := this.migrationContext.GetCutOverWindow(); window != nil {
+					fmt.Fprintf(writer, "%s\n", window)
+				} else {
+					fmt.Fprintln(writer, "")
+				}
+				return NoPrintStatusRule, nil
+			}
+			if err := this.migrationContext.ReadCutOverWindow(arg); err != nil {
+				return NoPrintStatusRule, err
+			}
+			return ForcePrintStatusAndHintRule, nil
+		}
+	case "cut-over-deadline":
+		{
+			if argIsQuestion {
+				if deadline := this.migrationContext.GetCutOverDeadline(); !deadline.IsZero() {
+					fmt.Fprintf(writer, "%s\n", deadline.Format(time.RFC3339))
+				} else {
+					fmt.Fprintln(writer, "")
+				}
+				return NoPrintStatusRule, nil
+			}
+			if err := this.migrationContext.ReadCutOverDeadline(arg); err != nil {
+				return NoPrintStatusRule, err
+			}
+			return ForcePrintStatusAndHintRule, nil
+		}
 	case "panic":
 		{
 			if arg == "" && this.migrationContext.ForceNamedPanicCommand {
@@ -426,7 +621,7 @@ help                                 # This message
 				return NoPrintStatusRule, err
 			}
 			err := fmt.Errorf("User commanded 'panic'. The migration will be aborted without cleanup. Please drop the gh-ost tables before trying again.")
-			this.migrationContext.PanicAbort <- err
+			this.migrationContext.SendPanicAbort(err)
 			return NoPrintStatusRule, err
 		}
 	default:
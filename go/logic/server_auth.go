@@ -0,0 +1,266 @@
+/*
+   Copyright 2022 GitHub Inc.
+	 See https://github.com/github/gh-ost/blob/master/LICENSE
+*/
+
+package logic
+
+import (
+	"bufio"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/github/gh-ost/go/base"
+)
+
+// AuthCommandPrefix prefixes a line carrying a token, which TCP clients send ahead of their command
+// when --serve-tcp-auth-file is given: "auth <token>\n<command>\n"
+const AuthCommandPrefix = "auth "
+
+// tcpClientTimeout bounds the time a TCP client has to complete the TLS handshake and send its
+// command, such that idle or slow clients do not hold on to connections
+var tcpClientTimeout = 10 * time.Second
+
+var (
+	ErrServerAuthenticationRequired = errors.New("Authentication required")
+	ErrServerAuthenticationFailed   = errors.New("Authentication failed")
+)
+
+// serverClient is who sent an interactive command
+type serverClient struct {
+	// peer is where the command came from: the socket file, or the TCP client's address
+	peer     string
+	identity string
+	role     base.ServerRole
+	// authError tells why a TCP client has no role
+	authError error
+}
+
+func (this *serverClient) String() string {
+	if this.identity != "" {
+		return fmt.Sprintf("%s (%s)", this.identity, this.peer)
+	}
+	return this.peer
+}
+
+// authorize checks the client's role allows running the command
+func (this *serverClient) authorize(command string, argIsQuestion bool) error {
+	switch this.role {
+	case base.AdminServerRole:
+		return nil
+	case base.ReadOnlyServerRole:
+		interactiveCommand, ok := lookupInteractiveCommand(command)
+		if !ok || interactiveCommand.ReadOnly || (interactiveCommand.Queryable && argIsQuestion) {
+			// Unknown commands are left to fail as such
+			return nil
+		}
+		return fmt.Errorf("Command %s requires the %s role; %s is %s", command, base.AdminServerRole, this, this.role)
+	}
+	if this.authError != nil {
+		return this.authError
+	}
+	return ErrServerAuthenticationRequired
+}
+
+// tcpTLSConfig returns the TLS configuration of the TCP interface: a server certificate, and, given
+// a CA, verification of client certificates. Without an auth file, which would otherwise allow
+// tokens, verified client certificates are required.
+func (this *Server) tcpTLSConfig() (*tls.Config, error) {
+	certificate, err := tls.LoadX509KeyPair(this.migrationContext.ServeTCPTLSCertFile, this.migrationContext.ServeTCPTLSKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("Cannot load --serve-tcp-tls-cert: %+v", err)
+	}
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{certificate},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if this.migrationContext.ServeTCPTLSCAFile != "" {
+		caCertificates, err := os.ReadFile(this.migrationContext.ServeTCPTLSCAFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.ClientCAs = x509.NewCertPool()
+		if !tlsConfig.ClientCAs.AppendCertsFromPEM(caCertificates) {
+			return nil, fmt.Errorf("No certificates found in --serve-tcp-tls-ca %s", this.migrationContext.ServeTCPTLSCAFile)
+		}
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+		if this.migrationContext.ServeTCPAuth != nil {
+			tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+		}
+	}
+	return tlsConfig, nil
+}
+
+// tcpClient returns the TCP client of a connection, as identified by its certificate
+func (this *Server) tcpClient(conn net.Conn) (*serverClient, error) {
+	client := &serverClient{peer: conn.RemoteAddr().String(), role: base.AdminServerRole}
+	var commonName string
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		if err := tlsConn.Handshake(); err != nil {
+			return client, fmt.Errorf("TLS handshake with %s failed: %+v", client.peer, err)
+		}
+		if certificates := tlsConn.ConnectionState().PeerCertificates; len(certificates) > 0 {
+			commonName = certificates[0].Subject.CommonName
+			client.identity = commonName
+		}
+	}
+	auth := this.migrationContext.ServeTCPAuth
+	if auth == nil {
+		return client, nil
+	}
+	client.role = base.NoServerRole
+	if commonName != "" {
+		if principal, ok := auth.CertPrincipal(commonName); ok {
+			client.role = principal.Role
+		} else {
+			client.authError = fmt.Errorf("Certificate of %s is not listed in --serve-tcp-auth-file", commonName)
+		}
+	}
+	return client, nil
+}
+
+// authenticate authenticates a client with the token of an auth line
+func (this *Server) authenticate(client *serverClient, token string) {
+	auth := this.migrationContext.ServeTCPAuth
+	if auth == nil {
+		// Authentication is not required: clients may well send tokens anyway
+		return
+	}
+	principal, ok := auth.TokenPrincipal(strings.TrimSpace(token))
+	if !ok {
+		client.role = base.NoServerRole
+		client.authError = ErrServerAuthenticationFailed
+		return
+	}
+	client.identity = principal.Identity
+	client.role = principal.Role
+	client.authError = nil
+}
+
+// handleTCPConnection serves a TCP client, who may authenticate ahead of its command
+func (this *Server) handleTCPConnection(conn net.Conn) (err error) {
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(tcpClientTimeout)); err != nil {
+		return err
+	}
+	client, err := this.tcpClient(conn)
+	if err != nil {
+		return this.log.Errore(err)
+	}
+	reader := bufio.NewReader(conn)
+	command, _, err := reader.ReadLine()
+	if err != nil {
+		return err
+	}
+	if token, ok := strings.CutPrefix(string(command), AuthCommandPrefix); ok {
+		this.authenticate(client, token)
+		if command, _, err = reader.ReadLine(); err != nil {
+			return err
+		}
+	}
+	// Commands such as cpu-profile take their time to respond
+	if err := conn.SetDeadline(time.Time{}); err != nil {
+		return err
+	}
+	return this.onServerCommand(client, string(command), bufio.NewWriter(conn))
+}
+
+// OpenAuditLog opens --serve-audit-log, if given, to append interactive commands to
+func (this *Server) OpenAuditLog() (err error) {
+	if this.migrationContext.ServeAuditLogFile == "" {
+		return nil
+	}
+	this.auditLog, err = os.OpenFile(this.migrationContext.ServeAuditLogFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	return err
+}
+
+// CloseAuditLog closes --serve-audit-log, if opened
+func (this *Server) CloseAuditLog() error {
+	if this.auditLog == nil {
+		return nil
+	}
+	return this.auditLog.Close()
+}
+
+// auditCommandNameMaxLength truncates command names in the audit log, which may be arbitrary client input
+const auditCommandNameMaxLength = 64
+
+// auditRedactedArgument replaces command arguments in the audit log. Arguments may hold queries or
+// connection text, and hence credentials, which must not persist in the log
+const auditRedactedArgument = "[redacted]"
+
+// audit records a command, who sent it, and whether it was allowed. The command's argument is
+// redacted, unless it is the "?" query.
+func (this *Server) audit(client *serverClient, command string, err error) {
+	if this.auditLog == nil {
+		return
+	}
+	tokens := strings.SplitN(command, "=", 2)
+	name := strings.TrimSpace(tokens[0])
+	if len(name) > auditCommandNameMaxLength {
+		name = name[:auditCommandNameMaxLength] + "..."
+	}
+	record := base.LogFields{
+		"time":    time.Now().Format(time.RFC3339Nano),
+		"uuid":    this.migrationContext.Uuid,
+		"peer":    client.peer,
+		"command": name,
+		"role":    client.role,
+		"allowed": true,
+	}
+	arg := ""
+	if len(tokens) > 1 {
+		arg = strings.TrimSpace(tokens[1])
+		if unquoted, err := strconv.Unquote(arg); err == nil {
+			arg = unquoted
+		}
+		if arg == "?" {
+			record["argument"] = arg
+		} else {
+			record["argument"] = auditRedactedArgument
+		}
+	}
+	if client.identity != "" {
+		record["identity"] = client.identity
+	}
+	var authorizationError *serverAuthorizationError
+	if errors.As(err, &authorizationError) {
+		record["allowed"] = false
+	}
+	if err != nil {
+		// Errors may quote the argument
+		message := err.Error()
+		if arg != "" && arg != "?" {
+			message = strings.ReplaceAll(message, arg, auditRedactedArgument)
+		}
+		record["error"] = message
+	}
+	line, _ := json.Marshal(record)
+
+	this.auditLogMutex.Lock()
+	defer this.auditLogMutex.Unlock()
+	if _, err := fmt.Fprintln(this.auditLog, string(line)); err != nil {
+		this.log.Errorf("Cannot write --serve-audit-log: %+v", err)
+	}
+}
+
+// serverAuthorizationError is a command refused to a client
+type serverAuthorizationError struct {
+	err error
+}
+
+func (this *serverAuthorizationError) Error() string {
+	return this.err.Error()
+}
+
+func (this *serverAuthorizationError) Unwrap() error {
+	return this.err
+}
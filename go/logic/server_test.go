@@ -1,6 +1,15 @@
 package logic
 
 import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -33,10 +42,7 @@ func TestServerRunCPUProfile(t *testing.T) {
 	})
 
 	t.Run("success", func(t *testing.T) {
-		s := &Server{
-			isCPUProfiling:   0,
-			migrationContext: base.NewMigrationContext(),
-		}
+		s := NewServer(base.NewMigrationContext(), nil, nil, nil)
 		defaultCPUProfileDuration = time.Millisecond * 10
 		profile, err := s.runCPUProfile("")
 		require.NoError(t, err)
@@ -45,10 +51,7 @@ func TestServerRunCPUProfile(t *testing.T) {
 	})
 
 	t.Run("success with block", func(t *testing.T) {
-		s := &Server{
-			isCPUProfiling:   0,
-			migrationContext: base.NewMigrationContext(),
-		}
+		s := NewServer(base.NewMigrationContext(), nil, nil, nil)
 		profile, err := s.runCPUProfile("10ms,block")
 		require.NoError(t, err)
 		require.NotNil(t, profile)
@@ -56,13 +59,183 @@ func TestServerRunCPUProfile(t *testing.T) {
 	})
 
 	t.Run("success with block and gzip", func(t *testing.T) {
-		s := &Server{
-			isCPUProfiling:   0,
-			migrationContext: base.NewMigrationContext(),
-		}
+		s := NewServer(base.NewMigrationContext(), nil, nil, nil)
 		profile, err := s.runCPUProfile("10ms,block,gzip")
 		require.NoError(t, err)
 		require.NotNil(t, profile)
 		require.Equal(t, int64(0), s.isCPUProfiling)
 	})
 }
+
+func TestServerOnServerCommand(t *testing.T) {
+	migrationContext := base.NewMigrationContext()
+	s := NewServer(migrationContext, nil, NewHooksExecutor(migrationContext), func(rule PrintStatusRule, writer io.Writer) {})
+
+	onServerCommand := func(command string) string {
+		var output bytes.Buffer
+		s.onServerCommand(&serverClient{peer: "test", role: base.AdminServerRole}, command, bufio.NewWriter(&output))
+		return output.String()
+	}
+
+	require.Equal(t, "1000\n", onServerCommand("chunk-size=?"))
+	require.Equal(t, "1000\n"+CtlReplyOK+"\n", onServerCommand(CtlCommandPrefix+"chunk-size=?"))
+	require.Equal(t, "Unknown command: nope\n"+CtlReplyError+"\n", onServerCommand(CtlCommandPrefix+"nope"))
+
+	help := onServerCommand("help")
+	require.Contains(t, help, "available commands:\nstatus                               # Print a detailed status message\n")
+	require.Contains(t, help, "throttle-control-replicas=<replicas> # Set a new comma delimited list of throttle control replicas\n")
+}
+
+func TestServerClientAuthorize(t *testing.T) {
+	admin := &serverClient{peer: "test", role: base.AdminServerRole}
+	readOnly := &serverClient{peer: "127.0.0.1:5555", identity: "grafana", role: base.ReadOnlyServerRole}
+	anonymous := &serverClient{peer: "127.0.0.1:5555"}
+
+	for _, command := range []string{"status", "info", "sup", "status-json", "coordinates", "help", "no-such-command"} {
+		require.NoError(t, readOnly.authorize(command, false), command)
+	}
+	require.NoError(t, readOnly.authorize("chunk-size", true))
+	require.NoError(t, readOnly.authorize("cut-over-window", true))
+	for _, command := range []string{"chunk-size", "throttle", "pause", "unpostpone", "cut-over", "panic", "cpu-profile"} {
+		err := readOnly.authorize(command, false)
+		require.Error(t, err, command)
+		require.Contains(t, err.Error(), "grafana (127.0.0.1:5555) is read-only")
+		require.NoError(t, admin.authorize(command, false), command)
+	}
+	// "?" does not query commands which set nothing
+	require.Error(t, readOnly.authorize("throttle", true))
+
+	require.Equal(t, ErrServerAuthenticationRequired, anonymous.authorize("status", false))
+	anonymous.authError = ErrServerAuthenticationFailed
+	require.Equal(t, ErrServerAuthenticationFailed, anonymous.authorize("status", false))
+}
+
+func TestServerHandleTCPConnectionTimeout(t *testing.T) {
+	defer func(timeout time.Duration) { tcpClientTimeout = timeout }(tcpClientTimeout)
+	tcpClientTimeout = 50 * time.Millisecond
+
+	migrationContext := base.NewMigrationContext()
+	s := NewServer(migrationContext, nil, NewHooksExecutor(migrationContext), func(rule PrintStatusRule, writer io.Writer) {})
+
+	// A client which never sends its command is let go of
+	serverConn, clientConn := net.Pipe()
+	defer clientConn.Close()
+	done := make(chan error)
+	go func() { done <- s.handleTCPConnection(serverConn) }()
+	select {
+	case err := <-done:
+		require.Error(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("handleTCPConnection did not time out")
+	}
+
+	// A client which authenticates, but never sends its command, likewise
+	serverConn, clientConn = net.Pipe()
+	defer clientConn.Close()
+	go func() { done <- s.handleTCPConnection(serverConn) }()
+	_, err := clientConn.Write([]byte(AuthCommandPrefix + "token\n"))
+	require.NoError(t, err)
+	select {
+	case err := <-done:
+		require.Error(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("handleTCPConnection did not time out")
+	}
+
+	// The deadline is lifted once the command is read
+	serverConn, clientConn = net.Pipe()
+	defer clientConn.Close()
+	go func() { done <- s.handleTCPConnection(serverConn) }()
+	_, err = clientConn.Write([]byte("chunk-size=?\n"))
+	require.NoError(t, err)
+	time.Sleep(2 * tcpClientTimeout)
+	response, err := bufio.NewReader(clientConn).ReadString('\n')
+	require.NoError(t, err)
+	require.Equal(t, "1000\n", response)
+	require.NoError(t, <-done)
+}
+
+func TestServerAuditLog(t *testing.T) {
+	migrationContext := base.NewMigrationContext()
+	migrationContext.ServeAuditLogFile = filepath.Join(t.TempDir(), "audit.log")
+	s := NewServer(migrationContext, nil, NewHooksExecutor(migrationContext), func(rule PrintStatusRule, writer io.Writer) {})
+	require.NoError(t, s.OpenAuditLog())
+
+	readOnly := &serverClient{peer: "127.0.0.1:5555", identity: "grafana", role: base.ReadOnlyServerRole}
+	var output bytes.Buffer
+	s.onServerCommand(readOnly, CtlCommandPrefix+"chunk-size=?", bufio.NewWriter(&output))
+	s.onServerCommand(readOnly, "chunk-size=10", bufio.NewWriter(&output))
+	s.onServerCommand(readOnly, "no-such-command", bufio.NewWriter(&output))
+	s.onServerCommand(readOnly, "replication-lag-query=?", bufio.NewWriter(&output))
+	admin := &serverClient{peer: "127.0.0.1:5556", identity: "alice", role: base.AdminServerRole}
+	s.onServerCommand(admin, "max-load=s3cr3t", bufio.NewWriter(&output))
+	require.Equal(t, int64(1000), migrationContext.ChunkSize)
+	require.NoError(t, s.CloseAuditLog())
+
+	content, err := os.ReadFile(migrationContext.ServeAuditLogFile)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	require.Len(t, lines, 5)
+	records := make([]map[string]interface{}, len(lines))
+	for i, line := range lines {
+		require.NoError(t, json.Unmarshal([]byte(line), &records[i]))
+	}
+	require.Equal(t, "chunk-size", records[0]["command"])
+	require.Equal(t, "?", records[0]["argument"])
+	require.Equal(t, "grafana", records[0]["identity"])
+	require.Equal(t, "127.0.0.1:5555", records[0]["peer"])
+	require.Equal(t, "read-only", records[0]["role"])
+	require.Equal(t, true, records[0]["allowed"])
+	require.NotContains(t, records[0], "error")
+
+	require.Equal(t, "chunk-size", records[1]["command"])
+	require.Equal(t, "[redacted]", records[1]["argument"])
+	require.Equal(t, false, records[1]["allowed"])
+	require.Contains(t, records[1]["error"], "requires the admin role")
+	// Allowed, though failing
+	require.Equal(t, true, records[2]["allowed"])
+	require.Equal(t, "Unknown command: no-such-command", records[2]["error"])
+	require.NotContains(t, records[2], "argument")
+	// Queryable by a read-only client
+	require.Equal(t, "replication-lag-query", records[3]["command"])
+	require.Equal(t, true, records[3]["allowed"])
+	// Arguments are redacted, errors included
+	require.Equal(t, "max-load", records[4]["command"])
+	require.Equal(t, "alice", records[4]["identity"])
+	require.Equal(t, "[redacted]", records[4]["argument"])
+	require.Equal(t, "Error parsing load condition: [redacted]", records[4]["error"])
+	require.NotContains(t, string(content), "s3cr3t")
+}
+
+func TestServerClose(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := listener.Addr().(*net.TCPAddr).Port
+	require.NoError(t, listener.Close())
+
+	migrationContext := base.NewMigrationContext()
+	migrationContext.ServeSocketFile = filepath.Join(t.TempDir(), "gh-ost.sock")
+	migrationContext.ServeTCPPort = int64(port)
+
+	// A second server may bind the socket file and TCP port of a closed one
+	for i := 0; i < 2; i++ {
+		s := NewServer(migrationContext, nil, NewHooksExecutor(migrationContext), func(rule PrintStatusRule, writer io.Writer) {})
+		require.NoError(t, s.BindSocketFile())
+		require.NoError(t, s.BindTCPPort())
+		require.NoError(t, s.Serve())
+
+		conn, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+		require.NoError(t, err)
+		_, err = conn.Write([]byte("chunk-size=?\n"))
+		require.NoError(t, err)
+		response, err := bufio.NewReader(conn).ReadString('\n')
+		require.NoError(t, err)
+		require.Equal(t, "1000\n", response)
+		require.NoError(t, conn.Close())
+
+		s.Close()
+		require.NoFileExists(t, migrationContext.ServeSocketFile)
+		_, err = net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+		require.Error(t, err)
+	}
+}
@@ -0,0 +1,342 @@
+/*
+   Copyright 2022 GitHub Inc.
+	 See https://github.com/github/gh-ost/blob/master/LICENSE
+*/
+
+package logic
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/github/gh-ost/go/base"
+	"github.com/github/gh-ost/go/binlog"
+	"github.com/github/gh-ost/go/sql"
+
+	"github.com/shopspring/decimal"
+)
+
+// spillSegmentBytes is the size beyond which a new segment file is started. A segment file is
+// removed once all of its events are read, which is how disk space is released.
+const spillSegmentBytes = 64 * bytesPerMB
+
+func init() {
+	// DECIMAL column values are read off the binary log as decimals
+	gob.Register(decimal.Decimal{})
+}
+
+// spilledEvent is an apply event as written to disk. Write functions cannot be written, and
+// stay in memory: they are referenced by FuncId.
+type spilledEvent struct {
+	FuncId            int64
+	DatabaseName      string
+	TableName         string
+	DML               binlog.EventDML
+	WhereColumnValues []interface{}
+	NewColumnValues   []interface{}
+	// gob decodes an empty []byte as nil, which would be applied as NULL. Positions of empty
+	// []byte values are kept aside so as to restore them.
+	WhereEmptyBytes []int
+	NewEmptyBytes   []int
+}
+
+// emptyBytesPositions returns the positions of empty, non-nil []byte values
+func emptyBytesPositions(values []interface{}) (positions []int) {
+	for i, value := range values {
+		if bytesValue, ok := value.([]byte); ok && bytesValue != nil && len(bytesValue) == 0 {
+			positions = append(positions, i)
+		}
+	}
+	return positions
+}
+
+// restoreEmptyBytes reverts the values at given positions, decoded as nil, to empty []byte
+func restoreEmptyBytes(values []interface{}, positions []int) []interface{} {
+	for _, i := range positions {
+		if i < len(values) {
+			values[i] = []byte{}
+		}
+	}
+	return values
+}
+
+func newSpilledEvent(eventStruct *applyEventStruct, funcId int64) *spilledEvent {
+	if eventStruct.dmlEvent == nil {
+		return &spilledEvent{FuncId: funcId}
+	}
+	event := &spilledEvent{
+		DatabaseName: eventStruct.dmlEvent.DatabaseName,
+		TableName:    eventStruct.dmlEvent.TableName,
+		DML:          eventStruct.dmlEvent.DML,
+	}
+	if eventStruct.dmlEvent.WhereColumnValues != nil {
+		event.WhereColumnValues = eventStruct.dmlEvent.WhereColumnValues.AbstractValues()
+		event.WhereEmptyBytes = emptyBytesPositions(event.WhereColumnValues)
+	}
+	if eventStruct.dmlEvent.NewColumnValues != nil {
+		event.NewColumnValues = eventStruct.dmlEvent.NewColumnValues.AbstractValues()
+		event.NewEmptyBytes = emptyBytesPositions(event.NewColumnValues)
+	}
+	return event
+}
+
+func (this *spilledEvent) toDMLEvent() *binlog.BinlogDMLEvent {
+	dmlEvent := binlog.NewBinlogDMLEvent(this.DatabaseName, this.TableName, this.DML)
+	if this.WhereColumnValues != nil {
+		dmlEvent.WhereColumnValues = sql.ToColumnValues(restoreEmptyBytes(this.WhereColumnValues, this.WhereEmptyBytes))
+	}
+	if this.NewColumnValues != nil {
+		dmlEvent.NewColumnValues = sql.ToColumnValues(restoreEmptyBytes(this.NewColumnValues, this.NewEmptyBytes))
+	}
+	return dmlEvent
+}
+
+// spillSegment is a file of length-prefixed, gob encoded events, written and read sequentially.
+type spillSegment struct {
+	path        string
+	writer      *os.File
+	reader      *os.File
+	size        int64
+	writeEvents int64
+	readEvents  int64
+}
+
+// SpillQueue sits in front of the in-memory queue of events to apply. As long as that queue has
+// room, events are passed on to it. Once it is full, that is when the applier lags, events are
+// written to disk rather than blocking the binlog streamer, and are read back in order as the
+// applier catches up.
+type SpillQueue struct {
+	migrationContext *base.MigrationContext
+	out              chan *applyEventStruct
+	dir              string
+	maxBytes         int64
+
+	mutex       *sync.Mutex
+	cond        *sync.Cond
+	segments    []*spillSegment
+	nextSegment int
+	funcs       map[int64]*applyEventStruct
+	nextFunc    int64
+	// depth is the number of events on disk yet to be passed on, and bytes their size
+	depth  int64
+	bytes  int64
+	closed bool
+	// done is closed on Teardown, releasing Drain should the applier no longer consume events
+	done chan struct{}
+}
+
+// NewSpillQueue creates a queue which passes events on to the given channel, spilling onto a new
+// directory under --dml-backlog-spill-dir.
+func NewSpillQueue(migrationContext *base.MigrationContext, out chan *applyEventStruct) (*SpillQueue, error) {
+	dir, err := os.MkdirTemp(migrationContext.DMLBacklogSpillDir, fmt.Sprintf("gh-ost-%s-%s-", migrationContext.DatabaseName, migrationContext.OriginalTableName))
+	if err != nil {
+		return nil, err
+	}
+	mutex := &sync.Mutex{}
+	return &SpillQueue{
+		migrationContext: migrationContext,
+		out:              out,
+		dir:              dir,
+		maxBytes:         migrationContext.DMLBacklogSpillMaxMB * bytesPerMB,
+		mutex:            mutex,
+		cond:             sync.NewCond(mutex),
+		funcs:            make(map[int64]*applyEventStruct),
+		done:             make(chan struct{}),
+	}, nil
+}
+
+// Push passes an event on to the in-memory queue, or spills it to disk if the in-memory queue is
+// full or if spilled events are yet to be passed on. Push blocks while spilled events exceed
+// --dml-backlog-spill-max-mb.
+func (this *SpillQueue) Push(eventStruct *applyEventStruct) error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	if this.depth == 0 {
+		select {
+		case this.out <- eventStruct:
+			return nil
+		default:
+		}
+	}
+	for this.bytes >= this.maxBytes && !this.closed {
+		this.cond.Wait()
+	}
+	if this.closed {
+		return nil
+	}
+	var funcId int64
+	if eventStruct.dmlEvent == nil {
+		this.nextFunc++
+		funcId = this.nextFunc
+		this.funcs[funcId] = eventStruct
+	}
+	size, err := this.write(newSpilledEvent(eventStruct, funcId))
+	if err != nil {
+		return err
+	}
+	this.depth++
+	this.bytes += size
+	this.cond.Broadcast()
+	return nil
+}
+
+// write appends an event to the last segment, starting a new segment as needed.
+func (this *SpillQueue) write(event *spilledEvent) (size int64, err error) {
+	var payload bytes.Buffer
+	if err := gob.NewEncoder(&payload).Encode(event); err != nil {
+		return 0, err
+	}
+	record := make([]byte, 4, 4+payload.Len())
+	binary.BigEndian.PutUint32(record, uint32(payload.Len()))
+	record = append(record, payload.Bytes()...)
+
+	if len(this.segments) == 0 || this.segments[len(this.segments)-1].size >= spillSegmentBytes {
+		if err := this.addSegment(); err != nil {
+			return 0, err
+		}
+	}
+	segment := this.segments[len(this.segments)-1]
+	if _, err := segment.writer.Write(record); err != nil {
+		return 0, err
+	}
+	segment.size += int64(len(record))
+	segment.writeEvents++
+	return int64(len(record)), nil
+}
+
+func (this *SpillQueue) addSegment() (err error) {
+	if len(this.segments) > 0 {
+		last := this.segments[len(this.segments)-1]
+		if err := last.writer.Close(); err != nil {
+			return err
+		}
+		last.writer = nil
+	}
+	segment := &spillSegment{path: filepath.Join(this.dir, fmt.Sprintf("segment-%06d", this.nextSegment))}
+	if segment.writer, err = os.OpenFile(segment.path, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0600); err != nil {
+		return err
+	}
+	if segment.reader, err = os.Open(segment.path); err != nil {
+		segment.writer.Close()
+		return err
+	}
+	this.segments = append(this.segments, segment)
+	this.nextSegment++
+	return nil
+}
+
+// read reads the next spilled event off the first segment not yet read through. It is only called by the drainer, while
+// depth is positive, and reads an event already completely written.
+func (this *SpillQueue) read() (eventStruct *applyEventStruct, size int64, err error) {
+	segment := this.segments[0]
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(segment.reader, header); err != nil {
+		return nil, 0, err
+	}
+	payload := make([]byte, binary.BigEndian.Uint32(header))
+	if _, err := io.ReadFull(segment.reader, payload); err != nil {
+		return nil, 0, err
+	}
+	event := &spilledEvent{}
+	if err := gob.NewDecoder(bytes.NewReader(payload)).Decode(event); err != nil {
+		return nil, 0, err
+	}
+	segment.readEvents++
+	size = int64(len(header) + len(payload))
+
+	if event.FuncId != 0 {
+		eventStruct = this.funcs[event.FuncId]
+		delete(this.funcs, event.FuncId)
+		return eventStruct, size, nil
+	}
+	return newApplyEventStructByDML(event.toDMLEvent()), size, nil
+}
+
+// releaseReadSegments removes segments which are done with: all events read, and no longer written.
+func (this *SpillQueue) releaseReadSegments() error {
+	for len(this.segments) > 0 {
+		segment := this.segments[0]
+		if segment.readEvents < segment.writeEvents || segment.writer != nil {
+			return nil
+		}
+		segment.reader.Close()
+		if err := os.Remove(segment.path); err != nil {
+			return err
+		}
+		this.segments = this.segments[1:]
+	}
+	return nil
+}
+
+// Drain passes spilled events on to the in-memory queue, in order, until the queue is torn down.
+// Events are counted as spilled until passed on, such that Push does not overtake them.
+func (this *SpillQueue) Drain() error {
+	for {
+		this.mutex.Lock()
+		for this.depth == 0 && !this.closed {
+			this.cond.Wait()
+		}
+		if this.closed {
+			this.mutex.Unlock()
+			return nil
+		}
+		var eventStruct *applyEventStruct
+		var size int64
+		err := this.releaseReadSegments()
+		if err == nil {
+			eventStruct, size, err = this.read()
+		}
+		this.mutex.Unlock()
+		if err != nil {
+			return err
+		}
+
+		select {
+		case this.out <- eventStruct:
+		case <-this.done:
+			return nil
+		}
+
+		this.mutex.Lock()
+		this.depth--
+		this.bytes -= size
+		this.cond.Broadcast()
+		this.mutex.Unlock()
+	}
+}
+
+// Backlog returns the number of spilled events yet to be applied, and their size in bytes
+func (this *SpillQueue) Backlog() (depth int64, bytes int64) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.depth, this.bytes
+}
+
+// Teardown closes the queue, waking up blocked callers, and removes its directory
+func (this *SpillQueue) Teardown() {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	if this.closed {
+		return
+	}
+	this.closed = true
+	close(this.done)
+	this.cond.Broadcast()
+	for _, segment := range this.segments {
+		if segment.writer != nil {
+			segment.writer.Close()
+		}
+		segment.reader.Close()
+	}
+	this.segments = nil
+	if err := os.RemoveAll(this.dir); err != nil {
+		this.migrationContext.Log.Errore(err)
+	}
+}
@@ -0,0 +1,113 @@
+/*
+   Copyright 2022 GitHub Inc.
+	 See https://github.com/github/gh-ost/blob/master/LICENSE
+*/
+
+package logic
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+
+	"github.com/github/gh-ost/go/base"
+	"github.com/github/gh-ost/go/binlog"
+	"github.com/github/gh-ost/go/sql"
+)
+
+func newTestSpillQueue(t *testing.T, out chan *applyEventStruct) *SpillQueue {
+	migrationContext := base.NewMigrationContext()
+	migrationContext.DatabaseName = "test"
+	migrationContext.OriginalTableName = "tbl"
+	migrationContext.DMLBacklogSpillDir = t.TempDir()
+	migrationContext.DMLBacklogSpillMaxMB = 1
+	spillQueue, err := NewSpillQueue(migrationContext, out)
+	require.NoError(t, err)
+	return spillQueue
+}
+
+func newTestSpillDMLEvent(id int64) *applyEventStruct {
+	dmlEvent := binlog.NewBinlogDMLEvent("test", "tbl", binlog.UpdateDML)
+	dmlEvent.WhereColumnValues = sql.ToColumnValues([]interface{}{id, "old", nil, []byte{}})
+	dmlEvent.NewColumnValues = sql.ToColumnValues([]interface{}{id, []byte("new\x00"), decimal.RequireFromString("12.50"), []byte{}})
+	return newApplyEventStructByDML(dmlEvent)
+}
+
+func TestSpillQueue(t *testing.T) {
+	out := make(chan *applyEventStruct, 2)
+	spillQueue := newTestSpillQueue(t, out)
+	defer spillQueue.Teardown()
+
+	var writeFunc tableWriteFunc = func() error { return nil }
+	for i := int64(1); i <= 5; i++ {
+		require.NoError(t, spillQueue.Push(newTestSpillDMLEvent(i)))
+	}
+	require.NoError(t, spillQueue.Push(newApplyEventStructByFunc(&writeFunc)))
+
+	// The in-memory queue took two events; the others are spilled
+	depth, bytes := spillQueue.Backlog()
+	require.Equal(t, int64(4), depth)
+	require.Greater(t, bytes, int64(0))
+
+	go spillQueue.Drain()
+	for i := int64(1); i <= 5; i++ {
+		eventStruct := <-out
+		require.NotNil(t, eventStruct.dmlEvent)
+		require.Equal(t, binlog.UpdateDML, eventStruct.dmlEvent.DML)
+		require.Equal(t, "tbl", eventStruct.dmlEvent.TableName)
+		whereValues := eventStruct.dmlEvent.WhereColumnValues.AbstractValues()
+		require.Equal(t, []interface{}{i, "old", nil, []byte{}}, whereValues)
+		// an empty string is not to be read back as NULL
+		require.Nil(t, whereValues[2])
+		require.NotNil(t, whereValues[3])
+		newValues := eventStruct.dmlEvent.NewColumnValues.AbstractValues()
+		require.Equal(t, i, newValues[0])
+		require.Equal(t, []byte("new\x00"), newValues[1])
+		require.True(t, decimal.RequireFromString("12.5").Equal(newValues[2].(decimal.Decimal)))
+		require.NotNil(t, newValues[3])
+		require.Equal(t, []byte{}, newValues[3])
+	}
+	eventStruct := <-out
+	require.Nil(t, eventStruct.dmlEvent)
+	require.Equal(t, &writeFunc, eventStruct.writeFunc)
+
+	require.Eventually(t, func() bool {
+		depth, bytes := spillQueue.Backlog()
+		return depth == 0 && bytes == 0
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestSpillQueueTeardown(t *testing.T) {
+	out := make(chan *applyEventStruct)
+	spillQueue := newTestSpillQueue(t, out)
+	require.NoError(t, spillQueue.Push(newTestSpillDMLEvent(1)))
+	require.Len(t, spillQueue.segments, 1)
+
+	spillQueue.Teardown()
+	_, err := os.Stat(spillQueue.dir)
+	require.True(t, os.IsNotExist(err))
+	// Events pushed once torn down are discarded
+	require.NoError(t, spillQueue.Push(newTestSpillDMLEvent(2)))
+}
+
+func TestSpillQueueTeardownReleasesDrain(t *testing.T) {
+	// No one consumes the in-memory queue, as when the migration aborted
+	out := make(chan *applyEventStruct)
+	spillQueue := newTestSpillQueue(t, out)
+	require.NoError(t, spillQueue.Push(newTestSpillDMLEvent(1)))
+
+	drained := make(chan error)
+	go func() {
+		drained <- spillQueue.Drain()
+	}()
+	spillQueue.Teardown()
+	select {
+	case err := <-drained:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Drain did not return once torn down")
+	}
+}
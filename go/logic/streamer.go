@@ -24,6 +24,7 @@ type BinlogEventListener struct {
 	databaseName string
 	tableName    string
 	onDmlEvent   func(event *binlog.BinlogDMLEvent) error
+	onEntry      func(entry *binlog.BinlogEntry) error
 }
 
 const (
@@ -38,11 +39,13 @@ type EventsStreamer struct {
 	db                       *gosql.DB
 	dbVersion                string
 	migrationContext         *base.MigrationContext
+	log                      base.Logger
 	initialBinlogCoordinates *mysql.BinlogCoordinates
 	listeners                [](*BinlogEventListener)
 	listenersMutex           *sync.Mutex
 	eventsChannel            chan *binlog.BinlogEntry
 	binlogReader             *binlog.GoMySQLReader
+	hooksExecutor            *HooksExecutor
 	name                     string
 }
 
@@ -50,9 +53,11 @@ func NewEventsStreamer(migrationContext *base.MigrationContext) *EventsStreamer
 	return &EventsStreamer{
 		connectionConfig: migrationContext.InspectorConnectionConfig,
 		migrationContext: migrationContext,
+		log:              migrationContext.ComponentLog("streamer"),
 		listeners:        [](*BinlogEventListener){},
 		listenersMutex:   &sync.Mutex{},
 		eventsChannel:    make(chan *binlog.BinlogEntry, EventsChannelBufferSize),
+		hooksExecutor:    NewHooksExecutor(migrationContext),
 		name:             "streamer",
 	}
 }
@@ -79,9 +84,33 @@ func (this *EventsStreamer) AddListener(
 	return nil
 }
 
+// AddEntryListener registers a new listener for binlog entries, on a per-table basis. Unlike
+// AddListener, the listener is handed the entry's coordinates along with the DML event.
+func (this *EventsStreamer) AddEntryListener(
+	async bool, databaseName string, tableName string, onEntry func(entry *binlog.BinlogEntry) error) (err error) {
+	this.listenersMutex.Lock()
+	defer this.listenersMutex.Unlock()
+
+	if databaseName == "" {
+		return fmt.Errorf("Empty database name in AddEntryListener")
+	}
+	if tableName == "" {
+		return fmt.Errorf("Empty table name in AddEntryListener")
+	}
+	listener := &BinlogEventListener{
+		async:        async,
+		databaseName: databaseName,
+		tableName:    tableName,
+		onEntry:      onEntry,
+	}
+	this.listeners = append(this.listeners, listener)
+	return nil
+}
+
 // notifyListeners will notify relevant listeners with given DML event. Only
 // listeners registered for changes on the table on which the DML operates are notified.
-func (this *EventsStreamer) notifyListeners(binlogEvent *binlog.BinlogDMLEvent) {
+func (this *EventsStreamer) notifyListeners(binlogEntry *binlog.BinlogEntry) {
+	binlogEvent := binlogEntry.DmlEvent
 	this.listenersMutex.Lock()
 	defer this.listenersMutex.Unlock()
 
@@ -93,39 +122,73 @@ func (this *EventsStreamer) notifyListeners(binlogEvent *binlog.BinlogDMLEvent)
 		if !strings.EqualFold(listener.tableName, binlogEvent.TableName) {
 			continue
 		}
-		if listener.async {
-			go func() {
+		notify := func() {
+			if listener.onEntry != nil {
+				listener.onEntry(binlogEntry)
+			} else {
 				listener.onDmlEvent(binlogEvent)
-			}()
+			}
+		}
+		if listener.async {
+			go notify()
 		} else {
-			listener.onDmlEvent(binlogEvent)
+			notify()
 		}
 	}
 }
 
 func (this *EventsStreamer) InitDBConnections() (err error) {
-	EventsStreamerUri := this.connectionConfig.GetDBUri(this.migrationContext.DatabaseName)
-	if this.db, _, err = mysql.GetDB(this.migrationContext.Uuid, EventsStreamerUri); err != nil {
+	if err := this.initDBConnection(); err != nil {
 		return err
 	}
-	version, err := base.ValidateConnection(this.db, this.connectionConfig, this.migrationContext, this.name)
-	if err != nil {
+	if err := this.readCurrentBinlogCoordinates(); err != nil {
 		return err
 	}
-	this.dbVersion = version
-	if err := this.readCurrentBinlogCoordinates(); err != nil {
+	if err := this.initBinlogReader(this.initialBinlogCoordinates); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// InitDBConnectionsAt connects the streamer such that it resumes right after the given coordinates,
+// which are those of a rows event that was already handled. Much like a reconnect, the reader is
+// positioned at the beginning of the binary log and skips all events up to and including the given
+// coordinates, so that table map events are read anew.
+func (this *EventsStreamer) InitDBConnectionsAt(coordinates mysql.BinlogCoordinates) (err error) {
+	if err := this.initDBConnection(); err != nil {
 		return err
 	}
+	this.initialBinlogCoordinates = &mysql.BinlogCoordinates{LogFile: coordinates.LogFile, LogPos: 4}
 	if err := this.initBinlogReader(this.initialBinlogCoordinates); err != nil {
 		return err
 	}
+	this.binlogReader.LastAppliedRowsEventHint = coordinates
+	return nil
+}
 
+func (this *EventsStreamer) initDBConnection() (err error) {
+	EventsStreamerUri := this.connectionConfig.GetDBUri(this.migrationContext.DatabaseName)
+	if this.db, _, err = mysql.GetDB(this.migrationContext.Uuid, EventsStreamerUri, this.connectionConfig); err != nil {
+		return err
+	}
+	version, err := base.ValidateConnection(this.db, this.connectionConfig, this.migrationContext, this.name)
+	if err != nil {
+		return err
+	}
+	this.dbVersion = version
 	return nil
 }
 
-// initBinlogReader creates and connects the reader: we hook up to a MySQL server as a replica
+// initBinlogReader creates and connects the reader: we hook up to a MySQL server as a replica.
+// Credentials are refreshed first, as those of a credential helper may have expired since the
+// reader last connected.
 func (this *EventsStreamer) initBinlogReader(binlogCoordinates *mysql.BinlogCoordinates) error {
-	goMySQLReader := binlog.NewGoMySQLReader(this.migrationContext)
+	connectionConfig := this.connectionConfig.Duplicate()
+	if err := connectionConfig.RefreshCredentials(); err != nil {
+		return err
+	}
+	goMySQLReader := binlog.NewGoMySQLReader(this.migrationContext, connectionConfig)
 	if err := goMySQLReader.ConnectBinlogStreamer(*binlogCoordinates); err != nil {
 		return err
 	}
@@ -137,6 +200,11 @@ func (this *EventsStreamer) GetCurrentBinlogCoordinates() *mysql.BinlogCoordinat
 	return this.binlogReader.GetCurrentBinlogCoordinates()
 }
 
+// GetCurrentEventTime returns the time at which the most recently streamed event was written
+func (this *EventsStreamer) GetCurrentEventTime() time.Time {
+	return this.binlogReader.GetCurrentEventTime()
+}
+
 func (this *EventsStreamer) GetReconnectBinlogCoordinates() *mysql.BinlogCoordinates {
 	return &mysql.BinlogCoordinates{LogFile: this.GetCurrentBinlogCoordinates().LogFile, LogPos: 4}
 }
@@ -161,7 +229,7 @@ func (this *EventsStreamer) readCurrentBinlogCoordinates() error {
 	if !foundMasterStatus {
 		return fmt.Errorf("Got no results from SHOW %s. Bailing out", strings.ToUpper(binaryLogStatusTerm))
 	}
-	this.migrationContext.Log.Debugf("Streamer binlog coordinates: %+v", *this.initialBinlogCoordinates)
+	this.log.Debugf("Streamer binlog coordinates: %+v", *this.initialBinlogCoordinates)
 	return nil
 }
 
@@ -171,7 +239,7 @@ func (this *EventsStreamer) StreamEvents(canStopStreaming func() bool) error {
 	go func() {
 		for binlogEntry := range this.eventsChannel {
 			if binlogEntry.DmlEvent != nil {
-				this.notifyListeners(binlogEntry.DmlEvent)
+				this.notifyListeners(binlogEntry)
 			}
 		}
 	}()
@@ -187,7 +255,7 @@ func (this *EventsStreamer) StreamEvents(canStopStreaming func() bool) error {
 				return nil
 			}
 
-			this.migrationContext.Log.Infof("StreamEvents encountered unexpected error: %+v", err)
+			this.log.Infof("StreamEvents encountered unexpected error: %+v", err)
 			this.migrationContext.MarkPointOfInterest()
 			time.Sleep(ReconnectStreamerSleepSeconds * time.Second)
 
@@ -203,7 +271,10 @@ func (this *EventsStreamer) StreamEvents(canStopStreaming func() bool) error {
 
 			// Reposition at same binlog file.
 			lastAppliedRowsEventHint = this.binlogReader.LastAppliedRowsEventHint
-			this.migrationContext.Log.Infof("Reconnecting... Will resume at %+v", lastAppliedRowsEventHint)
+			this.log.Infof("Reconnecting... Will resume at %+v", lastAppliedRowsEventHint)
+			if hookErr := this.hooksExecutor.onStreamerReconnect(err, lastAppliedRowsEventHint.DisplayString(), successiveFailures); hookErr != nil {
+				this.log.Errore(hookErr)
+			}
 			if err := this.initBinlogReader(this.GetReconnectBinlogCoordinates()); err != nil {
 				return err
 			}
@@ -214,7 +285,7 @@ func (this *EventsStreamer) StreamEvents(canStopStreaming func() bool) error {
 
 func (this *EventsStreamer) Close() (err error) {
 	err = this.binlogReader.Close()
-	this.migrationContext.Log.Infof("Closed streamer connection. err=%+v", err)
+	this.log.Infof("Closed streamer connection. err=%+v", err)
 	return err
 }
 
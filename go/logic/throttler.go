@@ -9,6 +9,7 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"sort"
 	"strings"
 	"sync/atomic"
 	"time"
@@ -40,27 +41,104 @@ var (
 
 const frenoMagicHint = "freno"
 
+// throttleHooksQueueSize is the number of throttle hooks which may await execution
+const throttleHooksQueueSize = 100
+
+// throttleScheduleOverrides records the throttle parameters which an entry of the throttle schedule
+// overrode: the values in effect beforehand, and the values the entry applied.
+type throttleScheduleOverrides struct {
+	entry               *base.ThrottleScheduleEntry
+	baseNiceRatio       float64
+	appliedNiceRatio    float64
+	baseMaxLagMillis    int64
+	appliedMaxLagMillis int64
+	baseChunkSize       int64
+	appliedChunkSize    int64
+}
+
+// galeraFlowControlSample is a reading of the total time a Galera member has been paused by flow control
+type galeraFlowControlSample struct {
+	flowControlPaused time.Duration
+	sampledAt         time.Time
+}
+
+// pausedFraction is the fraction of time the member was paused by flow control between two samples
+func (this *galeraFlowControlSample) pausedFraction(next *galeraFlowControlSample) float64 {
+	elapsed := next.sampledAt.Sub(this.sampledAt)
+	paused := next.flowControlPaused - this.flowControlPaused
+	if elapsed <= 0 || paused < 0 {
+		// The counter was reset, e.g. by FLUSH STATUS
+		return 0
+	}
+	return float64(paused) / float64(elapsed)
+}
+
 // Throttler collects metrics related to throttling and makes informed decision
 // whether throttling should take place.
 type Throttler struct {
 	appVersion        string
 	migrationContext  *base.MigrationContext
+	log               base.Logger
 	applier           *Applier
 	httpClient        *http.Client
 	httpClientTimeout time.Duration
 	inspector         *Inspector
 	finishedMigrating int64
+
+	throttleScheduleOverrides *throttleScheduleOverrides
+	galeraFlowControlSamples  map[mysql.InstanceKey]*galeraFlowControlSample
+
+	hooksExecutor *HooksExecutor
+	// hooks queues throttle related hooks, executed in order, such that slow hooks do not hold up
+	// throttle checks
+	hooks             chan func() error
+	hooksDone         chan struct{}
+	throttleBeginTime time.Time
 }
 
 func NewThrottler(migrationContext *base.MigrationContext, applier *Applier, inspector *Inspector, appVersion string) *Throttler {
 	return &Throttler{
 		appVersion:        appVersion,
 		migrationContext:  migrationContext,
+		log:               migrationContext.ComponentLog("throttler"),
 		applier:           applier,
 		httpClient:        &http.Client{},
 		httpClientTimeout: time.Duration(migrationContext.ThrottleHTTPTimeoutMillis) * time.Millisecond,
 		inspector:         inspector,
 		finishedMigrating: 0,
+
+		galeraFlowControlSamples: make(map[mysql.InstanceKey]*galeraFlowControlSample),
+
+		hooksExecutor: NewHooksExecutor(migrationContext),
+		hooks:         make(chan func() error, throttleHooksQueueSize),
+		hooksDone:     make(chan struct{}),
+	}
+}
+
+// enqueueHook queues a hook for execution by executeHooks. Should hooks back up, the hook is skipped.
+func (this *Throttler) enqueueHook(hook func() error) {
+	select {
+	case this.hooks <- hook:
+	default:
+		this.log.Warningf("Throttle hooks are backed up; skipping hook")
+	}
+}
+
+// executeHooks executes queued hooks. Failing hooks are logged; they do not fail the migration.
+// It returns upon Teardown; hooks still queued at that time are not executed.
+func (this *Throttler) executeHooks() {
+	for {
+		select {
+		case hook, ok := <-this.hooks:
+			if !ok {
+				return
+			}
+			if err := hook(); err != nil {
+				this.log.Errore(err)
+			}
+		case <-this.hooksDone:
+			return
+		}
 	}
 }
 
@@ -129,7 +207,7 @@ func parseChangelogHeartbeat(heartbeatValue string) (lag time.Duration, err erro
 // parseChangelogHeartbeat parses a string timestamp and deduces replication lag
 func (this *Throttler) parseChangelogHeartbeat(heartbeatValue string) (err error) {
 	if lag, err := parseChangelogHeartbeat(heartbeatValue); err != nil {
-		return this.migrationContext.Log.Errore(err)
+		return this.log.Errore(err)
 	} else {
 		atomic.StoreInt64(&this.migrationContext.CurrentLag, int64(lag))
 		return nil
@@ -151,13 +229,13 @@ func (this *Throttler) collectReplicationLag(firstThrottlingCollected chan<- boo
 			// This means we will always get a good heartbeat value.
 			// When running on replica, we should instead check the `SHOW SLAVE STATUS` output.
 			if lag, err := mysql.GetReplicationLagFromSlaveStatus(this.inspector.dbVersion, this.inspector.informationSchemaDb); err != nil {
-				return this.migrationContext.Log.Errore(err)
+				return this.log.Errore(err)
 			} else {
 				atomic.StoreInt64(&this.migrationContext.CurrentLag, int64(lag))
 			}
 		} else {
 			if heartbeatValue, err := this.inspector.readChangelogState("heartbeat"); err != nil {
-				return this.migrationContext.Log.Errore(err)
+				return this.log.Errore(err)
 			} else {
 				this.parseChangelogHeartbeat(heartbeatValue)
 			}
@@ -195,7 +273,7 @@ func (this *Throttler) collectControlReplicasLag() {
 		dbUri := connectionConfig.GetDBUri("information_schema")
 
 		var heartbeatValue string
-		db, _, err := mysql.GetDB(this.migrationContext.Uuid, dbUri)
+		db, _, err := mysql.GetDB(this.migrationContext.Uuid, dbUri, connectionConfig)
 		if err != nil {
 			return lag, err
 		}
@@ -211,6 +289,7 @@ func (this *Throttler) collectControlReplicasLag() {
 	readControlReplicasLag := func() (result *mysql.ReplicationLagResult) {
 		instanceKeyMap := this.migrationContext.GetThrottleControlReplicaKeys()
 		if instanceKeyMap.Len() == 0 {
+			this.migrationContext.SetControlReplicasLagResults(nil)
 			return result
 		}
 		lagResults := make(chan *mysql.ReplicationLagResult, instanceKeyMap.Len())
@@ -226,8 +305,10 @@ func (this *Throttler) collectControlReplicasLag() {
 				lagResults <- lagResult
 			}()
 		}
+		replicaLagResults := []mysql.ReplicationLagResult{}
 		for range *instanceKeyMap {
 			lagResult := <-lagResults
+			replicaLagResults = append(replicaLagResults, *lagResult)
 			if result == nil {
 				result = lagResult
 			} else if lagResult.Err != nil {
@@ -236,6 +317,10 @@ func (this *Throttler) collectControlReplicasLag() {
 				result = lagResult
 			}
 		}
+		sort.Slice(replicaLagResults, func(i, j int) bool {
+			return replicaLagResults[i].Key.StringCode() < replicaLagResults[j].Key.StringCode()
+		})
+		this.migrationContext.SetControlReplicasLagResults(replicaLagResults)
 		return result
 	}
 
@@ -272,6 +357,86 @@ func (this *Throttler) collectControlReplicasLag() {
 	}
 }
 
+// discoverControlReplicas periodically discovers the replicas of the applier's server, recursively, and
+// sets them as throttle control replicas. Replicas added throughout the migration are thus also checked for lag.
+func (this *Throttler) discoverControlReplicas() {
+	if !this.migrationContext.DiscoverThrottleControlReplicas {
+		return
+	}
+
+	discover := func() {
+		if atomic.LoadInt64(&this.migrationContext.HibernateUntil) > 0 {
+			return
+		}
+		applierKey := this.migrationContext.ApplierConnectionConfig.Key
+		visitedKeys := mysql.NewInstanceKeyMap()
+		visitedKeys.AddKey(applierKey)
+		discoveredKeys := mysql.NewInstanceKeyMap()
+		if err := this.discoverReplicasOf(this.migrationContext.ApplierConnectionConfig, visitedKeys, discoveredKeys); err != nil {
+			this.log.Errorf("Error discovering throttle control replicas of %+v: %+v", applierKey, err)
+			return
+		}
+		previousKeys := this.migrationContext.GetDiscoveredThrottleControlReplicaKeys()
+		changed := previousKeys.Len() != discoveredKeys.Len()
+		for key := range *discoveredKeys {
+			changed = changed || !previousKeys.HasKey(key)
+		}
+		if changed {
+			this.log.Infof("Discovered %d throttle control replicas: %s", discoveredKeys.Len(), discoveredKeys.ToCommaDelimitedList())
+		}
+		this.migrationContext.SetDiscoveredThrottleControlReplicaKeys(discoveredKeys)
+	}
+	discover()
+
+	ticker := time.NewTicker(time.Duration(this.migrationContext.ThrottleControlReplicasDiscoveryIntervalSeconds) * time.Second)
+	defer ticker.Stop()
+	for range ticker.C {
+		if atomic.LoadInt64(&this.finishedMigrating) > 0 {
+			return
+		}
+		discover()
+	}
+}
+
+// discoverReplicasOf adds the replicas of given server, and their own replicas, to discoveredKeys,
+// skipping excluded replicas. A replica which cannot be inspected is still added, but its own
+// replicas are not discovered.
+func (this *Throttler) discoverReplicasOf(connectionConfig *mysql.ConnectionConfig, visitedKeys *mysql.InstanceKeyMap, discoveredKeys *mysql.InstanceKeyMap) error {
+	db, _, err := mysql.GetDB(this.migrationContext.Uuid, connectionConfig.GetDBUri("information_schema"), connectionConfig)
+	if err != nil {
+		return err
+	}
+	var dbVersion string
+	if err := db.QueryRow(`select @@global.version`).Scan(&dbVersion); err != nil {
+		return err
+	}
+	replicaKeys, err := mysql.GetReplicaKeys(dbVersion, db, connectionConfig.Key.Port, this.migrationContext.ReplicaServerId)
+	if err != nil {
+		return err
+	}
+	for _, replicaKey := range replicaKeys {
+		if visitedKeys.HasKey(replicaKey) {
+			continue
+		}
+		visitedKeys.AddKey(replicaKey)
+		if this.migrationContext.IsThrottleControlReplicaExcluded(replicaKey) {
+			continue
+		}
+		if this.migrationContext.IsThrottleControlReplicaIncluded(replicaKey) {
+			discoveredKeys.AddKey(replicaKey)
+		}
+
+		replicaConnectionConfig := this.migrationContext.InspectorConnectionConfig.DuplicateCredentials(replicaKey)
+		if err := replicaConnectionConfig.RegisterTLSConfig(); err != nil {
+			return err
+		}
+		if err := this.discoverReplicasOf(replicaConnectionConfig, visitedKeys, discoveredKeys); err != nil {
+			this.log.Warningf("Cannot discover replicas of %+v: %+v", replicaKey, err)
+		}
+	}
+	return nil
+}
+
 func (this *Throttler) criticalLoadIsMet() (met bool, variableName string, value int64, threshold int64, err error) {
 	criticalLoad := this.migrationContext.GetCriticalLoad()
 	for variableName, threshold = range criticalLoad {
@@ -350,6 +515,7 @@ func (this *Throttler) collectThrottleHTTPStatus(firstThrottlingCollected chan<-
 
 // collectGeneralThrottleMetrics reads the once-per-sec metrics, and stores them onto this.migrationContext
 func (this *Throttler) collectGeneralThrottleMetrics() error {
+	this.applyThrottleSchedule()
 	if atomic.LoadInt64(&this.migrationContext.HibernateUntil) > 0 {
 		return nil
 	}
@@ -362,7 +528,7 @@ func (this *Throttler) collectGeneralThrottleMetrics() error {
 	// Regardless of throttle, we take opportunity to check for panic-abort
 	if this.migrationContext.PanicFlagFile != "" {
 		if base.FileExists(this.migrationContext.PanicFlagFile) {
-			this.migrationContext.PanicAbort <- fmt.Errorf("Found panic-file %s. Aborting without cleanup", this.migrationContext.PanicFlagFile)
+			this.migrationContext.SendPanicAbort(fmt.Errorf("Found panic-file %s. Aborting without cleanup", this.migrationContext.PanicFlagFile))
 		}
 	}
 
@@ -375,7 +541,11 @@ func (this *Throttler) collectGeneralThrottleMetrics() error {
 		hibernateDuration := time.Duration(this.migrationContext.CriticalLoadHibernateSeconds) * time.Second
 		hibernateUntilTime := time.Now().Add(hibernateDuration)
 		atomic.StoreInt64(&this.migrationContext.HibernateUntil, hibernateUntilTime.UnixNano())
-		this.migrationContext.Log.Errorf("critical-load met: %s=%d, >=%d. Will hibernate for the duration of %+v, until %+v", variableName, value, threshold, hibernateDuration, hibernateUntilTime)
+		this.log.Errorf("critical-load met: %s=%d, >=%d. Will hibernate for the duration of %+v, until %+v", variableName, value, threshold, hibernateDuration, hibernateUntilTime)
+		criticalLoad := fmt.Sprintf("%s=%d", variableName, value)
+		this.enqueueHook(func() error {
+			return this.hooksExecutor.onCriticalLoadHibernate(criticalLoad, hibernateDuration)
+		})
 		go func() {
 			time.Sleep(hibernateDuration)
 			this.migrationContext.SetThrottleGeneralCheckResult(base.NewThrottleCheckResult(true, "leaving hibernation", base.LeavingHibernationThrottleReasonHint))
@@ -385,32 +555,35 @@ func (this *Throttler) collectGeneralThrottleMetrics() error {
 	}
 
 	if criticalLoadMet && this.migrationContext.CriticalLoadIntervalMilliseconds == 0 {
-		this.migrationContext.PanicAbort <- fmt.Errorf("critical-load met: %s=%d, >=%d", variableName, value, threshold)
+		this.migrationContext.SendPanicAbort(fmt.Errorf("critical-load met: %s=%d, >=%d", variableName, value, threshold))
 	}
 	if criticalLoadMet && this.migrationContext.CriticalLoadIntervalMilliseconds > 0 {
-		this.migrationContext.Log.Errorf("critical-load met once: %s=%d, >=%d. Will check again in %d millis", variableName, value, threshold, this.migrationContext.CriticalLoadIntervalMilliseconds)
+		this.log.Errorf("critical-load met once: %s=%d, >=%d. Will check again in %d millis", variableName, value, threshold, this.migrationContext.CriticalLoadIntervalMilliseconds)
 		go func() {
 			timer := time.NewTimer(time.Millisecond * time.Duration(this.migrationContext.CriticalLoadIntervalMilliseconds))
 			<-timer.C
 			if criticalLoadMetAgain, variableName, value, threshold, _ := this.criticalLoadIsMet(); criticalLoadMetAgain {
-				this.migrationContext.PanicAbort <- fmt.Errorf("critical-load met again after %d millis: %s=%d, >=%d", this.migrationContext.CriticalLoadIntervalMilliseconds, variableName, value, threshold)
+				this.migrationContext.SendPanicAbort(fmt.Errorf("critical-load met again after %d millis: %s=%d, >=%d", this.migrationContext.CriticalLoadIntervalMilliseconds, variableName, value, threshold))
 			}
 		}()
 	}
 
 	// Back to throttle considerations
 
+	// Forced throttling is overridden when it risks the binary log being read getting purged
+	forcedThrottleOverridden := this.migrationContext.BinlogRetentionOverrideForcedThrottle && atomic.LoadInt64(&this.migrationContext.BinlogRetentionGuardBreached) > 0
+
 	// User-based throttle
-	if atomic.LoadInt64(&this.migrationContext.ThrottleCommandedByUser) > 0 {
+	if atomic.LoadInt64(&this.migrationContext.ThrottleCommandedByUser) > 0 && !forcedThrottleOverridden {
 		return setThrottle(true, "commanded by user", base.UserCommandThrottleReasonHint)
 	}
-	if this.migrationContext.ThrottleFlagFile != "" {
+	if this.migrationContext.ThrottleFlagFile != "" && !forcedThrottleOverridden {
 		if base.FileExists(this.migrationContext.ThrottleFlagFile) {
 			// Throttle file defined and exists!
 			return setThrottle(true, "flag-file", base.NoThrottleReasonHint)
 		}
 	}
-	if this.migrationContext.ThrottleAdditionalFlagFile != "" {
+	if this.migrationContext.ThrottleAdditionalFlagFile != "" && !forcedThrottleOverridden {
 		if base.FileExists(this.migrationContext.ThrottleAdditionalFlagFile) {
 			// 2nd Throttle file defined and exists!
 			return setThrottle(true, "flag-file", base.NoThrottleReasonHint)
@@ -427,6 +600,39 @@ func (this *Throttler) collectGeneralThrottleMetrics() error {
 			return setThrottle(true, fmt.Sprintf("max-load %s=%d >= %d", variableName, value, threshold), base.NoThrottleReasonHint)
 		}
 	}
+	maxInnoDBMetrics := this.migrationContext.GetMaxInnoDBMetrics()
+	for metricName, threshold := range maxInnoDBMetrics {
+		value, err := this.applier.ReadInnoDBMetric(metricName)
+		if err != nil {
+			return setThrottle(true, fmt.Sprintf("max-innodb-metrics %s %s", metricName, err), base.NoThrottleReasonHint)
+		}
+		if value >= threshold {
+			return setThrottle(true, fmt.Sprintf("max-innodb-metrics %s=%d >= %d", metricName, value, threshold), base.NoThrottleReasonHint)
+		}
+	}
+	if minFreeSpace := this.migrationContext.MinFreeDiskSpaceMB * bytesPerMB; minFreeSpace > 0 && this.migrationContext.FreeDiskSpaceQuery != "" {
+		freeSpace := atomic.LoadInt64(&this.migrationContext.FreeDiskSpaceBytes)
+		if freeSpace < 0 {
+			return setThrottle(true, "min-free-disk-space unknown", base.NoThrottleReasonHint)
+		}
+		if freeSpace < minFreeSpace {
+			return setThrottle(true, fmt.Sprintf("min-free-disk-space %dMB < %dMB", freeSpace/bytesPerMB, this.migrationContext.MinFreeDiskSpaceMB), base.NoThrottleReasonHint)
+		}
+	}
+	if maxQueue := this.migrationContext.MaxGroupReplicationQueue; maxQueue > 0 {
+		memberKey, queue, err := this.applier.ReadGroupReplicationQueue()
+		if err != nil {
+			return setThrottle(true, fmt.Sprintf("max-group-replication-queue %s", err), base.NoThrottleReasonHint)
+		}
+		if queue >= maxQueue {
+			return setThrottle(true, fmt.Sprintf("max-group-replication-queue %s=%d >= %d", memberKey, queue, maxQueue), base.NoThrottleReasonHint)
+		}
+	}
+	if this.migrationContext.MaxGaleraRecvQueue > 0 || this.migrationContext.MaxGaleraFlowControlPaused > 0 {
+		if reason := this.checkGaleraFlowControl(); reason != "" {
+			return setThrottle(true, reason, base.NoThrottleReasonHint)
+		}
+	}
 	if this.migrationContext.GetThrottleQuery() != "" {
 		if res, _ := this.applier.ExecuteThrottleQuery(); res > 0 {
 			return setThrottle(true, "throttle-query", base.NoThrottleReasonHint)
@@ -436,11 +642,102 @@ func (this *Throttler) collectGeneralThrottleMetrics() error {
 	return setThrottle(false, "", base.NoThrottleReasonHint)
 }
 
+// applyThrottleSchedule puts in effect the parameters of the throttle schedule entry which applies
+// now, if any, and returns that entry. Once an entry no longer applies, the parameters it overrode
+// are restored, unless changed meanwhile, e.g. by interactive command.
+func (this *Throttler) applyThrottleSchedule() *base.ThrottleScheduleEntry {
+	var entry *base.ThrottleScheduleEntry
+	if throttleSchedule := this.migrationContext.GetThrottleSchedule(); throttleSchedule != nil {
+		entry = throttleSchedule.ActiveEntry(time.Now())
+	}
+	overrides := this.throttleScheduleOverrides
+	if (overrides == nil && entry == nil) || (overrides != nil && overrides.entry == entry) {
+		return entry
+	}
+	if overrides != nil {
+		if overrides.entry.NiceRatio >= 0 && this.migrationContext.GetNiceRatio() == overrides.appliedNiceRatio {
+			this.migrationContext.SetNiceRatio(overrides.baseNiceRatio)
+		}
+		if overrides.entry.MaxLagMillis > 0 && atomic.LoadInt64(&this.migrationContext.MaxLagMillisecondsThrottleThreshold) == overrides.appliedMaxLagMillis {
+			this.migrationContext.SetMaxLagMillisecondsThrottleThreshold(overrides.baseMaxLagMillis)
+		}
+		if overrides.entry.ChunkSize > 0 && atomic.LoadInt64(&this.migrationContext.ChunkSize) == overrides.appliedChunkSize {
+			this.migrationContext.SetChunkSize(overrides.baseChunkSize)
+		}
+		this.log.Infof("Throttle schedule entry no longer applies: %s", overrides.entry)
+		this.throttleScheduleOverrides = nil
+	}
+	if entry != nil {
+		overrides = &throttleScheduleOverrides{entry: entry}
+		if entry.NiceRatio >= 0 {
+			overrides.baseNiceRatio = this.migrationContext.GetNiceRatio()
+			this.migrationContext.SetNiceRatio(entry.NiceRatio)
+			overrides.appliedNiceRatio = this.migrationContext.GetNiceRatio()
+		}
+		if entry.MaxLagMillis > 0 {
+			overrides.baseMaxLagMillis = atomic.LoadInt64(&this.migrationContext.MaxLagMillisecondsThrottleThreshold)
+			this.migrationContext.SetMaxLagMillisecondsThrottleThreshold(entry.MaxLagMillis)
+			overrides.appliedMaxLagMillis = atomic.LoadInt64(&this.migrationContext.MaxLagMillisecondsThrottleThreshold)
+		}
+		if entry.ChunkSize > 0 {
+			overrides.baseChunkSize = atomic.LoadInt64(&this.migrationContext.ChunkSize)
+			this.migrationContext.SetChunkSize(entry.ChunkSize)
+			overrides.appliedChunkSize = atomic.LoadInt64(&this.migrationContext.ChunkSize)
+		}
+		this.log.Infof("Throttle schedule entry applies: %s", entry)
+		this.throttleScheduleOverrides = overrides
+	}
+	this.migrationContext.SetActiveThrottleScheduleEntry(entry)
+	return entry
+}
+
+// checkGaleraFlowControl reads the receive queue of every member of the Galera cluster, as well as the
+// fraction of time each was paused by flow control since the previous check, and returns a throttle
+// reason should any exceed its threshold. A member falling behind pauses writes on all members.
+func (this *Throttler) checkGaleraFlowControl() (reason string) {
+	memberKeys, err := this.applier.ReadGaleraMemberKeys()
+	if err != nil {
+		return fmt.Sprintf("galera members %s", err)
+	}
+	samples := make(map[mysql.InstanceKey]*galeraFlowControlSample)
+	defer func() {
+		this.galeraFlowControlSamples = samples
+	}()
+	for _, memberKey := range memberKeys {
+		connectionConfig := this.migrationContext.InspectorConnectionConfig.DuplicateCredentials(memberKey)
+		if err := connectionConfig.RegisterTLSConfig(); err != nil {
+			return fmt.Sprintf("galera member %s %s", memberKey.String(), err)
+		}
+		db, _, err := mysql.GetDB(this.migrationContext.Uuid, connectionConfig.GetDBUri("information_schema"), connectionConfig)
+		if err != nil {
+			return fmt.Sprintf("galera member %s %s", memberKey.String(), err)
+		}
+		recvQueue, flowControlPaused, err := mysql.GetGaleraFlowControlStatus(db)
+		if err != nil {
+			return fmt.Sprintf("galera member %s %s", memberKey.String(), err)
+		}
+		sample := &galeraFlowControlSample{flowControlPaused: flowControlPaused, sampledAt: time.Now()}
+		samples[memberKey] = sample
+
+		if maxRecvQueue := this.migrationContext.MaxGaleraRecvQueue; maxRecvQueue > 0 && recvQueue >= maxRecvQueue && reason == "" {
+			reason = fmt.Sprintf("max-galera-recv-queue %s=%d >= %d", memberKey.String(), recvQueue, maxRecvQueue)
+		}
+		previousSample, found := this.galeraFlowControlSamples[memberKey]
+		if maxPaused := this.migrationContext.MaxGaleraFlowControlPaused; maxPaused > 0 && found && reason == "" {
+			if paused := previousSample.pausedFraction(sample); paused >= maxPaused {
+				reason = fmt.Sprintf("max-galera-flow-control-paused %s=%.2f >= %.2f", memberKey.String(), paused, maxPaused)
+			}
+		}
+	}
+	return reason
+}
+
 // initiateThrottlerCollection initiates the various processes that collect measurements
 // that may affect throttling. There are several components, all running independently,
 // that collect such metrics.
 func (this *Throttler) initiateThrottlerCollection(firstThrottlingCollected chan<- bool) {
 	go this.collectReplicationLag(firstThrottlingCollected)
+	go this.discoverControlReplicas()
 	go this.collectControlReplicasLag()
 	go this.collectThrottleHTTPStatus(firstThrottlingCollected)
 
@@ -468,15 +765,24 @@ func (this *Throttler) initiateThrottlerChecks() {
 		if shouldThrottle && !alreadyThrottling {
 			// New throttling
 			this.applier.WriteAndLogChangelog("throttle", throttleReason)
+			this.throttleBeginTime = time.Now()
+			this.enqueueHook(func() error {
+				return this.hooksExecutor.onThrottleBegin(throttleReason)
+			})
 		} else if shouldThrottle && alreadyThrottling && (currentReason != throttleReason) {
 			// Change of reason
 			this.applier.WriteAndLogChangelog("throttle", throttleReason)
 		} else if alreadyThrottling && !shouldThrottle {
 			// End of throttling
 			this.applier.WriteAndLogChangelog("throttle", "done throttling")
+			throttledDuration := time.Since(this.throttleBeginTime)
+			this.enqueueHook(func() error {
+				return this.hooksExecutor.onThrottleEnd(currentReason, throttledDuration)
+			})
 		}
 		this.migrationContext.SetThrottled(shouldThrottle, throttleReason, throttleReasonHint)
 	}
+	go this.executeHooks()
 	throttlerFunction()
 
 	ticker := time.NewTicker(100 * time.Millisecond)
@@ -490,9 +796,13 @@ func (this *Throttler) initiateThrottlerChecks() {
 }
 
 // throttle sees if throttling needs take place, and if so, continuously sleeps (blocks)
-// until throttling reasons are gone
+// until throttling reasons are gone, or the migration aborts or is over
 func (this *Throttler) throttle(onThrottled func()) {
 	for {
+		if atomic.LoadInt64(&this.finishedMigrating) > 0 || this.migrationContext.IsPanicAbortClosed() {
+			// Throttling is no longer collected, and would never be lifted
+			return
+		}
 		// IsThrottled() is non-blocking; the throttling decision making takes place asynchronously.
 		// Therefore calling IsThrottled() is cheap
 		if shouldThrottle, _, _ := this.migrationContext.IsThrottled(); !shouldThrottle {
@@ -506,6 +816,8 @@ func (this *Throttler) throttle(onThrottled func()) {
 }
 
 func (this *Throttler) Teardown() {
-	this.migrationContext.Log.Debugf("Tearing down...")
-	atomic.StoreInt64(&this.finishedMigrating, 1)
+	this.log.Debugf("Tearing down...")
+	if atomic.CompareAndSwapInt64(&this.finishedMigrating, 0, 1) {
+		close(this.hooksDone)
+	}
 }
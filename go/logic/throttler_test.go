@@ -0,0 +1,156 @@
+/*
+   Copyright 2022 GitHub Inc.
+	 See https://github.com/github/gh-ost/blob/master/LICENSE
+*/
+
+package logic
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/github/gh-ost/go/base"
+)
+
+func TestThrottlerApplyThrottleSchedule(t *testing.T) {
+	migrationContext := base.NewMigrationContext()
+	migrationContext.ThrottleScheduleTimezone = "UTC"
+	migrationContext.SetChunkSize(1000)
+	migrationContext.SetNiceRatio(0.5)
+	throttler := NewThrottler(migrationContext, nil, nil, "test")
+
+	require.Nil(t, throttler.applyThrottleSchedule())
+	require.Nil(t, migrationContext.GetActiveThrottleScheduleEntry())
+
+	require.NoError(t, migrationContext.ReadThrottleSchedule("00:00-24:00 nice-ratio=2 chunk-size=100"))
+	entry := throttler.applyThrottleSchedule()
+	require.NotNil(t, entry)
+	require.Equal(t, entry, migrationContext.GetActiveThrottleScheduleEntry())
+	require.Equal(t, float64(2), migrationContext.GetNiceRatio())
+	require.Equal(t, int64(100), atomic.LoadInt64(&migrationContext.ChunkSize))
+	require.Equal(t, int64(1500), atomic.LoadInt64(&migrationContext.MaxLagMillisecondsThrottleThreshold))
+
+	// Parameters changed while the entry applies are left as changed
+	migrationContext.SetChunkSize(200)
+	require.Equal(t, entry, throttler.applyThrottleSchedule())
+
+	require.NoError(t, migrationContext.ReadThrottleSchedule(""))
+	require.Nil(t, throttler.applyThrottleSchedule())
+	require.Nil(t, migrationContext.GetActiveThrottleScheduleEntry())
+	require.Equal(t, 0.5, migrationContext.GetNiceRatio())
+	require.Equal(t, int64(200), atomic.LoadInt64(&migrationContext.ChunkSize))
+}
+
+func TestGaleraFlowControlSamplePausedFraction(t *testing.T) {
+	now := time.Now()
+	sample := &galeraFlowControlSample{flowControlPaused: 2 * time.Second, sampledAt: now}
+
+	require.Equal(t, 0.25, sample.pausedFraction(&galeraFlowControlSample{flowControlPaused: 2500 * time.Millisecond, sampledAt: now.Add(2 * time.Second)}))
+	require.Equal(t, float64(0), sample.pausedFraction(&galeraFlowControlSample{flowControlPaused: 2 * time.Second, sampledAt: now.Add(time.Second)}))
+
+	// FLUSH STATUS resets the counter
+	require.Equal(t, float64(0), sample.pausedFraction(&galeraFlowControlSample{flowControlPaused: 0, sampledAt: now.Add(time.Second)}))
+	require.Equal(t, float64(0), sample.pausedFraction(&galeraFlowControlSample{flowControlPaused: 3 * time.Second, sampledAt: now}))
+}
+
+func TestThrottlerBinlogRetentionOverridesForcedThrottle(t *testing.T) {
+	migrationContext := base.NewMigrationContext()
+	throttler := NewThrottler(migrationContext, nil, nil, "test")
+
+	atomic.StoreInt64(&migrationContext.ThrottleCommandedByUser, 1)
+	atomic.StoreInt64(&migrationContext.BinlogRetentionGuardBreached, 1)
+	require.NoError(t, throttler.collectGeneralThrottleMetrics())
+	require.True(t, migrationContext.GetThrottleGeneralCheckResult().ShouldThrottle)
+
+	migrationContext.BinlogRetentionOverrideForcedThrottle = true
+	require.NoError(t, throttler.collectGeneralThrottleMetrics())
+	require.False(t, migrationContext.GetThrottleGeneralCheckResult().ShouldThrottle)
+
+	atomic.StoreInt64(&migrationContext.BinlogRetentionGuardBreached, 0)
+	require.NoError(t, throttler.collectGeneralThrottleMetrics())
+	require.True(t, migrationContext.GetThrottleGeneralCheckResult().ShouldThrottle)
+}
+
+func TestThrottlerMinFreeDiskSpace(t *testing.T) {
+	migrationContext := base.NewMigrationContext()
+	migrationContext.FreeDiskSpaceQuery = "select 1"
+	migrationContext.MinFreeDiskSpaceMB = 100
+	throttler := NewThrottler(migrationContext, nil, nil, "test")
+
+	// Unknown free space throttles
+	require.NoError(t, throttler.collectGeneralThrottleMetrics())
+	require.True(t, migrationContext.GetThrottleGeneralCheckResult().ShouldThrottle)
+
+	atomic.StoreInt64(&migrationContext.FreeDiskSpaceBytes, 50*bytesPerMB)
+	require.NoError(t, throttler.collectGeneralThrottleMetrics())
+	require.Equal(t, "min-free-disk-space 50MB < 100MB", migrationContext.GetThrottleGeneralCheckResult().Reason)
+
+	atomic.StoreInt64(&migrationContext.FreeDiskSpaceBytes, 200*bytesPerMB)
+	require.NoError(t, throttler.collectGeneralThrottleMetrics())
+	require.False(t, migrationContext.GetThrottleGeneralCheckResult().ShouldThrottle)
+}
+
+func TestThrottlerHooks(t *testing.T) {
+	migrationContext := base.NewMigrationContext()
+	throttler := NewThrottler(migrationContext, nil, nil, "test")
+
+	executed := []int{}
+	for i := 0; i < throttleHooksQueueSize+5; i++ {
+		i := i
+		throttler.enqueueHook(func() error {
+			executed = append(executed, i)
+			return nil
+		})
+	}
+	// Hooks beyond the queue size are skipped, and the others are executed in order
+	close(throttler.hooks)
+	throttler.executeHooks()
+	require.Len(t, executed, throttleHooksQueueSize)
+	for i, hook := range executed {
+		require.Equal(t, i, hook)
+	}
+}
+
+func TestThrottlerTeardownStopsHooks(t *testing.T) {
+	migrationContext := base.NewMigrationContext()
+	throttler := NewThrottler(migrationContext, nil, nil, "test")
+
+	done := make(chan struct{})
+	go func() {
+		throttler.executeHooks()
+		close(done)
+	}()
+	throttler.Teardown()
+	throttler.Teardown()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected executeHooks to return upon teardown")
+	}
+}
+
+func TestThrottlerThrottleReturnsOnAbort(t *testing.T) {
+	migrationContext := base.NewMigrationContext()
+	migrationContext.SetThrottled(true, "max-load", base.NoThrottleReasonHint)
+	throttler := NewThrottler(migrationContext, nil, nil, "test")
+
+	throttled := make(chan struct{})
+	go func() {
+		throttler.throttle(nil)
+		close(throttled)
+	}()
+	select {
+	case <-throttled:
+		t.Fatal("throttle returned while throttled")
+	case <-time.After(300 * time.Millisecond):
+	}
+	migrationContext.ClosePanicAbort()
+	select {
+	case <-throttled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("throttle did not return once the migration aborted")
+	}
+}
@@ -0,0 +1,337 @@
+/*
+   Copyright 2022 GitHub Inc.
+	 See https://github.com/github/gh-ost/blob/master/LICENSE
+*/
+
+package logic
+
+import (
+	"context"
+	gosql "database/sql"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/github/gh-ost/go/base"
+	"github.com/github/gh-ost/go/binlog"
+	"github.com/github/gh-ost/go/sql"
+)
+
+const maxShadowVerifyLoggedDiffs = 5
+
+// shadowVerifyRow is a row as read for verification. A nil row stands for a row that does not exist.
+type shadowVerifyRow []gosql.NullString
+
+// shadowVerifySuspect is a sampled row which did not verify. It is only counted as a mismatch if it
+// still does not verify on the next round, while the original row is unchanged: this rules out rows
+// which were changed after being sampled, and of which the latest events are yet to be applied.
+type shadowVerifySuspect struct {
+	rowValues   []interface{}
+	originalRow shadowVerifyRow
+}
+
+// ShadowVerifier continuously verifies the migration: it samples the rows affected by applied DML
+// events, and compares each sampled row on the original table with the same row on the ghost table.
+type ShadowVerifier struct {
+	migrationContext *base.MigrationContext
+	applier          *Applier
+
+	// verifyMutex is held throughout a verification round, so that Teardown can wait one out
+	verifyMutex *sync.Mutex
+
+	originalRowQueryBuilder *sql.DMLSelectQueryBuilder
+	ghostRowQueryBuilder    *sql.DMLSelectQueryBuilder
+	verifiedColumnNames     []string
+
+	samples      [][]interface{}
+	sampledCount int64
+	samplesMutex *sync.Mutex
+	suspects     []*shadowVerifySuspect
+
+	rowCopyComplete   int64
+	finishedMigrating int64
+	aborted           int64
+}
+
+func NewShadowVerifier(migrationContext *base.MigrationContext, applier *Applier) *ShadowVerifier {
+	return &ShadowVerifier{
+		migrationContext: migrationContext,
+		applier:          applier,
+		verifyMutex:      &sync.Mutex{},
+		samples:          [][]interface{}{},
+		samplesMutex:     &sync.Mutex{},
+	}
+}
+
+// prepareQueries builds the queries which read a row off the original and ghost tables. Only shared
+// columns are compared, excluding those populated by a `--column-expression`, and those of which the
+// migration changes the type or charset: their values legitimately read differently on either table.
+func (this *ShadowVerifier) prepareQueries() (err error) {
+	originalColumnNames := []string{}
+	ghostColumnNames := []string{}
+	changedColumnNames := []string{}
+	mappedSharedColumns := this.migrationContext.MappedSharedColumns.Columns()
+	for i, sharedColumn := range this.migrationContext.SharedColumns.Columns() {
+		ghostColumn := mappedSharedColumns[i]
+		if this.hasColumnExpression(ghostColumn.Name) {
+			continue
+		}
+		if sharedColumn.MySQLType != ghostColumn.MySQLType || sharedColumn.Charset != ghostColumn.Charset {
+			changedColumnNames = append(changedColumnNames, sharedColumn.Name)
+			continue
+		}
+		originalColumnNames = append(originalColumnNames, sharedColumn.Name)
+		ghostColumnNames = append(ghostColumnNames, ghostColumn.Name)
+	}
+	if len(changedColumnNames) > 0 {
+		this.migrationContext.Log.Infof("Shadow verification does not compare columns of changed type or charset: %s", strings.Join(changedColumnNames, ", "))
+	}
+	if len(originalColumnNames) == 0 {
+		return fmt.Errorf("Shadow verification found no columns to compare: all shared columns change type or charset, or are populated by --column-expression")
+	}
+	this.verifiedColumnNames = originalColumnNames
+
+	if this.originalRowQueryBuilder, err = sql.NewDMLSelectQueryBuilder(
+		this.migrationContext.DatabaseName,
+		this.migrationContext.OriginalTableName,
+		this.migrationContext.OriginalTableColumns,
+		originalColumnNames,
+		&this.migrationContext.UniqueKey.Columns,
+	); err != nil {
+		return err
+	}
+	if this.ghostRowQueryBuilder, err = sql.NewDMLSelectQueryBuilder(
+		this.migrationContext.DatabaseName,
+		this.migrationContext.GetGhostTableName(),
+		this.migrationContext.OriginalTableColumns,
+		ghostColumnNames,
+		&this.migrationContext.UniqueKey.Columns,
+	); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (this *ShadowVerifier) hasColumnExpression(ghostColumnName string) bool {
+	for columnName := range this.migrationContext.ColumnExpressions {
+		if strings.EqualFold(columnName, ghostColumnName) {
+			return true
+		}
+	}
+	return false
+}
+
+// MarkRowCopyComplete lets the verifier know all rows were copied: from now on, a row missing on
+// the ghost table is a mismatch, where before it could have been a row not yet copied.
+func (this *ShadowVerifier) MarkRowCopyComplete() {
+	atomic.StoreInt64(&this.rowCopyComplete, 1)
+}
+
+// Record takes the rows affected by applied DML events into the sample. Each verification round
+// verifies a uniformly random sample of the rows recorded since the previous round.
+func (this *ShadowVerifier) Record(dmlEvents [](*binlog.BinlogDMLEvent)) {
+	this.samplesMutex.Lock()
+	defer this.samplesMutex.Unlock()
+
+	sampleSize := atomic.LoadInt64(&this.migrationContext.ShadowVerifySampleSize)
+	for _, dmlEvent := range dmlEvents {
+		for _, columnValues := range []*sql.ColumnValues{dmlEvent.WhereColumnValues, dmlEvent.NewColumnValues} {
+			if columnValues == nil {
+				continue
+			}
+			this.sampledCount++
+			if int64(len(this.samples)) < sampleSize {
+				this.samples = append(this.samples, columnValues.AbstractValues())
+			} else if i := rand.Int63n(this.sampledCount); i < sampleSize {
+				this.samples[i] = columnValues.AbstractValues()
+			}
+		}
+	}
+}
+
+func (this *ShadowVerifier) takeSamples() (samples [][]interface{}) {
+	this.samplesMutex.Lock()
+	defer this.samplesMutex.Unlock()
+
+	samples = this.samples
+	this.samples = [][]interface{}{}
+	this.sampledCount = 0
+	return samples
+}
+
+// readRow reads a row by the unique key values found in the given row values. It returns nil when
+// no such row exists.
+func (this *ShadowVerifier) readRow(db *gosql.DB, queryBuilder *sql.DMLSelectQueryBuilder, rowValues []interface{}) (row shadowVerifyRow, err error) {
+	query, args, err := queryBuilder.BuildQuery(rowValues)
+	if err != nil {
+		return nil, err
+	}
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	// Both tables are read in UTC, so that TIMESTAMP values compare alike even across servers
+	if _, err := conn.ExecContext(ctx, "SET /* gh-ost */ SESSION time_zone = '+00:00'"); err != nil {
+		return nil, err
+	}
+	rows, err := conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+	row = make(shadowVerifyRow, len(this.verifiedColumnNames))
+	dest := make([]interface{}, len(row))
+	for i := range row {
+		dest[i] = &row[i]
+	}
+	if err := rows.Scan(dest...); err != nil {
+		return nil, err
+	}
+	return row, rows.Err()
+}
+
+// diffShadowVerifyRows returns a description of each difference between the original and ghost
+// rows; none when the rows match.
+func diffShadowVerifyRows(columnNames []string, originalRow, ghostRow shadowVerifyRow) (diffs []string) {
+	if originalRow == nil && ghostRow == nil {
+		return diffs
+	}
+	if originalRow == nil {
+		return append(diffs, "row is missing on original table, but exists on ghost table")
+	}
+	if ghostRow == nil {
+		return append(diffs, "row exists on original table, but is missing on ghost table")
+	}
+	describe := func(value gosql.NullString) string {
+		if !value.Valid {
+			return "NULL"
+		}
+		return fmt.Sprintf("%q", value.String)
+	}
+	for i, columnName := range columnNames {
+		if originalRow[i] != ghostRow[i] {
+			diffs = append(diffs, fmt.Sprintf("%s: original=%s, ghost=%s", sql.EscapeName(columnName), describe(originalRow[i]), describe(ghostRow[i])))
+		}
+	}
+	return diffs
+}
+
+func equalShadowVerifyRows(row, other shadowVerifyRow) bool {
+	if (row == nil) != (other == nil) || len(row) != len(other) {
+		return false
+	}
+	for i := range row {
+		if row[i] != other[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// verifyRow compares a single row between the original and ghost tables
+func (this *ShadowVerifier) verifyRow(rowValues []interface{}) (originalRow shadowVerifyRow, diffs []string, err error) {
+	if originalRow, err = this.readRow(this.applier.db, this.originalRowQueryBuilder, rowValues); err != nil {
+		return nil, nil, err
+	}
+	ghostRow, err := this.readRow(this.applier.targetDB, this.ghostRowQueryBuilder, rowValues)
+	if err != nil {
+		return nil, nil, err
+	}
+	if originalRow != nil && ghostRow == nil && atomic.LoadInt64(&this.rowCopyComplete) == 0 {
+		// The row may simply not have been copied yet
+		return originalRow, nil, nil
+	}
+	return originalRow, diffShadowVerifyRows(this.verifiedColumnNames, originalRow, ghostRow), nil
+}
+
+// verify runs a single verification round. Suspects of the previous round are verified again,
+// then the current sample is verified, making for this round's suspects.
+func (this *ShadowVerifier) verify() error {
+	suspects := this.suspects
+	this.suspects = nil
+	samples := this.takeSamples()
+
+	var mismatches int64
+	loggedDiffs := 0
+	for _, suspect := range suspects {
+		originalRow, diffs, err := this.verifyRow(suspect.rowValues)
+		if err != nil {
+			return err
+		}
+		if len(diffs) == 0 || !equalShadowVerifyRows(originalRow, suspect.originalRow) {
+			continue
+		}
+		mismatches++
+		if loggedDiffs < maxShadowVerifyLoggedDiffs {
+			loggedDiffs++
+			this.migrationContext.Log.Warningf("Shadow verification mismatch: %s", strings.Join(diffs, "; "))
+		}
+	}
+	for _, rowValues := range samples {
+		originalRow, diffs, err := this.verifyRow(rowValues)
+		if err != nil {
+			return err
+		}
+		if len(diffs) > 0 {
+			this.suspects = append(this.suspects, &shadowVerifySuspect{rowValues: rowValues, originalRow: originalRow})
+		}
+	}
+	atomic.AddInt64(&this.migrationContext.ShadowVerifiedRowsCount, int64(len(samples)))
+	totalMismatches := atomic.AddInt64(&this.migrationContext.ShadowVerifyMismatchesCount, mismatches)
+	this.migrationContext.Log.Debugf("Shadow verification: verified %d rows, %d suspects, %d mismatches", len(samples), len(this.suspects), mismatches)
+
+	maxMismatches := atomic.LoadInt64(&this.migrationContext.ShadowVerifyMaxMismatches)
+	if maxMismatches > 0 && totalMismatches >= maxMismatches && atomic.CompareAndSwapInt64(&this.aborted, 0, 1) {
+		this.migrationContext.SendPanicAbort(fmt.Errorf("Shadow verification found %d mismatching rows between original and ghost tables, reaching --shadow-verify-max-mismatches=%d", totalMismatches, maxMismatches))
+	}
+	return nil
+}
+
+// Run verifies the migration every --shadow-verify-interval-seconds, until torn down. Rounds are
+// skipped while throttled, as events are then not applied and rows would needlessly be suspected.
+func (this *ShadowVerifier) Run() {
+	interval := time.Duration(this.migrationContext.ShadowVerifyIntervalSeconds) * time.Second
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if isThrottled, _, _ := this.migrationContext.IsThrottled(); isThrottled {
+			continue
+		}
+		if !this.verifyUnlessFinished() {
+			return
+		}
+	}
+}
+
+// verifyUnlessFinished runs a verification round, unless torn down. It returns false once torn down.
+func (this *ShadowVerifier) verifyUnlessFinished() bool {
+	this.verifyMutex.Lock()
+	defer this.verifyMutex.Unlock()
+
+	if atomic.LoadInt64(&this.finishedMigrating) > 0 {
+		return false
+	}
+	if err := this.verify(); err != nil {
+		this.migrationContext.Log.Errore(err)
+	}
+	return true
+}
+
+// Teardown stops verification; it is called before cut-over, which locks the original table. It
+// waits for an ongoing verification round to complete, so that none overlaps the cut-over.
+func (this *ShadowVerifier) Teardown() {
+	atomic.StoreInt64(&this.finishedMigrating, 1)
+
+	this.verifyMutex.Lock()
+	defer this.verifyMutex.Unlock()
+}
@@ -0,0 +1,127 @@
+/*
+   Copyright 2022 GitHub Inc.
+	 See https://github.com/github/gh-ost/blob/master/LICENSE
+*/
+
+package logic
+
+import (
+	gosql "database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/github/gh-ost/go/base"
+	"github.com/github/gh-ost/go/binlog"
+	"github.com/github/gh-ost/go/sql"
+)
+
+func TestDiffShadowVerifyRows(t *testing.T) {
+	columnNames := []string{"id", "name", "category"}
+	row := shadowVerifyRow{
+		{String: "1", Valid: true},
+		{String: "gh-ost", Valid: true},
+		{},
+	}
+
+	t.Run("match", func(t *testing.T) {
+		require.Empty(t, diffShadowVerifyRows(columnNames, row, row))
+		require.Empty(t, diffShadowVerifyRows(columnNames, nil, nil))
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		require.Len(t, diffShadowVerifyRows(columnNames, row, nil), 1)
+		require.Len(t, diffShadowVerifyRows(columnNames, nil, row), 1)
+	})
+
+	t.Run("differ", func(t *testing.T) {
+		ghostRow := shadowVerifyRow{
+			{String: "1", Valid: true},
+			{String: "gh-ost?", Valid: true},
+			{String: "", Valid: true},
+		}
+		require.Equal(t, []string{
+			"`name`: original=\"gh-ost\", ghost=\"gh-ost?\"",
+			"`category`: original=NULL, ghost=\"\"",
+		}, diffShadowVerifyRows(columnNames, row, ghostRow))
+	})
+}
+
+func TestEqualShadowVerifyRows(t *testing.T) {
+	row := shadowVerifyRow{{String: "1", Valid: true}}
+	require.True(t, equalShadowVerifyRows(row, shadowVerifyRow{{String: "1", Valid: true}}))
+	require.True(t, equalShadowVerifyRows(nil, nil))
+	require.False(t, equalShadowVerifyRows(row, nil))
+	require.False(t, equalShadowVerifyRows(row, shadowVerifyRow{{String: "1"}}))
+	require.False(t, equalShadowVerifyRows(row, shadowVerifyRow{{String: "1", Valid: true}, gosql.NullString{}}))
+}
+
+func TestShadowVerifierRecord(t *testing.T) {
+	migrationContext := base.NewMigrationContext()
+	migrationContext.ShadowVerifySampleSize = 10
+	verifier := NewShadowVerifier(migrationContext, nil)
+
+	dmlEvents := [](*binlog.BinlogDMLEvent){}
+	for i := 0; i < 100; i++ {
+		dmlEvent := binlog.NewBinlogDMLEvent("test", "tbl", binlog.UpdateDML)
+		dmlEvent.WhereColumnValues = sql.ToColumnValues([]interface{}{i})
+		dmlEvent.NewColumnValues = sql.ToColumnValues([]interface{}{i})
+		dmlEvents = append(dmlEvents, dmlEvent)
+	}
+	verifier.Record(dmlEvents[:3])
+	require.Len(t, verifier.takeSamples(), 6)
+	require.Empty(t, verifier.takeSamples())
+
+	verifier.Record(dmlEvents)
+	samples := verifier.takeSamples()
+	require.Len(t, samples, 10)
+	for _, sample := range samples {
+		require.Len(t, sample, 1)
+	}
+}
+
+func TestShadowVerifierPrepareQueries(t *testing.T) {
+	migrationContext := base.NewMigrationContext()
+	migrationContext.DatabaseName = "test"
+	migrationContext.OriginalTableName = "tbl"
+	migrationContext.OriginalTableColumns = sql.NewColumnList([]string{"id", "name", "created_at", "price", "notes"})
+	migrationContext.SharedColumns = sql.NewColumnList([]string{"id", "name", "created_at", "price", "notes"})
+	migrationContext.MappedSharedColumns = sql.NewColumnList([]string{"id", "name", "created_at", "price", "notes"})
+	migrationContext.UniqueKey = &sql.UniqueKey{Name: "PRIMARY", Columns: *sql.NewColumnList([]string{"id"})}
+
+	originalTypes := []string{"int", "varchar(32)", "datetime", "decimal(10,2)", "text"}
+	ghostTypes := []string{"int", "varchar(64)", "datetime(3)", "decimal(10,2)", "text"}
+	for i, columnName := range migrationContext.SharedColumns.Names() {
+		migrationContext.SharedColumns.GetColumn(columnName).MySQLType = originalTypes[i]
+		migrationContext.MappedSharedColumns.GetColumn(columnName).MySQLType = ghostTypes[i]
+	}
+	migrationContext.SharedColumns.SetCharset("notes", "latin1")
+	migrationContext.MappedSharedColumns.SetCharset("notes", "utf8mb4")
+
+	verifier := NewShadowVerifier(migrationContext, nil)
+	require.NoError(t, verifier.prepareQueries())
+	require.Equal(t, []string{"id", "price"}, verifier.verifiedColumnNames)
+
+	migrationContext.MappedSharedColumns.GetColumn("id").MySQLType = "bigint"
+	migrationContext.MappedSharedColumns.GetColumn("price").MySQLType = "decimal(12,4)"
+	require.Error(t, verifier.prepareQueries())
+}
+
+func TestShadowVerifierTeardown(t *testing.T) {
+	verifier := NewShadowVerifier(base.NewMigrationContext(), nil)
+	verifier.verifyMutex.Lock()
+	tornDown := make(chan struct{})
+	go func() {
+		verifier.Teardown()
+		close(tornDown)
+	}()
+	select {
+	case <-tornDown:
+		t.Fatal("Teardown did not wait for the ongoing verification round")
+	case <-time.After(50 * time.Millisecond):
+	}
+	verifier.verifyMutex.Unlock()
+	<-tornDown
+	require.False(t, verifier.verifyUnlessFinished())
+}
@@ -26,6 +26,7 @@ type ConnectionConfig struct {
 	Key                  InstanceKey
 	User                 string
 	Password             string
+	CredentialHelper     string
 	ImpliedKey           *InstanceKey
 	tlsConfig            *tls.Config
 	Timeout              float64
@@ -47,6 +48,7 @@ func (this *ConnectionConfig) DuplicateCredentials(key InstanceKey) *ConnectionC
 		Key:                  key,
 		User:                 this.User,
 		Password:             this.Password,
+		CredentialHelper:     this.CredentialHelper,
 		tlsConfig:            this.tlsConfig,
 		Timeout:              this.Timeout,
 		TransactionIsolation: this.TransactionIsolation,
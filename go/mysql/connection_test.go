@@ -38,6 +38,7 @@ func TestDuplicateCredentials(t *testing.T) {
 	c.Key = InstanceKey{Hostname: "myhost", Port: 3306}
 	c.User = "gromit"
 	c.Password = "penguin"
+	c.CredentialHelper = "vault-mysql-credentials"
 	c.tlsConfig = &tls.Config{
 		InsecureSkipVerify: true,
 		ServerName:         "feathers",
@@ -52,6 +53,7 @@ func TestDuplicateCredentials(t *testing.T) {
 	require.Equal(t, 3310, dup.ImpliedKey.Port)
 	require.Equal(t, "gromit", dup.User)
 	require.Equal(t, "penguin", dup.Password)
+	require.Equal(t, "vault-mysql-credentials", dup.CredentialHelper)
 	require.Equal(t, "otherhost", dup.tlsConfig.ServerName)
 	require.Equal(t, c.tlsConfig.Certificates, dup.tlsConfig.Certificates)
 	require.Equal(t, c.tlsConfig.RootCAs, dup.tlsConfig.RootCAs)
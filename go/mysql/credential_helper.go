@@ -0,0 +1,79 @@
+/*
+   Copyright 2022 GitHub Inc.
+	 See https://github.com/github/gh-ost/blob/master/LICENSE
+*/
+
+package mysql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// CredentialHelperTimeout is how long a credential helper may take to respond
+var CredentialHelperTimeout = 30 * time.Second
+
+// helperCredentials is what a credential helper prints on stdout
+type helperCredentials struct {
+	User     string `json:"user"`
+	Password string `json:"password"`
+}
+
+// RefreshCredentials invokes the credential helper, if any, and takes on the credentials it
+// responds with. The helper is a shell command, handed the host, port and current user in the
+// GH_OST_CREDENTIAL_HOST, GH_OST_CREDENTIAL_PORT and GH_OST_CREDENTIAL_USER environment variables,
+// which prints a JSON object such as {"user": "gh-ost", "password": "..."}. An empty user keeps
+// the current one.
+//
+// Credentials are refreshed upon connecting, and reconnecting, such that short-lived credentials,
+// such as authentication tokens, are renewed.
+func (this *ConnectionConfig) RefreshCredentials() error {
+	if this.CredentialHelper == "" {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), CredentialHelperTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "/bin/sh", "-c", this.CredentialHelper)
+	cmd.Env = append(os.Environ(),
+		fmt.Sprintf("GH_OST_CREDENTIAL_HOST=%s", this.Key.Hostname),
+		fmt.Sprintf("GH_OST_CREDENTIAL_PORT=%d", this.Key.Port),
+		fmt.Sprintf("GH_OST_CREDENTIAL_USER=%s", this.User),
+	)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		// The helper's stdout may hold secrets; its stderr is what explains a failure
+		return fmt.Errorf("Credential helper failed for %s: %+v: %s", this.Key.DisplayString(), err, strings.TrimSpace(stderr.String()))
+	}
+	credentials := helperCredentials{}
+	if err := json.Unmarshal(output, &credentials); err != nil {
+		return fmt.Errorf("Cannot parse credential helper response for %s: %+v", this.Key.DisplayString(), err)
+	}
+	if credentials.User != "" {
+		this.User = credentials.User
+	}
+	this.Password = credentials.Password
+	return nil
+}
+
+// beforeConnect refreshes credentials ahead of each connection a pool establishes, whenever that
+// is, rather than only ahead of connecting explicitly. A duplicate config is refreshed, as
+// connections may be established concurrently.
+func (this *ConnectionConfig) beforeConnect(ctx context.Context, config *mysql.Config) error {
+	credentials := this.Duplicate()
+	if err := credentials.RefreshCredentials(); err != nil {
+		return err
+	}
+	config.User = credentials.User
+	config.Passwd = credentials.Password
+	return nil
+}
@@ -0,0 +1,81 @@
+/*
+   Copyright 2022 GitHub Inc.
+	 See https://github.com/github/gh-ost/blob/master/LICENSE
+*/
+
+package mysql
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRefreshCredentials(t *testing.T) {
+	c := NewConnectionConfig()
+	c.Key = InstanceKey{Hostname: "myhost", Port: 3306}
+	c.User = "gromit"
+	c.Password = "penguin"
+	require.NoError(t, c.RefreshCredentials())
+	require.Equal(t, "penguin", c.Password)
+
+	c.CredentialHelper = `echo "{\"password\": \"token-for-$GH_OST_CREDENTIAL_USER@$GH_OST_CREDENTIAL_HOST:$GH_OST_CREDENTIAL_PORT\"}"`
+	require.NoError(t, c.RefreshCredentials())
+	require.Equal(t, "gromit", c.User)
+	require.Equal(t, "token-for-gromit@myhost:3306", c.Password)
+
+	c.CredentialHelper = `echo '{"user": "wallace", "password": "token"}'`
+	require.NoError(t, c.RefreshCredentials())
+	require.Equal(t, "wallace", c.User)
+	require.Equal(t, "token", c.Password)
+
+	c.CredentialHelper = `echo "token expired" >&2; exit 1`
+	err := c.RefreshCredentials()
+	require.ErrorContains(t, err, "token expired")
+
+	c.CredentialHelper = `echo token`
+	require.Error(t, c.RefreshCredentials())
+}
+
+func TestNewConnectorRefreshesCredentials(t *testing.T) {
+	invokedPath := filepath.Join(t.TempDir(), "invoked")
+	c := NewConnectionConfig()
+	c.Key = InstanceKey{Hostname: "127.0.0.1", Port: 1}
+	c.User = "gromit"
+	c.Password = "penguin"
+	c.Timeout = 1
+	c.CredentialHelper = `echo "$GH_OST_CREDENTIAL_USER" >> ` + invokedPath + `; echo '{"password": "token"}'`
+
+	connector, err := newConnector(c.GetDBUri("test"), c, "")
+	require.NoError(t, err)
+	for i := 0; i < 2; i++ {
+		// nothing listens on the port; the helper is invoked nonetheless
+		_, err = connector.Connect(context.Background())
+		require.Error(t, err)
+	}
+	invoked, err := os.ReadFile(invokedPath)
+	require.NoError(t, err)
+	require.Equal(t, "gromit\ngromit\n", string(invoked))
+}
+
+func TestGetDBCacheKey(t *testing.T) {
+	c := NewConnectionConfig()
+	c.Key = InstanceKey{Hostname: "myhost", Port: 3306}
+	c.User = "gromit"
+	c.Password = "penguin"
+	key, err := getDBCacheKey("uuid", c.GetDBUri("test"))
+	require.NoError(t, err)
+	require.NotContains(t, key, "penguin")
+
+	c.Password = "token"
+	rotatedKey, err := getDBCacheKey("uuid", c.GetDBUri("test"))
+	require.NoError(t, err)
+	require.Equal(t, key, rotatedKey)
+
+	otherKey, err := getDBCacheKey("uuid", c.GetDBUri("information_schema"))
+	require.NoError(t, err)
+	require.NotEqual(t, key, otherKey)
+}
@@ -0,0 +1,268 @@
+/*
+   Copyright 2022 GitHub Inc.
+	 See https://github.com/github/gh-ost/blob/master/LICENSE
+*/
+
+package mysql
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// maxOptionFileIncludeDepth guards against option files which include one another
+const maxOptionFileIncludeDepth = 10
+
+// OptionFileGroups are the option file groups gh-ost reads
+var OptionFileGroups = []string{"client", "gh-ost"}
+
+// Options are option values, as read off MySQL option files, by option name. Names are lowercase,
+// with underscores turned into dashes, as MySQL treats both alike.
+type Options map[string]string
+
+func optionName(name string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(name)), "_", "-")
+}
+
+// unescapeOptionValue applies the escape sequences MySQL recognizes in option values. A backslash
+// followed by any other character is kept, as in Windows paths.
+func unescapeOptionValue(value string) string {
+	var unescaped strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] != '\\' || i+1 == len(value) {
+			unescaped.WriteByte(value[i])
+			continue
+		}
+		switch value[i+1] {
+		case 'b':
+			unescaped.WriteByte('\b')
+		case 't':
+			unescaped.WriteByte('\t')
+		case 'n':
+			unescaped.WriteByte('\n')
+		case 'r':
+			unescaped.WriteByte('\r')
+		case 's':
+			unescaped.WriteByte(' ')
+		case '\\', '\'', '"':
+			unescaped.WriteByte(value[i+1])
+		default:
+			unescaped.WriteByte('\\')
+			unescaped.WriteByte(value[i+1])
+		}
+		i++
+	}
+	return unescaped.String()
+}
+
+// parseOptionValue parses the value of an option line: quoted, in which case it may hold `#`, or
+// unquoted, in which case a `#` starts a comment.
+func parseOptionValue(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if value != "" && (value[0] == '\'' || value[0] == '"') {
+		quote := value[0]
+		for i := 1; i < len(value); i++ {
+			if value[i] == '\\' {
+				i++
+				continue
+			}
+			if value[i] == quote {
+				rest := strings.TrimSpace(value[i+1:])
+				if rest != "" && rest[0] != '#' {
+					return "", fmt.Errorf("unexpected %q following quoted value", rest)
+				}
+				return unescapeOptionValue(value[1:i]), nil
+			}
+		}
+		return "", fmt.Errorf("unterminated quoted value")
+	}
+	if i := strings.Index(value, "#"); i >= 0 {
+		value = strings.TrimSpace(value[:i])
+	}
+	return unescapeOptionValue(value), nil
+}
+
+// optionFileReader reads options of given groups off option files, following !include and
+// !includedir directives
+type optionFileReader struct {
+	groups  map[string]bool
+	options Options
+}
+
+func (this *optionFileReader) read(name string, content io.Reader, depth int) error {
+	if depth > maxOptionFileIncludeDepth {
+		return fmt.Errorf("Option file %s: includes nested deeper than %d", name, maxOptionFileIncludeDepth)
+	}
+	inGroup := false
+	scanner := bufio.NewScanner(content)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		if directive, path, ok := strings.Cut(line, " "); ok && (directive == "!include" || directive == "!includedir") {
+			path = strings.TrimSpace(path)
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(filepath.Dir(name), path)
+			}
+			var err error
+			if directive == "!include" {
+				err = this.readFile(path, depth+1)
+			} else {
+				err = this.readDir(path, depth+1)
+			}
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		if line[0] == '[' {
+			if !strings.HasSuffix(line, "]") {
+				return fmt.Errorf("Option file %s, line %d: malformed group %q", name, lineNumber, line)
+			}
+			inGroup = this.groups[strings.ToLower(strings.TrimSpace(line[1:len(line)-1]))]
+			continue
+		}
+		if !inGroup {
+			continue
+		}
+		key, value, _ := strings.Cut(line, "=")
+		value, err := parseOptionValue(value)
+		if err != nil {
+			return fmt.Errorf("Option file %s, line %d: %+v", name, lineNumber, err)
+		}
+		this.options[optionName(key)] = value
+	}
+	return scanner.Err()
+}
+
+func (this *optionFileReader) readFile(path string, depth int) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return this.read(path, file, depth)
+}
+
+// readDir reads the option files in a directory, in name order, as MySQL does: those named *.cnf
+func (this *optionFileReader) readDir(dir string, depth int) error {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.cnf"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		if err := this.readFile(path, depth); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func newOptionFileReader(groups []string) *optionFileReader {
+	reader := &optionFileReader{groups: map[string]bool{}, options: Options{}}
+	for _, group := range groups {
+		reader.groups[strings.ToLower(group)] = true
+	}
+	return reader
+}
+
+// ReadOptionFile reads the options of the given groups off a MySQL option file, such as ~/.my.cnf.
+// As with MySQL clients, options further down, including those of included files, override those
+// before them.
+func ReadOptionFile(path string, groups ...string) (Options, error) {
+	reader := newOptionFileReader(groups)
+	if err := reader.readFile(path, 0); err != nil {
+		return nil, err
+	}
+	return reader.options, nil
+}
+
+// DefaultLoginPathFile returns the login path file mysql_config_editor writes: $MYSQL_TEST_LOGIN_FILE,
+// or ~/.mylogin.cnf
+func DefaultLoginPathFile() string {
+	if loginFile := os.Getenv("MYSQL_TEST_LOGIN_FILE"); loginFile != "" {
+		return loginFile
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".mylogin.cnf")
+}
+
+// decryptLoginPathFile decrypts a login path file, as written by mysql_config_editor: a 4 byte
+// header and a 20 byte key, followed by lines, each a 4 byte little-endian length followed by the
+// AES-128-ECB encrypted, padded, line.
+func decryptLoginPathFile(data []byte) ([]byte, error) {
+	const headerLength = 4
+	const keyLength = 20
+	if len(data) < headerLength+keyLength {
+		return nil, fmt.Errorf("too short")
+	}
+	// The AES key is the 20 byte key folded onto 16 bytes
+	aesKey := make([]byte, aes.BlockSize)
+	for i, b := range data[headerLength : headerLength+keyLength] {
+		aesKey[i%aes.BlockSize] ^= b
+	}
+	block, err := aes.NewCipher(aesKey)
+	if err != nil {
+		return nil, err
+	}
+
+	var plain bytes.Buffer
+	data = data[headerLength+keyLength:]
+	for len(data) > 0 {
+		if len(data) < 4 {
+			return nil, fmt.Errorf("truncated line length")
+		}
+		length := int(binary.LittleEndian.Uint32(data))
+		data = data[4:]
+		if length == 0 || length%aes.BlockSize != 0 || length > len(data) {
+			return nil, fmt.Errorf("invalid line length %d", length)
+		}
+		line := make([]byte, length)
+		for i := 0; i < length; i += aes.BlockSize {
+			block.Decrypt(line[i:i+aes.BlockSize], data[i:i+aes.BlockSize])
+		}
+		data = data[length:]
+		padding := int(line[length-1])
+		if padding < 1 || padding > aes.BlockSize {
+			return nil, fmt.Errorf("invalid padding")
+		}
+		line = line[:length-padding]
+		if !bytes.HasSuffix(line, []byte("\n")) {
+			line = append(line, '\n')
+		}
+		plain.Write(line)
+	}
+	return plain.Bytes(), nil
+}
+
+// ReadLoginPath reads the options of a login path, such as written by `mysql_config_editor set
+// --login-path=...`, off the given login path file: those of its client group and of the login
+// path's own group.
+func ReadLoginPath(loginPathFile string, loginPath string) (Options, error) {
+	data, err := os.ReadFile(loginPathFile)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := decryptLoginPathFile(data)
+	if err != nil {
+		return nil, fmt.Errorf("Cannot decrypt login path file %s: %+v", loginPathFile, err)
+	}
+	reader := newOptionFileReader([]string{"client", loginPath})
+	if err := reader.read(loginPathFile, bytes.NewReader(plain), 0); err != nil {
+		return nil, err
+	}
+	return reader.options, nil
+}
@@ -0,0 +1,126 @@
+/*
+   Copyright 2022 GitHub Inc.
+	 See https://github.com/github/gh-ost/blob/master/LICENSE
+*/
+
+package mysql
+
+import (
+	"bytes"
+	"crypto/aes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadOptionFile(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "conf.d"), 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "my.cnf"), []byte(`
+# credentials
+[mysqld]
+user=mysql
+
+[client]
+user = gromit
+password = "pen#guin"  # quoted
+host=db1.example.com
+!include extra.cnf
+!includedir conf.d
+
+[mysql]
+password=ignored
+`), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "extra.cnf"), []byte("[client]\nsocket=/tmp/mysql.sock\n"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "conf.d", "a.cnf"), []byte("[gh-ost]\nuser=wallace\n"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "conf.d", "b.cnf"), []byte("[client]\nssl_mode = 'REQUIRED'\n"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "conf.d", "ignored.txt"), []byte("[client]\nuser=ignored\n"), 0600))
+
+	options, err := ReadOptionFile(filepath.Join(dir, "my.cnf"), OptionFileGroups...)
+	require.NoError(t, err)
+	require.Equal(t, Options{
+		"user":     "wallace",
+		"password": "pen#guin",
+		"host":     "db1.example.com",
+		"socket":   "/tmp/mysql.sock",
+		"ssl-mode": "REQUIRED",
+	}, options)
+
+	_, err = ReadOptionFile(filepath.Join(dir, "missing.cnf"), OptionFileGroups...)
+	require.Error(t, err)
+
+	// Files including themselves
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "loop.cnf"), []byte("!include loop.cnf\n"), 0600))
+	_, err = ReadOptionFile(filepath.Join(dir, "loop.cnf"), OptionFileGroups...)
+	require.Error(t, err)
+}
+
+func TestParseOptionValue(t *testing.T) {
+	for value, expected := range map[string]string{
+		``:                   ``,
+		` secret `:           `secret`,
+		`secret # comment`:   `secret`,
+		`'se cret'`:          `se cret`,
+		`"se'cr#et" # note`:  `se'cr#et`,
+		`"se\"cret"`:         `se"cret`,
+		`C:\mysql\data`:      `C:\mysql\data`,
+		`tab\there\sspace\\`: "tab\there space\\",
+	} {
+		parsed, err := parseOptionValue(value)
+		require.NoError(t, err, value)
+		require.Equal(t, expected, parsed, value)
+	}
+	for _, value := range []string{`"unterminated`, `'quoted' trailing`} {
+		_, err := parseOptionValue(value)
+		require.Error(t, err, value)
+	}
+}
+
+// writeLoginPathFile writes a login path file the way mysql_config_editor does
+func writeLoginPathFile(t *testing.T, content string) string {
+	key := []byte("0123456789abcdefghij")
+	aesKey := make([]byte, aes.BlockSize)
+	for i, b := range key {
+		aesKey[i%aes.BlockSize] ^= b
+	}
+	block, err := aes.NewCipher(aesKey)
+	require.NoError(t, err)
+
+	var data bytes.Buffer
+	data.Write([]byte{0, 0, 0, 0})
+	data.Write(key)
+	for _, line := range bytes.SplitAfter([]byte(content), []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+		padding := aes.BlockSize - len(line)%aes.BlockSize
+		line = append(line, bytes.Repeat([]byte{byte(padding)}, padding)...)
+		for i := 0; i < len(line); i += aes.BlockSize {
+			block.Encrypt(line[i:i+aes.BlockSize], line[i:i+aes.BlockSize])
+		}
+		require.NoError(t, binary.Write(&data, binary.LittleEndian, uint32(len(line))))
+		data.Write(line)
+	}
+	loginPathFile := filepath.Join(t.TempDir(), ".mylogin.cnf")
+	require.NoError(t, os.WriteFile(loginPathFile, data.Bytes(), 0600))
+	return loginPathFile
+}
+
+func TestReadLoginPath(t *testing.T) {
+	loginPathFile := writeLoginPathFile(t, "[client]\nuser = \"gromit\"\npassword = \"cheese\"\n[migrations]\nuser = \"wallace\"\nhost = \"db1.example.com\"\n[other]\nuser = \"other\"\n")
+
+	options, err := ReadLoginPath(loginPathFile, "migrations")
+	require.NoError(t, err)
+	require.Equal(t, Options{"user": "wallace", "password": "cheese", "host": "db1.example.com"}, options)
+
+	options, err = ReadLoginPath(loginPathFile, "client")
+	require.NoError(t, err)
+	require.Equal(t, Options{"user": "gromit", "password": "cheese"}, options)
+
+	require.NoError(t, os.WriteFile(loginPathFile, []byte("[client]\nuser=gromit\n"), 0600))
+	_, err = ReadLoginPath(loginPathFile, "client")
+	require.Error(t, err)
+}
@@ -18,6 +18,7 @@ var MysqlReplicaTermMap = map[string]string{
 	"Relay_Master_Log_File": "Relay_Source_Log_File",
 	"Slave_IO_Running":      "Replica_IO_Running",
 	"Slave_SQL_Running":     "Replica_SQL_Running",
+	"Server_id":             "Server_Id",
 	"master status":         "binary log status",
 	"slave hosts":           "replicas",
 	"slave status":          "replica status",
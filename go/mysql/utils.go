@@ -7,6 +7,7 @@ package mysql
 
 import (
 	gosql "database/sql"
+	"database/sql/driver"
 	"fmt"
 	"strings"
 	"sync"
@@ -14,13 +15,16 @@ import (
 
 	"github.com/github/gh-ost/go/sql"
 
-	"github.com/openark/golib/log"
+	"github.com/go-sql-driver/mysql"
 	"github.com/openark/golib/sqlutils"
 )
 
 const (
 	MaxTableNameLength   = 64
 	MaxDBPoolConnections = 3
+	// MigrationConnectionAttribute is a connection attribute valued by the migration's UUID. It tells
+	// the migration's own sessions apart in performance_schema.session_connect_attrs.
+	MigrationConnectionAttribute = "gh_ost_migration"
 )
 
 type ReplicationLagResult struct {
@@ -44,21 +48,54 @@ func (this *ReplicationLagResult) HasLag() bool {
 	return this.Lag > 0
 }
 
-// knownDBs is a DB cache by uri
+// knownDBs is a DB cache by uri, less the password
 var knownDBs map[string]*gosql.DB = make(map[string]*gosql.DB)
 var knownDBsMutex = &sync.Mutex{}
 
-func GetDB(migrationUuid string, mysql_uri string) (db *gosql.DB, exists bool, err error) {
-	cacheKey := migrationUuid + ":" + mysql_uri
+// newConnector creates a connector by given uri. Should connectionConfig have a credential helper,
+// the helper is invoked before every connection is established.
+func newConnector(mysql_uri string, connectionConfig *ConnectionConfig, connectionAttributes string) (driver.Connector, error) {
+	config, err := mysql.ParseDSN(mysql_uri)
+	if err != nil {
+		return nil, err
+	}
+	config.ConnectionAttributes = connectionAttributes
+	if connectionConfig != nil && connectionConfig.CredentialHelper != "" {
+		if err := config.Apply(mysql.BeforeConnect(connectionConfig.beforeConnect)); err != nil {
+			return nil, err
+		}
+	}
+	return mysql.NewConnector(config)
+}
+
+// getDBCacheKey returns the key by which the DB of given uri is cached. The password is left out,
+// such that credentials rotated by a credential helper do not create a new pool.
+func getDBCacheKey(migrationUuid string, mysql_uri string) (string, error) {
+	config, err := mysql.ParseDSN(mysql_uri)
+	if err != nil {
+		return "", err
+	}
+	config.Passwd = ""
+	return migrationUuid + ":" + config.FormatDSN(), nil
+}
+
+// GetDB returns the cached DB of given uri, creating it if need be. connectionConfig is that of
+// the server connected to, by which credentials are refreshed as new connections are made.
+func GetDB(migrationUuid string, mysql_uri string, connectionConfig *ConnectionConfig) (db *gosql.DB, exists bool, err error) {
+	cacheKey, err := getDBCacheKey(migrationUuid, mysql_uri)
+	if err != nil {
+		return nil, false, err
+	}
 
 	knownDBsMutex.Lock()
 	defer knownDBsMutex.Unlock()
 
 	if db, exists = knownDBs[cacheKey]; !exists {
-		db, err = gosql.Open("mysql", mysql_uri)
+		connector, err := newConnector(mysql_uri, connectionConfig, fmt.Sprintf("%s:%s", MigrationConnectionAttribute, migrationUuid))
 		if err != nil {
 			return nil, false, err
 		}
+		db = gosql.OpenDB(connector)
 		db.SetMaxOpenConns(MaxDBPoolConnections)
 		db.SetMaxIdleConns(MaxDBPoolConnections)
 		knownDBs[cacheKey] = db
@@ -88,10 +125,11 @@ func GetReplicationLagFromSlaveStatus(dbVersion string, informationSchemaDb *gos
 func GetMasterKeyFromSlaveStatus(dbVersion string, connectionConfig *ConnectionConfig) (masterKey *InstanceKey, err error) {
 	currentUri := connectionConfig.GetDBUri("information_schema")
 	// This function is only called once, okay to not have a cached connection pool
-	db, err := gosql.Open("mysql", currentUri)
+	connector, err := newConnector(currentUri, connectionConfig, "")
 	if err != nil {
 		return nil, err
 	}
+	db := gosql.OpenDB(connector)
 	defer db.Close()
 
 	showReplicaStatusQuery := fmt.Sprintf("show %s", ReplicaTermFor(dbVersion, `slave status`))
@@ -130,8 +168,6 @@ func GetMasterKeyFromSlaveStatus(dbVersion string, connectionConfig *ConnectionC
 }
 
 func GetMasterConnectionConfigSafe(dbVersion string, connectionConfig *ConnectionConfig, visitedKeys *InstanceKeyMap, allowMasterMaster bool) (masterConfig *ConnectionConfig, err error) {
-	log.Debugf("Looking for %s on %+v", ReplicaTermFor(dbVersion, "master"), connectionConfig.Key)
-
 	masterKey, err := GetMasterKeyFromSlaveStatus(dbVersion, connectionConfig)
 	if err != nil {
 		return nil, err
@@ -148,7 +184,6 @@ func GetMasterConnectionConfigSafe(dbVersion string, connectionConfig *Connectio
 		return nil, err
 	}
 
-	log.Debugf("%s of %+v is %+v", ReplicaTermFor(dbVersion, "master"), connectionConfig.Key, masterConfig.Key)
 	if visitedKeys.HasKey(masterConfig.Key) {
 		if allowMasterMaster {
 			return connectionConfig, nil
@@ -187,6 +222,75 @@ func GetSelfBinlogCoordinates(dbVersion string, db *gosql.DB) (selfBinlogCoordin
 	return selfBinlogCoordinates, err
 }
 
+// GetReplicaKeys lists the replicas of the server on given DB, via SHOW REPLICAS. That list only
+// holds the hostname of replicas which set report_host; other replicas are listed by the host of their
+// binlog dump connection, and are assumed to listen on the given port. The binlog dump connection of
+// the given server id, and any from the host this process connects from, are not replicas.
+func GetReplicaKeys(dbVersion string, db *gosql.DB, defaultPort int, excludeServerId uint) (replicaKeys []InstanceKey, err error) {
+	reportedHostnames := map[string]bool{}
+	unreportedReplicas := 0
+	showReplicasQuery := fmt.Sprintf("show %s", ReplicaTermFor(dbVersion, "slave hosts"))
+	err = sqlutils.QueryRowsMap(db, showReplicasQuery, func(m sqlutils.RowMap) error {
+		if uint(m.GetInt64(ReplicaTermFor(dbVersion, "Server_id"))) == excludeServerId {
+			return nil
+		}
+		replicaKey := InstanceKey{Hostname: m.GetString("Host"), Port: m.GetInt("Port")}
+		if replicaKey.Hostname == "" {
+			unreportedReplicas++
+			return nil
+		}
+		reportedHostnames[replicaKey.Hostname] = true
+		replicaKeys = append(replicaKeys, replicaKey)
+		return nil
+	})
+	if err != nil || unreportedReplicas == 0 {
+		return replicaKeys, err
+	}
+
+	query := `
+		select
+			substring_index(host, ':', 1) as hostname
+		from
+			information_schema.processlist
+		where
+			command in ('Binlog Dump', 'Binlog Dump GTID')
+			and substring_index(host, ':', 1) != (
+				select substring_index(host, ':', 1) from information_schema.processlist where id = connection_id()
+			)
+	`
+	err = sqlutils.QueryRowsMap(db, query, func(m sqlutils.RowMap) error {
+		hostname := m.GetString("hostname")
+		if hostname == "" || reportedHostnames[hostname] {
+			return nil
+		}
+		reportedHostnames[hostname] = true
+		replicaKeys = append(replicaKeys, InstanceKey{Hostname: hostname, Port: defaultPort})
+		return nil
+	})
+	return replicaKeys, err
+}
+
+// GetGaleraFlowControlStatus reads the receive queue of the Galera member on given DB, and the total
+// time its replication has been paused by flow control
+func GetGaleraFlowControlStatus(db *gosql.DB) (recvQueue int64, flowControlPaused time.Duration, err error) {
+	query := `show /* gh-ost */ global status where variable_name in ('wsrep_local_recv_queue', 'wsrep_flow_control_paused_ns')`
+	found := 0
+	err = sqlutils.QueryRowsMap(db, query, func(m sqlutils.RowMap) error {
+		switch strings.ToLower(m.GetString("Variable_name")) {
+		case "wsrep_local_recv_queue":
+			recvQueue = m.GetInt64("Value")
+		case "wsrep_flow_control_paused_ns":
+			flowControlPaused = time.Duration(m.GetInt64("Value"))
+		}
+		found++
+		return nil
+	})
+	if err == nil && found < 2 {
+		err = fmt.Errorf("wsrep_local_recv_queue and wsrep_flow_control_paused_ns not found; is this a Galera member?")
+	}
+	return recvQueue, flowControlPaused, err
+}
+
 // GetInstanceKey reads hostname and port on given DB
 func GetInstanceKey(db *gosql.DB) (instanceKey *InstanceKey, err error) {
 	instanceKey = &InstanceKey{}
@@ -208,7 +312,6 @@ func GetTableColumns(db *gosql.DB, databaseName, tableName string) (*sql.ColumnL
 		columnName := rowMap.GetString("Field")
 		columnNames = append(columnNames, columnName)
 		if strings.Contains(rowMap.GetString("Extra"), " GENERATED") {
-			log.Debugf("%s is a generated column", columnName)
 			virtualColumnNames = append(virtualColumnNames, columnName)
 		}
 		return nil
@@ -217,7 +320,7 @@ func GetTableColumns(db *gosql.DB, databaseName, tableName string) (*sql.ColumnL
 		return nil, nil, err
 	}
 	if len(columnNames) == 0 {
-		return nil, nil, log.Errorf("Found 0 columns on %s.%s. Bailing out",
+		return nil, nil, fmt.Errorf("Found 0 columns on %s.%s. Bailing out",
 			sql.EscapeName(databaseName),
 			sql.EscapeName(tableName),
 		)
@@ -0,0 +1,36 @@
+/*
+   Copyright 2022 GitHub Inc.
+	 See https://github.com/github/gh-ost/blob/master/LICENSE
+*/
+
+package mysql
+
+import (
+	"strings"
+
+	version "github.com/hashicorp/go-version"
+)
+
+const (
+	// RenameUnderLockTablesMinVersion is the first MySQL version to allow RENAME TABLE on tables
+	// locked by LOCK TABLES ... WRITE, in the same session
+	RenameUnderLockTablesMinVersion = "8.0.13"
+)
+
+// IsMySQLVersionAtLeast returns true when the given server version, as reported by `select @@version`,
+// is a MySQL version equal to or greater than the given minimal version. Suffixes such as `-log` are
+// ignored. MariaDB versions, which follow a different numbering, and unparsable versions return false.
+func IsMySQLVersionAtLeast(mysqlVersion string, minVersion string) bool {
+	if strings.Contains(strings.ToLower(mysqlVersion), "mariadb") {
+		return false
+	}
+	vs, err := version.NewVersion(strings.SplitN(mysqlVersion, "-", 2)[0])
+	if err != nil {
+		return false
+	}
+	minVs, err := version.NewVersion(minVersion)
+	if err != nil {
+		return false
+	}
+	return vs.GreaterThanOrEqual(minVs)
+}
@@ -0,0 +1,24 @@
+/*
+   Copyright 2022 GitHub Inc.
+	 See https://github.com/github/gh-ost/blob/master/LICENSE
+*/
+
+package mysql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsMySQLVersionAtLeast(t *testing.T) {
+	require.True(t, IsMySQLVersionAtLeast("8.0.13", RenameUnderLockTablesMinVersion))
+	require.True(t, IsMySQLVersionAtLeast("8.0.13-log", RenameUnderLockTablesMinVersion))
+	require.True(t, IsMySQLVersionAtLeast("8.0.40", RenameUnderLockTablesMinVersion))
+	require.True(t, IsMySQLVersionAtLeast("8.4.3", RenameUnderLockTablesMinVersion))
+	require.True(t, IsMySQLVersionAtLeast("9.1.0-commercial", RenameUnderLockTablesMinVersion))
+	require.False(t, IsMySQLVersionAtLeast("8.0.12", RenameUnderLockTablesMinVersion))
+	require.False(t, IsMySQLVersionAtLeast("5.7.44-log", RenameUnderLockTablesMinVersion))
+	require.False(t, IsMySQLVersionAtLeast("10.6.16-MariaDB-log", RenameUnderLockTablesMinVersion))
+	require.False(t, IsMySQLVersionAtLeast("", RenameUnderLockTablesMinVersion))
+}
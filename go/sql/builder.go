@@ -7,6 +7,7 @@ package sql
 
 import (
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 )
@@ -50,6 +51,56 @@ func buildColumnsPreparedValues(columns *ColumnList) []string {
 	return values
 }
 
+// getColumnExpression returns the expression configured for given ghost column, if any.
+// Column names are matched case-insensitively, in the same way MySQL treats them.
+func getColumnExpression(columnExpressions map[string]string, columnName string) (expression string, found bool) {
+	for expressionColumn, expression := range columnExpressions {
+		if strings.EqualFold(expressionColumn, columnName) {
+			return expression, true
+		}
+	}
+	return "", false
+}
+
+// getExpressionOnlyColumns returns, sorted by name, the expression columns which are not listed in given
+// column names. These are ghost-only columns, which get populated solely by their expression.
+func getExpressionOnlyColumns(columnExpressions map[string]string, columnNames []string) (expressionOnlyColumns []string) {
+	for expressionColumn := range columnExpressions {
+		isListed := false
+		for _, columnName := range columnNames {
+			if strings.EqualFold(expressionColumn, columnName) {
+				isListed = true
+				break
+			}
+		}
+		if !isListed {
+			expressionOnlyColumns = append(expressionOnlyColumns, expressionColumn)
+		}
+	}
+	sort.Strings(expressionOnlyColumns)
+	return expressionOnlyColumns
+}
+
+// buildColumnExpressionToken returns a scalar subquery which evaluates given expression against a single
+// row image of the original table. The row's values are passed as prepared arguments, in table column order,
+// and are aliased by the original column names so that the expression reads just as it does on row copy.
+func buildColumnExpressionToken(expression string, tableColumns *ColumnList) string {
+	rowValues := make([]string, 0, tableColumns.Len())
+	for _, column := range tableColumns.Columns() {
+		rowValues = append(rowValues, fmt.Sprintf("? as %s", EscapeName(column.Name)))
+	}
+	return fmt.Sprintf("(select (%s) from (select %s) as `_gho_row`)", expression, strings.Join(rowValues, ", "))
+}
+
+// buildColumnExpressionArgs returns the arguments matching a token created by buildColumnExpressionToken
+func buildColumnExpressionArgs(tableColumns *ColumnList, args []interface{}) []interface{} {
+	rowArgs := make([]interface{}, 0, tableColumns.Len())
+	for i, column := range tableColumns.Columns() {
+		rowArgs = append(rowArgs, column.convertArg(args[i], false))
+	}
+	return rowArgs
+}
+
 func buildPreparedValues(length int) []string {
 	values := make([]string, length)
 	for i := 0; i < length; i++ {
@@ -107,21 +158,22 @@ func BuildSetPreparedClause(columns *ColumnList) (result string, err error) {
 	}
 	setTokens := []string{}
 	for _, column := range columns.Columns() {
-		var setToken string
-		if column.timezoneConversion != nil {
-			setToken = fmt.Sprintf("%s=convert_tz(?, '%s', '%s')", EscapeName(column.Name), column.timezoneConversion.ToTimezone, "+00:00")
-		} else if column.enumToTextConversion {
-			setToken = fmt.Sprintf("%s=ELT(?, %s)", EscapeName(column.Name), column.EnumValues)
-		} else if column.Type == JSONColumnType {
-			setToken = fmt.Sprintf("%s=convert(? using utf8mb4)", EscapeName(column.Name))
-		} else {
-			setToken = fmt.Sprintf("%s=?", EscapeName(column.Name))
-		}
-		setTokens = append(setTokens, setToken)
+		setTokens = append(setTokens, buildSetPreparedToken(column))
 	}
 	return strings.Join(setTokens, ", "), nil
 }
 
+func buildSetPreparedToken(column Column) string {
+	if column.timezoneConversion != nil {
+		return fmt.Sprintf("%s=convert_tz(?, '%s', '%s')", EscapeName(column.Name), column.timezoneConversion.ToTimezone, "+00:00")
+	} else if column.enumToTextConversion {
+		return fmt.Sprintf("%s=ELT(?, %s)", EscapeName(column.Name), column.EnumValues)
+	} else if column.Type == JSONColumnType {
+		return fmt.Sprintf("%s=convert(? using utf8mb4)", EscapeName(column.Name))
+	}
+	return fmt.Sprintf("%s=?", EscapeName(column.Name))
+}
+
 func BuildRangeComparison(columns []string, values []string, args []interface{}, comparisonSign ValueComparisonSign) (result string, explodedArgs []interface{}, err error) {
 	if len(columns) == 0 {
 		return "", explodedArgs, fmt.Errorf("Got 0 columns in GetRangeComparison")
@@ -182,49 +234,77 @@ func BuildRangePreparedComparison(columns *ColumnList, args []interface{}, compa
 	return BuildRangeComparison(columns.Names(), values, args, comparisonSign)
 }
 
-func BuildRangeInsertQuery(databaseName, originalTableName, ghostTableName string, sharedColumns []string, mappedSharedColumns []string, uniqueKey string, uniqueKeyColumns *ColumnList, rangeStartValues, rangeEndValues []string, rangeStartArgs, rangeEndArgs []interface{}, includeRangeStartValues bool, transactionalTable bool, noWait bool) (result string, explodedArgs []interface{}, err error) {
-	if len(sharedColumns) == 0 {
-		return "", explodedArgs, fmt.Errorf("Got 0 shared columns in BuildRangeInsertQuery")
+// buildRangeSelectColumns returns the escaped listings of columns to select from the original table, and of the
+// ghost table columns to populate by them. Columns with an expression select that expression instead, and
+// expression-only columns are appended to both listings.
+func buildRangeSelectColumns(sharedColumns []string, mappedSharedColumns []string, columnExpressions map[string]string) (selectColumns []string, ghostColumns []string) {
+	selectColumns = duplicateNames(sharedColumns)
+	for i := range selectColumns {
+		if expression, ok := getColumnExpression(columnExpressions, mappedSharedColumns[i]); ok {
+			selectColumns[i] = fmt.Sprintf("(%s)", expression)
+		} else {
+			selectColumns[i] = EscapeName(selectColumns[i])
+		}
 	}
-	databaseName = EscapeName(databaseName)
-	originalTableName = EscapeName(originalTableName)
-	ghostTableName = EscapeName(ghostTableName)
-
-	mappedSharedColumns = duplicateNames(mappedSharedColumns)
-	for i := range mappedSharedColumns {
-		mappedSharedColumns[i] = EscapeName(mappedSharedColumns[i])
+	expressionOnlyColumns := getExpressionOnlyColumns(columnExpressions, mappedSharedColumns)
+	for _, expressionColumn := range expressionOnlyColumns {
+		selectColumns = append(selectColumns, fmt.Sprintf("(%s)", columnExpressions[expressionColumn]))
 	}
-	mappedSharedColumnsListing := strings.Join(mappedSharedColumns, ", ")
 
-	sharedColumns = duplicateNames(sharedColumns)
-	for i := range sharedColumns {
-		sharedColumns[i] = EscapeName(sharedColumns[i])
+	ghostColumns = append(duplicateNames(mappedSharedColumns), expressionOnlyColumns...)
+	for i := range ghostColumns {
+		ghostColumns[i] = EscapeName(ghostColumns[i])
 	}
-	sharedColumnsListing := strings.Join(sharedColumns, ", ")
+	return selectColumns, ghostColumns
+}
 
-	uniqueKey = EscapeName(uniqueKey)
+// buildRangeComparisons returns the lower and upper bound comparisons of a unique key range
+func buildRangeComparisons(uniqueKeyColumns *ColumnList, rangeStartValues, rangeEndValues []string, rangeStartArgs, rangeEndArgs []interface{}, includeRangeStartValues bool) (rangeStartComparison string, rangeEndComparison string, explodedArgs []interface{}, err error) {
 	var minRangeComparisonSign ValueComparisonSign = GreaterThanComparisonSign
 	if includeRangeStartValues {
 		minRangeComparisonSign = GreaterThanOrEqualsComparisonSign
 	}
 	rangeStartComparison, rangeExplodedArgs, err := BuildRangeComparison(uniqueKeyColumns.Names(), rangeStartValues, rangeStartArgs, minRangeComparisonSign)
 	if err != nil {
-		return "", explodedArgs, err
+		return "", "", explodedArgs, err
 	}
 	explodedArgs = append(explodedArgs, rangeExplodedArgs...)
-	transactionalClause := ""
-	if transactionalTable {
-		if noWait {
-			transactionalClause = "for share nowait"
-		} else {
-			transactionalClause = "lock in share mode"
-		}
+	rangeEndComparison, rangeExplodedArgs, err = BuildRangeComparison(uniqueKeyColumns.Names(), rangeEndValues, rangeEndArgs, LessThanOrEqualsComparisonSign)
+	if err != nil {
+		return "", "", explodedArgs, err
 	}
-	rangeEndComparison, rangeExplodedArgs, err := BuildRangeComparison(uniqueKeyColumns.Names(), rangeEndValues, rangeEndArgs, LessThanOrEqualsComparisonSign)
+	explodedArgs = append(explodedArgs, rangeExplodedArgs...)
+	return rangeStartComparison, rangeEndComparison, explodedArgs, nil
+}
+
+func buildTransactionalClause(transactionalTable bool, noWait bool) string {
+	if !transactionalTable {
+		return ""
+	}
+	if noWait {
+		return "for share nowait"
+	}
+	return "lock in share mode"
+}
+
+func BuildRangeInsertQuery(databaseName, originalTableName, ghostTableName string, sharedColumns []string, mappedSharedColumns []string, columnExpressions map[string]string, uniqueKey string, uniqueKeyColumns *ColumnList, rangeStartValues, rangeEndValues []string, rangeStartArgs, rangeEndArgs []interface{}, includeRangeStartValues bool, transactionalTable bool, noWait bool) (result string, explodedArgs []interface{}, err error) {
+	if len(sharedColumns) == 0 {
+		return "", explodedArgs, fmt.Errorf("Got 0 shared columns in BuildRangeInsertQuery")
+	}
+	databaseName = EscapeName(databaseName)
+	originalTableName = EscapeName(originalTableName)
+	ghostTableName = EscapeName(ghostTableName)
+
+	selectColumns, ghostColumns := buildRangeSelectColumns(sharedColumns, mappedSharedColumns, columnExpressions)
+	sharedColumnsListing := strings.Join(selectColumns, ", ")
+	mappedSharedColumnsListing := strings.Join(ghostColumns, ", ")
+
+	uniqueKey = EscapeName(uniqueKey)
+	rangeStartComparison, rangeEndComparison, explodedArgs, err := buildRangeComparisons(uniqueKeyColumns, rangeStartValues, rangeEndValues, rangeStartArgs, rangeEndArgs, includeRangeStartValues)
 	if err != nil {
 		return "", explodedArgs, err
 	}
-	explodedArgs = append(explodedArgs, rangeExplodedArgs...)
+	transactionalClause := buildTransactionalClause(transactionalTable, noWait)
 	result = fmt.Sprintf(`
 		insert /* gh-ost %s.%s */ ignore
 		into
@@ -245,10 +325,73 @@ func BuildRangeInsertQuery(databaseName, originalTableName, ghostTableName strin
 	return result, explodedArgs, nil
 }
 
-func BuildRangeInsertPreparedQuery(databaseName, originalTableName, ghostTableName string, sharedColumns []string, mappedSharedColumns []string, uniqueKey string, uniqueKeyColumns *ColumnList, rangeStartArgs, rangeEndArgs []interface{}, includeRangeStartValues bool, transactionalTable bool, noWait bool) (result string, explodedArgs []interface{}, err error) {
+func BuildRangeInsertPreparedQuery(databaseName, originalTableName, ghostTableName string, sharedColumns []string, mappedSharedColumns []string, columnExpressions map[string]string, uniqueKey string, uniqueKeyColumns *ColumnList, rangeStartArgs, rangeEndArgs []interface{}, includeRangeStartValues bool, transactionalTable bool, noWait bool) (result string, explodedArgs []interface{}, err error) {
 	rangeStartValues := buildColumnsPreparedValues(uniqueKeyColumns)
 	rangeEndValues := buildColumnsPreparedValues(uniqueKeyColumns)
-	return BuildRangeInsertQuery(databaseName, originalTableName, ghostTableName, sharedColumns, mappedSharedColumns, uniqueKey, uniqueKeyColumns, rangeStartValues, rangeEndValues, rangeStartArgs, rangeEndArgs, includeRangeStartValues, transactionalTable, noWait)
+	return BuildRangeInsertQuery(databaseName, originalTableName, ghostTableName, sharedColumns, mappedSharedColumns, columnExpressions, uniqueKey, uniqueKeyColumns, rangeStartValues, rangeEndValues, rangeStartArgs, rangeEndArgs, includeRangeStartValues, transactionalTable, noWait)
+}
+
+// BuildRangeSelectPreparedQuery builds a query reading a range of rows from the original table, for them to be
+// written onto a ghost table on another server. Selected values come in the order of the returned ghost columns.
+func BuildRangeSelectPreparedQuery(databaseName, originalTableName string, sharedColumns []string, mappedSharedColumns []string, columnExpressions map[string]string, uniqueKey string, uniqueKeyColumns *ColumnList, rangeStartArgs, rangeEndArgs []interface{}, includeRangeStartValues bool, transactionalTable bool, noWait bool) (result string, ghostColumns []string, explodedArgs []interface{}, err error) {
+	if len(sharedColumns) == 0 {
+		return "", ghostColumns, explodedArgs, fmt.Errorf("Got 0 shared columns in BuildRangeSelectPreparedQuery")
+	}
+	databaseName = EscapeName(databaseName)
+	originalTableName = EscapeName(originalTableName)
+
+	selectColumns, ghostColumns := buildRangeSelectColumns(sharedColumns, mappedSharedColumns, columnExpressions)
+
+	uniqueKey = EscapeName(uniqueKey)
+	rangeStartValues := buildColumnsPreparedValues(uniqueKeyColumns)
+	rangeEndValues := buildColumnsPreparedValues(uniqueKeyColumns)
+	rangeStartComparison, rangeEndComparison, explodedArgs, err := buildRangeComparisons(uniqueKeyColumns, rangeStartValues, rangeEndValues, rangeStartArgs, rangeEndArgs, includeRangeStartValues)
+	if err != nil {
+		return "", ghostColumns, explodedArgs, err
+	}
+	transactionalClause := buildTransactionalClause(transactionalTable, noWait)
+	result = fmt.Sprintf(`
+		select /* gh-ost %s.%s */ %s
+		from
+			%s.%s
+		force index (%s)
+		where
+			(%s and %s)
+			%s`,
+		databaseName, originalTableName, strings.Join(selectColumns, ", "),
+		databaseName, originalTableName, uniqueKey,
+		rangeStartComparison, rangeEndComparison, transactionalClause)
+	return result, ghostColumns, explodedArgs, nil
+}
+
+// BuildMultiRowInsertPreparedQuery builds an `insert ignore` of given number of rows. The column names are
+// expected to be escaped, as returned by BuildRangeSelectPreparedQuery.
+func BuildMultiRowInsertPreparedQuery(databaseName, tableName string, columns []string, rowsCount int) (result string, err error) {
+	if len(columns) == 0 {
+		return "", fmt.Errorf("Got 0 columns in BuildMultiRowInsertPreparedQuery")
+	}
+	if rowsCount <= 0 {
+		return "", fmt.Errorf("Got %d rows in BuildMultiRowInsertPreparedQuery", rowsCount)
+	}
+	databaseName = EscapeName(databaseName)
+	tableName = EscapeName(tableName)
+
+	rowValues := fmt.Sprintf("(%s)", strings.Join(buildPreparedValues(len(columns)), ", "))
+	rowsValues := make([]string, rowsCount)
+	for i := range rowsValues {
+		rowsValues[i] = rowValues
+	}
+	result = fmt.Sprintf(`
+		insert /* gh-ost %s.%s */ ignore
+		into
+			%s.%s
+			(%s)
+		values
+			%s`,
+		databaseName, tableName,
+		databaseName, tableName, strings.Join(columns, ", "),
+		strings.Join(rowsValues, ", "))
+	return result, nil
 }
 
 func BuildUniqueKeyRangeEndPreparedQueryViaOffset(databaseName, tableName string, uniqueKeyColumns *ColumnList, rangeStartArgs, rangeEndArgs []interface{}, chunkSize int64, includeRangeStartValues bool, hint string) (result string, explodedArgs []interface{}, err error) {
@@ -500,10 +643,79 @@ func (b *DMLDeleteQueryBuilder) BuildQuery(args []interface{}) (string, []interf
 	return b.preparedStatement, uniqueKeyArgs, nil
 }
 
+// DMLSelectQueryBuilder can build SELECT queries which read the row a DML event refers to, by
+// the event's unique key values.
+// It holds the prepared query statement so it doesn't need to be recreated every time.
+type DMLSelectQueryBuilder struct {
+	tableColumns, uniqueKeyColumns *ColumnList
+	preparedStatement              string
+}
+
+// NewDMLSelectQueryBuilder creates a new DMLSelectQueryBuilder.
+// It prepares the SELECT query statement, which reads the given select columns.
+// Returns an error if no select columns or no unique key columns are given
+// or the prepared statement cannot be built.
+func NewDMLSelectQueryBuilder(databaseName, tableName string, tableColumns *ColumnList, selectColumns []string, uniqueKeyColumns *ColumnList) (*DMLSelectQueryBuilder, error) {
+	if len(selectColumns) == 0 {
+		return nil, fmt.Errorf("no select columns found in NewDMLSelectQueryBuilder")
+	}
+	if uniqueKeyColumns.Len() == 0 {
+		return nil, fmt.Errorf("no unique key columns found in NewDMLSelectQueryBuilder")
+	}
+	databaseName = EscapeName(databaseName)
+	tableName = EscapeName(tableName)
+	selectColumns = duplicateNames(selectColumns)
+	for i := range selectColumns {
+		selectColumns[i] = EscapeName(selectColumns[i])
+	}
+	equalsComparison, err := BuildEqualsPreparedComparison(uniqueKeyColumns.Names())
+	if err != nil {
+		return nil, err
+	}
+
+	stmt := fmt.Sprintf(`
+		select /* gh-ost %s.%s */
+			%s
+		from
+			%s.%s
+		where
+			%s`,
+		databaseName, tableName,
+		strings.Join(selectColumns, ", "),
+		databaseName, tableName,
+		equalsComparison,
+	)
+
+	b := &DMLSelectQueryBuilder{
+		tableColumns:      tableColumns,
+		uniqueKeyColumns:  uniqueKeyColumns,
+		preparedStatement: stmt,
+	}
+	return b, nil
+}
+
+// BuildQuery builds the arguments array for a DML event SELECT query.
+// It returns the query string and the unique key arguments array.
+// Returns an error if the number of arguments is not equal to the number of table columns.
+func (b *DMLSelectQueryBuilder) BuildQuery(args []interface{}) (string, []interface{}, error) {
+	if len(args) != b.tableColumns.Len() {
+		return "", nil, fmt.Errorf("args count differs from table column count in BuildDMLSelectQuery")
+	}
+	uniqueKeyArgs := make([]interface{}, 0, b.uniqueKeyColumns.Len())
+	for _, column := range b.uniqueKeyColumns.Columns() {
+		tableOrdinal := b.tableColumns.Ordinals[column.Name]
+		arg := column.convertArg(args[tableOrdinal], true)
+		uniqueKeyArgs = append(uniqueKeyArgs, arg)
+	}
+	return b.preparedStatement, uniqueKeyArgs, nil
+}
+
 // DMLInsertQueryBuilder can build INSERT queries for DML events.
 // It holds the prepared query statement so it doesn't need to be recreated every time.
 type DMLInsertQueryBuilder struct {
 	tableColumns, sharedColumns *ColumnList
+	expressionColumns           []bool
+	expressionOnlyColumnsCount  int
 	preparedStatement           string
 }
 
@@ -511,7 +723,7 @@ type DMLInsertQueryBuilder struct {
 // It prepares the INSERT query statement.
 // Returns an error if no shared columns are given, the shared columns are not a subset of the table columns,
 // or the prepared statement cannot be built.
-func NewDMLInsertQueryBuilder(databaseName, tableName string, tableColumns, sharedColumns, mappedSharedColumns *ColumnList) (*DMLInsertQueryBuilder, error) {
+func NewDMLInsertQueryBuilder(databaseName, tableName string, tableColumns, sharedColumns, mappedSharedColumns *ColumnList, columnExpressions map[string]string) (*DMLInsertQueryBuilder, error) {
 	if !sharedColumns.IsSubsetOf(tableColumns) {
 		return nil, fmt.Errorf("shared columns is not a subset of table columns in NewDMLInsertQueryBuilder")
 	}
@@ -520,11 +732,22 @@ func NewDMLInsertQueryBuilder(databaseName, tableName string, tableColumns, shar
 	}
 	databaseName = EscapeName(databaseName)
 	tableName = EscapeName(tableName)
-	mappedSharedColumnNames := duplicateNames(mappedSharedColumns.Names())
+	expressionOnlyColumns := getExpressionOnlyColumns(columnExpressions, mappedSharedColumns.Names())
+	mappedSharedColumnNames := append(duplicateNames(mappedSharedColumns.Names()), expressionOnlyColumns...)
 	for i := range mappedSharedColumnNames {
 		mappedSharedColumnNames[i] = EscapeName(mappedSharedColumnNames[i])
 	}
 	preparedValues := buildColumnsPreparedValues(mappedSharedColumns)
+	expressionColumns := make([]bool, len(preparedValues))
+	for i, column := range mappedSharedColumns.Columns() {
+		if expression, ok := getColumnExpression(columnExpressions, column.Name); ok {
+			preparedValues[i] = buildColumnExpressionToken(expression, tableColumns)
+			expressionColumns[i] = true
+		}
+	}
+	for _, expressionColumn := range expressionOnlyColumns {
+		preparedValues = append(preparedValues, buildColumnExpressionToken(columnExpressions[expressionColumn], tableColumns))
+	}
 
 	stmt := fmt.Sprintf(`
 		replace /* gh-ost %s.%s */
@@ -540,9 +763,11 @@ func NewDMLInsertQueryBuilder(databaseName, tableName string, tableColumns, shar
 	)
 
 	return &DMLInsertQueryBuilder{
-		tableColumns:      tableColumns,
-		sharedColumns:     sharedColumns,
-		preparedStatement: stmt,
+		tableColumns:               tableColumns,
+		sharedColumns:              sharedColumns,
+		expressionColumns:          expressionColumns,
+		expressionOnlyColumnsCount: len(expressionOnlyColumns),
+		preparedStatement:          stmt,
 	}, nil
 }
 
@@ -554,11 +779,18 @@ func (b *DMLInsertQueryBuilder) BuildQuery(args []interface{}) (string, []interf
 		return "", nil, fmt.Errorf("args count differs from table column count in BuildDMLInsertQuery")
 	}
 	sharedArgs := make([]interface{}, 0, b.sharedColumns.Len())
-	for _, column := range b.sharedColumns.Columns() {
+	for i, column := range b.sharedColumns.Columns() {
+		if b.expressionColumns[i] {
+			sharedArgs = append(sharedArgs, buildColumnExpressionArgs(b.tableColumns, args)...)
+			continue
+		}
 		tableOrdinal := b.tableColumns.Ordinals[column.Name]
 		arg := column.convertArg(args[tableOrdinal], false)
 		sharedArgs = append(sharedArgs, arg)
 	}
+	for i := 0; i < b.expressionOnlyColumnsCount; i++ {
+		sharedArgs = append(sharedArgs, buildColumnExpressionArgs(b.tableColumns, args)...)
+	}
 	return b.preparedStatement, sharedArgs, nil
 }
 
@@ -566,6 +798,8 @@ func (b *DMLInsertQueryBuilder) BuildQuery(args []interface{}) (string, []interf
 // It holds the prepared query statement so it doesn't need to be recreated every time.
 type DMLUpdateQueryBuilder struct {
 	tableColumns, sharedColumns, uniqueKeyColumns *ColumnList
+	expressionColumns                             []bool
+	expressionOnlyColumnsCount                    int
 	preparedStatement                             string
 }
 
@@ -573,7 +807,7 @@ type DMLUpdateQueryBuilder struct {
 // It prepares the UPDATE query statement.
 // Returns an error if no shared columns are given, the shared columns are not a subset of the table columns,
 // no unique key columns are given or the prepared statement cannot be built.
-func NewDMLUpdateQueryBuilder(databaseName, tableName string, tableColumns, sharedColumns, mappedSharedColumns, uniqueKeyColumns *ColumnList) (*DMLUpdateQueryBuilder, error) {
+func NewDMLUpdateQueryBuilder(databaseName, tableName string, tableColumns, sharedColumns, mappedSharedColumns *ColumnList, columnExpressions map[string]string, uniqueKeyColumns *ColumnList) (*DMLUpdateQueryBuilder, error) {
 	if !sharedColumns.IsSubsetOf(tableColumns) {
 		return nil, fmt.Errorf("shared columns is not a subset of table columns in NewDMLUpdateQueryBuilder")
 	}
@@ -595,6 +829,23 @@ func NewDMLUpdateQueryBuilder(databaseName, tableName string, tableColumns, shar
 	if err != nil {
 		return nil, err
 	}
+	expressionColumns := make([]bool, mappedSharedColumns.Len())
+	expressionOnlyColumns := getExpressionOnlyColumns(columnExpressions, mappedSharedColumns.Names())
+	if len(columnExpressions) > 0 {
+		setTokens := []string{}
+		for i, column := range mappedSharedColumns.Columns() {
+			if expression, ok := getColumnExpression(columnExpressions, column.Name); ok {
+				setTokens = append(setTokens, fmt.Sprintf("%s=%s", EscapeName(column.Name), buildColumnExpressionToken(expression, tableColumns)))
+				expressionColumns[i] = true
+			} else {
+				setTokens = append(setTokens, buildSetPreparedToken(column))
+			}
+		}
+		for _, expressionColumn := range expressionOnlyColumns {
+			setTokens = append(setTokens, fmt.Sprintf("%s=%s", EscapeName(expressionColumn), buildColumnExpressionToken(columnExpressions[expressionColumn], tableColumns)))
+		}
+		setClause = strings.Join(setTokens, ", ")
+	}
 
 	equalsComparison, err := BuildEqualsPreparedComparison(uniqueKeyColumns.Names())
 	if err != nil {
@@ -613,10 +864,12 @@ func NewDMLUpdateQueryBuilder(databaseName, tableName string, tableColumns, shar
 		equalsComparison,
 	)
 	return &DMLUpdateQueryBuilder{
-		tableColumns:      tableColumns,
-		sharedColumns:     sharedColumns,
-		uniqueKeyColumns:  uniqueKeyColumns,
-		preparedStatement: stmt,
+		tableColumns:               tableColumns,
+		sharedColumns:              sharedColumns,
+		uniqueKeyColumns:           uniqueKeyColumns,
+		expressionColumns:          expressionColumns,
+		expressionOnlyColumnsCount: len(expressionOnlyColumns),
+		preparedStatement:          stmt,
 	}, nil
 }
 
@@ -624,11 +877,18 @@ func NewDMLUpdateQueryBuilder(databaseName, tableName string, tableColumns, shar
 // It returns the query string, the shared arguments array, and the unique key arguments array.
 func (b *DMLUpdateQueryBuilder) BuildQuery(valueArgs, whereArgs []interface{}) (string, []interface{}, []interface{}, error) {
 	sharedArgs := make([]interface{}, 0, b.sharedColumns.Len())
-	for _, column := range b.sharedColumns.Columns() {
+	for i, column := range b.sharedColumns.Columns() {
+		if b.expressionColumns[i] {
+			sharedArgs = append(sharedArgs, buildColumnExpressionArgs(b.tableColumns, valueArgs)...)
+			continue
+		}
 		tableOrdinal := b.tableColumns.Ordinals[column.Name]
 		arg := column.convertArg(valueArgs[tableOrdinal], false)
 		sharedArgs = append(sharedArgs, arg)
 	}
+	for i := 0; i < b.expressionOnlyColumnsCount; i++ {
+		sharedArgs = append(sharedArgs, buildColumnExpressionArgs(b.tableColumns, valueArgs)...)
+	}
 
 	uniqueKeyArgs := make([]interface{}, 0, b.uniqueKeyColumns.Len())
 	for _, column := range b.uniqueKeyColumns.Columns() {
@@ -171,7 +171,7 @@ func TestBuildRangeInsertQuery(t *testing.T) {
 		rangeStartArgs := []interface{}{3}
 		rangeEndArgs := []interface{}{103}
 
-		query, explodedArgs, err := BuildRangeInsertQuery(databaseName, originalTableName, ghostTableName, sharedColumns, sharedColumns, uniqueKey, uniqueKeyColumns, rangeStartValues, rangeEndValues, rangeStartArgs, rangeEndArgs, true, true, true)
+		query, explodedArgs, err := BuildRangeInsertQuery(databaseName, originalTableName, ghostTableName, sharedColumns, sharedColumns, nil, uniqueKey, uniqueKeyColumns, rangeStartValues, rangeEndValues, rangeStartArgs, rangeEndArgs, true, true, true)
 		require.NoError(t, err)
 		expected := `
 			insert /* gh-ost mydb.tbl */ ignore
@@ -199,7 +199,7 @@ func TestBuildRangeInsertQuery(t *testing.T) {
 		rangeStartArgs := []interface{}{3, 17}
 		rangeEndArgs := []interface{}{103, 117}
 
-		query, explodedArgs, err := BuildRangeInsertQuery(databaseName, originalTableName, ghostTableName, sharedColumns, sharedColumns, uniqueKey, uniqueKeyColumns, rangeStartValues, rangeEndValues, rangeStartArgs, rangeEndArgs, true, true, true)
+		query, explodedArgs, err := BuildRangeInsertQuery(databaseName, originalTableName, ghostTableName, sharedColumns, sharedColumns, nil, uniqueKey, uniqueKeyColumns, rangeStartValues, rangeEndValues, rangeStartArgs, rangeEndArgs, true, true, true)
 		require.NoError(t, err)
 		expected := `
 			insert /* gh-ost mydb.tbl */ ignore
@@ -241,7 +241,7 @@ func TestBuildRangeInsertQueryRenameMap(t *testing.T) {
 		rangeStartArgs := []interface{}{3}
 		rangeEndArgs := []interface{}{103}
 
-		query, explodedArgs, err := BuildRangeInsertQuery(databaseName, originalTableName, ghostTableName, sharedColumns, mappedSharedColumns, uniqueKey, uniqueKeyColumns, rangeStartValues, rangeEndValues, rangeStartArgs, rangeEndArgs, true, true, true)
+		query, explodedArgs, err := BuildRangeInsertQuery(databaseName, originalTableName, ghostTableName, sharedColumns, mappedSharedColumns, nil, uniqueKey, uniqueKeyColumns, rangeStartValues, rangeEndValues, rangeStartArgs, rangeEndArgs, true, true, true)
 		require.NoError(t, err)
 		expected := `
 			insert /* gh-ost mydb.tbl */ ignore
@@ -270,7 +270,7 @@ func TestBuildRangeInsertQueryRenameMap(t *testing.T) {
 		rangeStartArgs := []interface{}{3, 17}
 		rangeEndArgs := []interface{}{103, 117}
 
-		query, explodedArgs, err := BuildRangeInsertQuery(databaseName, originalTableName, ghostTableName, sharedColumns, mappedSharedColumns, uniqueKey, uniqueKeyColumns, rangeStartValues, rangeEndValues, rangeStartArgs, rangeEndArgs, true, true, true)
+		query, explodedArgs, err := BuildRangeInsertQuery(databaseName, originalTableName, ghostTableName, sharedColumns, mappedSharedColumns, nil, uniqueKey, uniqueKeyColumns, rangeStartValues, rangeEndValues, rangeStartArgs, rangeEndArgs, true, true, true)
 		require.NoError(t, err)
 		expected := `
 			insert /* gh-ost mydb.tbl */ ignore
@@ -294,6 +294,43 @@ func TestBuildRangeInsertQueryRenameMap(t *testing.T) {
 	}
 }
 
+func TestBuildRangeInsertQueryColumnExpressions(t *testing.T) {
+	databaseName := "mydb"
+	originalTableName := "tbl"
+	ghostTableName := "ghost"
+	sharedColumns := []string{"id", "first", "last", "email"}
+	uniqueKey := "PRIMARY"
+	uniqueKeyColumns := NewColumnList([]string{"id"})
+	rangeStartValues := []string{"@v1s"}
+	rangeEndValues := []string{"@v1e"}
+	rangeStartArgs := []interface{}{3}
+	rangeEndArgs := []interface{}{103}
+	columnExpressions := map[string]string{
+		"full_name": "concat(first, ' ', last)",
+		"email":     "lower(email)",
+	}
+
+	query, explodedArgs, err := BuildRangeInsertQuery(databaseName, originalTableName, ghostTableName, sharedColumns, sharedColumns, columnExpressions, uniqueKey, uniqueKeyColumns, rangeStartValues, rangeEndValues, rangeStartArgs, rangeEndArgs, true, false, false)
+	require.NoError(t, err)
+	expected := `
+		insert /* gh-ost mydb.tbl */ ignore
+		into
+			mydb.ghost
+			(id, first, last, email, full_name)
+		(
+			select id, first, last, (lower(email)), (concat(first, ' ', last))
+			from
+				mydb.tbl
+			force index (PRIMARY)
+			where
+				(((id > @v1s) or ((id = @v1s)))
+				and
+				((id < @v1e) or ((id = @v1e))))
+		)`
+	require.Equal(t, normalizeQuery(expected), normalizeQuery(query))
+	require.Equal(t, []interface{}{3, 3, 103, 103}, explodedArgs)
+}
+
 func TestBuildRangeInsertPreparedQuery(t *testing.T) {
 	databaseName := "mydb"
 	originalTableName := "tbl"
@@ -305,7 +342,7 @@ func TestBuildRangeInsertPreparedQuery(t *testing.T) {
 		rangeStartArgs := []interface{}{3, 17}
 		rangeEndArgs := []interface{}{103, 117}
 
-		query, explodedArgs, err := BuildRangeInsertPreparedQuery(databaseName, originalTableName, ghostTableName, sharedColumns, sharedColumns, uniqueKey, uniqueKeyColumns, rangeStartArgs, rangeEndArgs, true, true, true)
+		query, explodedArgs, err := BuildRangeInsertPreparedQuery(databaseName, originalTableName, ghostTableName, sharedColumns, sharedColumns, nil, uniqueKey, uniqueKeyColumns, rangeStartArgs, rangeEndArgs, true, true, true)
 		require.NoError(t, err)
 		expected := `
 			insert /* gh-ost mydb.tbl */ ignore
@@ -325,6 +362,73 @@ func TestBuildRangeInsertPreparedQuery(t *testing.T) {
 	}
 }
 
+func TestBuildRangeSelectPreparedQuery(t *testing.T) {
+	databaseName := "mydb"
+	originalTableName := "tbl"
+	sharedColumns := []string{"id", "name", "position"}
+	mappedSharedColumns := []string{"id", "name", "location"}
+	uniqueKey := "PRIMARY"
+	uniqueKeyColumns := NewColumnList([]string{"id"})
+	rangeStartArgs := []interface{}{3}
+	rangeEndArgs := []interface{}{103}
+	{
+		query, ghostColumns, explodedArgs, err := BuildRangeSelectPreparedQuery(databaseName, originalTableName, sharedColumns, mappedSharedColumns, nil, uniqueKey, uniqueKeyColumns, rangeStartArgs, rangeEndArgs, false, true, false)
+		require.NoError(t, err)
+		expected := `
+			select /* gh-ost mydb.tbl */ id, name, position
+			from
+				mydb.tbl
+			force index (PRIMARY)
+			where
+				(((id > ?)) and ((id < ?) or ((id = ?))))
+				lock in share mode`
+		require.Equal(t, normalizeQuery(expected), normalizeQuery(query))
+		require.Equal(t, []string{"`id`", "`name`", "`location`"}, ghostColumns)
+		require.Equal(t, []interface{}{3, 103, 103}, explodedArgs)
+	}
+	{
+		columnExpressions := map[string]string{"name_length": "char_length(name)"}
+		query, ghostColumns, _, err := BuildRangeSelectPreparedQuery(databaseName, originalTableName, sharedColumns, mappedSharedColumns, columnExpressions, uniqueKey, uniqueKeyColumns, rangeStartArgs, rangeEndArgs, true, false, false)
+		require.NoError(t, err)
+		expected := `
+			select /* gh-ost mydb.tbl */ id, name, position, (char_length(name))
+			from
+				mydb.tbl
+			force index (PRIMARY)
+			where
+				(((id > ?) or ((id = ?))) and ((id < ?) or ((id = ?))))`
+		require.Equal(t, normalizeQuery(expected), normalizeQuery(query))
+		require.Equal(t, []string{"`id`", "`name`", "`location`", "`name_length`"}, ghostColumns)
+	}
+	{
+		_, _, _, err := BuildRangeSelectPreparedQuery(databaseName, originalTableName, []string{}, []string{}, nil, uniqueKey, uniqueKeyColumns, rangeStartArgs, rangeEndArgs, true, false, false)
+		require.Error(t, err)
+	}
+}
+
+func TestBuildMultiRowInsertPreparedQuery(t *testing.T) {
+	{
+		query, err := BuildMultiRowInsertPreparedQuery("mydb", "ghost", []string{"`id`", "`name`"}, 3)
+		require.NoError(t, err)
+		expected := `
+			insert /* gh-ost mydb.ghost */ ignore
+			into
+				mydb.ghost
+				(id, name)
+			values
+				(?, ?), (?, ?), (?, ?)`
+		require.Equal(t, normalizeQuery(expected), normalizeQuery(query))
+	}
+	{
+		_, err := BuildMultiRowInsertPreparedQuery("mydb", "ghost", []string{}, 3)
+		require.Error(t, err)
+	}
+	{
+		_, err := BuildMultiRowInsertPreparedQuery("mydb", "ghost", []string{"`id`"}, 0)
+		require.Error(t, err)
+	}
+}
+
 func TestBuildUniqueKeyRangeEndPreparedQuery(t *testing.T) {
 	databaseName := "mydb"
 	originalTableName := "tbl"
@@ -469,6 +573,47 @@ func TestBuildDMLDeleteQuery(t *testing.T) {
 	}
 }
 
+func TestBuildDMLSelectQuery(t *testing.T) {
+	databaseName := "mydb"
+	tableName := "tbl"
+	tableColumns := NewColumnList([]string{"id", "name", "rank", "position", "age"})
+	args := []interface{}{3, "testname", "first", 17, 23}
+	{
+		uniqueKeyColumns := NewColumnList([]string{"name", "position"})
+		builder, err := NewDMLSelectQueryBuilder(databaseName, tableName, tableColumns, []string{"id", "name", "age"}, uniqueKeyColumns)
+		require.NoError(t, err)
+
+		query, uniqueKeyArgs, err := builder.BuildQuery(args)
+		require.NoError(t, err)
+		expected := `
+			select /* gh-ost mydb.tbl */
+					id, name, age
+				from
+					mydb.tbl
+				where
+					((name = ?) and (position = ?))
+		`
+		require.Equal(t, normalizeQuery(expected), normalizeQuery(query))
+		require.Equal(t, []interface{}{"testname", 17}, uniqueKeyArgs)
+	}
+	{
+		uniqueKeyColumns := NewColumnList([]string{"id"})
+		builder, err := NewDMLSelectQueryBuilder(databaseName, tableName, tableColumns, []string{"id"}, uniqueKeyColumns)
+		require.NoError(t, err)
+
+		_, _, err = builder.BuildQuery([]interface{}{3})
+		require.Error(t, err)
+	}
+	{
+		_, err := NewDMLSelectQueryBuilder(databaseName, tableName, tableColumns, []string{}, NewColumnList([]string{"id"}))
+		require.Error(t, err)
+	}
+	{
+		_, err := NewDMLSelectQueryBuilder(databaseName, tableName, tableColumns, []string{"id"}, NewColumnList([]string{}))
+		require.Error(t, err)
+	}
+}
+
 func TestBuildDMLDeleteQuerySignedUnsigned(t *testing.T) {
 	databaseName := "mydb"
 	tableName := "tbl"
@@ -516,7 +661,7 @@ func TestBuildDMLInsertQuery(t *testing.T) {
 	args := []interface{}{3, "testname", "first", 17, 23}
 	{
 		sharedColumns := NewColumnList([]string{"id", "name", "position", "age"})
-		builder, err := NewDMLInsertQueryBuilder(databaseName, tableName, tableColumns, sharedColumns, sharedColumns)
+		builder, err := NewDMLInsertQueryBuilder(databaseName, tableName, tableColumns, sharedColumns, sharedColumns, nil)
 		require.NoError(t, err)
 		query, sharedArgs, err := builder.BuildQuery(args)
 		require.NoError(t, err)
@@ -532,7 +677,7 @@ func TestBuildDMLInsertQuery(t *testing.T) {
 	}
 	{
 		sharedColumns := NewColumnList([]string{"position", "name", "age", "id"})
-		builder, err := NewDMLInsertQueryBuilder(databaseName, tableName, tableColumns, sharedColumns, sharedColumns)
+		builder, err := NewDMLInsertQueryBuilder(databaseName, tableName, tableColumns, sharedColumns, sharedColumns, nil)
 		require.NoError(t, err)
 		query, sharedArgs, err := builder.BuildQuery(args)
 		require.NoError(t, err)
@@ -548,12 +693,12 @@ func TestBuildDMLInsertQuery(t *testing.T) {
 	}
 	{
 		sharedColumns := NewColumnList([]string{"position", "name", "surprise", "id"})
-		_, err := NewDMLInsertQueryBuilder(databaseName, tableName, tableColumns, sharedColumns, sharedColumns)
+		_, err := NewDMLInsertQueryBuilder(databaseName, tableName, tableColumns, sharedColumns, sharedColumns, nil)
 		require.Error(t, err)
 	}
 	{
 		sharedColumns := NewColumnList([]string{})
-		_, err := NewDMLInsertQueryBuilder(databaseName, tableName, tableColumns, sharedColumns, sharedColumns)
+		_, err := NewDMLInsertQueryBuilder(databaseName, tableName, tableColumns, sharedColumns, sharedColumns, nil)
 		require.Error(t, err)
 	}
 }
@@ -567,7 +712,7 @@ func TestBuildDMLInsertQuerySignedUnsigned(t *testing.T) {
 		// testing signed
 		args := []interface{}{3, "testname", "first", int8(-1), 23}
 		sharedColumns := NewColumnList([]string{"id", "name", "position", "age"})
-		builder, err := NewDMLInsertQueryBuilder(databaseName, tableName, tableColumns, sharedColumns, sharedColumns)
+		builder, err := NewDMLInsertQueryBuilder(databaseName, tableName, tableColumns, sharedColumns, sharedColumns, nil)
 		require.NoError(t, err)
 		query, sharedArgs, err := builder.BuildQuery(args)
 		require.NoError(t, err)
@@ -585,7 +730,7 @@ func TestBuildDMLInsertQuerySignedUnsigned(t *testing.T) {
 		// testing unsigned
 		args := []interface{}{3, "testname", "first", int8(-1), 23}
 		sharedColumns.SetUnsigned("position")
-		builder, err := NewDMLInsertQueryBuilder(databaseName, tableName, tableColumns, sharedColumns, sharedColumns)
+		builder, err := NewDMLInsertQueryBuilder(databaseName, tableName, tableColumns, sharedColumns, sharedColumns, nil)
 		require.NoError(t, err)
 		query, sharedArgs, err := builder.BuildQuery(args)
 		require.NoError(t, err)
@@ -603,7 +748,7 @@ func TestBuildDMLInsertQuerySignedUnsigned(t *testing.T) {
 		// testing unsigned
 		args := []interface{}{3, "testname", "first", int32(-1), 23}
 		sharedColumns.SetUnsigned("position")
-		builder, err := NewDMLInsertQueryBuilder(databaseName, tableName, tableColumns, sharedColumns, sharedColumns)
+		builder, err := NewDMLInsertQueryBuilder(databaseName, tableName, tableColumns, sharedColumns, sharedColumns, nil)
 		require.NoError(t, err)
 		query, sharedArgs, err := builder.BuildQuery(args)
 		require.NoError(t, err)
@@ -619,6 +764,33 @@ func TestBuildDMLInsertQuerySignedUnsigned(t *testing.T) {
 	}
 }
 
+func TestBuildDMLInsertQueryColumnExpressions(t *testing.T) {
+	databaseName := "mydb"
+	tableName := "tbl"
+	tableColumns := NewColumnList([]string{"id", "first", "last", "email"})
+	sharedColumns := NewColumnList([]string{"id", "first", "last", "email"})
+	columnExpressions := map[string]string{
+		"full_name": "concat(first, ' ', last)",
+		"EMAIL":     "lower(email)",
+	}
+	args := []interface{}{3, "John", "Doe", "JD@Example.com"}
+	builder, err := NewDMLInsertQueryBuilder(databaseName, tableName, tableColumns, sharedColumns, sharedColumns, columnExpressions)
+	require.NoError(t, err)
+	query, sharedArgs, err := builder.BuildQuery(args)
+	require.NoError(t, err)
+	expected := `
+		replace /* gh-ost mydb.tbl */
+			into mydb.tbl
+				(id, first, last, email, full_name)
+			values
+				(?, ?, ?,
+				(select (lower(email)) from (select ? as id, ? as first, ? as last, ? as email) as _gho_row),
+				(select (concat(first, ' ', last)) from (select ? as id, ? as first, ? as last, ? as email) as _gho_row))
+	`
+	require.Equal(t, normalizeQuery(expected), normalizeQuery(query))
+	require.Equal(t, []interface{}{3, "John", "Doe", 3, "John", "Doe", "JD@Example.com", 3, "John", "Doe", "JD@Example.com"}, sharedArgs)
+}
+
 func TestBuildDMLUpdateQuery(t *testing.T) {
 	databaseName := "mydb"
 	tableName := "tbl"
@@ -628,7 +800,7 @@ func TestBuildDMLUpdateQuery(t *testing.T) {
 	{
 		sharedColumns := NewColumnList([]string{"id", "name", "position", "age"})
 		uniqueKeyColumns := NewColumnList([]string{"position"})
-		builder, err := NewDMLUpdateQueryBuilder(databaseName, tableName, tableColumns, sharedColumns, sharedColumns, uniqueKeyColumns)
+		builder, err := NewDMLUpdateQueryBuilder(databaseName, tableName, tableColumns, sharedColumns, sharedColumns, nil, uniqueKeyColumns)
 		require.NoError(t, err)
 		query, sharedArgs, uniqueKeyArgs, err := builder.BuildQuery(valueArgs, whereArgs)
 		require.NoError(t, err)
@@ -646,7 +818,7 @@ func TestBuildDMLUpdateQuery(t *testing.T) {
 	{
 		sharedColumns := NewColumnList([]string{"id", "name", "position", "age"})
 		uniqueKeyColumns := NewColumnList([]string{"position", "name"})
-		builder, err := NewDMLUpdateQueryBuilder(databaseName, tableName, tableColumns, sharedColumns, sharedColumns, uniqueKeyColumns)
+		builder, err := NewDMLUpdateQueryBuilder(databaseName, tableName, tableColumns, sharedColumns, sharedColumns, nil, uniqueKeyColumns)
 		require.NoError(t, err)
 		query, sharedArgs, uniqueKeyArgs, err := builder.BuildQuery(valueArgs, whereArgs)
 		require.NoError(t, err)
@@ -664,7 +836,7 @@ func TestBuildDMLUpdateQuery(t *testing.T) {
 	{
 		sharedColumns := NewColumnList([]string{"id", "name", "position", "age"})
 		uniqueKeyColumns := NewColumnList([]string{"age"})
-		builder, err := NewDMLUpdateQueryBuilder(databaseName, tableName, tableColumns, sharedColumns, sharedColumns, uniqueKeyColumns)
+		builder, err := NewDMLUpdateQueryBuilder(databaseName, tableName, tableColumns, sharedColumns, sharedColumns, nil, uniqueKeyColumns)
 		require.NoError(t, err)
 		query, sharedArgs, uniqueKeyArgs, err := builder.BuildQuery(valueArgs, whereArgs)
 		require.NoError(t, err)
@@ -682,7 +854,7 @@ func TestBuildDMLUpdateQuery(t *testing.T) {
 	{
 		sharedColumns := NewColumnList([]string{"id", "name", "position", "age"})
 		uniqueKeyColumns := NewColumnList([]string{"age", "position", "id", "name"})
-		builder, err := NewDMLUpdateQueryBuilder(databaseName, tableName, tableColumns, sharedColumns, sharedColumns, uniqueKeyColumns)
+		builder, err := NewDMLUpdateQueryBuilder(databaseName, tableName, tableColumns, sharedColumns, sharedColumns, nil, uniqueKeyColumns)
 		require.NoError(t, err)
 		query, sharedArgs, uniqueKeyArgs, err := builder.BuildQuery(valueArgs, whereArgs)
 		require.NoError(t, err)
@@ -700,20 +872,20 @@ func TestBuildDMLUpdateQuery(t *testing.T) {
 	{
 		sharedColumns := NewColumnList([]string{"id", "name", "position", "age"})
 		uniqueKeyColumns := NewColumnList([]string{"age", "surprise"})
-		_, err := NewDMLUpdateQueryBuilder(databaseName, tableName, tableColumns, sharedColumns, sharedColumns, uniqueKeyColumns)
+		_, err := NewDMLUpdateQueryBuilder(databaseName, tableName, tableColumns, sharedColumns, sharedColumns, nil, uniqueKeyColumns)
 		require.Error(t, err)
 	}
 	{
 		sharedColumns := NewColumnList([]string{"id", "name", "position", "age"})
 		uniqueKeyColumns := NewColumnList([]string{})
-		_, err := NewDMLUpdateQueryBuilder(databaseName, tableName, tableColumns, sharedColumns, sharedColumns, uniqueKeyColumns)
+		_, err := NewDMLUpdateQueryBuilder(databaseName, tableName, tableColumns, sharedColumns, sharedColumns, nil, uniqueKeyColumns)
 		require.Error(t, err)
 	}
 	{
 		sharedColumns := NewColumnList([]string{"id", "name", "position", "age"})
 		mappedColumns := NewColumnList([]string{"id", "name", "role", "age"})
 		uniqueKeyColumns := NewColumnList([]string{"id"})
-		builder, err := NewDMLUpdateQueryBuilder(databaseName, tableName, tableColumns, sharedColumns, mappedColumns, uniqueKeyColumns)
+		builder, err := NewDMLUpdateQueryBuilder(databaseName, tableName, tableColumns, sharedColumns, mappedColumns, nil, uniqueKeyColumns)
 		require.NoError(t, err)
 		query, sharedArgs, uniqueKeyArgs, err := builder.BuildQuery(valueArgs, whereArgs)
 		require.NoError(t, err)
@@ -730,6 +902,34 @@ func TestBuildDMLUpdateQuery(t *testing.T) {
 	}
 }
 
+func TestBuildDMLUpdateQueryColumnExpressions(t *testing.T) {
+	databaseName := "mydb"
+	tableName := "tbl"
+	tableColumns := NewColumnList([]string{"id", "first", "last"})
+	sharedColumns := NewColumnList([]string{"id", "first", "last"})
+	uniqueKeyColumns := NewColumnList([]string{"id"})
+	columnExpressions := map[string]string{
+		"full_name": "concat(first, ' ', last)",
+	}
+	valueArgs := []interface{}{3, "John", "Doe"}
+	whereArgs := []interface{}{3, "Jon", "Doe"}
+	builder, err := NewDMLUpdateQueryBuilder(databaseName, tableName, tableColumns, sharedColumns, sharedColumns, columnExpressions, uniqueKeyColumns)
+	require.NoError(t, err)
+	query, sharedArgs, uniqueKeyArgs, err := builder.BuildQuery(valueArgs, whereArgs)
+	require.NoError(t, err)
+	expected := `
+		update /* gh-ost mydb.tbl */
+			mydb.tbl
+				set id=?, first=?, last=?,
+				full_name=(select (concat(first, ' ', last)) from (select ? as id, ? as first, ? as last) as _gho_row)
+			where
+				((id = ?))
+	`
+	require.Equal(t, normalizeQuery(expected), normalizeQuery(query))
+	require.Equal(t, []interface{}{3, "John", "Doe", 3, "John", "Doe"}, sharedArgs)
+	require.Equal(t, []interface{}{3}, uniqueKeyArgs)
+}
+
 func TestBuildDMLUpdateQuerySignedUnsigned(t *testing.T) {
 	databaseName := "mydb"
 	tableName := "tbl"
@@ -738,7 +938,7 @@ func TestBuildDMLUpdateQuerySignedUnsigned(t *testing.T) {
 	whereArgs := []interface{}{3, "testname", "findme", int8(-3), 56}
 	sharedColumns := NewColumnList([]string{"id", "name", "position", "age"})
 	uniqueKeyColumns := NewColumnList([]string{"position"})
-	builder, err := NewDMLUpdateQueryBuilder(databaseName, tableName, tableColumns, sharedColumns, sharedColumns, uniqueKeyColumns)
+	builder, err := NewDMLUpdateQueryBuilder(databaseName, tableName, tableColumns, sharedColumns, sharedColumns, nil, uniqueKeyColumns)
 	require.NoError(t, err)
 	{
 		// test signed
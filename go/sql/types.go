@@ -44,6 +44,7 @@ type Column struct {
 	Charset              string
 	Type                 ColumnType
 	EnumValues           string
+	MySQLType            string // the full column type, e.g. "decimal(10,2) unsigned"
 	timezoneConversion   *TimezoneConversion
 	enumToTextConversion bool
 	// add Octet length for binary type, fix bytes with suffix "00" get clipped in mysql binlog.
@@ -106,6 +107,19 @@ func (this *Column) convertArg(arg interface{}, isUniqueKeyColumn bool) interfac
 	return arg
 }
 
+// ConvertToText converts a value, as read from the binary log, onto a value suited for text based
+// representations such as JSON: character strings are decoded from the column's character set,
+// and unsigned integers are read as such.
+func (this *Column) ConvertToText(arg interface{}) interface{} {
+	if s, ok := arg.(string); ok {
+		if encoding, ok := charsetEncodingMap[this.Charset]; ok {
+			arg, _ = encoding.NewDecoder().String(s)
+		}
+		return arg
+	}
+	return this.convertArg(arg, false)
+}
+
 func NewColumns(names []string) []Column {
 	result := make([]Column, len(names))
 	for i := range names {
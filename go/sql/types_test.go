@@ -40,3 +40,24 @@ func TestGetColumn(t *testing.T) {
 		require.Nil(t, column)
 	}
 }
+
+func TestConvertToText(t *testing.T) {
+	{
+		column := Column{Name: "c", Charset: "utf8mb4"}
+		require.Equal(t, "héllo", column.ConvertToText("héllo"))
+	}
+	{
+		column := Column{Name: "c", Charset: "latin1"}
+		require.Equal(t, "é", column.ConvertToText(string([]byte{0xe9})))
+	}
+	{
+		column := Column{Name: "c", IsUnsigned: true}
+		require.Equal(t, uint8(255), column.ConvertToText(int8(-1)))
+		require.Equal(t, "18446744073709551615", column.ConvertToText(int64(-1)))
+	}
+	{
+		column := Column{Name: "c"}
+		require.Equal(t, int32(-1), column.ConvertToText(int32(-1)))
+		require.Nil(t, column.ConvertToText(nil))
+	}
+}
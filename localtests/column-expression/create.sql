@@ -0,0 +1,26 @@
+drop table if exists gh_ost_test;
+create table gh_ost_test (
+  id int auto_increment,
+  first_name varchar(32) not null,
+  last_name varchar(32) not null,
+  email varchar(128) not null,
+  primary key (id)
+) auto_increment=1;
+
+insert into gh_ost_test values (null, 'John', 'Doe', 'John.Doe@Example.com');
+insert into gh_ost_test values (null, 'Jane', 'Roe', 'JANE@example.com');
+
+drop event if exists gh_ost_test;
+delimiter ;;
+create event gh_ost_test
+  on schedule every 1 second
+  starts current_timestamp
+  ends current_timestamp + interval 60 second
+  on completion not preserve
+  enable
+  do
+begin
+  insert into gh_ost_test values (null, 'Max', 'Mustermann', 'Max@Example.COM');
+  update gh_ost_test set last_name = 'Smith' where id = 1;
+  update gh_ost_test set email = concat('Upper.', email) where id = 2;
+end ;;
@@ -0,0 +1,35 @@
+drop table if exists gh_ost_test;
+create table gh_ost_test (
+  id int auto_increment,
+  i int not null,
+  color varchar(32),
+  ts timestamp default current_timestamp,
+  dt datetime,
+  primary key(id),
+  unique key i_color_uidx(i, color)
+) auto_increment=1;
+
+drop event if exists gh_ost_test;
+delimiter ;;
+create event gh_ost_test
+  on schedule every 1 second
+  starts current_timestamp
+  ends current_timestamp + interval 60 second
+  on completion not preserve
+  enable
+  do
+begin
+  -- A burst of writes every second keeps DML events pending as the tables are locked. These are
+  -- then applied by the very session holding the locks, on its own time_zone and sql_mode.
+  set session time_zone='+05:00';
+  insert into gh_ost_test (i, color, ts, dt) values
+    (unix_timestamp() * 10 + 1, 'red', now(), now()),
+    (unix_timestamp() * 10 + 2, 'green', now(), now()),
+    (unix_timestamp() * 10 + 3, 'blue', now(), now());
+  set session time_zone='-03:00';
+  update gh_ost_test set ts = now(), dt = now(), color = concat(color, '+') where id % 7 = second(now()) % 7 and length(color) < 30;
+  set session time_zone='+00:00';
+  delete from gh_ost_test where id % 13 = second(now()) % 13 order by id limit 2;
+  insert into gh_ost_test (i, color, ts, dt) values (unix_timestamp() * 10 + 2, 'green', null, null)
+    on duplicate key update color = 'orange', dt = null;
+end ;;
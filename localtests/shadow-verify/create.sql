@@ -0,0 +1,27 @@
+drop table if exists gh_ost_test;
+create table gh_ost_test (
+  id int auto_increment,
+  t varchar(128) charset latin1 collate latin1_swedish_ci,
+  e enum('red', 'green', 'blue') not null default 'red',
+  ts timestamp default current_timestamp,
+  primary key(id)
+) auto_increment=1 charset latin1 collate latin1_swedish_ci;
+
+insert into gh_ost_test values (null, md5(rand()), 'green', now());
+insert into gh_ost_test values (null, 'átesting', 'blue', now());
+
+drop event if exists gh_ost_test;
+delimiter ;;
+create event gh_ost_test
+  on schedule every 1 second
+  starts current_timestamp
+  ends current_timestamp + interval 60 second
+  on completion not preserve
+  enable
+  do
+begin
+  insert into gh_ost_test values (null, md5(rand()), 'green', now());
+  insert into gh_ost_test values (null, 'átesting-ins', 'blue', now());
+  update gh_ost_test set t = concat(t, 'ñ'), e = 'red', ts = now() where id = 2;
+  delete from gh_ost_test where id = 1;
+end ;;